@@ -5,42 +5,71 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/onosproject/onos-lib-go/pkg/certs"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-o1t/pkg/config"
 	"github.com/onosproject/onos-o1t/pkg/manager"
 )
 
 var log = logging.GetLogger()
 
+// version is the version of onos-o1t, set when it is built
+var version = "dev"
+
 func main() {
 	caPath := flag.String("caPath", "", "path to CA certificate")
 	keyPath := flag.String("keyPath", "", "path to client private key")
 	certPath := flag.String("certPath", "", "path to client certificate")
-	configPath := flag.String("configPath", "/etc/onos/config/config.json", "path to config.json file")
+	configPath := flag.String("configPath", "", "path to config.json file, "+config.DefaultPath+" if it exists by default")
 	grpcPort := flag.Int("grpcPort", 5150, "grpc Port number")
-	gnmiEndpoint := flag.String("gnmiEndpoint", "onos-config:5150", "address of onos-config")
-	netconfPort := flag.Int("baseURL", 8300, "base port for NBI of O1T Netconf SSH server")
-
-	ready := make(chan bool)
+	gnmiEndpoint := flag.String("gnmiEndpoint", config.DefaultGnmiEndpoint, "address of onos-config, deprecated by southbound.gnmiEndpoint")
+	netconfPort := flag.Int("baseURL", config.DefaultNetconfPort, "port of the first listener of the O1T Netconf SSH server, deprecated by listeners")
 
 	flag.Parse()
 
-	_, err := certs.HandleCertPaths(*caPath, *keyPath, *certPath, true)
-	if err != nil {
-		log.Fatal(err)
+	// the legacy flags set on the command line override the settings of the configuration file
+	overrides := map[string]func(*config.Config){
+		"gnmiEndpoint": func(c *config.Config) { c.Southbound.GnmiEndpoint = *gnmiEndpoint },
+		"baseURL": func(c *config.Config) {
+			if len(c.Listeners) == 0 {
+				c.Listeners = append(c.Listeners, config.Listener{})
+			}
+			c.Listeners[0].Port = *netconfPort
+			// another listener of the same port, such as the default one of port 830, is dropped
+			listeners := c.Listeners[:1]
+			for _, listener := range c.Listeners[1:] {
+				if listener.FD != 0 || listener.Address != c.Listeners[0].Address || listener.Port != *netconfPort {
+					listeners = append(listeners, listener)
+				}
+			}
+			c.Listeners = listeners
+		},
 	}
+	setFlags := []string{}
+	flag.Visit(func(f *flag.Flag) {
+		if _, ok := overrides[f.Name]; ok {
+			setFlags = append(setFlags, f.Name)
+		}
+	})
 
 	cfg := manager.Config{
-		CAPath:       *caPath,
-		KeyPath:      *keyPath,
-		CertPath:     *certPath,
-		GRPCPort:     *grpcPort,
-		ConfigPath:   *configPath,
-		NetconfPort:  *netconfPort,
-		GnmiEndpoint: *gnmiEndpoint,
+		CAPath:     *caPath,
+		KeyPath:    *keyPath,
+		CertPath:   *certPath,
+		GRPCPort:   *grpcPort,
+		ConfigPath: *configPath,
+		Version:    version,
+		Overrides: func(c *config.Config) {
+			for _, name := range setFlags {
+				overrides[name](c)
+			}
+		},
 	}
 
 	opts, err := certs.HandleCertPaths(*caPath, *keyPath, *certPath, true)
@@ -55,6 +84,21 @@ func main() {
 		log.Fatal(err)
 	}
 
-	mgr.Run()
-	<-ready
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Info("Received SIGHUP, reloading the configuration")
+			_ = mgr.Reload()
+		}
+	}()
+
+	err = mgr.Run(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Info("Stopped onos-o1t")
 }
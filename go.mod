@@ -3,7 +3,6 @@ module github.com/onosproject/onos-o1t
 go 1.16
 
 require (
-	github.com/basgys/goxml2json v1.1.1-0.20181031222924-996d9fc8d313
 	github.com/google/gnxi v0.0.0-20220519184815-4f29cc8bd253
 	github.com/google/uuid v1.3.0
 	github.com/json-iterator/go v1.1.12 // indirect
@@ -16,9 +15,17 @@ require (
 	github.com/onosproject/onos-ric-sdk-go v0.8.9
 	github.com/onosproject/onos-test v0.6.6
 	github.com/openconfig/gnmi v0.0.0-20220503232738-6eb133c65a13
+	github.com/openconfig/goyang v1.0.0
 	github.com/openshift-telco/go-netconf-client v0.0.0-20211201160131-f3f08f0df531
+	github.com/prometheus/client_golang v1.11.0
 	github.com/rogpeppe/go-internal v1.8.0 // indirect
 	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.27.0
+	go.opentelemetry.io/otel v1.2.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.2.0
+	go.opentelemetry.io/otel/sdk v1.2.0
+	go.opentelemetry.io/otel/trace v1.2.0
 	golang.org/x/crypto v0.0.0-20220331220935-ae2d96664a29
 	google.golang.org/grpc v1.46.0
+	google.golang.org/protobuf v1.28.0
 )
@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("audit")
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	// redacted replaces the values of the redacted leaves
+	redacted = "***"
+)
+
+// Record is the audit record of a NETCONF rpc
+type Record struct {
+	Timestamp     time.Time         `json:"timestamp"`
+	User          string            `json:"user"`
+	SourceAddress string            `json:"source-address"`
+	Transport     string            `json:"transport,omitempty"`
+	SessionID     string            `json:"session-id"`
+	MessageID     string            `json:"message-id,omitempty"`
+	Operation     string            `json:"operation"`
+	Targets       []string          `json:"targets,omitempty"`
+	Namespace     string            `json:"namespace,omitempty"`
+	Paths         []string          `json:"paths,omitempty"`
+	Values        map[string]string `json:"values,omitempty"`
+	Outcome       string            `json:"outcome"`
+	ErrorTag      string            `json:"error-tag,omitempty"`
+	Duration      float64           `json:"duration-ms"`
+}
+
+// SetDuration sets the duration of the rpc in milliseconds
+func (r *Record) SetDuration(duration time.Duration) {
+	r.Duration = float64(duration.Microseconds()) / 1000
+}
+
+// SyslogConfig configures the syslog sink of the audit trail
+type SyslogConfig struct {
+	// Network is either udp or tcp, empty disables the syslog sink
+	Network  string
+	Address  string
+	Facility int
+	AppName  string
+}
+
+// Config configures the audit trail
+type Config struct {
+	// File is the path of the audit log file, empty disables the file sink
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+	Syslog     SyslogConfig
+	// Redact lists the names of the leaves whose values are not written in the audit trail
+	Redact []string
+}
+
+// Auditor records the audit trail of NETCONF rpcs
+type Auditor interface {
+	// Log appends the record to the audit trail
+	Log(record Record)
+
+	// Close closes the sinks of the audit trail
+	Close() error
+}
+
+type sink interface {
+	write(record Record, line []byte) error
+	close() error
+}
+
+type auditor struct {
+	mu     sync.Mutex
+	sinks  []sink
+	redact map[string]bool
+}
+
+// NewAuditor creates an auditor writing to the sinks enabled in the configuration
+func NewAuditor(config Config) (Auditor, error) {
+	a := &auditor{
+		redact: make(map[string]bool),
+	}
+	for _, leaf := range config.Redact {
+		a.redact[leaf] = true
+	}
+
+	if config.File != "" {
+		file, err := newFileSink(config.File, config.MaxSizeMB, config.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		a.sinks = append(a.sinks, file)
+		log.Infof("Writing audit trail to %s", config.File)
+	}
+
+	if config.Syslog.Network != "" {
+		syslog, err := newSyslogSink(config.Syslog)
+		if err != nil {
+			_ = a.Close()
+			return nil, err
+		}
+		a.sinks = append(a.sinks, syslog)
+		log.Infof("Sending audit trail to syslog %s://%s", config.Syslog.Network, config.Syslog.Address)
+	}
+
+	return a, nil
+}
+
+func (a *auditor) Log(record Record) {
+	a.redactRecord(&record)
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, s := range a.sinks {
+		err = s.write(record, line)
+		if err != nil {
+			log.Warnf("Error writing audit record: %v", err)
+		}
+	}
+}
+
+func (a *auditor) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var err error
+	for _, s := range a.sinks {
+		closeErr := s.close()
+		if closeErr != nil {
+			err = closeErr
+		}
+	}
+	a.sinks = nil
+	return err
+}
+
+// redactRecord replaces the values of the redacted leaves, either addressed by the path
+// of the value or nested in a JSON value
+func (a *auditor) redactRecord(record *Record) {
+	if len(a.redact) == 0 || len(record.Values) == 0 {
+		return
+	}
+
+	values := make(map[string]string, len(record.Values))
+	for path, value := range record.Values {
+		if a.isRedacted(lastElem(path)) {
+			values[path] = redacted
+			continue
+		}
+
+		var tree interface{}
+		if json.Unmarshal([]byte(value), &tree) != nil {
+			values[path] = value
+			continue
+		}
+		redactedValue, err := json.Marshal(a.redactTree(tree))
+		if err != nil {
+			values[path] = redacted
+			continue
+		}
+		values[path] = string(redactedValue)
+	}
+	record.Values = values
+}
+
+func (a *auditor) redactTree(tree interface{}) interface{} {
+	switch node := tree.(type) {
+	case map[string]interface{}:
+		for name, child := range node {
+			if a.isRedacted(name) {
+				node[name] = redacted
+				continue
+			}
+			node[name] = a.redactTree(child)
+		}
+	case []interface{}:
+		for i, child := range node {
+			node[i] = a.redactTree(child)
+		}
+	}
+	return tree
+}
+
+// isRedacted checks the name of a leaf, ignoring its module prefix
+func (a *auditor) isRedacted(name string) bool {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	return a.redact[name]
+}
+
+// lastElem returns the name of the last element of a path, without its keys
+func lastElem(path string) string {
+	start, depth := 0, 0
+	for i, c := range path {
+		switch {
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case c == '/' && depth == 0:
+			start = i + 1
+		}
+	}
+	elem := path[start:]
+	if i := strings.Index(elem, "["); i >= 0 {
+		elem = elem[:i]
+	}
+	return elem
+}
+
+// NewNoopAuditor returns an auditor discarding all records
+func NewNoopAuditor() Auditor {
+	return &auditor{}
+}
+
+var _ Auditor = &auditor{}
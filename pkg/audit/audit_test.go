@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name   string
+		redact []string
+		values map[string]string
+		want   map[string]string
+	}{
+		{
+			name:   "no redacted leaf",
+			values: map[string]string{"/user/password": "secret"},
+			want:   map[string]string{"/user/password": "secret"},
+		},
+		{
+			name:   "leaf path",
+			redact: []string{"password"},
+			values: map[string]string{"/user/password": "secret", "/user/name": "alice"},
+			want:   map[string]string{"/user/password": "***", "/user/name": "alice"},
+		},
+		{
+			name:   "leaf path with keys",
+			redact: []string{"key"},
+			values: map[string]string{"/keys/key[name=a/b]": "secret"},
+			want:   map[string]string{"/keys/key[name=a/b]": "***"},
+		},
+		{
+			name:   "nested in a JSON value",
+			redact: []string{"password"},
+			values: map[string]string{"/users": `{"user":[{"name":"alice","password":"secret"}]}`},
+			want:   map[string]string{"/users": `{"user":[{"name":"alice","password":"***"}]}`},
+		},
+		{
+			name:   "qualified by its module",
+			redact: []string{"password"},
+			values: map[string]string{"/": `{"users:password":"secret"}`},
+			want:   map[string]string{"/": `{"users:password":"***"}`},
+		},
+		{
+			name:   "value that is not JSON",
+			redact: []string{"password"},
+			values: map[string]string{"/user/name": "alice"},
+			want:   map[string]string{"/user/name": "alice"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := NewAuditor(Config{Redact: test.redact})
+			if err != nil {
+				t.Fatal(err)
+			}
+			record := Record{Values: test.values}
+			a.(*auditor).redactRecord(&record)
+			if !reflect.DeepEqual(record.Values, test.want) {
+				t.Errorf("values %v, want %v", record.Values, test.want)
+			}
+		})
+	}
+}
+
+func TestRecordFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	a, err := NewAuditor(Config{File: path, Redact: []string{"password"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := Record{
+		Timestamp:     time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC),
+		User:          "alice",
+		SourceAddress: "10.0.0.1",
+		Transport:     "ssh",
+		SessionID:     "1",
+		MessageID:     "42",
+		Operation:     "edit-config",
+		Targets:       []string{"kpimon"},
+		Namespace:     "kpimon:ric:1.0.0",
+		Paths:         []string{"/report_period/password"},
+		Values:        map[string]string{"/report_period/password": "secret"},
+		Outcome:       OutcomeFailure,
+		ErrorTag:      "access-denied",
+	}
+	record.SetDuration(1500 * time.Microsecond)
+	a.Log(record)
+	err = a.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"timestamp":      "2022-05-01T12:00:00Z",
+		"user":           "alice",
+		"source-address": "10.0.0.1",
+		"transport":      "ssh",
+		"session-id":     "1",
+		"message-id":     "42",
+		"operation":      "edit-config",
+		"targets":        []interface{}{"kpimon"},
+		"namespace":      "kpimon:ric:1.0.0",
+		"paths":          []interface{}{"/report_period/password"},
+		"values":         map[string]interface{}{"/report_period/password": "***"},
+		"outcome":        "failure",
+		"error-tag":      "access-denied",
+		"duration-ms":    1.5,
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("record %s, want %v", data, want)
+	}
+}
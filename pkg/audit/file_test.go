@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := newFileSink(path, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.close()
+	// each file holds two lines of 10 bytes
+	sink.maxSize = 25
+
+	for _, line := range []string{"record-01", "record-02", "record-03", "record-04", "record-05", "record-06", "record-07"} {
+		err = sink.write(Record{}, []byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string][]string{
+		path:                {"record-07"},
+		backupName(path, 1): {"record-05", "record-06"},
+		backupName(path, 2): {"record-03", "record-04"},
+		backupName(path, 3): nil,
+	} {
+		file, err := os.Open(name)
+		if want == nil {
+			if err == nil {
+				file.Close()
+				t.Errorf("backup %s kept beyond the max backups", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+		if !reflect.DeepEqual(lines, want) {
+			t.Errorf("%s has %v, want %v", name, lines, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultSyslogFacility is the local0 facility
+	DefaultSyslogFacility = 16
+	DefaultSyslogAppName  = "onos-o1t"
+
+	syslogVersion        = 1
+	syslogMsgID          = "audit"
+	syslogWriteTimeout   = time.Second
+	syslogSeverityInfo   = 6
+	syslogSeverityNotice = 5
+
+	// syslogQueueSize is the number of records queued while the syslog server is slow or
+	// unreachable, the records being dropped once the queue is full
+	syslogQueueSize = 1024
+	// syslogRetryInterval is the delay before sending a record again after a failure, doubled
+	// after each failure up to syslogMaxRetryInterval
+	syslogRetryInterval    = time.Second
+	syslogMaxRetryInterval = 30 * time.Second
+)
+
+// syslogSink sends the records as RFC 5424 messages, framed with octet counting (RFC 6587) over tcp.
+// The records are queued and sent in the background, connecting again to the syslog server
+// after a failure.
+type syslogSink struct {
+	config   SyslogConfig
+	hostname string
+	// conn is only used by the goroutine sending the queued messages
+	conn    net.Conn
+	queue   chan []byte
+	dropped int
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newSyslogSink(config SyslogConfig) (*syslogSink, error) {
+	if config.Facility == 0 {
+		config.Facility = DefaultSyslogFacility
+	}
+	if config.AppName == "" {
+		config.AppName = DefaultSyslogAppName
+	}
+	if config.Facility < 0 || config.Facility > 23 {
+		return nil, fmt.Errorf("invalid syslog facility %d", config.Facility)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	s := &syslogSink{
+		config:   config,
+		hostname: hostname,
+		queue:    make(chan []byte, syslogQueueSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	err = s.connect()
+	if err != nil {
+		log.Warnf("Unable to connect to syslog %s://%s, the audit records are queued until it is reachable: %v",
+			config.Network, config.Address, err)
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *syslogSink) connect() error {
+	conn, err := net.DialTimeout(s.config.Network, s.config.Address, syslogWriteTimeout)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// format formats the record as a RFC 5424 message, failed rpcs with the notice severity
+func (s *syslogSink) format(record Record, line []byte) []byte {
+	severity := syslogSeverityInfo
+	if record.Outcome == OutcomeFailure {
+		severity = syslogSeverityNotice
+	}
+	priority := s.config.Facility*8 + severity
+
+	header := fmt.Sprintf("<%d>%d %s %s %s %d %s - ",
+		priority, syslogVersion, record.Timestamp.UTC().Format(time.RFC3339Nano),
+		s.hostname, s.config.AppName, os.Getpid(), syslogMsgID)
+	message := append([]byte(header), line...)
+
+	if s.config.Network == "tcp" {
+		message = append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+	}
+	return message
+}
+
+// write queues the record, dropping it if the queue is full
+func (s *syslogSink) write(record Record, line []byte) error {
+	select {
+	case s.queue <- s.format(record, line):
+		return nil
+	default:
+	}
+	s.dropped++
+	if s.dropped == 1 || s.dropped%syslogQueueSize == 0 {
+		log.Warnf("Dropped %d audit records, the syslog queue is full", s.dropped)
+	}
+	return nil
+}
+
+// run sends the queued messages until the sink is closed, then sends the messages still
+// queued unless the syslog server is unreachable
+func (s *syslogSink) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case message := <-s.queue:
+			if !s.sendRetrying(message) {
+				return
+			}
+		case <-s.done:
+			for {
+				select {
+				case message := <-s.queue:
+					if s.send(message) != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendRetrying sends the message, retrying after a backoff until it is sent or the sink is
+// closed, returning false in the latter case
+func (s *syslogSink) sendRetrying(message []byte) bool {
+	retry := syslogRetryInterval
+	for {
+		err := s.send(message)
+		if err == nil {
+			return true
+		}
+		log.Warnf("Error sending audit record to syslog %s://%s, retrying in %s: %v",
+			s.config.Network, s.config.Address, retry, err)
+		select {
+		case <-s.done:
+			return false
+		case <-time.After(retry):
+		}
+		retry *= 2
+		if retry > syslogMaxRetryInterval {
+			retry = syslogMaxRetryInterval
+		}
+	}
+}
+
+func (s *syslogSink) send(message []byte) error {
+	// a broken tcp connection is reconnected once before giving up the message
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			err := s.connect()
+			if err != nil {
+				return err
+			}
+		}
+
+		err := s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+		if err == nil {
+			_, err = s.conn.Write(message)
+		}
+		if err == nil {
+			return nil
+		}
+
+		s.conn.Close()
+		s.conn = nil
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+// close stops the sink once the queued messages are sent, or the syslog server found
+// unreachable
+func (s *syslogSink) close() error {
+	close(s.done)
+	<-s.stopped
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
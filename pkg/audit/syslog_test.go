@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogReconnect(t *testing.T) {
+	// the address of a syslog server not started yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	auditor, err := NewAuditor(Config{Syslog: SyslogConfig{Network: "tcp", Address: address}})
+	if err != nil {
+		t.Fatalf("unreachable syslog server not tolerated: %v", err)
+	}
+	defer auditor.Close()
+
+	start := time.Now()
+	auditor.Log(Record{User: "alice", SessionID: "1", Operation: "get-config", Outcome: OutcomeSuccess})
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("audit record sent synchronously in %s", elapsed)
+	}
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_ = listener.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("audit trail not sent once the syslog server is reachable: %v", err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message, err := bufio.NewReader(conn).ReadString('}')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(message, `"user":"alice"`) || !strings.Contains(message, "onos-o1t") {
+		t.Errorf("unexpected syslog message %q", message)
+	}
+}
@@ -0,0 +1,329 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("config")
+
+// Config is the configuration of onos-o1t loaded from the config.json file. The settings
+// of the sections documented as live are applied when the file is reloaded, the others
+// require a restart.
+type Config struct {
+	Listeners  []Listener `json:"listeners"`
+	CallHome   []CallHome `json:"callHome,omitempty"`
+	SSH        SSH        `json:"ssh"`
+	Southbound Southbound `json:"southbound"`
+	// Timeouts is live, except the shutdown timeout
+	Timeouts Timeouts `json:"timeouts"`
+	// Namespace is live
+	Namespace Namespace `json:"namespace"`
+	Topo      Topo      `json:"topo"`
+	// Logging is live
+	Logging Logging `json:"logging"`
+	// Features is live
+	Features Features `json:"features"`
+	Audit    Audit    `json:"audit"`
+	Tracing  Tracing  `json:"tracing"`
+	Health   Health   `json:"health"`
+	HTTP     HTTP     `json:"http"`
+	RESTCONF RESTCONF `json:"restconf"`
+	YANG     YANG     `json:"yang"`
+	Replay   Replay   `json:"replay"`
+}
+
+// Listener is an address the NETCONF SSH server listens on
+type Listener struct {
+	// Address is an IPv4 or IPv6 address, with a zone for link-local IPv6 addresses, all the
+	// addresses being listened on if empty
+	Address string `json:"address,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	// Auth lists the SSH authentication methods accepted on the listener, publickey or
+	// password, all the configured methods if empty
+	Auth []string `json:"auth,omitempty"`
+	// FD is the file descriptor of a socket inherited already listening, such as with systemd
+	// socket activation, used instead of the address and port
+	FD int `json:"fd,omitempty"`
+	// TLS makes the listener serve NETCONF over TLS (RFC 7589) with mutual TLS instead of SSH
+	TLS *ListenerTLS `json:"tls,omitempty"`
+}
+
+// ListenerTLS configures a NETCONF over TLS listener
+type ListenerTLS struct {
+	// CertPath, KeyPath and CAPath are the server certificate and key and the CA of the client
+	// certificates, those given on the command line by default
+	CertPath string `json:"certPath,omitempty"`
+	KeyPath  string `json:"keyPath,omitempty"`
+	CAPath   string `json:"caPath,omitempty"`
+	// CertToName are the rules mapping the client certificates to NETCONF usernames, the first
+	// rule selected by the certificate that can derive a username being applied
+	CertToName []CertToName `json:"certToName"`
+}
+
+// CertToName is a rule mapping a client certificate to a NETCONF username, as the entries of
+// the cert-to-name list of the ietf-x509-cert-to-name module
+type CertToName struct {
+	// Fingerprint is the tls-fingerprint, such as 04:a3:...:5f for SHA-256, of the client
+	// certificate or of a CA certificate of its chain selecting the rule, any certificate
+	// selecting it if empty
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// MapType is specified, san-rfc822-name, san-dns-name, san-ip-address, san-any or
+	// common-name, Name the username of the specified map type
+	MapType string `json:"mapType"`
+	Name    string `json:"name,omitempty"`
+}
+
+// CallHome is a NETCONF client, such as the SMO, onos-o1t connects to with SSH Call Home
+// (RFC 8071) to run the SSH server side of the connection
+type CallHome struct {
+	Name string `json:"name"`
+	// Address is the host or host:port of the client, the port being 4334 if missing
+	Address string `json:"address"`
+	// Mode is persistent, reconnecting whenever the connection is closed, or periodic,
+	// connecting once per period and closing the connection when its last session ends
+	Mode   string   `json:"mode"`
+	Period Duration `json:"period,omitempty"`
+	// InitialBackoff is the delay before connecting again after a failure, doubled after each
+	// consecutive failure up to MaxBackoff
+	InitialBackoff Duration `json:"initialBackoff,omitempty"`
+	MaxBackoff     Duration `json:"maxBackoff,omitempty"`
+	// Auth lists the SSH authentication methods accepted from the client, publickey or
+	// password, all the configured methods if empty
+	Auth []string `json:"auth,omitempty"`
+}
+
+// HostPort returns the host:port of the client, with the call home port if it has none
+func (c CallHome) HostPort() string {
+	if _, _, err := net.SplitHostPort(c.Address); err == nil {
+		return c.Address
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(c.Address, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(DefaultCallHomePort))
+}
+
+// SSH configures the NETCONF SSH server, its authentication and keepalive settings are live
+type SSH struct {
+	// HostKeys are the PEM files of the host private keys, a RSA key is generated if empty
+	HostKeys []string `json:"hostKeys,omitempty"`
+	// AuthorizedKeys is a file in the authorized_keys format of the public keys accepted for
+	// all users, any public key is accepted if empty and no user has a password
+	AuthorizedKeys string `json:"authorizedKeys,omitempty"`
+	// Users maps the users accepted with password authentication to their bcrypt password hash
+	Users             map[string]string `json:"users,omitempty"`
+	KeepaliveInterval Duration          `json:"keepaliveInterval"`
+	KeepaliveCountMax int               `json:"keepaliveCountMax"`
+}
+
+// Southbound configures the connection to onos-config
+type Southbound struct {
+	GnmiEndpoint string `json:"gnmiEndpoint"`
+}
+
+// Timeouts configures the timeouts of the NETCONF sessions and southbound requests
+type Timeouts struct {
+	RPC      Duration `json:"rpc"`
+	Gnmi     Duration `json:"gnmi"`
+	Idle     Duration `json:"idle"`
+	Shutdown Duration `json:"shutdown"`
+	// SessionRetention is the time an ended session is kept in the store with its end reason
+	SessionRetention Duration `json:"sessionRetention"`
+}
+
+// Namespace configures the namespaces of the capabilities derived from the onos-topo entities
+// and the mappings of the namespaces of YANG modules to the targets of onos-config
+type Namespace struct {
+	Prefix string `json:"prefix"`
+	// Mappings are selected in order for the data of their namespace, before the mappings of
+	// the onos-topo entities
+	Mappings []NamespaceMapping `json:"mappings,omitempty"`
+}
+
+// NamespaceMapping maps the data of the namespace of a YANG module, such as that of an O-RAN
+// module, to a target and model of onos-config in get-config and edit-config
+type NamespaceMapping struct {
+	Namespace string `json:"namespace"`
+	// Target is the target of the data, the value of the selector leaf if empty
+	Target  string `json:"target,omitempty"`
+	Model   string `json:"model"`
+	Version string `json:"version"`
+	// Leaf is the name of a leaf of the data, such as a list key, selecting the mapping when
+	// its value is equal to Value or starts with Prefix, or for any value if both are empty
+	Leaf   string `json:"leaf,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// Topo configures the onos-topo entities whose configurables are NETCONF capabilities
+type Topo struct {
+	Kind string `json:"kind"`
+	// Registration registers onos-o1t as an entity of onos-topo
+	Registration TopoRegistration `json:"registration"`
+}
+
+// TopoRegistration configures the entity registering onos-o1t in onos-topo, with its
+// endpoints, capabilities and version, refreshed while it runs and removed when it stops
+type TopoRegistration struct {
+	// ID is the id of the entity, empty disabling the registration
+	ID string `json:"id"`
+	// Address is the host advertised in the endpoints, the hostname by default
+	Address string `json:"address,omitempty"`
+	// RefreshInterval is the interval of the registrations, the lease of the entity expiring
+	// after three intervals
+	RefreshInterval Duration `json:"refreshInterval"`
+}
+
+// Logging configures the level of the root logger and of the named loggers
+type Logging struct {
+	Level   string            `json:"level"`
+	Loggers map[string]string `json:"loggers,omitempty"`
+}
+
+// Features toggles the optional NETCONF operations
+type Features struct {
+	KillSession bool `json:"killSession"`
+	Monitoring  bool `json:"monitoring"`
+}
+
+// Audit configures the audit trail of the NETCONF rpcs
+type Audit struct {
+	File       string   `json:"file,omitempty"`
+	MaxSizeMB  int      `json:"maxSizeMB"`
+	MaxBackups int      `json:"maxBackups"`
+	Syslog     string   `json:"syslog,omitempty"`
+	Redact     []string `json:"redact,omitempty"`
+}
+
+// Tracing configures the export of traces to an OTLP collector
+type Tracing struct {
+	Endpoint    string  `json:"endpoint,omitempty"`
+	Insecure    bool    `json:"insecure"`
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// Health configures the health checks, its readiness policy is live
+type Health struct {
+	Interval        Duration `json:"interval"`
+	ReadinessPolicy string   `json:"readinessPolicy"`
+}
+
+// HTTP configures the HTTP server of the metrics and probes
+type HTTP struct {
+	Port int `json:"port"`
+}
+
+// RESTCONF configures the RESTCONF server (RFC 8040), disabled if its port is 0. Its clients
+// are authenticated with HTTP basic authentication against the users of the ssh section.
+type RESTCONF struct {
+	// AllowAnonymous accepts the clients without credentials as the anonymous user
+	AllowAnonymous bool `json:"allowAnonymous"`
+	// Address is the address listened on, all the addresses if empty
+	Address string `json:"address,omitempty"`
+	Port    int    `json:"port"`
+	// Insecure serves RESTCONF over HTTP instead of HTTPS
+	Insecure bool `json:"insecure"`
+	// CertPath and KeyPath are the server certificate and key, those given on the command line
+	// by default
+	CertPath string `json:"certPath,omitempty"`
+	KeyPath  string `json:"keyPath,omitempty"`
+}
+
+// YANG configures the YANG modules of the models of the targets, used to translate the
+// NETCONF data to the typed JSON of the gNMI requests. The data is translated without schema
+// when no module of a model is found.
+type YANG struct {
+	// Dirs are searched recursively for the .yang files of the modules, the modules of a model
+	// being those listed by its model plugin registered in onos-config, those of a directory
+	// named <name>-<version> or <name>, or the module named as the model
+	Dirs []string `json:"dirs,omitempty"`
+}
+
+// Replay configures the replay log of the notifications of the event streams (RFC 5277),
+// replay being disabled if its directory is empty
+type Replay struct {
+	Dir string `json:"dir,omitempty"`
+	// MaxAge and MaxSizeMB bound the notifications kept in the log, the oldest being removed
+	// first
+	MaxAge    Duration `json:"maxAge"`
+	MaxSizeMB int      `json:"maxSizeMB"`
+}
+
+// Duration is a time.Duration in the format of time.ParseDuration, such as "30s"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s, expected a string such as \"30s\"", data)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Duration returns the duration as a time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// Load reads the configuration file, the settings missing from the file keeping their
+// default value. Without a path, the file of DefaultPath is read and the default
+// configuration is returned if it does not exist.
+func Load(path string) (*Config, error) {
+	config := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		log.Warnf("Configuration file %s not found, using the default configuration", path)
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+
+	log.Infof("Loaded configuration file %s", path)
+	return config, nil
+}
+
+// Redacted returns a copy of the configuration without secrets, such as the password hashes
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	if len(c.SSH.Users) > 0 {
+		redacted.SSH.Users = make(map[string]string, len(c.SSH.Users))
+		for user := range c.SSH.Users {
+			redacted.SSH.Users[user] = "***"
+		}
+	}
+	return &redacted
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	err := os.WriteFile(path, []byte(`{"http": {"port": 8080}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.HTTP.Port != 8080 || config.Southbound.GnmiEndpoint != DefaultGnmiEndpoint {
+		t.Errorf("loaded http port %d and gnmi endpoint %s", config.HTTP.Port, config.Southbound.GnmiEndpoint)
+	}
+	// the legacy port is listened on alongside the IANA port
+	if len(config.Listeners) != 2 || config.Listeners[0].Port != DefaultNetconfPort || config.Listeners[1].Port != IANANetconfPort {
+		t.Errorf("default listeners %v", config.Listeners)
+	}
+
+	// a file given explicitly must exist
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Error("missing configuration file loaded")
+	}
+
+	err = os.WriteFile(path, []byte(`{"http": {"prot": 8080}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load(path)
+	if err == nil {
+		t.Error("unknown setting loaded")
+	}
+}
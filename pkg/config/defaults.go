@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"time"
+)
+
+const (
+	AuthPublicKey = "publickey"
+	AuthPassword  = "password"
+
+	CallHomePersistent = "persistent"
+	CallHomePeriodic   = "periodic"
+
+	// ReadinessReady accepts new sessions only when all the components are up,
+	// ReadinessSouthbound only when onos-config is up and ReadinessAlways whatever their health
+	ReadinessReady      = "ready"
+	ReadinessSouthbound = "southbound"
+	ReadinessAlways     = "always"
+
+	// DefaultPath is the configuration file read when no path is given
+	DefaultPath = "/etc/onos/config/config.json"
+
+	// DefaultCallHomePort is the port assigned to NETCONF Call Home over SSH
+	DefaultCallHomePort = 4334
+
+	// DefaultNetconfPort is the legacy port of onos-o1t, listened on alongside the
+	// IANANetconfPort assigned to NETCONF over SSH, which requires privileges to bind
+	DefaultNetconfPort       = 8300
+	IANANetconfPort          = 830
+	DefaultGnmiEndpoint      = "onos-config:5150"
+	DefaultNamespacePrefix   = "http://opennetworking.org"
+	DefaultTopoKind          = "o1t"
+	DefaultTopoRegistration  = "onos-o1t"
+	DefaultTopoRefresh       = 30 * time.Second
+	DefaultLoggingLevel      = "info"
+	DefaultKeepaliveInterval = 30 * time.Second
+	DefaultKeepaliveCountMax = 3
+	DefaultRPCTimeout        = 10 * time.Second
+	DefaultGnmiTimeout       = 3 * time.Second
+	DefaultShutdownTimeout   = 10 * time.Second
+	DefaultSessionRetention  = 10 * time.Minute
+	DefaultAuditMaxSizeMB    = 100
+	DefaultAuditMaxBackups   = 5
+	DefaultHealthInterval    = 5 * time.Second
+	DefaultReadinessPolicy   = ReadinessReady
+	DefaultHTTPPort          = 7070
+	DefaultReplayMaxAge      = 24 * time.Hour
+	DefaultReplayMaxSizeMB   = 100
+)
+
+// Default returns the default configuration
+func Default() *Config {
+	return &Config{
+		Listeners: []Listener{
+			{
+				Port: DefaultNetconfPort,
+			},
+			{
+				Port: IANANetconfPort,
+			},
+		},
+		SSH: SSH{
+			KeepaliveInterval: Duration(DefaultKeepaliveInterval),
+			KeepaliveCountMax: DefaultKeepaliveCountMax,
+		},
+		Southbound: Southbound{
+			GnmiEndpoint: DefaultGnmiEndpoint,
+		},
+		Timeouts: Timeouts{
+			RPC:              Duration(DefaultRPCTimeout),
+			Gnmi:             Duration(DefaultGnmiTimeout),
+			Shutdown:         Duration(DefaultShutdownTimeout),
+			SessionRetention: Duration(DefaultSessionRetention),
+		},
+		Namespace: Namespace{
+			Prefix: DefaultNamespacePrefix,
+		},
+		Topo: Topo{
+			Kind: DefaultTopoKind,
+			Registration: TopoRegistration{
+				ID:              DefaultTopoRegistration,
+				RefreshInterval: Duration(DefaultTopoRefresh),
+			},
+		},
+		Logging: Logging{
+			Level: DefaultLoggingLevel,
+		},
+		Features: Features{
+			KillSession: true,
+			Monitoring:  true,
+		},
+		Audit: Audit{
+			MaxSizeMB:  DefaultAuditMaxSizeMB,
+			MaxBackups: DefaultAuditMaxBackups,
+		},
+		Tracing: Tracing{
+			SampleRatio: 1,
+		},
+		Health: Health{
+			Interval:        Duration(DefaultHealthInterval),
+			ReadinessPolicy: DefaultReadinessPolicy,
+		},
+		HTTP: HTTP{
+			Port: DefaultHTTPPort,
+		},
+		Replay: Replay{
+			MaxAge:    Duration(DefaultReplayMaxAge),
+			MaxSizeMB: DefaultReplayMaxSizeMB,
+		},
+	}
+}
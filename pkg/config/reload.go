@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+)
+
+// Reload returns the configuration made of the live settings of the next configuration and
+// the other settings of the current one, with the names of the settings requiring a restart
+// that differ in the next configuration
+func (c *Config) Reload(next *Config) (*Config, []string) {
+	reloaded := *c
+	reloaded.SSH.AuthorizedKeys = next.SSH.AuthorizedKeys
+	reloaded.SSH.Users = next.SSH.Users
+	reloaded.SSH.KeepaliveInterval = next.SSH.KeepaliveInterval
+	reloaded.SSH.KeepaliveCountMax = next.SSH.KeepaliveCountMax
+	reloaded.Timeouts.RPC = next.Timeouts.RPC
+	reloaded.Timeouts.Gnmi = next.Timeouts.Gnmi
+	reloaded.Timeouts.Idle = next.Timeouts.Idle
+	reloaded.Timeouts.SessionRetention = next.Timeouts.SessionRetention
+	reloaded.Namespace = next.Namespace
+	reloaded.Logging = next.Logging
+	reloaded.Features = next.Features
+	reloaded.Health.ReadinessPolicy = next.Health.ReadinessPolicy
+
+	restart := []string{}
+	changed := func(name string, current, next interface{}) {
+		if !reflect.DeepEqual(current, next) {
+			restart = append(restart, name)
+		}
+	}
+	changed("listeners", c.Listeners, next.Listeners)
+	changed("callHome", c.CallHome, next.CallHome)
+	changed("ssh.hostKeys", c.SSH.HostKeys, next.SSH.HostKeys)
+	changed("southbound", c.Southbound, next.Southbound)
+	changed("timeouts.shutdown", c.Timeouts.Shutdown, next.Timeouts.Shutdown)
+	changed("topo", c.Topo, next.Topo)
+	changed("audit", c.Audit, next.Audit)
+	changed("tracing", c.Tracing, next.Tracing)
+	changed("health.interval", c.Health.Interval, next.Health.Interval)
+	changed("http", c.HTTP, next.HTTP)
+	changed("restconf", c.RESTCONF, next.RESTCONF)
+	changed("yang", c.YANG, next.YANG)
+	changed("replay", c.Replay, next.Replay)
+
+	return &reloaded, restart
+}
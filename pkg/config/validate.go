@@ -0,0 +1,331 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ValidationError lists the invalid settings of a configuration
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(e.Errors, "; "))
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, fmt.Sprintf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+// Validate checks the settings of the configuration, returning a ValidationError listing
+// all the invalid settings
+func (c *Config) Validate() error {
+	e := &ValidationError{}
+
+	if len(c.Listeners) == 0 && len(c.CallHome) == 0 {
+		e.add("listeners", "at least one listener or call home client is required")
+	}
+	bound := make(map[string]string)
+	for i, listener := range c.Listeners {
+		field := fmt.Sprintf("listeners[%d]", i)
+		validateAuth(e, field+".auth", listener.Auth)
+		if listener.TLS != nil {
+			validateListenerTLS(e, field, listener)
+		}
+		if listener.FD < 0 {
+			e.add(field+".fd", "must not be negative")
+		}
+		if listener.FD > 0 {
+			if listener.Address != "" || listener.Port != 0 {
+				e.add(field, "fd excludes address and port")
+			}
+			continue
+		}
+		if listener.Address != "" && !isIPAddress(listener.Address) {
+			e.add(field+".address", "%q is not an IP address", listener.Address)
+		}
+		validatePort(e, field+".port", listener.Port, false)
+
+		address := net.JoinHostPort(listener.Address, strconv.Itoa(listener.Port))
+		if other, ok := bound[address]; ok {
+			e.add(field, "%s is already listened on by %s", address, other)
+		}
+		bound[address] = field
+	}
+
+	names := make(map[string]bool)
+	for i, client := range c.CallHome {
+		field := fmt.Sprintf("callHome[%d]", i)
+		if client.Name == "" {
+			e.add(field+".name", "must not be empty")
+		} else if names[client.Name] {
+			e.add(field+".name", "%q is already the name of another client", client.Name)
+		}
+		names[client.Name] = true
+		if client.Address == "" {
+			e.add(field+".address", "must not be empty")
+		} else if _, port, err := net.SplitHostPort(client.HostPort()); err != nil {
+			e.add(field+".address", "%q is not a host or host:port: %v", client.Address, err)
+		} else if number, err := strconv.Atoi(port); err != nil {
+			e.add(field+".address", "%q is not a valid port", port)
+		} else {
+			validatePort(e, field+".address", number, false)
+		}
+		switch client.Mode {
+		case CallHomePersistent:
+		case CallHomePeriodic:
+			if client.Period <= 0 {
+				e.add(field+".period", "must be positive in periodic mode")
+			}
+		default:
+			e.add(field+".mode", "%q must be %s or %s", client.Mode, CallHomePersistent, CallHomePeriodic)
+		}
+		if client.InitialBackoff < 0 {
+			e.add(field+".initialBackoff", "must not be negative")
+		}
+		if client.MaxBackoff < 0 || (client.MaxBackoff > 0 && client.MaxBackoff < client.InitialBackoff) {
+			e.add(field+".maxBackoff", "must not be lower than the initial backoff")
+		}
+		validateAuth(e, field+".auth", client.Auth)
+	}
+
+	for user, hash := range c.SSH.Users {
+		_, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			e.add(fmt.Sprintf("ssh.users[%s]", user), "not a bcrypt password hash: %v", err)
+		}
+	}
+	if c.SSH.KeepaliveInterval < 0 {
+		e.add("ssh.keepaliveInterval", "must not be negative")
+	}
+	if c.SSH.KeepaliveInterval > 0 && c.SSH.KeepaliveCountMax < 1 {
+		e.add("ssh.keepaliveCountMax", "must be at least 1 when keepalives are enabled")
+	}
+
+	if c.Southbound.GnmiEndpoint == "" {
+		e.add("southbound.gnmiEndpoint", "must not be empty")
+	}
+
+	if c.Timeouts.RPC <= 0 {
+		e.add("timeouts.rpc", "must be positive")
+	}
+	if c.Timeouts.Gnmi <= 0 {
+		e.add("timeouts.gnmi", "must be positive")
+	} else if c.Timeouts.Gnmi > c.Timeouts.RPC {
+		// the gNMI requests of a rpc are bounded by the rpc timeout
+		e.add("timeouts.gnmi", "%s must not exceed timeouts.rpc %s", c.Timeouts.Gnmi.Duration(), c.Timeouts.RPC.Duration())
+	}
+	if c.Timeouts.Idle < 0 {
+		e.add("timeouts.idle", "must not be negative")
+	}
+	if c.Timeouts.Shutdown <= 0 {
+		e.add("timeouts.shutdown", "must be positive")
+	}
+	if c.Timeouts.SessionRetention <= 0 {
+		e.add("timeouts.sessionRetention", "must be positive")
+	}
+
+	if prefix, err := url.Parse(c.Namespace.Prefix); err != nil || prefix.Scheme == "" || strings.HasSuffix(c.Namespace.Prefix, "/") {
+		e.add("namespace.prefix", "%q must be an absolute URI without trailing slash", c.Namespace.Prefix)
+	}
+	for i, mapping := range c.Namespace.Mappings {
+		field := fmt.Sprintf("namespace.mappings[%d]", i)
+		if mapping.Namespace == "" {
+			e.add(field+".namespace", "must not be empty")
+		}
+		if mapping.Model == "" {
+			e.add(field+".model", "must not be empty")
+		}
+		if mapping.Version == "" {
+			e.add(field+".version", "must not be empty")
+		}
+		if mapping.Target == "" && mapping.Leaf == "" {
+			e.add(field+".target", "must not be empty without a selector leaf")
+		}
+		if mapping.Leaf == "" && (mapping.Value != "" || mapping.Prefix != "") {
+			e.add(field+".leaf", "must not be empty with a value or prefix")
+		}
+		if mapping.Value != "" && mapping.Prefix != "" {
+			e.add(field+".prefix", "excludes value")
+		}
+	}
+
+	if c.Topo.Kind == "" {
+		e.add("topo.kind", "must not be empty")
+	}
+	if c.Topo.Registration.ID != "" && c.Topo.Registration.RefreshInterval <= 0 {
+		e.add("topo.registration.refreshInterval", "must be positive")
+	}
+
+	if _, err := ParseLevel(c.Logging.Level); err != nil {
+		e.add("logging.level", "%v", err)
+	}
+	for name, level := range c.Logging.Loggers {
+		if _, err := ParseLevel(level); err != nil {
+			e.add(fmt.Sprintf("logging.loggers[%s]", name), "%v", err)
+		}
+	}
+
+	if c.Audit.MaxSizeMB < 1 {
+		e.add("audit.maxSizeMB", "must be at least 1")
+	}
+	if c.Audit.MaxBackups < 0 {
+		e.add("audit.maxBackups", "must not be negative")
+	}
+	if _, _, err := ParseSyslogURL(c.Audit.Syslog); err != nil {
+		e.add("audit.syslog", "%v", err)
+	}
+
+	if c.Tracing.SampleRatio <= 0 || c.Tracing.SampleRatio > 1 {
+		e.add("tracing.sampleRatio", "must be in ]0, 1]")
+	}
+
+	if c.Health.Interval <= 0 {
+		e.add("health.interval", "must be positive")
+	}
+	switch c.Health.ReadinessPolicy {
+	case ReadinessReady, ReadinessSouthbound, ReadinessAlways:
+	default:
+		e.add("health.readinessPolicy", "%q must be one of %s, %s or %s", c.Health.ReadinessPolicy,
+			ReadinessReady, ReadinessSouthbound, ReadinessAlways)
+	}
+
+	validatePort(e, "http.port", c.HTTP.Port, true)
+
+	validatePort(e, "restconf.port", c.RESTCONF.Port, true)
+	if c.RESTCONF.Address != "" && !isIPAddress(c.RESTCONF.Address) {
+		e.add("restconf.address", "%q is not an IP address", c.RESTCONF.Address)
+	}
+	if c.RESTCONF.Port != 0 && c.RESTCONF.Port == c.HTTP.Port {
+		e.add("restconf.port", "%d is already the port of the HTTP server", c.RESTCONF.Port)
+	}
+	if c.RESTCONF.Port != 0 && len(c.SSH.Users) == 0 && !c.RESTCONF.AllowAnonymous {
+		e.add("restconf.port", "requires ssh.users to authenticate the clients, or restconf.allowAnonymous")
+	}
+	if c.RESTCONF.Insecure && (c.RESTCONF.CertPath != "" || c.RESTCONF.KeyPath != "") {
+		e.add("restconf.insecure", "no certificate is used by an insecure server")
+	}
+
+	for i, dir := range c.YANG.Dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			e.add(fmt.Sprintf("yang.dirs[%d]", i), "%q is not a directory", dir)
+		}
+	}
+
+	if c.Replay.MaxAge <= 0 {
+		e.add("replay.maxAge", "must be positive")
+	}
+	if c.Replay.MaxSizeMB < 1 {
+		e.add("replay.maxSizeMB", "must be at least 1")
+	}
+
+	if len(e.Errors) > 0 {
+		return e
+	}
+	return nil
+}
+
+func validateListenerTLS(e *ValidationError, field string, listener Listener) {
+	if len(listener.Auth) > 0 {
+		e.add(field+".auth", "TLS clients are authenticated by their certificate")
+	}
+	if len(listener.TLS.CertToName) == 0 {
+		e.add(field+".tls.certToName", "at least one rule is required")
+	}
+	for i, rule := range listener.TLS.CertToName {
+		ruleField := fmt.Sprintf("%s.tls.certToName[%d]", field, i)
+		switch rule.MapType {
+		case "specified":
+			if rule.Name == "" {
+				e.add(ruleField+".name", "must not be empty with the specified map type")
+			}
+		case "san-rfc822-name", "san-dns-name", "san-ip-address", "san-any", "common-name":
+			if rule.Name != "" {
+				e.add(ruleField+".name", "only allowed with the specified map type")
+			}
+		default:
+			e.add(ruleField+".mapType", "unknown map type %q", rule.MapType)
+		}
+	}
+}
+
+func validateAuth(e *ValidationError, field string, methods []string) {
+	for _, method := range methods {
+		if method != AuthPublicKey && method != AuthPassword {
+			e.add(field, "unknown authentication method %q, expected %s or %s", method, AuthPublicKey, AuthPassword)
+		}
+	}
+}
+
+// isIPAddress checks the address is an IP address, with a zone for IPv6 addresses
+func isIPAddress(address string) bool {
+	ip := address
+	if i := strings.LastIndex(address, "%"); i >= 0 {
+		ip = address[:i]
+		if !strings.Contains(ip, ":") || i == len(address)-1 {
+			return false
+		}
+	}
+	return net.ParseIP(ip) != nil
+}
+
+func validatePort(e *ValidationError, field string, port int, allowZero bool) {
+	if (port == 0 && allowZero) || (port > 0 && port <= 65535) {
+		return
+	}
+	e.add(field, "%d is not a valid port", port)
+}
+
+// ParseLevel parses the name of a logging level
+func ParseLevel(level string) (logging.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return logging.DebugLevel, nil
+	case "info":
+		return logging.InfoLevel, nil
+	case "warn":
+		return logging.WarnLevel, nil
+	case "error":
+		return logging.ErrorLevel, nil
+	}
+	return logging.InfoLevel, fmt.Errorf("unknown logging level %q, expected debug, info, warn or error", level)
+}
+
+// ParseSyslogURL parses a syslog address in the form network://host:port, an empty address
+// disabling syslog
+func ParseSyslogURL(url string) (network, address string, err error) {
+	if url == "" {
+		return "", "", nil
+	}
+	parts := strings.SplitN(url, "://", 2)
+	if len(parts) != 2 || (parts[0] != "udp" && parts[0] != "tcp") {
+		return "", "", fmt.Errorf("invalid syslog address %s, expected udp://host:port or tcp://host:port", url)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ApplyLogging sets the levels of the root logger and of the named loggers
+func (c *Config) ApplyLogging() {
+	level, err := ParseLevel(c.Logging.Level)
+	if err == nil {
+		logging.SetLevel(level)
+	}
+	for name, levelName := range c.Logging.Loggers {
+		level, err := ParseLevel(levelName)
+		if err == nil {
+			logging.GetLogger(name).SetLevel(level)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidateTimeouts(t *testing.T) {
+	tests := []struct {
+		name  string
+		rpc   time.Duration
+		gnmi  time.Duration
+		field string
+	}{
+		{name: "defaults", rpc: DefaultRPCTimeout, gnmi: DefaultGnmiTimeout},
+		{name: "gnmi equal to rpc", rpc: 2 * time.Second, gnmi: 2 * time.Second},
+		{name: "gnmi beyond rpc", rpc: time.Second, gnmi: 3 * time.Second, field: "timeouts.gnmi"},
+		{name: "no rpc timeout", rpc: 0, gnmi: time.Second, field: "timeouts.rpc"},
+		{name: "no gnmi timeout", rpc: time.Second, gnmi: 0, field: "timeouts.gnmi"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Default()
+			c.Timeouts.RPC = Duration(test.rpc)
+			c.Timeouts.Gnmi = Duration(test.gnmi)
+			err := c.Validate()
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.field) {
+				t.Errorf("error %v, want an error of %s", err, test.field)
+			}
+		})
+	}
+}
+
+func TestValidateYANG(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		dirs  []string
+		field string
+	}{
+		{name: "defaults"},
+		{name: "dirs", dirs: []string{dir}},
+		{name: "missing dir", dirs: []string{dir + "/missing"}, field: "yang.dirs[0]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Default()
+			c.YANG.Dirs = test.dirs
+			err := c.Validate()
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.field) {
+				t.Errorf("error %v, want an error of %s", err, test.field)
+			}
+		})
+	}
+}
+
+func TestValidateAuditAndHealth(t *testing.T) {
+	tests := []struct {
+		name            string
+		syslog          string
+		readinessPolicy string
+		field           string
+	}{
+		{name: "defaults", readinessPolicy: DefaultReadinessPolicy},
+		{name: "udp syslog", syslog: "udp://127.0.0.1:514", readinessPolicy: ReadinessSouthbound},
+		{name: "tcp syslog", syslog: "tcp://syslog:601", readinessPolicy: ReadinessAlways},
+		{name: "syslog without network", syslog: "127.0.0.1:514", readinessPolicy: ReadinessReady, field: "audit.syslog"},
+		{name: "unknown syslog network", syslog: "unix:///dev/log", readinessPolicy: ReadinessReady, field: "audit.syslog"},
+		{name: "unknown readiness policy", readinessPolicy: "never", field: "health.readinessPolicy"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Default()
+			c.Audit.Syslog = test.syslog
+			c.Health.ReadinessPolicy = test.readinessPolicy
+			err := c.Validate()
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.field) {
+				t.Errorf("error %v, want an error of %s", err, test.field)
+			}
+		})
+	}
+
+	network, address, err := ParseSyslogURL("tcp://syslog:601")
+	if err != nil || network != "tcp" || address != "syslog:601" {
+		t.Errorf("syslog parsed as %s %s %v, want tcp syslog:601", network, address, err)
+	}
+}
+
+func TestValidateRESTCONF(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		port           int
+		users          map[string]string
+		allowAnonymous bool
+		field          string
+	}{
+		{name: "disabled"},
+		{name: "users", port: 8443, users: map[string]string{"alice": string(hash)}},
+		{name: "anonymous", port: 8443, allowAnonymous: true},
+		{name: "no authentication", port: 8443, field: "restconf.port"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Default()
+			c.RESTCONF.Port = test.port
+			c.RESTCONF.Insecure = true
+			c.SSH.Users = test.users
+			c.RESTCONF.AllowAnonymous = test.allowAnonymous
+			err := c.Validate()
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.field) {
+				t.Errorf("error %v, want an error of %s", err, test.field)
+			}
+		})
+	}
+}
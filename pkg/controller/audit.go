@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-o1t/pkg/audit"
+	"github.com/onosproject/onos-o1t/pkg/metrics"
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+const (
+	// ErrorTagMalformedMessage is counted for the rpcs whose operation could not be decoded
+	ErrorTagMalformedMessage = "malformed-message"
+)
+
+type auditRecordKey struct{}
+
+// withAuditRecord returns a context carrying the audit record of the rpc being handled
+func withAuditRecord(ctx context.Context, record *audit.Record) context.Context {
+	return context.WithValue(ctx, auditRecordKey{}, record)
+}
+
+// auditRecordFrom returns the audit record of the rpc being handled, or a record that is
+// discarded if the context does not carry one
+func auditRecordFrom(ctx context.Context) *audit.Record {
+	if record, ok := ctx.Value(auditRecordKey{}).(*audit.Record); ok {
+		return record
+	}
+	return &audit.Record{}
+}
+
+// newAuditRecord starts the audit record of a rpc received in the session
+func (o1 *o1Controller) newAuditRecord(ctx context.Context, sessionID, messageID, operation string) *audit.Record {
+	record := &audit.Record{
+		Timestamp: time.Now(),
+		SessionID: sessionID,
+		MessageID: messageID,
+		Operation: operation,
+	}
+
+	entry, err := o1.Store.Get(ctx, store.Key{SessionID: sessionID})
+	if err == nil {
+		if value, ok := entry.Value.(*store.SessionValue); ok {
+			record.User = value.User
+			record.SourceAddress = value.SourceHost
+			record.Transport = value.Transport
+		}
+	}
+	return record
+}
+
+// finishAuditRecord sets the outcome of the rpc from its reply, logs the record and
+// updates the rpc metrics
+func (o1 *o1Controller) finishAuditRecord(record *audit.Record, reply []byte, err error) {
+	record.Outcome = audit.OutcomeSuccess
+
+	if err != nil {
+		record.Outcome = audit.OutcomeFailure
+		record.ErrorTag = auditErrorTag(err)
+	} else if reply != nil {
+		rpcReply := new(RPCReply)
+		if xml.Unmarshal(reply, rpcReply) == nil && len(rpcReply.Errors) > 0 {
+			record.Outcome = audit.OutcomeFailure
+			record.ErrorTag = rpcReply.Errors[0].Tag
+		}
+	}
+
+	o1.logAuditRecord(record)
+}
+
+// finishDataAuditRecord sets the outcome of an operation on a data resource from its error,
+// logs the record and updates the rpc metrics
+func (o1 *o1Controller) finishDataAuditRecord(record *audit.Record, err error) {
+	record.Outcome = audit.OutcomeSuccess
+
+	if err != nil {
+		record.Outcome = audit.OutcomeFailure
+		record.ErrorTag = auditErrorTag(err)
+	}
+
+	o1.logAuditRecord(record)
+}
+
+func (o1 *o1Controller) logAuditRecord(record *audit.Record) {
+	duration := time.Since(record.Timestamp)
+	record.SetDuration(duration)
+
+	o1.auditor.Log(*record)
+
+	target := ""
+	if len(record.Targets) > 0 {
+		target = record.Targets[0]
+	}
+	metrics.ObserveRPC(record.Operation, target, record.Outcome, duration)
+}
+
+// auditErrorTag returns the error-tag recorded for the error of a rpc: the tag of an
+// rpc-error, that of the rpc-error mapped from a gNMI error, or operation-failed
+func auditErrorTag(err error) string {
+	if rpcErr, ok := err.(RPCError); ok {
+		return rpcErr.Tag
+	}
+	if tag := gnmiRPCError(err).Tag; tag != "" {
+		return tag
+	}
+	return ErrorTagOperationFailed
+}
+
+// auditGet records the target and paths of a gNMI get request
+func auditGet(record *audit.Record, namespace string, request *gnmi.GetRequest) {
+	record.Namespace = namespace
+	record.Targets = requestTargets(request.Prefix, request.Path)
+	for _, path := range request.Path {
+		record.Paths = append(record.Paths, pathToString(request.Prefix, path))
+	}
+}
+
+// auditSet records the target, paths and values of a gNMI set request
+func auditSet(record *audit.Record, namespace string, request *gnmi.SetRequest) {
+	record.Namespace = namespace
+	record.Values = make(map[string]string)
+
+	paths := make([]*gnmi.Path, 0, len(request.Delete)+len(request.Replace)+len(request.Update))
+	paths = append(paths, request.Delete...)
+	for _, updates := range [][]*gnmi.Update{request.Replace, request.Update} {
+		for _, update := range updates {
+			paths = append(paths, update.Path)
+			record.Values[pathToString(request.Prefix, update.Path)] = typedValueString(update.Val)
+		}
+	}
+
+	record.Targets = requestTargets(request.Prefix, paths)
+	for _, path := range paths {
+		record.Paths = append(record.Paths, pathToString(request.Prefix, path))
+	}
+}
+
+func requestTargets(prefix *gnmi.Path, paths []*gnmi.Path) []string {
+	targets := []string{}
+	seen := make(map[string]bool)
+	add := func(target string) {
+		if target != "" && !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	add(prefix.GetTarget())
+	for _, path := range paths {
+		add(path.GetTarget())
+	}
+	return targets
+}
+
+// pathToString formats the prefix and path in the gNMI path string notation
+func pathToString(prefix, path *gnmi.Path) string {
+	var b strings.Builder
+	for _, p := range []*gnmi.Path{prefix, path} {
+		for _, elem := range p.GetElem() {
+			b.WriteString("/")
+			b.WriteString(elem.Name)
+			for name, value := range elem.Key {
+				fmt.Fprintf(&b, "[%s=%s]", name, value)
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
+
+func typedValueString(value *gnmi.TypedValue) string {
+	switch v := value.GetValue().(type) {
+	case *gnmi.TypedValue_JsonVal:
+		return string(v.JsonVal)
+	case *gnmi.TypedValue_JsonIetfVal:
+		return string(v.JsonIetfVal)
+	case *gnmi.TypedValue_StringVal:
+		return v.StringVal
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// gnmiRPCError maps the status of a gNMI error to a NETCONF rpc-error
+func gnmiRPCError(gnmiErr error) RPCError {
+	// errors of the translation of the request are reported as they are
+	switch e := gnmiErr.(type) {
+	case RPCError:
+		return e
+	case *schema.Error:
+		return schemaRPCError(e)
+	}
+
+	// errors returned by the gNMI client carry a gRPC status, other errors are typed errors
+	status, ok := grpcstatus.FromError(gnmiErr)
+	if !ok {
+		status = errors.Status(gnmiErr)
+	}
+
+	tag := ErrorTagOperationFailed
+	switch status.Code() {
+	case codes.InvalidArgument:
+		tag = ErrorTagInvalidValue
+	case codes.NotFound:
+		tag = ErrorTagDataMissing
+	case codes.AlreadyExists:
+		tag = ErrorTagDataExists
+	case codes.PermissionDenied, codes.Unauthenticated:
+		tag = ErrorTagAccessDenied
+	case codes.Unimplemented:
+		tag = ErrorTagOperationNotSupported
+	case codes.ResourceExhausted:
+		tag = ErrorTagResourceDenied
+	}
+
+	return RPCError{
+		Type:     ErrorTypeApplication,
+		Tag:      tag,
+		Severity: ErrorSeverityError,
+		Message:  fmt.Sprintf("%s: %s", status.Code(), status.Message()),
+	}
+}
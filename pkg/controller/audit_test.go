@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/onosproject/onos-o1t/pkg/audit"
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/southbound"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingAuditor keeps the records of the audit trail
+type recordingAuditor struct {
+	mu      sync.Mutex
+	records []audit.Record
+}
+
+func (a *recordingAuditor) Log(record audit.Record) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.records = append(a.records, record)
+}
+
+func (a *recordingAuditor) Close() error {
+	return nil
+}
+
+// failingClient is a southbound whose gNMI set requests fail with an error
+type failingClient struct {
+	southbound.GnmiClient
+	err error
+}
+
+func (c *failingClient) Set(ctx context.Context, request *gnmi.SetRequest) (*gnmi.SetResponse, error) {
+	return nil, c.err
+}
+
+func TestAuditErrorTag(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		tag  string
+	}{
+		{name: "rpc-error", err: RPCError{Tag: ErrorTagInvalidValue}, tag: ErrorTagInvalidValue},
+		{name: "gnmi not found", err: status.Error(codes.NotFound, "no target"), tag: ErrorTagDataMissing},
+		{name: "gnmi timeout", err: status.Error(codes.DeadlineExceeded, "timeout"), tag: ErrorTagOperationFailed},
+		{name: "other error", err: errors.New("reply can not be marshalled"), tag: ErrorTagOperationFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if tag := auditErrorTag(test.err); tag != test.tag {
+				t.Errorf("error tag %s, want %s", tag, test.tag)
+			}
+		})
+	}
+}
+
+func TestAuditFailedEditConfig(t *testing.T) {
+	auditor := &recordingAuditor{}
+	client := &failingClient{err: status.Error(codes.PermissionDenied, "denied")}
+	topo := rnib.NewFakeClient(rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:1.0.0"})
+	o1 := NewO1Controller(store.NewStore(), topo, client, WithAuditor(auditor)).(*o1Controller)
+	defer o1.Close()
+
+	ctx := context.Background()
+	_, err := o1.Capabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o1.OpenSession(ctx, SessionInfo{SessionID: "1", User: "alice", SourceHost: "10.0.0.1", Transport: "ssh"}, newFakeSession())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o1.Handler(ctx, "1", []byte(`<rpc message-id="42" xmlns="urn:ietf:params:xml:ns:netconf:base:1.1"><edit-config><target><running/></target>`+
+		`<config><report_period xmlns="http://opennetworking.org/kpimon:ric:1.0.0"><interval>7</interval></report_period></config></edit-config></rpc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditor.mu.Lock()
+	defer auditor.mu.Unlock()
+	if len(auditor.records) != 1 {
+		t.Fatalf("%d audit records, want 1", len(auditor.records))
+	}
+	record := auditor.records[0]
+	if record.User != "alice" || record.SourceAddress != "10.0.0.1" || record.Transport != "ssh" || record.SessionID != "1" {
+		t.Errorf("record of session %s user %s from %s over %s", record.SessionID, record.User, record.SourceAddress, record.Transport)
+	}
+	if record.MessageID != "42" || record.Operation != "edit-config" {
+		t.Errorf("record of message %s operation %s", record.MessageID, record.Operation)
+	}
+	if record.Outcome != audit.OutcomeFailure || record.ErrorTag != ErrorTagAccessDenied {
+		t.Errorf("record outcome %s error-tag %s, want %s %s", record.Outcome, record.ErrorTag, audit.OutcomeFailure, ErrorTagAccessDenied)
+	}
+	if len(record.Targets) != 1 || record.Targets[0] != "kpimon" {
+		t.Errorf("record targets %v, want [kpimon]", record.Targets)
+	}
+	if len(record.Paths) != 1 || record.Values[record.Paths[0]] == "" {
+		t.Errorf("record paths %v values %v", record.Paths, record.Values)
+	}
+}
@@ -0,0 +1,20 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// UpdateCallHome stores the status of the connection to a call home client
+func (o1 *o1Controller) UpdateCallHome(ctx context.Context, name string, status store.CallHomeValue) error {
+	key := store.Key{
+		CallHome: name,
+	}
+	_, err := o1.Store.Update(ctx, key, &status)
+	return err
+}
@@ -29,15 +29,19 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/onosproject/onos-lib-go/pkg/errors"
-
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-o1t/pkg/audit"
+	"github.com/onosproject/onos-o1t/pkg/replay"
 	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/schema"
 	"github.com/onosproject/onos-o1t/pkg/southbound"
 	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
 	"github.com/openconfig/gnmi/proto/gnmi"
 )
 
@@ -53,6 +57,13 @@ var (
 		"urn:ietf:params:netconf:capability:writable-running:1.0",
 		"urn:ietf:params:netconf:capability:rollback-on-error:1.0",
 		"urn:ietf:params:netconf:capability:xpath:1.0",
+		"urn:ietf:params:netconf:capability:validate:1.1",
+		"urn:ietf:params:netconf:capability:notification:1.0",
+		"urn:ietf:params:netconf:capability:interleave:1.0",
+		"urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring?module=ietf-netconf-monitoring&revision=2010-10-04",
+		"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications?module=ietf-netconf-notifications&revision=2012-02-06",
+		"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications?module=ietf-subscribed-notifications&revision=2019-09-09",
+		"urn:ietf:params:xml:ns:yang:ietf-yang-push?module=ietf-yang-push&revision=2019-09-09&features=on-change",
 	}
 )
 
@@ -63,63 +74,208 @@ type TargetsNames []TargetName
 
 type o1Controller struct {
 	capabilities []string
+	// helloCapabilities are the capabilities of the hello sent to each session by session id
+	helloCapabilities map[string][]string
+	// library is the yang-library of the capabilities
+	library *YangLibrary
+	// topoMappings are the namespace mappings of the onos-topo entities
+	topoMappings []NamespaceMapping
 	gnmiClient   southbound.GnmiClient
 	Store        store.Store
 	rnibClient   rnib.TopoClient
-	GnmiTimeout  time.Duration
+	auditor      audit.Auditor
+	health       HealthReporter
+	schemas      SchemaProvider
+	// replayLog is nil if replay is disabled
+	replayLog *replay.Log
+
+	mu       sync.RWMutex
+	settings Settings
+	// sessions registered by their session id
+	sessions map[string]Session
+	// sessionCounters are the counters of the sessions, stats those of the server
+	sessionCounters map[string]*counters
+	stats           statistics
+	// subscriptions are the notification subscriptions by session id, pushSubscriptions the
+	// YANG-Push subscriptions by subscription id
+	subscriptions      map[string]*subscription
+	pushSubscriptions  map[uint32]*pushSubscription
+	lastSubscriptionID uint32
+	// recorders cancel the recording of the changes of the targets in the replay log, by
+	// target:name:version
+	recorders map[string]context.CancelFunc
+	// storeMu serializes the read-modify-write of the session entries of the store
+	storeMu sync.Mutex
+	// ctx is the lifetime of the controller, cancelled by Close to stop its background tasks,
+	// wg tracking their goroutines
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// topo caches the o1t entities of onos-topo, capabilitiesMu serializes the updates of the
+	// capabilities
+	topo           *topoCache
+	capabilitiesMu sync.Mutex
 }
 
 type O1Controller interface {
 	Handler(context.Context, string, []byte) ([]byte, error)
+	OpenSession(context.Context, SessionInfo, Session) error
+	EndSession(context.Context, string, store.EndReason) error
+	TerminateSessions(context.Context) error
+	Configure(Settings)
+	UpdateCallHome(context.Context, string, store.CallHomeValue) error
+	Capabilities(context.Context) ([]string, error)
+	ModuleCapabilities(context.Context) ([]string, error)
+	GetData(context.Context, string, DataResource) (*DataValue, error)
+	EditData(context.Context, string, DataOperation, DataResource, []byte) (bool, error)
+	// Close stops the background tasks of the controller
+	Close()
 }
 
-func NewO1Controller(Store store.Store, rnibClient rnib.TopoClient, gnmiClient southbound.GnmiClient) O1Controller {
+// Option configures the O1 controller
+type Option func(*o1Controller)
 
-	o1t := &o1Controller{
-		capabilities: []string{},
-		gnmiClient:   gnmiClient,
-		Store:        Store,
-		rnibClient:   rnibClient,
-		GnmiTimeout:  3 * time.Second,
+// WithAuditor sets the auditor recording the rpcs handled by the controller
+func WithAuditor(auditor audit.Auditor) Option {
+	return func(o1 *o1Controller) {
+		o1.auditor = auditor
 	}
+}
 
-	gnmiCtx, cancel := context.WithTimeout(context.Background(), o1t.GnmiTimeout)
+func NewO1Controller(Store store.Store, rnibClient rnib.TopoClient, gnmiClient southbound.GnmiClient, opts ...Option) O1Controller {
+
+	o1t := &o1Controller{
+		capabilities:      []string{},
+		gnmiClient:        gnmiClient,
+		Store:             Store,
+		rnibClient:        rnibClient,
+		settings:          DefaultSettings(),
+		auditor:           audit.NewNoopAuditor(),
+		sessions:          make(map[string]Session),
+		sessionCounters:   make(map[string]*counters),
+		stats:             statistics{startTime: time.Now()},
+		subscriptions:     make(map[string]*subscription),
+		pushSubscriptions: make(map[uint32]*pushSubscription),
+		recorders:         make(map[string]context.CancelFunc),
+		helloCapabilities: make(map[string][]string),
+		topo:              newTopoCache(),
+	}
+	for _, opt := range opts {
+		opt(o1t)
+	}
+	// the pruning reads the settings, which the options may set
+	o1t.ctx, o1t.cancel = context.WithCancel(context.Background())
+	o1t.startSessionPruning(o1t.ctx)
+
+	gnmiCtx, cancel := o1t.gnmiContext(context.Background())
 	defer cancel()
 
 	_, err := o1t.Capabilities(gnmiCtx)
 	if err != nil {
 		log.Warn(err)
 	}
+	o1t.wg.Add(1)
+	go func() {
+		defer o1t.wg.Done()
+		o1t.watchTopo(o1t.ctx)
+	}()
 
 	return o1t
 }
 
+// Close stops the pruning of the ended sessions, the watch of the entities of onos-topo and the
+// recording of the changes of the targets in the replay log, and waits for them to end
+func (o1 *o1Controller) Close() {
+	o1.cancel()
+	o1.stopRecording()
+	o1.wg.Wait()
+}
+
 func (o1 *o1Controller) Handler(ctx context.Context, sessionID string, rawMessage []byte) ([]byte, error) {
 	rawXML := string(rawMessage)
-	log.Infof("Decode received rawXML %s", rawXML)
+	log.Debugf("Decode received rawXML %s", rawXML)
 
-	switch {
-	case strings.Contains(rawXML, "<request-hello"):
+	if strings.HasPrefix(rawXML, "<request-hello") {
 		hello, err := o1.Hello(ctx, sessionID)
 		return hello, err
-	case strings.Contains(rawXML, "<close"):
-		close, err := o1.CloseSession(ctx, sessionID)
+	}
+
+	messageID, operation, err := parseRPCOperation(rawMessage)
+	if err != nil {
+		log.Debugf("Malformed message received %s", rawXML)
+		o1.countRPC(sessionID, ErrorTagMalformedMessage)
+		return nil, err
+	}
+
+	if operation == "hello" {
+		o1.checkHello(sessionID, rawMessage)
+		return nil, nil
+	}
+
+	tracing.SetAttributes(ctx, tracing.MessageIDKey.String(messageID), tracing.OperationKey.String(operation))
+
+	record := o1.newAuditRecord(ctx, sessionID, messageID, operation)
+	reply, err := o1.handleRPC(withAuditRecord(ctx, record), sessionID, messageID, operation, rawMessage)
+	o1.finishAuditRecord(record, reply, err)
+	o1.countRPC(sessionID, record.ErrorTag)
+
+	if record.ErrorTag != "" {
+		tracing.SetAttributes(ctx, tracing.ErrorTagKey.String(record.ErrorTag))
+		tracing.SetError(ctx, record.ErrorTag)
+	}
+	return reply, err
+}
+
+func (o1 *o1Controller) handleRPC(ctx context.Context, sessionID, messageID, operation string, rawMessage []byte) ([]byte, error) {
+	if !o1.currentSettings().Features.enabled(operation) {
+		return buildErrorReply(messageID, featureDisabledError(operation))
+	}
+
+	switch operation {
+	case "close-session":
+		close, err := o1.CloseSession(ctx, sessionID, rawMessage)
 		return close, err
-	case strings.Contains(rawXML, "<kill"):
-		kill, err := o1.KillSession(ctx, sessionID)
+	case "kill-session":
+		kill, err := o1.KillSession(ctx, sessionID, rawMessage)
 		return kill, err
-	case strings.Contains(rawXML, "<hello"):
-		// err := o1.Capabilities(rawMessage)
-		return nil, nil
-	case strings.Contains(rawXML, "<get-config"):
+	case "get":
+		rawReply, err := o1.GetState(ctx, sessionID, rawMessage)
+		return rawReply, err
+	case "get-schema":
+		rawReply, err := o1.GetSchema(ctx, sessionID, rawMessage)
+		return rawReply, err
+	case "get-config":
 		rawReply, err := o1.Get(ctx, sessionID, rawMessage)
 		return rawReply, err
-	case strings.Contains(rawXML, "<edit-config"):
+	case "edit-config":
 		rawReply, err := o1.Set(ctx, sessionID, rawMessage)
 		return rawReply, err
+	case "validate":
+		rawReply, err := o1.Validate(ctx, sessionID, rawMessage)
+		return rawReply, err
+	case "create-subscription":
+		rawReply, err := o1.CreateSubscription(ctx, sessionID, rawMessage)
+		return rawReply, err
+	case "establish-subscription":
+		rawReply, err := o1.EstablishSubscription(ctx, sessionID, rawMessage)
+		return rawReply, err
+	case "modify-subscription":
+		rawReply, err := o1.ModifySubscription(ctx, sessionID, rawMessage)
+		return rawReply, err
+	case "delete-subscription":
+		rawReply, err := o1.DeleteSubscription(ctx, sessionID, rawMessage)
+		return rawReply, err
+	case "kill-subscription":
+		rawReply, err := o1.KillSubscription(ctx, sessionID, rawMessage)
+		return rawReply, err
 	default:
-		log.Infof("Unknown message type received %s", rawXML)
-		return nil, fmt.Errorf("unknown message type received %s", rawXML)
+		log.Debugf("Unknown message type received %s", rawMessage)
+		return buildErrorReply(messageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagOperationNotSupported,
+			Severity: ErrorSeverityError,
+			Message:  fmt.Sprintf("operation %s not supported", operation),
+		})
 	}
 }
 
@@ -129,32 +285,39 @@ func (o1 *o1Controller) Get(ctx context.Context, sessionID string, requestXML []
 	var reply []byte
 	var response *gnmi.GetResponse
 
-	request, namespace, err := ParseGetConfig(requestXML)
+	_, span := tracing.Start(ctx, "controller.ParseGetConfig")
+	request, namespace, err := ParseGetConfig(requestXML, o1.currentMappings())
+	tracing.End(span, err)
 
 	if err != nil {
-		reply, err = o1.buildGetReply(requestXML, response, err)
+		reply, err = o1.buildGetReply(requestXML, nil, nil, response, err)
 		if err != nil {
 			return nil, err
 		}
 
 	} else {
-		response, gnmiErr := o1.gnmiClient.Get(ctx, request)
-
 		ns := fmt.Sprintf("%s:%s:%s", namespace.Target, namespace.Name, namespace.Version)
+		auditGet(auditRecordFrom(ctx), ns, request)
+		tracing.SetAttributes(ctx, tracing.NamespaceKey.String(ns), tracing.TargetKey.String(namespace.Target))
+
+		gnmiCtx, cancel := o1.gnmiContext(ctx)
+		response, gnmiErr := o1.gnmiClient.Get(gnmiCtx, request)
+		cancel()
+
 		err = o1.UpdateStoreOperation(ctx, sessionID, "get-config", ns, gnmiErr)
 		if err != nil {
 			return nil, err
 		}
 
-		log.Infof(response.String())
+		log.Debugf("%v", response)
 
-		reply, err = o1.buildGetReply(requestXML, response, gnmiErr)
+		reply, err = o1.buildGetReply(requestXML, request.Path[0], o1.modelSchema(ctx, namespace), response, gnmiErr)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	log.Infof("get reply %s", string(reply))
+	log.Debugf("get reply %s", string(reply))
 	return reply, nil
 }
 
@@ -164,90 +327,170 @@ func (o1 *o1Controller) Set(ctx context.Context, sessionID string, requestXML []
 	var reply []byte
 	var response *gnmi.GetResponse
 
-	request, namespace, err := ParseEditConfig(requestXML, o1.capabilities)
+	_, span := tracing.Start(ctx, "controller.ParseEditConfig")
+	edit, err := ParseEditConfig(requestXML, o1.sessionCapabilities(sessionID), o1.currentMappings(), func(namespace Namespace) *schema.Schema {
+		return o1.modelSchema(ctx, namespace)
+	})
+	tracing.End(span, err)
 
 	if err != nil {
-		reply, err = o1.buildGetReply(requestXML, response, err)
+		reply, err = o1.buildGetReply(requestXML, nil, nil, response, err)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		response, gnmiErr := o1.gnmiClient.Set(ctx, request)
+		ns := strings.Join(edit.Namespaces(), ",")
+		record := auditRecordFrom(ctx)
+		auditSet(record, ns, edit.SetRequest())
+		tracing.SetAttributes(ctx, tracing.NamespaceKey.String(ns), tracing.TargetKey.String(strings.Join(record.Targets, ",")))
+
+		operation := "edit-config"
+		var results []partResult
+		if edit.TestOption == TestOptionTestOnly {
+			operation = store.OperationTestOnly
+			results = o1.testEdit(ctx, edit)
+		} else {
+			results = o1.applyEdit(ctx, edit)
+			var applied []configEdit
+			for i, result := range results {
+				if result.applied {
+					applied = append(applied, configEdit{namespace: edit.Parts[i].Namespace, request: edit.Parts[i].Request})
+				}
+			}
+			o1.notifyConfigChange(ctx, sessionID, applied)
+		}
 
-		ns := fmt.Sprintf("%s:%s:%s", namespace.Target, namespace.Name, namespace.Version)
-		err = o1.UpdateStoreOperation(ctx, sessionID, "edit-config", ns, gnmiErr)
+		err = o1.UpdateStoreEdit(ctx, sessionID, operation, edit, results)
 		if err != nil {
 			return nil, err
 		}
 
-		log.Infof(response.String())
-		reply, err = o1.buildEditReply(requestXML, response, gnmiErr)
+		reply, err = buildEditReply(requestXML, edit, results)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	log.Infof("edit reply %s", string(reply))
+	log.Debugf("edit reply %s", string(reply))
 	return reply, nil
 }
 
+// Capabilities returns the capabilities of the o1t entities of the topo cache, or of onos-topo
+// until the cache is synced, notifying their changes to the subscribed sessions
 func (o1 *o1Controller) Capabilities(ctx context.Context) ([]string, error) {
-	capabilities := []string{}
-
-	configurables, err := o1.rnibClient.GetO1tConfigurables(ctx)
-	if err != nil {
-		return nil, err
+	entities, synced := o1.topo.snapshot()
+	if !synced {
+		var err error
+		entities, err = o1.rnibClient.GetO1tEntities(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	for _, conf := range configurables {
-		capab := strings.Join([]string{ONF_CAPABILITY_PREFIX, conf}, "/")
+	// the capabilities are updated by the hellos and the topo events in turn
+	o1.capabilitiesMu.Lock()
+	defer o1.capabilitiesMu.Unlock()
+
+	capabilities := []string{}
+	prefix := o1.currentSettings().NamespacePrefix
+	var namespaces []Namespace
+	var topoMappings []NamespaceMapping
+	for _, entity := range entities {
+		capab := strings.Join([]string{prefix, entity.Configurable}, "/")
 		capabilities = append(capabilities, capab)
+		if namespace, err := ParseNamespace(capab); err == nil {
+			namespaces = append(namespaces, namespace)
+		}
+		for _, mapping := range entity.Mappings {
+			topoMappings = append(topoMappings, NamespaceMapping(mapping))
+		}
 	}
 
 	capabilities = append(capabilities, O1T_CAPABILITIES_DEFAULT...)
 
+	// the models of the targets are also advertised by the capabilities of their modules
+	library := o1.yangLibrary(ctx, namespaces)
+	capabilities = append(capabilities, libraryCapabilities(library)...)
+
+	o1.mu.Lock()
+	previous := o1.capabilities
 	o1.capabilities = capabilities
+	o1.library = library
+	o1.topoMappings = topoMappings
+	o1.mu.Unlock()
+
+	if len(previous) > 0 {
+		o1.notifyCapabilityChange(previous, capabilities)
+	}
+	o1.recordTargets()
 
 	return capabilities, nil
 }
 
+// currentCapabilities returns the capabilities of the last hello or capabilities request
+func (o1 *o1Controller) currentCapabilities() []string {
+	o1.mu.RLock()
+	defer o1.mu.RUnlock()
+
+	return o1.capabilities
+}
+
+// sessionCapabilities returns the capabilities of the hello sent to the session, the current
+// capabilities for the sessions without hello
+func (o1 *o1Controller) sessionCapabilities(sessionID string) []string {
+	o1.mu.RLock()
+	defer o1.mu.RUnlock()
+
+	if capabilities, ok := o1.helloCapabilities[sessionID]; ok {
+		return capabilities
+	}
+	return o1.capabilities
+}
+
 func (o1 *o1Controller) Hello(ctx context.Context, sessionID string) ([]byte, error) {
 	hello := new(Hello)
 
-	_, err := o1.Capabilities(ctx)
+	id, err := strconv.Atoi(sessionID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid session id %s: %v", sessionID, err)
 	}
-	hello.Capabilities = o1.capabilities
+	hello.SessionID = id
 
-	output, err := xml.Marshal(hello)
+	hello.Capabilities, err = o1.Capabilities(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("build hello message %s", output)
+	// the session keeps the capabilities of its hello while the targets change
+	o1.mu.Lock()
+	o1.helloCapabilities[sessionID] = hello.Capabilities
+	o1.mu.Unlock()
 
-	err = o1.CreateStoreOperation(ctx, sessionID)
+	output, err := xml.Marshal(hello)
 	if err != nil {
-		log.Warn(err)
+		return nil, err
 	}
 
+	log.Debugf("build hello message %s", output)
+
 	return output, nil
 
 }
 
-func (o1 *o1Controller) CloseSession(ctx context.Context, sessionID string) ([]byte, error) {
-	close := new(CloseSession)
-	close.CloseSession = ""
-	close.MessageID = NewUUID()
+func (o1 *o1Controller) CloseSession(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(CloseSession)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
 
-	output, err := xml.Marshal(close)
+	output, err := buildOkReply(request.MessageID)
 	if err != nil {
 		return nil, err
 	}
-	log.Infof("build close message %s", output)
+	log.Debugf("build close message %s", output)
 
-	err = o1.DeleteStoreOperation(ctx, sessionID)
+	err = o1.EndSession(ctx, sessionID, store.EndReasonClosed)
 	if err != nil {
 		log.Warn(err)
 	}
@@ -256,23 +499,61 @@ func (o1 *o1Controller) CloseSession(ctx context.Context, sessionID string) ([]b
 
 }
 
-func (o1 *o1Controller) KillSession(ctx context.Context, sessionID string) ([]byte, error) {
-	kill := new(KillSession)
-	kill.MessageID = NewUUID()
-	kill.SessionID = sessionID
+func (o1 *o1Controller) KillSession(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(KillSession)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	killedID := strings.TrimSpace(request.SessionID)
 
-	output, err := xml.Marshal(kill)
+	o1.mu.RLock()
+	_, alive := o1.sessions[killedID]
+	o1.mu.RUnlock()
+
+	if killedID == sessionID || !alive {
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  fmt.Sprintf("session %s can not be killed", killedID),
+		})
+	}
+
+	err = o1.endSession(ctx, killedID, store.EndReasonKilled, sessionID)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	output, err := buildOkReply(request.MessageID)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("build kill message %s", output)
+	log.Debugf("build kill message %s", output)
 
 	return output, nil
 
 }
 
-func (o1 *o1Controller) buildGetReply(requestXML []byte, response *gnmi.GetResponse, gnmiErr error) ([]byte, error) {
+func buildOkReply(messageID string) ([]byte, error) {
+	reply := new(RPCReply)
+	reply.MessageID = messageID
+	reply.Data = "<ok/>"
+	return xml.Marshal(reply)
+}
+
+func buildErrorReply(messageID string, rpcErrors ...RPCError) ([]byte, error) {
+	reply := new(RPCReply)
+	reply.MessageID = messageID
+	reply.Errors = rpcErrors
+	return xml.Marshal(reply)
+}
+
+// buildGetReply builds the reply of a get-config, the data at the path being translated with
+// the schema of its model if it is known
+func (o1 *o1Controller) buildGetReply(requestXML []byte, path *gnmi.Path, modelSchema *schema.Schema, response *gnmi.GetResponse, gnmiErr error) ([]byte, error) {
 
 	request := new(GetConfig)
 
@@ -285,25 +566,28 @@ func (o1 *o1Controller) buildGetReply(requestXML []byte, response *gnmi.GetRespo
 	reply.MessageID = request.MessageID
 
 	if gnmiErr != nil {
-		status := errors.Status(gnmiErr)
-		rpcError := new(RPCError)
-		rpcError.Type = status.Code().String()
-		rpcError.Message = status.Message()
-		reply.Errors = append(reply.Errors, *rpcError)
+		reply.Errors = append(reply.Errors, gnmiRPCError(gnmiErr))
 	} else {
 		value, err := GetResponseUpdate(response)
 		if err != nil {
 			return nil, err
 		}
-
-		//TODO build message using xml go structure of the model referenced by the target
-		valTmp := value.Value.(*gnmi.TypedValue_JsonVal).JsonVal
-		xmlVal, err := xml.Marshal(string(valTmp))
+		jsonVal, err := typedValueJSON(value)
 		if err != nil {
 			return nil, err
 		}
 
-		reply.Data = string(xmlVal)
+		var data []byte
+		if jsonVal != nil {
+			if updatePath := response.Notification[0].Update[0].GetPath(); len(updatePath.GetElem()) > 0 {
+				path = updatePath
+			}
+			data, err = schema.JSONToXML(modelSchema, path, jsonVal, request.Filter.XMLNS)
+			if err != nil {
+				return nil, err
+			}
+		}
+		reply.Data = "<data>" + string(data) + "</data>"
 	}
 
 	output, err := xml.Marshal(reply)
@@ -311,38 +595,26 @@ func (o1 *o1Controller) buildGetReply(requestXML []byte, response *gnmi.GetRespo
 		return nil, err
 	}
 
-	log.Infof("build get reply message %s", output)
+	log.Debugf("build get reply message %s", output)
 
 	return output, nil
 
 }
 
-func (o1 *o1Controller) buildEditReply(requestXML []byte, response *gnmi.SetResponse, gnmiErr error) ([]byte, error) {
+// buildEditReply builds the reply of an edit-config, ok if all its parts were applied and the
+// errors of the parts that failed or were not applied otherwise
+func buildEditReply(requestXML []byte, edit *EditRequest, results []partResult) ([]byte, error) {
 	request := new(EditConfig)
 	err := xml.Unmarshal([]byte(requestXML), request)
 	if err != nil {
 		return nil, err
 	}
 
-	reply := new(RPCReply)
-	reply.MessageID = request.MessageID
-
-	if gnmiErr != nil {
-		status := errors.Status(gnmiErr)
-		rpcError := new(RPCError)
-		rpcError.Type = status.Code().String()
-		rpcError.Message = status.Message()
-		reply.Errors = append(reply.Errors, *rpcError)
-	} else {
-		reply.Data = "<ok/>"
-	}
-
-	output, err := xml.Marshal(reply)
-	if err != nil {
-		return nil, err
+	rpcErrors := editErrors(edit, results)
+	if len(rpcErrors) > 0 {
+		return buildErrorReply(request.MessageID, rpcErrors...)
 	}
-
-	return output, nil
+	return buildOkReply(request.MessageID)
 }
 
 func GetResponseUpdate(gr *gnmi.GetResponse) (*gnmi.TypedValue, error) {
@@ -362,24 +634,31 @@ func GetResponseUpdate(gr *gnmi.GetResponse) (*gnmi.TypedValue, error) {
 	}, nil
 }
 
-func (o1 *o1Controller) CreateStoreOperation(ctx context.Context, sessionID string) error {
+func (o1 *o1Controller) CreateStoreOperation(ctx context.Context, info SessionInfo) error {
 
 	key := store.Key{
-		SessionID: sessionID,
+		SessionID: info.SessionID,
 	}
 
 	value := &store.SessionValue{
-		Alive:      true,
-		Operations: make(map[string]store.Operation),
+		Alive:        true,
+		User:         info.User,
+		SSHSessionID: info.SSHSessionID,
+		SourceHost:   info.SourceHost,
+		Transport:    info.Transport,
+		LoginTime:    uint64(time.Now().UnixNano()),
+		Operations:   make(map[string]store.Operation),
 	}
 
-	log.Infof("Create store session %s", sessionID)
+	log.Infof("Create store session %s user %s %s session %s", info.SessionID, info.User, info.Transport, info.SSHSessionID)
 	_, err := o1.Store.Put(ctx, key, value)
 	return err
 
 }
 
 func (o1 *o1Controller) DeleteStoreOperation(ctx context.Context, sessionID string) error {
+	o1.storeMu.Lock()
+	defer o1.storeMu.Unlock()
 
 	key := store.Key{
 		SessionID: sessionID,
@@ -391,52 +670,64 @@ func (o1 *o1Controller) DeleteStoreOperation(ctx context.Context, sessionID stri
 
 }
 
-func (o1 *o1Controller) UpdateStoreOperation(ctx context.Context, sessionID, operation, namespace string, gnmiErr error) error {
-	log.Info("Update Store")
-
-	status := true
-	if gnmiErr != nil {
-		status = false
-	}
+// updateSession applies the update function to a copy of the session value and stores the
+// result if the function returns true. A session missing from the store, whose entry was
+// pruned once the session retention elapsed, is not updated.
+func (o1 *o1Controller) updateSession(ctx context.Context, sessionID string, update func(value *store.SessionValue) bool) error {
+	o1.storeMu.Lock()
+	defer o1.storeMu.Unlock()
 
 	key := store.Key{
 		SessionID: sessionID,
 	}
 
-	entryValue := &store.SessionValue{
-		Alive:      true,
-		Operations: make(map[string]store.Operation),
-	}
-
 	entry, err := o1.Store.Get(ctx, key)
 	if err != nil {
-		log.Warn(err)
-	} else {
-		entryValue = entry.Value.(*store.SessionValue)
+		log.Debugf("Session %s not updated: %v", sessionID, err)
+		return nil
+	}
+	current := *entry.Value.(*store.SessionValue)
+	value := &current
+
+	operations := make(map[string]store.Operation, len(value.Operations))
+	for ts, op := range value.Operations {
+		operations[ts] = op
+	}
+	value.Operations = operations
+
+	if !update(value) {
+		return nil
 	}
 
-	log.Infof("Entry value %+v", entryValue)
+	_, err = o1.Store.Update(ctx, key, value)
+	return err
+}
+
+func (o1 *o1Controller) UpdateStoreOperation(ctx context.Context, sessionID, operation, namespace string, gnmiErr error) error {
+	log.Info("Update Store")
 
-	ops := entryValue.Operations
+	status := true
+	if gnmiErr != nil {
+		status = false
+	}
 
 	timestamp := time.Now()
 	newOp := store.Operation{
 		Name:      operation,
 		Namespace: namespace,
 		Status:    status,
-		Timestamp: uint64(timestamp.UnixNano()),
-	}
-	ops[timestamp.String()] = newOp
-
-	value := &store.SessionValue{
-		Alive:      true,
-		Operations: ops,
 	}
 
 	log.Infof("Update store session %s operation %s namespace %s status %v", sessionID, operation, namespace, status)
-	_, err = o1.Store.Update(ctx, key, value)
-	log.Infof("New Entry value %+v ", value)
-
-	return err
+	return o1.addStoreOperation(ctx, sessionID, timestamp, newOp)
+}
 
+// addStoreOperation adds the operation done at the timestamp to the session in the store
+func (o1 *o1Controller) addStoreOperation(ctx context.Context, sessionID string, timestamp time.Time, newOp store.Operation) error {
+	newOp.Timestamp = uint64(timestamp.UnixNano())
+	return o1.updateSession(ctx, sessionID, func(value *store.SessionValue) bool {
+		value.Operations[timestamp.String()] = newOp
+		log.Debugf("New Entry value %+v ", value)
+		return true
+	})
 }
@@ -0,0 +1,413 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// DataOperation is an operation on a data resource of the running datastore, the RESTCONF
+// methods (RFC 8040) mapping to the edit operations of NETCONF
+type DataOperation string
+
+const (
+	DataGet     DataOperation = "get"
+	DataCreate  DataOperation = "create"
+	DataMerge   DataOperation = "merge"
+	DataReplace DataOperation = "replace"
+	DataDelete  DataOperation = "delete"
+)
+
+// PathSegment is a data node of the path of a data resource, Keys being the values of the
+// keys of a list entry
+type PathSegment struct {
+	Name string
+	Keys []string
+}
+
+// DataResource identifies a data resource by the YANG module of its top-level data node and
+// the path of the resource, as the RESTCONF data resource identifiers
+type DataResource struct {
+	Module string
+	// Target selects the target implementing the module when several targets implement it
+	Target string
+	Path   []PathSegment
+}
+
+// DataValue is the JSON value of a data resource and the namespace of its module
+type DataValue struct {
+	Namespace string
+	Value     []byte
+}
+
+// GetData gets the value of the data resource from the target implementing its module
+func (o1 *o1Controller) GetData(ctx context.Context, sessionID string, resource DataResource) (*DataValue, error) {
+	record := o1.newAuditRecord(ctx, sessionID, "", string(DataGet))
+	value, err := o1.getData(withAuditRecord(ctx, record), sessionID, resource)
+	o1.finishDataAuditRecord(record, err)
+	return value, err
+}
+
+// EditData applies the operation to the data resource, the value being the JSON value of the
+// resource for all operations but delete. It returns whether the resource was created.
+func (o1 *o1Controller) EditData(ctx context.Context, sessionID string, operation DataOperation, resource DataResource, value []byte) (bool, error) {
+	record := o1.newAuditRecord(ctx, sessionID, "", string(operation))
+	created, err := o1.editData(withAuditRecord(ctx, record), sessionID, operation, resource, value)
+	o1.finishDataAuditRecord(record, err)
+	return created, err
+}
+
+func (o1 *o1Controller) getData(ctx context.Context, sessionID string, resource DataResource) (*DataValue, error) {
+	capability, namespace, err := o1.resolveModule(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+	path, err := dataPath(o1.modelSchema(ctx, namespace), namespace, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	request := dataGetRequest(namespace, path)
+	ns := fmt.Sprintf("%s:%s:%s", namespace.Target, namespace.Name, namespace.Version)
+	auditGet(auditRecordFrom(ctx), ns, request)
+	tracing.SetAttributes(ctx, tracing.NamespaceKey.String(ns), tracing.TargetKey.String(namespace.Target))
+
+	gnmiCtx, cancel := o1.gnmiContext(ctx)
+	response, gnmiErr := o1.gnmiClient.Get(gnmiCtx, request)
+	cancel()
+
+	err = o1.UpdateStoreOperation(ctx, sessionID, string(DataGet), ns, gnmiErr)
+	if err != nil {
+		return nil, err
+	}
+	if gnmiErr != nil {
+		return nil, gnmiRPCError(gnmiErr)
+	}
+
+	value, err := GetResponseUpdate(response)
+	if err != nil {
+		return nil, err
+	}
+	jsonVal, err := typedValueJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	if jsonVal == nil {
+		return nil, dataMissingError(resource)
+	}
+
+	return &DataValue{
+		Namespace: capability,
+		Value:     jsonVal,
+	}, nil
+}
+
+func (o1 *o1Controller) editData(ctx context.Context, sessionID string, operation DataOperation, resource DataResource, value []byte) (bool, error) {
+	_, namespace, err := o1.resolveModule(ctx, resource)
+	if err != nil {
+		return false, err
+	}
+	path, err := dataPath(o1.modelSchema(ctx, namespace), namespace, resource)
+	if err != nil {
+		return false, err
+	}
+
+	exists := false
+	if operation != DataMerge {
+		exists, err = o1.dataExists(ctx, namespace, path)
+		if err != nil {
+			return false, err
+		}
+	}
+	switch {
+	case operation == DataCreate && exists:
+		return false, RPCError{
+			Type:     ErrorTypeApplication,
+			Tag:      ErrorTagDataExists,
+			Severity: ErrorSeverityError,
+			Path:     resourcePath(resource),
+			Message:  "data resource already exists",
+		}
+	case operation == DataDelete && !exists:
+		return false, dataMissingError(resource)
+	}
+
+	request := &gnmi.SetRequest{
+		Prefix: &gnmi.Path{
+			Target: namespace.Target,
+		},
+	}
+	update := &gnmi.Update{
+		Path: path,
+		Val: &gnmi.TypedValue{
+			Value: &gnmi.TypedValue_JsonVal{JsonVal: value},
+		},
+	}
+	switch operation {
+	case DataDelete:
+		request.Delete = []*gnmi.Path{path}
+	case DataReplace:
+		request.Replace = []*gnmi.Update{update}
+	case DataCreate, DataMerge:
+		request.Update = []*gnmi.Update{update}
+	default:
+		return false, fmt.Errorf("unknown data operation %s", operation)
+	}
+
+	ns := fmt.Sprintf("%s:%s:%s", namespace.Target, namespace.Name, namespace.Version)
+	auditSet(auditRecordFrom(ctx), ns, request)
+	tracing.SetAttributes(ctx, tracing.NamespaceKey.String(ns), tracing.TargetKey.String(namespace.Target))
+
+	gnmiCtx, cancel := o1.gnmiContext(ctx)
+	_, gnmiErr := o1.gnmiClient.Set(gnmiCtx, request)
+	cancel()
+
+	err = o1.UpdateStoreOperation(ctx, sessionID, string(operation), ns, gnmiErr)
+	if err != nil {
+		return false, err
+	}
+	if gnmiErr != nil {
+		return false, gnmiRPCError(gnmiErr)
+	}
+	o1.notifyConfigChange(ctx, sessionID, []configEdit{{namespace: namespace, request: request}})
+
+	created := operation == DataCreate || (operation == DataReplace && !exists)
+	return created, nil
+}
+
+// dataExists checks the target has a value for the path
+func (o1 *o1Controller) dataExists(ctx context.Context, namespace Namespace, path *gnmi.Path) (bool, error) {
+	gnmiCtx, cancel := o1.gnmiContext(ctx)
+	response, err := o1.gnmiClient.Get(gnmiCtx, dataGetRequest(namespace, path))
+	cancel()
+	if err != nil {
+		if status, ok := grpcstatus.FromError(err); ok && status.Code() == codes.NotFound {
+			return false, nil
+		}
+		return false, gnmiRPCError(err)
+	}
+
+	value, err := GetResponseUpdate(response)
+	if err != nil {
+		return false, err
+	}
+	jsonVal, err := typedValueJSON(value)
+	if err != nil {
+		return false, err
+	}
+	return jsonVal != nil, nil
+}
+
+// ModuleCapabilities returns the capabilities of the modules implemented by the targets of
+// onos-topo, without the capabilities of the NETCONF protocol
+func (o1 *o1Controller) ModuleCapabilities(ctx context.Context) ([]string, error) {
+	capabilities, err := o1.Capabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := o1.currentSettings().NamespacePrefix + "/"
+	modules := []string{}
+	for _, capability := range capabilities {
+		if strings.HasPrefix(capability, prefix) {
+			modules = append(modules, capability)
+		}
+	}
+	return modules, nil
+}
+
+// resolveModule returns the capability and the namespace of the target implementing the
+// module of the resource
+func (o1 *o1Controller) resolveModule(ctx context.Context, resource DataResource) (string, Namespace, error) {
+	capabilities, err := o1.ModuleCapabilities(ctx)
+	if err != nil {
+		return "", Namespace{}, err
+	}
+
+	var matches []string
+	var namespaces []Namespace
+	for _, capability := range capabilities {
+		namespace, err := ParseNamespace(capability)
+		if err != nil || namespace.Name != resource.Module {
+			continue
+		}
+		if resource.Target != "" && namespace.Target != resource.Target {
+			continue
+		}
+		matches = append(matches, capability)
+		namespaces = append(namespaces, namespace)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", Namespace{}, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagDataMissing,
+			Severity: ErrorSeverityError,
+			Path:     resourcePath(resource),
+			Message:  fmt.Sprintf("module %s is not implemented by any target", resource.Module),
+		}
+	case 1:
+		return matches[0], namespaces[0], nil
+	}
+
+	targets := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		targets = append(targets, namespace.Target)
+	}
+	return "", Namespace{}, RPCError{
+		Type:     ErrorTypeProtocol,
+		Tag:      ErrorTagInvalidValue,
+		Severity: ErrorSeverityError,
+		Path:     resourcePath(resource),
+		Message:  fmt.Sprintf("module %s is implemented by the targets %s, a target must be selected", resource.Module, strings.Join(targets, ", ")),
+	}
+}
+
+// dataPath returns the gNMI path of the data resource on the target, the names of the keys of
+// the list entries being those of the schema of the module in their YANG order
+func dataPath(modelSchema *schema.Schema, namespace Namespace, resource DataResource) (*gnmi.Path, error) {
+	path := &gnmi.Path{
+		Elem:   make([]*gnmi.PathElem, 0, len(resource.Path)),
+		Target: namespace.Target,
+	}
+	var entry *yang.Entry
+	for i, segment := range resource.Path {
+		if modelSchema != nil {
+			switch {
+			case i == 0:
+				entry = modelSchema.Root(resource.Module, segment.Name)
+			case entry != nil:
+				entry = schema.Child(entry, segment.Name)
+			}
+		}
+		elem := &gnmi.PathElem{Name: segment.Name}
+		if len(segment.Keys) > 0 {
+			keys, err := listKeys(modelSchema, entry, segment, resource)
+			if err != nil {
+				return nil, err
+			}
+			elem.Key = make(map[string]string, len(keys))
+			for j, key := range keys {
+				elem.Key[key] = segment.Keys[j]
+			}
+		}
+		path.Elem = append(path.Elem, elem)
+	}
+	return path, nil
+}
+
+// listKeys returns the names of the keys of the list entry of the segment, from the entry of
+// the list in the schema of the module
+func listKeys(modelSchema *schema.Schema, entry *yang.Entry, segment PathSegment, resource DataResource) ([]string, error) {
+	if modelSchema == nil {
+		return nil, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagOperationNotSupported,
+			Severity: ErrorSeverityError,
+			Path:     resourcePath(resource),
+			Message:  fmt.Sprintf("list entry %s can not be addressed without the schema of module %s", segment.Name, resource.Module),
+		}
+	}
+	keys := []string{}
+	if entry != nil {
+		keys = schema.Keys(entry)
+	}
+	if len(keys) != len(segment.Keys) {
+		return nil, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Path:     resourcePath(resource),
+			Message:  fmt.Sprintf("%s is not a list of module %s with %d keys", segment.Name, resource.Module, len(segment.Keys)),
+		}
+	}
+	return keys, nil
+}
+
+func dataGetRequest(namespace Namespace, path *gnmi.Path) *gnmi.GetRequest {
+	return &gnmi.GetRequest{
+		Prefix: &gnmi.Path{
+			Target: namespace.Target,
+		},
+		Path: []*gnmi.Path{path},
+		UseModels: []*gnmi.ModelData{
+			{
+				Name:    namespace.Name,
+				Version: namespace.Version,
+			},
+		},
+	}
+}
+
+// resourcePath formats the path of the resource as an instance identifier of its module
+func resourcePath(resource DataResource) string {
+	var b strings.Builder
+	for i, segment := range resource.Path {
+		b.WriteString("/")
+		if i == 0 {
+			b.WriteString(resource.Module + ":")
+		}
+		b.WriteString(segment.Name)
+		for _, key := range segment.Keys {
+			fmt.Fprintf(&b, "[%s]", key)
+		}
+	}
+	return b.String()
+}
+
+func dataMissingError(resource DataResource) RPCError {
+	return RPCError{
+		Type:     ErrorTypeApplication,
+		Tag:      ErrorTagDataMissing,
+		Severity: ErrorSeverityError,
+		Path:     resourcePath(resource),
+		Message:  "data resource not found",
+	}
+}
+
+// typedValueJSON returns the JSON encoding of the value, nil if the value is missing
+func typedValueJSON(value *gnmi.TypedValue) ([]byte, error) {
+	var scalar interface{}
+	switch v := value.GetValue().(type) {
+	case nil:
+		return nil, nil
+	case *gnmi.TypedValue_JsonVal:
+		return nonNullJSON(v.JsonVal), nil
+	case *gnmi.TypedValue_JsonIetfVal:
+		return nonNullJSON(v.JsonIetfVal), nil
+	case *gnmi.TypedValue_StringVal:
+		scalar = v.StringVal
+	case *gnmi.TypedValue_IntVal:
+		scalar = v.IntVal
+	case *gnmi.TypedValue_UintVal:
+		scalar = v.UintVal
+	case *gnmi.TypedValue_BoolVal:
+		scalar = v.BoolVal
+	case *gnmi.TypedValue_DoubleVal:
+		scalar = v.DoubleVal
+	default:
+		scalar = typedValueString(value)
+	}
+	return json.Marshal(scalar)
+}
+
+func nonNullJSON(value []byte) []byte {
+	trimmed := strings.TrimSpace(string(value))
+	if trimmed == "" || trimmed == "null" {
+		return nil
+	}
+	return value
+}
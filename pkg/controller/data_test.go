@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/onosproject/onos-o1t/pkg/schema"
+)
+
+func TestDataPath(t *testing.T) {
+	ricSchema, ok := ricRegistry(t).Schema(context.Background(), "ric", "1.0.0")
+	if !ok {
+		t.Fatal("no schema of the ric model")
+	}
+	namespace := Namespace{Target: "kpimon", Name: "ric", Version: "1.0.0"}
+	cell := DataResource{Module: "ric", Path: []PathSegment{
+		{Name: "ric"},
+		{Name: "cell", Keys: []string{"001-01", "7"}},
+		{Name: "pci"},
+	}}
+
+	tests := []struct {
+		name     string
+		schema   *schema.Schema
+		resource DataResource
+		keys     map[string]string
+		tag      string
+	}{
+		{
+			name:     "keys named in their YANG order",
+			schema:   ricSchema,
+			resource: cell,
+			keys:     map[string]string{"plmn": "001-01", "cid": "7"},
+		},
+		{
+			name:     "list entry without schema",
+			resource: cell,
+			tag:      ErrorTagOperationNotSupported,
+		},
+		{
+			name:   "wrong number of keys",
+			schema: ricSchema,
+			resource: DataResource{Module: "ric", Path: []PathSegment{
+				{Name: "ric"},
+				{Name: "cell", Keys: []string{"7"}},
+			}},
+			tag: ErrorTagInvalidValue,
+		},
+		{
+			name:   "keys of a container",
+			schema: ricSchema,
+			resource: DataResource{Module: "ric", Path: []PathSegment{
+				{Name: "ric", Keys: []string{"1"}},
+			}},
+			tag: ErrorTagInvalidValue,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := dataPath(test.schema, namespace, test.resource)
+			if test.tag != "" {
+				rpcErr, ok := err.(RPCError)
+				if !ok || rpcErr.Tag != test.tag {
+					t.Fatalf("error %v, want the error-tag %s", err, test.tag)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path.Target != "kpimon" || len(path.Elem) != 3 || path.Elem[2].Name != "pci" {
+				t.Fatalf("unexpected path %v", path)
+			}
+			if !reflect.DeepEqual(path.Elem[1].Key, test.keys) {
+				t.Errorf("keys %v, want %v", path.Elem[1].Key, test.keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const (
+	// ErrorOptionStopOnError applies the parts of an edit in order until one fails, the
+	// default error-option
+	ErrorOptionStopOnError = "stop-on-error"
+	// ErrorOptionContinueOnError applies all the parts of an edit, even after a failure
+	ErrorOptionContinueOnError = "continue-on-error"
+	// ErrorOptionRollbackOnError applies all the parts of an edit in a single transaction
+	ErrorOptionRollbackOnError = "rollback-on-error"
+)
+
+// parseErrorOption returns the error-option of an edit-config, stop-on-error if it is not set
+func parseErrorOption(errorOption string) (string, error) {
+	switch errorOption = strings.TrimSpace(errorOption); errorOption {
+	case "":
+		return ErrorOptionStopOnError, nil
+	case ErrorOptionStopOnError, ErrorOptionContinueOnError, ErrorOptionRollbackOnError:
+		return errorOption, nil
+	}
+	return "", RPCError{
+		Type:     ErrorTypeProtocol,
+		Tag:      ErrorTagInvalidValue,
+		Severity: ErrorSeverityError,
+		Message:  fmt.Sprintf("invalid error-option %s", errorOption),
+		Info:     "<error-info><bad-element>error-option</bad-element></error-info>",
+	}
+}
+
+// EditRequest is the translation of an edit-config
+type EditRequest struct {
+	// Parts are the parts of the edit, in the order of the config
+	Parts       []EditPart
+	TestOption  string
+	ErrorOption string
+}
+
+// EditPart is a top-level data node of the config of an edit, with the gNMI SetRequest
+// applying it to its target
+type EditPart struct {
+	Namespace Namespace
+	// Path is the path of the data node, the error-path of its errors
+	Path    string
+	Request *gnmi.SetRequest
+	// Config is the JSON object of the data node, nil if the node is deleted
+	Config []byte
+}
+
+// Namespaces returns the namespaces of the parts as target:name:version, each once
+func (r *EditRequest) Namespaces() []string {
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, part := range r.Parts {
+		ns := fmt.Sprintf("%s:%s:%s", part.Namespace.Target, part.Namespace.Name, part.Namespace.Version)
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// SetRequest returns the gNMI SetRequest applying all the parts in a single transaction. The
+// target is set in the prefix when the parts have the same target, in the paths otherwise.
+func (r *EditRequest) SetRequest() *gnmi.SetRequest {
+	request := &gnmi.SetRequest{
+		Prefix: &gnmi.Path{
+			Elem: []*gnmi.PathElem{},
+		},
+	}
+
+	target := ""
+	for i, part := range r.Parts {
+		if i == 0 {
+			target = part.Namespace.Target
+		} else if part.Namespace.Target != target {
+			target = ""
+			break
+		}
+	}
+	request.Prefix.Target = target
+
+	targetPath := func(part EditPart, path *gnmi.Path) *gnmi.Path {
+		if target != "" {
+			return path
+		}
+		return &gnmi.Path{Elem: path.GetElem(), Target: part.Namespace.Target}
+	}
+	targetUpdates := func(part EditPart, updates []*gnmi.Update) []*gnmi.Update {
+		var targeted []*gnmi.Update
+		for _, update := range updates {
+			targeted = append(targeted, &gnmi.Update{Path: targetPath(part, update.Path), Val: update.Val})
+		}
+		return targeted
+	}
+	for _, part := range r.Parts {
+		for _, path := range part.Request.Delete {
+			request.Delete = append(request.Delete, targetPath(part, path))
+		}
+		request.Replace = append(request.Replace, targetUpdates(part, part.Request.Replace)...)
+		request.Update = append(request.Update, targetUpdates(part, part.Request.Update)...)
+	}
+	return request
+}
+
+// partResult is the outcome of a part of an edit
+type partResult struct {
+	applied bool
+	// skipped is set for the parts not applied after the failure of a previous part
+	skipped bool
+	err     error
+}
+
+// applyEdit applies the parts of the edit according to its error-option, in a single gNMI
+// set request for rollback-on-error and in a set request for each part otherwise
+func (o1 *o1Controller) applyEdit(ctx context.Context, edit *EditRequest) []partResult {
+	results := make([]partResult, len(edit.Parts))
+
+	if edit.ErrorOption == ErrorOptionRollbackOnError {
+		err := o1.set(ctx, edit.SetRequest())
+		for i := range results {
+			results[i] = partResult{applied: err == nil, err: err}
+		}
+		return results
+	}
+
+	failed := false
+	for i, part := range edit.Parts {
+		if failed && edit.ErrorOption == ErrorOptionStopOnError {
+			results[i].skipped = true
+			continue
+		}
+		err := o1.set(ctx, part.Request)
+		results[i] = partResult{applied: err == nil, err: err}
+		failed = failed || err != nil
+	}
+	return results
+}
+
+// testEdit checks each part of the edit as a dry run, without applying it
+func (o1 *o1Controller) testEdit(ctx context.Context, edit *EditRequest) []partResult {
+	results := make([]partResult, len(edit.Parts))
+	for i, part := range edit.Parts {
+		results[i].err = o1.dryRun(ctx, part)
+	}
+	return results
+}
+
+// set sends the gNMI set request to the southbound
+func (o1 *o1Controller) set(ctx context.Context, request *gnmi.SetRequest) error {
+	gnmiCtx, cancel := o1.gnmiContext(ctx)
+	defer cancel()
+
+	response, err := o1.gnmiClient.Set(gnmiCtx, request)
+	log.Debugf("%v", response)
+	return err
+}
+
+// editErrors returns the rpc-errors of the parts of an edit that failed or were not applied,
+// an error of a rollback-on-error transaction being reported once. The messages are prefixed
+// by the namespace of their part when the edit has several namespaces.
+func editErrors(edit *EditRequest, results []partResult) []RPCError {
+	multiNamespace := len(edit.Namespaces()) > 1
+
+	var rpcErrors []RPCError
+	for i, result := range results {
+		part := edit.Parts[i]
+		var rpcErr RPCError
+		switch {
+		case result.skipped:
+			rpcErr = RPCError{
+				Type:     ErrorTypeApplication,
+				Tag:      ErrorTagOperationFailed,
+				Severity: ErrorSeverityError,
+				Message:  "not applied after the failure of a previous part of the edit",
+			}
+		case result.err != nil:
+			rpcErr = gnmiRPCError(result.err)
+			if edit.ErrorOption == ErrorOptionRollbackOnError && len(edit.Parts) > 1 {
+				return append(rpcErrors, rpcErr)
+			}
+		default:
+			continue
+		}
+		if rpcErr.Path == "" {
+			rpcErr.Path = part.Path
+		}
+		if multiNamespace {
+			rpcErr.Message = fmt.Sprintf("%s:%s:%s: %s", part.Namespace.Target, part.Namespace.Name, part.Namespace.Version, rpcErr.Message)
+		}
+		rpcErrors = append(rpcErrors, rpcErr)
+	}
+	return rpcErrors
+}
+
+// UpdateStoreEdit records the operation of an edit in the store, with the outcome of each of
+// its parts
+func (o1 *o1Controller) UpdateStoreEdit(ctx context.Context, sessionID, operation string, edit *EditRequest, results []partResult) error {
+	newOp := store.Operation{
+		Name:      operation,
+		Namespace: strings.Join(edit.Namespaces(), ","),
+		Status:    true,
+	}
+	for i, result := range results {
+		part := edit.Parts[i]
+		opPart := store.OperationPart{
+			Namespace: fmt.Sprintf("%s:%s:%s", part.Namespace.Target, part.Namespace.Name, part.Namespace.Version),
+			Path:      part.Path,
+			Applied:   result.applied,
+		}
+		switch {
+		case result.err != nil:
+			opPart.Error = result.err.Error()
+			newOp.Status = false
+		case result.skipped:
+			opPart.Error = "not applied"
+			newOp.Status = false
+		}
+		newOp.Parts = append(newOp.Parts, opPart)
+	}
+
+	log.Infof("Update store session %s operation %s namespace %s status %v", sessionID, operation, newOp.Namespace, newOp.Status)
+	return o1.addStoreOperation(ctx, sessionID, time.Now(), newOp)
+}
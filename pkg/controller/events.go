@@ -0,0 +1,274 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// NetconfNotificationsNamespace is the namespace of the NETCONF base notifications of RFC 6470
+const NetconfNotificationsNamespace = "urn:ietf:params:xml:ns:yang:ietf-netconf-notifications"
+
+// ChangedBy is the server or the session at the origin of a change
+type ChangedBy struct {
+	Server     *struct{} `xml:"server,omitempty"`
+	Username   string    `xml:"username,omitempty"`
+	SessionID  string    `xml:"session-id,omitempty"`
+	SourceHost string    `xml:"source-host,omitempty"`
+}
+
+type NetconfConfigChange struct {
+	XMLName   xml.Name           `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-config-change"`
+	ChangedBy ChangedBy          `xml:"changed-by"`
+	Datastore string             `xml:"datastore"`
+	Edits     []ConfigChangeEdit `xml:"edit"`
+}
+
+type ConfigChangeEdit struct {
+	Target    EditTarget `xml:"target"`
+	Operation string     `xml:"operation"`
+}
+
+// EditTarget is the instance-identifier of an edited node, its prefix being declared by its
+// namespace attribute
+type EditTarget struct {
+	Attrs []xml.Attr `xml:",any,attr"`
+	Path  string     `xml:",chardata"`
+}
+
+type NetconfSessionStart struct {
+	XMLName    xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-session-start"`
+	Username   string   `xml:"username"`
+	SessionID  string   `xml:"session-id"`
+	SourceHost string   `xml:"source-host,omitempty"`
+}
+
+type NetconfSessionEnd struct {
+	XMLName           xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-session-end"`
+	Username          string   `xml:"username"`
+	SessionID         string   `xml:"session-id"`
+	SourceHost        string   `xml:"source-host,omitempty"`
+	KilledBy          string   `xml:"killed-by,omitempty"`
+	TerminationReason string   `xml:"termination-reason"`
+}
+
+type NetconfCapabilityChange struct {
+	XMLName   xml.Name  `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-capability-change"`
+	ChangedBy ChangedBy `xml:"changed-by"`
+	Added     []string  `xml:"added-capability"`
+	Deleted   []string  `xml:"deleted-capability"`
+}
+
+// eventSelection selects the event notifications of the NETCONF stream sent to a session
+type eventSelection struct {
+	selected bool
+	// names are the names of the selected notifications, all of them being selected if empty
+	names map[string]bool
+}
+
+func (s eventSelection) selects(name string) bool {
+	return s.selected && (len(s.names) == 0 || s.names[name])
+}
+
+// configEdit is a gNMI set request applied to the target of a namespace
+type configEdit struct {
+	namespace Namespace
+	request   *gnmi.SetRequest
+}
+
+// publishEvent sends an event notification of the NETCONF stream to the sessions whose
+// subscription selects it, after appending it to the replay log. The events are published by
+// the code causing them rather than from a watch of the store: a set of the configuration, the
+// opening and ending of a session, or a change of the capabilities, so that they are queued to
+// the sessions in the order they happened.
+func (o1 *o1Controller) publishEvent(name string, event interface{}) {
+	data, err := xml.Marshal(event)
+	if err != nil {
+		log.Warnf("Notification %s can not be built: %v", name, err)
+		return
+	}
+	now := time.Now()
+	notification, err := xml.Marshal(&Notification{
+		EventTime: eventTime(now.UnixNano()),
+		Data:      string(data),
+	})
+	if err != nil {
+		log.Warnf("Notification %s can not be built: %v", name, err)
+		return
+	}
+	o1.recordEvent(name, now, notification)
+
+	var sessions []NotificationSession
+	o1.mu.RLock()
+	for _, sub := range o1.subscriptions {
+		if sub.live && sub.events.selects(name) {
+			sessions = append(sessions, sub.session)
+		}
+	}
+	o1.mu.RUnlock()
+
+	for _, session := range sessions {
+		err = session.Notify(notification)
+		if err != nil {
+			log.Debugf("Notification %s can not be sent: %v", name, err)
+		}
+	}
+}
+
+// netconfSessionID returns the session-id of a NETCONF session, zero for the sessions of the
+// other protocols
+func netconfSessionID(sessionID string) string {
+	if _, err := strconv.ParseUint(sessionID, 10, 32); err != nil {
+		return "0"
+	}
+	return sessionID
+}
+
+// notifyConfigChange publishes the netconf-config-change of the edits applied by the session
+// to the running datastore, an edit of each path of their set requests
+func (o1 *o1Controller) notifyConfigChange(ctx context.Context, sessionID string, edits []configEdit) {
+	change := &NetconfConfigChange{
+		ChangedBy: ChangedBy{SessionID: netconfSessionID(sessionID)},
+		Datastore: "running",
+	}
+	entry, err := o1.Store.Get(ctx, store.Key{SessionID: sessionID})
+	if err == nil {
+		if value, ok := entry.Value.(*store.SessionValue); ok {
+			change.ChangedBy.Username = value.User
+			change.ChangedBy.SourceHost = value.SourceHost
+		}
+	}
+
+	for _, edit := range edits {
+		prefix := edit.request.GetPrefix()
+		target := func(path *gnmi.Path) EditTarget {
+			return EditTarget{
+				Attrs: []xml.Attr{{Name: xml.Name{Local: "xmlns:" + edit.namespace.Name}, Value: o1.namespaceCapability(edit.namespace)}},
+				Path:  instanceIdentifier(edit.namespace.Name, joinPaths(prefix, path)),
+			}
+		}
+		for _, path := range edit.request.Delete {
+			change.Edits = append(change.Edits, ConfigChangeEdit{Target: target(path), Operation: "delete"})
+		}
+		for _, update := range edit.request.Replace {
+			change.Edits = append(change.Edits, ConfigChangeEdit{Target: target(update.Path), Operation: "replace"})
+		}
+		for _, update := range edit.request.Update {
+			change.Edits = append(change.Edits, ConfigChangeEdit{Target: target(update.Path), Operation: "merge"})
+		}
+	}
+	if len(change.Edits) == 0 {
+		return
+	}
+	o1.publishEvent("netconf-config-change", change)
+}
+
+// namespaceCapability returns the capability of the namespace
+func (o1 *o1Controller) namespaceCapability(namespace Namespace) string {
+	return o1.currentSettings().NamespacePrefix + "/" + namespace.Target + ":" + namespace.Name + ":" + namespace.Version
+}
+
+// instanceIdentifier returns the instance-identifier of a gNMI path, its nodes qualified by
+// the prefix and the keys of list entries in key name order
+func instanceIdentifier(prefix string, path *gnmi.Path) string {
+	var identifier strings.Builder
+	for _, elem := range path.GetElem() {
+		identifier.WriteString("/" + prefix + ":" + elem.Name)
+
+		names := make([]string, 0, len(elem.Key))
+		for name := range elem.Key {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := elem.Key[name]
+			quote := "'"
+			if strings.Contains(value, "'") {
+				quote = `"`
+			}
+			identifier.WriteString("[" + prefix + ":" + name + "=" + quote + value + quote + "]")
+		}
+	}
+	if identifier.Len() == 0 {
+		return "/"
+	}
+	return identifier.String()
+}
+
+// terminationReasons are the termination-reason of the end reasons of the sessions
+var terminationReasons = map[store.EndReason]string{
+	store.EndReasonClosed:         "closed",
+	store.EndReasonKilled:         "killed",
+	store.EndReasonIdleTimeout:    "timeout",
+	store.EndReasonTransportError: "dropped",
+	store.EndReasonShutdown:       "other",
+}
+
+// publishSessionStart publishes the netconf-session-start of an opened NETCONF session
+func (o1 *o1Controller) publishSessionStart(info SessionInfo) {
+	if netconfSessionID(info.SessionID) == "0" {
+		return
+	}
+	o1.publishEvent("netconf-session-start", &NetconfSessionStart{
+		Username:   info.User,
+		SessionID:  info.SessionID,
+		SourceHost: info.SourceHost,
+	})
+}
+
+// publishSessionEnd publishes the netconf-session-end of an ended NETCONF session
+func (o1 *o1Controller) publishSessionEnd(sessionID string, value *store.SessionValue) {
+	if netconfSessionID(sessionID) == "0" {
+		return
+	}
+	end := &NetconfSessionEnd{
+		Username:          value.User,
+		SessionID:         sessionID,
+		SourceHost:        value.SourceHost,
+		TerminationReason: terminationReasons[value.EndReason],
+	}
+	if value.EndReason == store.EndReasonKilled {
+		end.KilledBy = netconfSessionID(value.KilledBy)
+	}
+	o1.publishEvent("netconf-session-end", end)
+}
+
+// notifyCapabilityChange publishes the netconf-capability-change of the capabilities added and
+// deleted by the server, if any
+func (o1 *o1Controller) notifyCapabilityChange(previous, current []string) {
+	change := &NetconfCapabilityChange{
+		ChangedBy: ChangedBy{Server: &struct{}{}},
+		Added:     difference(current, previous),
+		Deleted:   difference(previous, current),
+	}
+	if len(change.Added) == 0 && len(change.Deleted) == 0 {
+		return
+	}
+	log.Infof("Capabilities added %v deleted %v", change.Added, change.Deleted)
+	o1.publishEvent("netconf-capability-change", change)
+}
+
+// difference returns the elements of a that are not in b
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	var diff []string
+	for _, s := range a {
+		if !in[s] {
+			diff = append(diff, s)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	gnxi "github.com/google/gnxi/utils/xpath"
+)
+
+// NamespaceMapping maps the data of the namespace of a YANG module, such as the namespace of
+// an O-RAN module, to a target and model of onos-config. The mappings of a namespace are
+// selected in order, by the value of their selector leaf if they have one.
+type NamespaceMapping struct {
+	// Namespace is the namespace of the module
+	Namespace string
+	// Target is the target of the data, named by the value of the selector leaf if empty
+	Target  string
+	Model   string
+	Version string
+	// Leaf is the name of a leaf of the data selecting the mapping, such as a list key
+	Leaf string
+	// Value and Prefix select the mapping when the value of the leaf is equal to Value or
+	// starts with Prefix, any value selecting it when both are empty
+	Value  string
+	Prefix string
+}
+
+// selects checks the mapping selects the data with the value of its leaf, found false if
+// the data has no such leaf
+func (m NamespaceMapping) selects(value string, found bool) bool {
+	if m.Leaf == "" {
+		return true
+	}
+	if !found {
+		return false
+	}
+	switch {
+	case m.Value != "":
+		return value == m.Value
+	case m.Prefix != "":
+		return strings.HasPrefix(value, m.Prefix)
+	}
+	return value != ""
+}
+
+// mapNamespace returns the target and model of the data of the namespace selected by the
+// mappings, leafValue returning the value of a leaf of the data. mapped is false if no
+// mapping has the namespace, the namespace being that of a capability.
+func mapNamespace(mappings []NamespaceMapping, namespace string, leafValue func(leaf string) (string, bool)) (Namespace, bool, error) {
+	mapped := false
+	for _, mapping := range mappings {
+		if mapping.Namespace != namespace {
+			continue
+		}
+		mapped = true
+		value, found := "", false
+		if mapping.Leaf != "" {
+			value, found = leafValue(mapping.Leaf)
+		}
+		if !mapping.selects(value, found) {
+			continue
+		}
+		target := mapping.Target
+		if target == "" {
+			target = value
+		}
+		return Namespace{Target: target, Name: mapping.Model, Version: mapping.Version}, true, nil
+	}
+	if mapped {
+		return Namespace{}, true, RPCError{
+			Type:     ErrorTypeApplication,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  fmt.Sprintf("no target is mapped to the data of namespace %s", namespace),
+		}
+	}
+	return Namespace{}, false, nil
+}
+
+// xpathKeyValue returns a leaf value function returning the value of the first key of the
+// elements of the xpath with the name of the leaf
+func xpathKeyValue(xpath string) func(leaf string) (string, bool) {
+	return func(leaf string) (string, bool) {
+		path, err := gnxi.ToGNMIPath(xpath)
+		if err != nil {
+			return "", false
+		}
+		for _, elem := range path.Elem {
+			if value, ok := elem.Key[leaf]; ok {
+				return value, true
+			}
+		}
+		return "", false
+	}
+}
+
+// dataLeafValue returns a leaf value function returning the text of the first element of the
+// XML data with the name of the leaf
+func dataLeafValue(data string) func(leaf string) (string, bool) {
+	return func(leaf string) (string, bool) {
+		decoder := xml.NewDecoder(strings.NewReader(data))
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return "", false
+			}
+			element, ok := token.(xml.StartElement)
+			if !ok || element.Name.Local != leaf {
+				continue
+			}
+			var value string
+			if decoder.DecodeElement(&value, &element) != nil {
+				return "", false
+			}
+			return strings.TrimSpace(value), true
+		}
+	}
+}
+
+// currentMappings returns the namespace mappings of the settings followed by those of the
+// onos-topo entities
+func (o1 *o1Controller) currentMappings() []NamespaceMapping {
+	o1.mu.RLock()
+	defer o1.mu.RUnlock()
+
+	mappings := make([]NamespaceMapping, 0, len(o1.settings.NamespaceMappings)+len(o1.topoMappings))
+	mappings = append(mappings, o1.settings.NamespaceMappings...)
+	return append(mappings, o1.topoMappings...)
+}
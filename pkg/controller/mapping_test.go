@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"testing"
+)
+
+const oranNamespace = "urn:o-ran:hardware:1.0"
+
+func TestMapNamespace(t *testing.T) {
+	mappings := []NamespaceMapping{
+		{Namespace: oranNamespace, Leaf: "name", Value: "ru-1", Target: "du-1", Model: "ric", Version: "1.0.0"},
+		{Namespace: oranNamespace, Leaf: "name", Prefix: "cell-", Target: "cu-1", Model: "ric", Version: "2.0.0"},
+		{Namespace: oranNamespace, Leaf: "name", Model: "hw", Version: "1.0.0"},
+		{Namespace: "urn:o-ran:sync:1.0", Target: "sync", Model: "sync", Version: "1.0.0"},
+		{Namespace: "urn:o-ran:fm:1.0", Leaf: "name", Value: "alarm", Target: "fm", Model: "fm", Version: "1.0.0"},
+	}
+	leaf := func(value string) func(string) (string, bool) {
+		return func(leaf string) (string, bool) {
+			return value, leaf == "name" && value != ""
+		}
+	}
+	tests := []struct {
+		name      string
+		namespace string
+		leafValue func(string) (string, bool)
+		want      Namespace
+		unmapped  bool
+		err       bool
+	}{
+		{name: "by value", namespace: oranNamespace, leafValue: leaf("ru-1"), want: Namespace{Target: "du-1", Name: "ric", Version: "1.0.0"}},
+		{name: "by prefix", namespace: oranNamespace, leafValue: leaf("cell-7"), want: Namespace{Target: "cu-1", Name: "ric", Version: "2.0.0"}},
+		{name: "target named by the leaf", namespace: oranNamespace, leafValue: leaf("ru-2"), want: Namespace{Target: "ru-2", Name: "hw", Version: "1.0.0"}},
+		{name: "without selector", namespace: "urn:o-ran:sync:1.0", leafValue: leaf(""), want: Namespace{Target: "sync", Name: "sync", Version: "1.0.0"}},
+		{name: "no leaf", namespace: oranNamespace, leafValue: leaf(""), err: true},
+		{name: "no match", namespace: "urn:o-ran:fm:1.0", leafValue: leaf("fault"), err: true},
+		{name: "capability namespace", namespace: kpimonNamespace, leafValue: leaf("ru-1"), unmapped: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			namespace, mapped, err := mapNamespace(mappings, test.namespace, test.leafValue)
+			if mapped == test.unmapped {
+				t.Fatalf("mapped %t, want %t", mapped, !test.unmapped)
+			}
+			if test.err {
+				rpcErr, ok := err.(RPCError)
+				if !ok || rpcErr.Tag != ErrorTagInvalidValue {
+					t.Fatalf("error %v, want %s", err, ErrorTagInvalidValue)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if namespace != test.want {
+				t.Errorf("namespace %+v, want %+v", namespace, test.want)
+			}
+		})
+	}
+}
+
+func TestLeafValues(t *testing.T) {
+	xpath := xpathKeyValue("/hardware/component[name=ru-1]/sensor[id=4]")
+	if value, ok := xpath("name"); !ok || value != "ru-1" {
+		t.Errorf("xpath key %q %t, want ru-1", value, ok)
+	}
+	if value, ok := xpath("id"); !ok || value != "4" {
+		t.Errorf("xpath key %q %t, want 4", value, ok)
+	}
+	if _, ok := xpath("class"); ok {
+		t.Error("missing xpath key found")
+	}
+
+	data := dataLeafValue(`<hardware xmlns="` + oranNamespace + `"><component><class>radio</class><name> ru-1 </name></component><name>ru-2</name></hardware>`)
+	if value, ok := data("name"); !ok || value != "ru-1" {
+		t.Errorf("data leaf %q %t, want ru-1", value, ok)
+	}
+	if _, ok := data("serial"); ok {
+		t.Error("missing data leaf found")
+	}
+}
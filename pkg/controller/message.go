@@ -32,6 +32,27 @@ import (
 	"io"
 )
 
+const (
+	ErrorTypeProtocol    = "protocol"
+	ErrorTypeApplication = "application"
+	ErrorTypeRPC         = "rpc"
+
+	ErrorTagInvalidValue          = "invalid-value"
+	ErrorTagOperationNotSupported = "operation-not-supported"
+	ErrorTagOperationFailed       = "operation-failed"
+	ErrorTagLockDenied            = "lock-denied"
+	ErrorTagInUse                 = "in-use"
+	ErrorTagDataMissing           = "data-missing"
+	ErrorTagDataExists            = "data-exists"
+	ErrorTagAccessDenied          = "access-denied"
+	ErrorTagResourceDenied        = "resource-denied"
+	ErrorTagUnknownElement        = "unknown-element"
+	ErrorTagBadElement            = "bad-element"
+	ErrorTagMissingElement        = "missing-element"
+
+	ErrorSeverityError = "error"
+)
+
 func NewUUID() string {
 	b := make([]byte, 16)
 	_, _ = io.ReadFull(rand.Reader, b)
@@ -50,11 +71,18 @@ type RPCError struct {
 	Type     string `xml:"error-type"`
 	Tag      string `xml:"error-tag"`
 	Severity string `xml:"error-severity"`
+	AppTag   string `xml:"error-app-tag,omitempty"`
 	Path     string `xml:"error-path"`
 	Message  string `xml:"error-message"`
 	Info     string `xml:",innerxml"`
 }
 
+// Error returns the message of the rpc-error, so it can be returned as an error by the
+// operations of the controller that do not reply a NETCONF rpc
+func (e RPCError) Error() string {
+	return e.Message
+}
+
 type RPCReply struct {
 	XMLName        xml.Name   `xml:"urn:ietf:params:xml:ns:netconf:base:1.1 rpc-reply"`
 	MessageID      string     `xml:"message-id,attr"`
@@ -92,9 +120,23 @@ type EditConfig struct {
 	RPC
 	Target           *Datastore `xml:"edit-config>target"`
 	DefaultOperation string     `xml:"edit-config>default-operation,omitempty"`
+	TestOption       string     `xml:"edit-config>test-option,omitempty"`
+	ErrorOption      string     `xml:"edit-config>error-option,omitempty"`
 	Config           *config    `xml:"edit-config>config"`
 }
 
+// Validate validates the config of its source, inline or a datastore
+type Validate struct {
+	RPC
+	Source *ValidateSource `xml:"validate>source"`
+}
+
+// ValidateSource is the source of a validate rpc
+type ValidateSource struct {
+	DatastoreName
+	Config *config `xml:"config"`
+}
+
 type Hello struct {
 	XMLName      xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.1 hello"`
 	Capabilities []string `xml:"capabilities>capability"`
@@ -110,3 +152,25 @@ type KillSession struct {
 	RPC
 	SessionID string `xml:"kill-session>session-id"`
 }
+
+// DatastoreName parses the datastore element of source and target parameters
+type DatastoreName struct {
+	Candidate *struct{} `xml:"candidate"`
+	Running   *struct{} `xml:"running"`
+	Startup   *struct{} `xml:"startup"`
+}
+
+// Name returns the name of the referenced datastore
+func (d *DatastoreName) Name() string {
+	switch {
+	case d == nil:
+		return ""
+	case d.Running != nil:
+		return "running"
+	case d.Candidate != nil:
+		return "candidate"
+	case d.Startup != nil:
+		return "startup"
+	}
+	return ""
+}
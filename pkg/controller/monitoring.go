@@ -0,0 +1,472 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-o1t/pkg/health"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+const (
+	NetconfMonitoringNamespace = "urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"
+	// O1tHealthNamespace is the namespace of the health of onos-o1t, augmenting netconf-state
+	O1tHealthNamespace = "http://opennetworking.org/o1t/health"
+
+	HealthStatusReady    = "ready"
+	HealthStatusDegraded = "degraded"
+)
+
+// HealthReporter reports the health of the components of onos-o1t
+type HealthReporter interface {
+	Statuses() []health.ComponentStatus
+}
+
+// WithHealth reports the health of the components in the netconf-state of ietf-netconf-monitoring
+func WithHealth(reporter HealthReporter) Option {
+	return func(o1 *o1Controller) {
+		o1.health = reporter
+	}
+}
+
+type Get struct {
+	RPC
+	Filter *SubtreeFilter `xml:"get>filter"`
+}
+
+type SubtreeFilter struct {
+	Type string `xml:"type,attr,omitempty"`
+	Data string `xml:",innerxml"`
+}
+
+// NetconfState is the netconf-state container of ietf-netconf-monitoring (RFC 6022), its
+// containers being omitted when the filter does not select them
+type NetconfState struct {
+	XMLName      xml.Name                `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring netconf-state"`
+	Capabilities *MonitoringCapabilities `xml:"capabilities,omitempty"`
+	Datastores   *Datastores             `xml:"datastores,omitempty"`
+	Schemas      *Schemas                `xml:"schemas,omitempty"`
+	Sessions     *Sessions               `xml:"sessions,omitempty"`
+	Statistics   *Statistics             `xml:"statistics,omitempty"`
+	Health       *O1tHealth              `xml:"http://opennetworking.org/o1t/health health,omitempty"`
+}
+
+type MonitoringCapabilities struct {
+	Capabilities []string `xml:"capability"`
+}
+
+type Datastores struct {
+	Datastores []MonitoringDatastore `xml:"datastore"`
+}
+
+type MonitoringDatastore struct {
+	Name string `xml:"name"`
+}
+
+type Schemas struct {
+	Schemas []MonitoringSchema `xml:"schema"`
+}
+
+// MonitoringSchema is a YANG module or submodule that can be retrieved with get-schema
+type MonitoringSchema struct {
+	Identifier string `xml:"identifier"`
+	Version    string `xml:"version"`
+	Format     string `xml:"format"`
+	Namespace  string `xml:"namespace"`
+	Location   string `xml:"location"`
+}
+
+type Sessions struct {
+	Sessions []MonitoringSession `xml:"session"`
+}
+
+type MonitoringSession struct {
+	SessionID        string `xml:"session-id"`
+	Transport        string `xml:"transport"`
+	Username         string `xml:"username"`
+	SourceHost       string `xml:"source-host,omitempty"`
+	LoginTime        string `xml:"login-time"`
+	InRPCs           uint32 `xml:"in-rpcs"`
+	InBadRPCs        uint32 `xml:"in-bad-rpcs"`
+	OutRPCErrors     uint32 `xml:"out-rpc-errors"`
+	OutNotifications uint32 `xml:"out-notifications"`
+}
+
+type Statistics struct {
+	NetconfStartTime string `xml:"netconf-start-time"`
+	InBadHellos      uint32 `xml:"in-bad-hellos"`
+	InSessions       uint32 `xml:"in-sessions"`
+	DroppedSessions  uint32 `xml:"dropped-sessions"`
+	InRPCs           uint32 `xml:"in-rpcs"`
+	InBadRPCs        uint32 `xml:"in-bad-rpcs"`
+	OutRPCErrors     uint32 `xml:"out-rpc-errors"`
+	OutNotifications uint32 `xml:"out-notifications"`
+}
+
+type GetSchema struct {
+	RPC
+	Identifier string `xml:"get-schema>identifier"`
+	Version    string `xml:"get-schema>version"`
+	Format     string `xml:"get-schema>format"`
+}
+
+// Netconf is the netconf container of the event streams of RFC 5277
+type Netconf struct {
+	XMLName xml.Name      `xml:"urn:ietf:params:xml:ns:netmod:notification netconf"`
+	Streams []EventStream `xml:"streams>stream"`
+}
+
+type EventStream struct {
+	Name                  string `xml:"name"`
+	Description           string `xml:"description"`
+	ReplaySupport         bool   `xml:"replaySupport"`
+	ReplayLogCreationTime string `xml:"replayLogCreationTime,omitempty"`
+}
+
+type O1tHealth struct {
+	Status     string            `xml:"status"`
+	Components []HealthComponent `xml:"component"`
+}
+
+type HealthComponent struct {
+	Name   string `xml:"name"`
+	Status string `xml:"status"`
+	Error  string `xml:"error,omitempty"`
+}
+
+// GetState replies the state data of ietf-netconf-monitoring and the event streams selected by
+// the subtree filter of the request
+func (o1 *o1Controller) GetState(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(Get)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Filter != nil && request.Filter.Type != "" && request.Filter.Type != "subtree" {
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  "get filter must be subtree",
+		})
+	}
+
+	data := ""
+	selected, children := true, map[string]bool(nil)
+	if request.Filter != nil {
+		selected, children = filterSelection(request.Filter, NetconfMonitoringNamespace, "netconf-state")
+	}
+	if selected {
+		state, err := o1.netconfState(ctx, children)
+		if err != nil {
+			return nil, err
+		}
+		stateXML, err := xml.Marshal(state)
+		if err != nil {
+			return nil, err
+		}
+		data = string(stateXML)
+	}
+	if request.Filter != nil {
+		selected, _ = filterSelection(request.Filter, NetmodNotificationNamespace, "netconf")
+	}
+	if selected {
+		streams, err := xml.Marshal(&Netconf{Streams: o1.eventStreams()})
+		if err != nil {
+			return nil, err
+		}
+		data += string(streams)
+	}
+	if request.Filter != nil {
+		selected, children = filterSelection(request.Filter, YangLibraryNamespace, "yang-library")
+	} else {
+		children = nil
+	}
+	if selected {
+		library, err := xml.Marshal(o1.currentLibrary(children))
+		if err != nil {
+			return nil, err
+		}
+		data += string(library)
+	}
+
+	reply := new(RPCReply)
+	reply.MessageID = request.MessageID
+	reply.Data = "<data>" + data + "</data>"
+	return xml.Marshal(reply)
+}
+
+// filterSelection checks a top level element of the subtree filter selects the container of
+// the namespace, returning the names of the child elements of the container it selects, nil
+// for all of them when an element selecting it has no child
+func filterSelection(filter *SubtreeFilter, namespace, name string) (bool, map[string]bool) {
+	decoder := xml.NewDecoder(bytes.NewReader([]byte(filter.Data)))
+	depth := 0
+	selected, all := false, false
+	children := make(map[string]bool)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if depth == 0 && element.Name.Space == namespace && element.Name.Local == name {
+				selected = true
+				names, err := childNames(decoder)
+				if err != nil {
+					return selected, nil
+				}
+				all = all || len(names) == 0
+				for _, name := range names {
+					children[name] = true
+				}
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if !selected || all {
+		return selected, nil
+	}
+	return selected, children
+}
+
+// childNames returns the names of the child elements of the element just started, reading
+// the decoder up to its end element
+func childNames(decoder *xml.Decoder) ([]string, error) {
+	var names []string
+	depth := 1
+	for depth > 0 {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if depth == 1 {
+				names = append(names, element.Name.Local)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return names, nil
+}
+
+// netconfState returns the netconf-state container with the children selected, all of them
+// if nil
+func (o1 *o1Controller) netconfState(ctx context.Context, children map[string]bool) (*NetconfState, error) {
+	selects := func(name string) bool {
+		return children == nil || children[name]
+	}
+	state := &NetconfState{}
+	if selects("capabilities") {
+		state.Capabilities = &MonitoringCapabilities{Capabilities: o1.currentCapabilities()}
+	}
+	if selects("datastores") {
+		state.Datastores = o1.datastores()
+	}
+	if selects("schemas") {
+		state.Schemas = o1.monitoringSchemas()
+	}
+	if selects("sessions") {
+		sessions, err := o1.monitoringSessions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		state.Sessions = sessions
+	}
+	if selects("statistics") {
+		state.Statistics = o1.statistics()
+	}
+	if selects("health") {
+		state.Health = o1.healthState()
+	}
+	return state, nil
+}
+
+// datastores returns the running datastore
+func (o1 *o1Controller) datastores() *Datastores {
+	return &Datastores{Datastores: []MonitoringDatastore{{Name: "running"}}}
+}
+
+// monitoringSchemas returns the YANG modules and submodules of the schemas, retrieved with
+// get-schema
+func (o1 *o1Controller) monitoringSchemas() *Schemas {
+	schemas := &Schemas{}
+	if o1.schemas == nil {
+		return schemas
+	}
+	for _, module := range o1.schemas.ModuleInfos() {
+		schemas.Schemas = append(schemas.Schemas, MonitoringSchema{
+			Identifier: module.Name,
+			Version:    module.Revision,
+			Format:     "yang",
+			Namespace:  module.Namespace,
+			Location:   "NETCONF",
+		})
+	}
+	return schemas
+}
+
+// transports are the identities of ietf-netconf-monitoring of the NETCONF transports
+var transports = map[string]string{
+	"ssh": "netconf-ssh",
+	"tls": "netconf-tls",
+}
+
+// monitoringSessions returns the alive NETCONF sessions of the store, with their counters
+func (o1 *o1Controller) monitoringSessions(ctx context.Context) (*Sessions, error) {
+	ch := make(chan *store.Entry)
+	done := make(chan []MonitoringSession)
+
+	go func() {
+		sessions := []MonitoringSession{}
+		for entry := range ch {
+			value, ok := entry.Value.(*store.SessionValue)
+			if !ok || !value.Alive || netconfSessionID(entry.Key.SessionID) == "0" {
+				continue
+			}
+			transport, ok := transports[value.Transport]
+			if !ok {
+				continue
+			}
+			c := o1.countersOf(entry.Key.SessionID)
+			sessions = append(sessions, MonitoringSession{
+				SessionID:        entry.Key.SessionID,
+				Transport:        transport,
+				Username:         value.User,
+				SourceHost:       value.SourceHost,
+				LoginTime:        eventTime(int64(value.LoginTime)),
+				InRPCs:           c.inRPCs,
+				InBadRPCs:        c.inBadRPCs,
+				OutRPCErrors:     c.outRPCErrors,
+				OutNotifications: c.outNotifications,
+			})
+		}
+		done <- sessions
+	}()
+
+	err := o1.Store.Entries(ctx, ch)
+	sessions := <-done
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		a, _ := strconv.ParseUint(sessions[i].SessionID, 10, 32)
+		b, _ := strconv.ParseUint(sessions[j].SessionID, 10, 32)
+		return a < b
+	})
+	return &Sessions{Sessions: sessions}, nil
+}
+
+// statistics returns the global statistics of the server
+func (o1 *o1Controller) statistics() *Statistics {
+	c := o1.stats.load()
+	return &Statistics{
+		NetconfStartTime: o1.stats.startTime.UTC().Format(time.RFC3339Nano),
+		InBadHellos:      atomic.LoadUint32(&o1.stats.inBadHellos),
+		InSessions:       atomic.LoadUint32(&o1.stats.inSessions),
+		DroppedSessions:  atomic.LoadUint32(&o1.stats.droppedSessions),
+		InRPCs:           c.inRPCs,
+		InBadRPCs:        c.inBadRPCs,
+		OutRPCErrors:     c.outRPCErrors,
+		OutNotifications: c.outNotifications,
+	}
+}
+
+// healthState returns the health of the components of onos-o1t, nil if it is not reported
+func (o1 *o1Controller) healthState() *O1tHealth {
+	if o1.health == nil {
+		return nil
+	}
+
+	health := &O1tHealth{
+		Status: HealthStatusReady,
+	}
+	for _, status := range o1.health.Statuses() {
+		component := HealthComponent{
+			Name:   status.Name,
+			Status: "up",
+		}
+		if !status.Up {
+			component.Status = "down"
+			component.Error = status.Error
+			health.Status = HealthStatusDegraded
+		}
+		health.Components = append(health.Components, component)
+	}
+	return health
+}
+
+// sourceEscaper escapes the YANG source of a schema as XML text, keeping its line breaks
+var sourceEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// GetSchema replies the YANG source of a module or submodule of the schemas
+func (o1 *o1Controller) GetSchema(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(GetSchema)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	identifier := strings.TrimSpace(request.Identifier)
+	if identifier == "" {
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagMissingElement,
+			Severity: ErrorSeverityError,
+			Message:  "get-schema requires an identifier",
+			Info:     "<error-info><bad-element>identifier</bad-element></error-info>",
+		})
+	}
+	format := strings.TrimSpace(request.Format)
+	if i := strings.Index(format, ":"); i >= 0 {
+		format = format[i+1:]
+	}
+	if format != "" && format != "yang" {
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  fmt.Sprintf("format %s not supported", request.Format),
+			Info:     "<error-info><bad-element>format</bad-element></error-info>",
+		})
+	}
+
+	source, ok := "", false
+	if o1.schemas != nil {
+		source, ok = o1.schemas.Source(identifier, strings.TrimSpace(request.Version))
+	}
+	if !ok {
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  fmt.Sprintf("schema %s not found", identifier),
+			Info:     "<error-info><bad-element>identifier</bad-element></error-info>",
+		})
+	}
+
+	reply := new(RPCReply)
+	reply.MessageID = request.MessageID
+	reply.Data = `<data xmlns="` + NetconfMonitoringNamespace + `">` + sourceEscaper.Replace(source) + "</data>"
+	return xml.Marshal(reply)
+}
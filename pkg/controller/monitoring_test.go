@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/onosproject/onos-o1t/pkg/health"
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// fakeSchemas provides the sources of the modules of the test
+type fakeSchemas struct {
+	modules []schema.ModuleInfo
+	sources map[string]string
+}
+
+func (s *fakeSchemas) Schema(ctx context.Context, name, version string) (*schema.Schema, bool) {
+	return nil, false
+}
+
+func (s *fakeSchemas) ModuleInfos() []schema.ModuleInfo {
+	return s.modules
+}
+
+func (s *fakeSchemas) Source(name, revision string) (string, bool) {
+	source, ok := s.sources[name+"@"+revision]
+	if !ok && revision == "" {
+		for key, value := range s.sources {
+			if strings.HasPrefix(key, name+"@") {
+				return value, true
+			}
+		}
+	}
+	return source, ok
+}
+
+// fakeHealth reports the statuses of the test
+type fakeHealth []health.ComponentStatus
+
+func (h fakeHealth) Statuses() []health.ComponentStatus {
+	return h
+}
+
+func TestFilterSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		selected bool
+		children []string
+	}{
+		{name: "container", filter: `<netconf-state xmlns="` + NetconfMonitoringNamespace + `"/>`, selected: true},
+		{name: "children", filter: `<netconf-state xmlns="` + NetconfMonitoringNamespace + `"><sessions/><statistics/></netconf-state>`, selected: true, children: []string{"sessions", "statistics"}},
+		{name: "container and children", filter: `<netconf-state xmlns="` + NetconfMonitoringNamespace + `"><sessions/></netconf-state><netconf-state xmlns="` + NetconfMonitoringNamespace + `"/>`, selected: true},
+		{name: "other namespace", filter: `<netconf-state xmlns="urn:other"/>`},
+		{name: "nested", filter: `<top xmlns="` + NetconfMonitoringNamespace + `"><netconf-state/></top>`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, children := filterSelection(&SubtreeFilter{Data: test.filter}, NetconfMonitoringNamespace, "netconf-state")
+			if selected != test.selected {
+				t.Fatalf("selected %t, want %t", selected, test.selected)
+			}
+			if len(children) != len(test.children) {
+				t.Fatalf("children %v, want %v", children, test.children)
+			}
+			for _, name := range test.children {
+				if !children[name] {
+					t.Errorf("child %s not selected", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGetState(t *testing.T) {
+	topo := rnib.NewFakeClient(rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:1.0.0"})
+	reporter := fakeHealth{{Name: "onos-config", Up: true}, {Name: "onos-topo", Error: "unavailable"}}
+	schemas := &fakeSchemas{modules: []schema.ModuleInfo{{Name: "ric", Revision: "2022-01-01", Namespace: "urn:onf:ric"}}}
+	o1 := NewO1Controller(store.NewStore(), topo, nil, WithHealth(reporter), WithSchemas(schemas)).(*o1Controller)
+	defer o1.Close()
+
+	ctx := context.Background()
+	_, err := o1.Capabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range []SessionInfo{
+		{SessionID: "2", User: "bob", SourceHost: "10.0.0.2", Transport: "tls"},
+		{SessionID: "1", User: "alice", SourceHost: "10.0.0.1", Transport: "ssh"},
+		{SessionID: "restconf-1", User: "carol", Transport: "restconf"},
+		{SessionID: "3", User: "dave", Transport: "ssh"},
+	} {
+		err = o1.OpenSession(ctx, info, newFakeSession())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = o1.EndSession(ctx, "3", store.EndReasonClosed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitEnded(t, o1.Store, "3")
+
+	get := func(filter string) string {
+		t.Helper()
+		reply, err := o1.Handler(ctx, "1", []byte(`<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.1"><get>`+filter+`</get></rpc>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(reply)
+	}
+
+	// the sessions selected alone are the alive NETCONF sessions, in session-id order
+	reply := get(`<filter type="subtree"><netconf-state xmlns="` + NetconfMonitoringNamespace + `"><sessions/></netconf-state></filter>`)
+	var data struct {
+		State NetconfState `xml:"data>netconf-state"`
+	}
+	err = xml.Unmarshal([]byte(reply), &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.State.Sessions == nil || data.State.Capabilities != nil || data.State.Statistics != nil || data.State.Health != nil {
+		t.Fatalf("containers of netconf-state other than sessions in %s", reply)
+	}
+	if strings.Contains(reply, "streams") || strings.Contains(reply, "yang-library") {
+		t.Errorf("containers not selected in %s", reply)
+	}
+	sessions := data.State.Sessions.Sessions
+	if len(sessions) != 2 || sessions[0].SessionID != "1" || sessions[1].SessionID != "2" {
+		t.Fatalf("sessions %+v, want 1 and 2", sessions)
+	}
+	if sessions[0].Transport != "netconf-ssh" || sessions[0].Username != "alice" || sessions[0].SourceHost != "10.0.0.1" {
+		t.Errorf("session %+v", sessions[0])
+	}
+	if sessions[1].Transport != "netconf-tls" {
+		t.Errorf("session 2 over %s", sessions[1].Transport)
+	}
+
+	// the counters include the previous get
+	reply = get(`<filter type="subtree"><netconf-state xmlns="` + NetconfMonitoringNamespace + `"/></filter>`)
+	var all struct {
+		State NetconfState `xml:"data>netconf-state"`
+	}
+	err = xml.Unmarshal([]byte(reply), &all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := all.State
+	if state.Sessions.Sessions[0].InRPCs != 1 || state.Statistics == nil || state.Statistics.InRPCs < 1 {
+		t.Errorf("rpcs of session 1 not counted in %s", reply)
+	}
+	if state.Capabilities == nil || !strings.Contains(strings.Join(state.Capabilities.Capabilities, " "), kpimonNamespace) {
+		t.Errorf("capabilities %+v", state.Capabilities)
+	}
+	if state.Datastores == nil || len(state.Datastores.Datastores) != 1 || state.Datastores.Datastores[0].Name != "running" {
+		t.Errorf("datastores %+v", state.Datastores)
+	}
+	if state.Schemas == nil || len(state.Schemas.Schemas) != 1 || state.Schemas.Schemas[0] != (MonitoringSchema{
+		Identifier: "ric", Version: "2022-01-01", Format: "yang", Namespace: "urn:onf:ric", Location: "NETCONF",
+	}) {
+		t.Errorf("schemas %+v", state.Schemas)
+	}
+	if state.Health == nil || state.Health.Status != HealthStatusDegraded || len(state.Health.Components) != 2 ||
+		state.Health.Components[1].Status != "down" || state.Health.Components[1].Error != "unavailable" {
+		t.Errorf("health %+v", state.Health)
+	}
+
+	// without filter, the event streams and the YANG library follow netconf-state
+	reply = get("")
+	for _, want := range []string{"<netconf-state", "<netconf xmlns=\"" + NetmodNotificationNamespace, "<yang-library"} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("no %s in %s", want, reply)
+		}
+	}
+
+	if reply := get(`<filter type="xpath" select="/netconf-state"/>`); !strings.Contains(reply, ErrorTagInvalidValue) {
+		t.Errorf("xpath filter accepted: %s", reply)
+	}
+}
+
+func TestGetSchema(t *testing.T) {
+	schemas := &fakeSchemas{sources: map[string]string{"ric@2022-01-01": "module ric { description \"a < b & c\"; }"}}
+	o1 := NewO1Controller(store.NewStore(), rnib.NewFakeClient(), nil, WithSchemas(schemas)).(*o1Controller)
+	defer o1.Close()
+
+	tests := []struct {
+		name    string
+		request string
+		// want is in the reply
+		want string
+	}{
+		{name: "source", request: `<identifier>ric</identifier><version>2022-01-01</version><format>yang</format>`, want: "module ric { description \"a &lt; b &amp; c\"; }"},
+		{name: "latest revision", request: `<identifier>ric</identifier>`, want: "module ric"},
+		{name: "prefixed format", request: `<identifier>ric</identifier><format xmlns:ncm="` + NetconfMonitoringNamespace + `">ncm:yang</format>`, want: "module ric"},
+		{name: "no identifier", request: ``, want: "<error-tag>missing-element</error-tag>"},
+		{name: "format", request: `<identifier>ric</identifier><format>yin</format>`, want: "<bad-element>format</bad-element>"},
+		{name: "unknown revision", request: `<identifier>ric</identifier><version>2020-01-01</version>`, want: "schema ric not found"},
+		{name: "unknown module", request: `<identifier>e2</identifier>`, want: "<bad-element>identifier</bad-element>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reply, err := o1.GetSchema(context.Background(), "1", []byte(`<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.1"><get-schema xmlns="`+
+				NetconfMonitoringNamespace+`">`+test.request+`</get-schema></rpc>`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(reply), test.want) {
+				t.Errorf("no %s in %s", test.want, reply)
+			}
+		})
+	}
+}
@@ -0,0 +1,560 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	gnxi "github.com/google/gnxi/utils/xpath"
+)
+
+const (
+	// NotificationNamespace is the namespace of the notifications and of create-subscription
+	NotificationNamespace = "urn:ietf:params:xml:ns:netconf:notification:1.0"
+	// NetconfStream is the default event stream, carrying the changes of all the targets
+	NetconfStream = "NETCONF"
+
+	// subscribeRetryInterval is the delay before a failed gNMI subscription is opened again
+	subscribeRetryInterval = 5 * time.Second
+)
+
+// NotificationSession is a session whose transport can send notifications
+type NotificationSession interface {
+	Session
+	// Notify queues the notification for the client of the session, sent between the replies
+	// of its rpcs
+	Notify(notification []byte) error
+	// Flush waits for the queued notifications to be sent, until the session is closed or the
+	// context is done
+	Flush(ctx context.Context) error
+}
+
+type CreateSubscription struct {
+	RPC
+	Stream    string       `xml:"create-subscription>stream"`
+	Filter    *EventFilter `xml:"create-subscription>filter"`
+	StartTime string       `xml:"create-subscription>startTime"`
+	StopTime  string       `xml:"create-subscription>stopTime"`
+}
+
+// EventFilter is the subtree or xpath filter of a subscription
+type EventFilter struct {
+	Type   string `xml:"type,attr,omitempty"`
+	XMLNS  string `xml:"xmlns,attr,omitempty"`
+	Select string `xml:"select,attr,omitempty"`
+	Data   string `xml:",innerxml"`
+}
+
+type Notification struct {
+	XMLName   xml.Name `xml:"urn:ietf:params:xml:ns:netconf:notification:1.0 notification"`
+	EventTime string   `xml:"eventTime"`
+	Data      string   `xml:",innerxml"`
+}
+
+// subscription is the notification subscription of a session
+type subscription struct {
+	sessionID string
+	session   NotificationSession
+	// events selects the event notifications of the NETCONF stream sent to the session
+	events eventSelection
+	// live is set once the replay of the subscription is complete, the live notifications
+	// being sent to the session from then on
+	live   bool
+	cancel context.CancelFunc
+}
+
+// subscriptionTarget is a target of a subscription, with the paths of its data to be notified
+type subscriptionTarget struct {
+	capability string
+	namespace  Namespace
+	paths      []*gnmi.Path
+}
+
+// CreateSubscription subscribes the session to the event notifications and the changes of the
+// targets of the stream selected by the filter, the changes being received from gNMI
+// ON_CHANGE subscriptions to onos-config and sent as notifications. With a start time, the
+// notifications of the replay log are sent first.
+func (o1 *o1Controller) CreateSubscription(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(CreateSubscription)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	o1.mu.RLock()
+	session, notifiable := o1.sessions[sessionID].(NotificationSession)
+	o1.mu.RUnlock()
+
+	if !notifiable {
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagOperationNotSupported,
+			Severity: ErrorSeverityError,
+			Message:  "the transport of the session can not send notifications",
+		})
+	}
+	start, stop, rpcErr := o1.replayWindow(request.StartTime, request.StopTime)
+	if rpcErr != nil {
+		return buildErrorReply(request.MessageID, *rpcErr)
+	}
+
+	targets, events, rpcErr := o1.subscriptionTargets(strings.TrimSpace(request.Stream), request.Filter)
+	if rpcErr != nil {
+		return buildErrorReply(request.MessageID, *rpcErr)
+	}
+
+	o1.mu.Lock()
+	if _, ok := o1.subscriptions[sessionID]; ok {
+		o1.mu.Unlock()
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInUse,
+			Severity: ErrorSeverityError,
+			Message:  "the session already has a subscription",
+		})
+	}
+	subscriptionCtx, cancel := context.WithCancel(context.Background())
+	sub := &subscription{sessionID: sessionID, session: session, events: events, live: start.IsZero(), cancel: cancel}
+	o1.subscriptions[sessionID] = sub
+	o1.mu.Unlock()
+
+	var namespaces []string
+	for _, target := range targets {
+		namespaces = append(namespaces, target.name())
+		if sub.live {
+			go o1.subscribeTarget(subscriptionCtx, session, o1.changeStream(target))
+		}
+	}
+	if !sub.live {
+		go o1.replaySubscription(subscriptionCtx, sub, targets, start, stop)
+		log.Infof("Session %s subscribed to %s with replay from %s", sessionID, strings.Join(namespaces, ","), start.Format(time.RFC3339))
+	} else {
+		log.Infof("Session %s subscribed to %s", sessionID, strings.Join(namespaces, ","))
+	}
+
+	err = o1.UpdateStoreOperation(ctx, sessionID, "create-subscription", strings.Join(namespaces, ","), nil)
+	if err != nil {
+		return nil, err
+	}
+	return buildOkReply(request.MessageID)
+}
+
+// cancelSubscription cancels the subscription of the session, if any
+func (o1 *o1Controller) cancelSubscription(sessionID string) {
+	o1.mu.Lock()
+	sub, ok := o1.subscriptions[sessionID]
+	delete(o1.subscriptions, sessionID)
+	o1.mu.Unlock()
+
+	if ok {
+		log.Infof("Cancel subscription of session %s", sessionID)
+		sub.cancel()
+	}
+}
+
+// capabilityTargets returns the targets of the capabilities of the current namespace prefix
+func (o1 *o1Controller) capabilityTargets() []subscriptionTarget {
+	var targets []subscriptionTarget
+	for _, capability := range o1.currentCapabilities() {
+		if !strings.HasPrefix(capability, o1.currentSettings().NamespacePrefix+"/") {
+			continue
+		}
+		namespace, err := ParseNamespace(capability)
+		if err != nil {
+			continue
+		}
+		targets = append(targets, subscriptionTarget{capability: capability, namespace: namespace})
+	}
+	return targets
+}
+
+// name returns the target:name:version of the namespace of the target
+func (t subscriptionTarget) name() string {
+	return fmt.Sprintf("%s:%s:%s", t.namespace.Target, t.namespace.Name, t.namespace.Version)
+}
+
+// subscriptionTargets returns the targets of the stream selected by the filter, and the event
+// notifications it selects. The NETCONF stream has the event notifications and all the
+// targets of the capabilities, and the other streams are named by the target:name:version of
+// a capability. The event notifications are selected by the names of the top-level elements
+// of the filter in their namespace.
+func (o1 *o1Controller) subscriptionTargets(stream string, filter *EventFilter) ([]subscriptionTarget, eventSelection, *RPCError) {
+	if stream == "" {
+		stream = NetconfStream
+	}
+	events := eventSelection{}
+
+	var targets []subscriptionTarget
+	for _, target := range o1.capabilityTargets() {
+		if stream == NetconfStream || stream == target.name() {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 && stream != NetconfStream {
+		return nil, events, &RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  fmt.Sprintf("stream %s does not exist", stream),
+			Info:     "<error-info><bad-element>stream</bad-element></error-info>",
+		}
+	}
+
+	if filter == nil {
+		for i := range targets {
+			targets[i].paths = []*gnmi.Path{{Elem: []*gnmi.PathElem{}}}
+		}
+		events.selected = stream == NetconfStream
+		return targets, events, nil
+	}
+
+	paths, err := filterPaths(filter)
+	if err != nil {
+		return nil, events, &RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  fmt.Sprintf("invalid filter: %v", err),
+			Info:     "<error-info><bad-element>filter</bad-element></error-info>",
+		}
+	}
+
+	if eventPaths, ok := paths[NetconfNotificationsNamespace]; ok && stream == NetconfStream {
+		events.selected = true
+		events.names = make(map[string]bool)
+		for _, path := range eventPaths {
+			if len(path.Elem) == 0 {
+				events.names = nil
+				break
+			}
+			events.names[path.Elem[0].Name] = true
+		}
+	}
+
+	var filtered []subscriptionTarget
+	for _, target := range targets {
+		targetPaths, ok := paths[target.capability]
+		if !ok && stream != NetconfStream {
+			// the filter of a target stream needs no namespace
+			targetPaths, ok = paths[""]
+		}
+		if ok {
+			target.paths = targetPaths
+			filtered = append(filtered, target)
+		}
+	}
+	if len(filtered) == 0 && !events.selected {
+		return nil, events, &RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  fmt.Sprintf("the filter selects no data of stream %s", stream),
+			Info:     "<error-info><bad-element>filter</bad-element></error-info>",
+		}
+	}
+	return filtered, events, nil
+}
+
+// filterPaths returns the gNMI paths selected by a filter, by namespace. The select of a xpath
+// filter is a path in the namespace of the filter. The top-level elements of a subtree filter
+// select the data of their namespace, that of the filter by default. The containment elements
+// are followed, the elements with content being the keys of the list entries of the path.
+func filterPaths(filter *EventFilter) (map[string][]*gnmi.Path, error) {
+	paths := make(map[string][]*gnmi.Path)
+	switch filter.Type {
+	case "xpath":
+		path, err := filterPath(filter.Select)
+		if err != nil {
+			return nil, err
+		}
+		paths[filter.XMLNS] = append(paths[filter.XMLNS], path)
+	case "", "subtree":
+		nodes, err := parseFilterNodes(filter.Data)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			namespace := node.name.Space
+			if namespace == "" {
+				namespace = filter.XMLNS
+			}
+			for _, elems := range node.paths() {
+				paths[namespace] = append(paths[namespace], &gnmi.Path{Elem: elems})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("filter type %s not supported", filter.Type)
+	}
+	return paths, nil
+}
+
+// filterPath returns the gNMI path of the xpath of a filter, the quotes of the values of its
+// keys being removed
+func filterPath(xpath string) (*gnmi.Path, error) {
+	path, err := gnxi.ToGNMIPath(xpath)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range path.Elem {
+		for name, value := range elem.Key {
+			if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+				elem.Key[name] = value[1 : len(value)-1]
+			}
+		}
+	}
+	return path, nil
+}
+
+// filterNode is an element of a subtree filter
+type filterNode struct {
+	name     xml.Name
+	text     string
+	children []*filterNode
+}
+
+func parseFilterNodes(data string) ([]*filterNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+
+	var roots []*filterNode
+	var stack []*filterNode
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return roots, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &filterNode{name: t.Name}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else {
+				roots = append(roots, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(bytes.TrimSpace(t))
+			}
+		}
+	}
+}
+
+// paths returns the paths selected by the filter node
+func (n *filterNode) paths() [][]*gnmi.PathElem {
+	elem := &gnmi.PathElem{Name: n.name.Local}
+	var selections []*filterNode
+	for _, child := range n.children {
+		if len(child.children) == 0 && child.text != "" {
+			if elem.Key == nil {
+				elem.Key = make(map[string]string)
+			}
+			elem.Key[child.name.Local] = child.text
+			continue
+		}
+		selections = append(selections, child)
+	}
+
+	if len(selections) == 0 {
+		return [][]*gnmi.PathElem{{elem}}
+	}
+	var paths [][]*gnmi.PathElem
+	for _, selection := range selections {
+		for _, childPath := range selection.paths() {
+			paths = append(paths, append([]*gnmi.PathElem{elem}, childPath...))
+		}
+	}
+	return paths
+}
+
+// targetStream is a gNMI subscription to the paths of a target, whose notifications are
+// translated and sent to a session
+type targetStream struct {
+	target subscriptionTarget
+	mode   gnmi.SubscriptionMode
+	// sampleInterval is the interval of a SAMPLE subscription, in nanoseconds
+	sampleInterval uint64
+	// updatesOnly skips the initial values of the paths
+	updatesOnly bool
+	// build translates a gNMI notification to the notification sent, nil for none; synced
+	// is set once the initial values are received
+	build func(ctx context.Context, update *gnmi.Notification, synced bool) ([]byte, error)
+}
+
+// changeStream returns the ON_CHANGE stream of the changes of the target of a create-subscription
+func (o1 *o1Controller) changeStream(target subscriptionTarget) targetStream {
+	return targetStream{
+		target:      target,
+		mode:        gnmi.SubscriptionMode_ON_CHANGE,
+		updatesOnly: true,
+		build: func(ctx context.Context, update *gnmi.Notification, synced bool) ([]byte, error) {
+			return o1.buildNotification(ctx, target, update)
+		},
+	}
+}
+
+// subscribeTarget sends the notifications of the stream to the session until ctx is done,
+// opening the gNMI subscription again when it fails
+func (o1 *o1Controller) subscribeTarget(ctx context.Context, session NotificationSession, stream targetStream) {
+	for {
+		err := o1.streamTarget(ctx, session, stream)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warnf("Subscription to target %s failed: %v", stream.target.namespace.Target, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(subscribeRetryInterval):
+		}
+	}
+}
+
+// streamTarget opens the gNMI subscription of the stream and sends each notification received
+// to the session
+func (o1 *o1Controller) streamTarget(ctx context.Context, session NotificationSession, stream targetStream) error {
+	target := stream.target
+	request := &gnmi.SubscribeRequest{
+		Request: &gnmi.SubscribeRequest_Subscribe{
+			Subscribe: &gnmi.SubscriptionList{
+				Prefix: &gnmi.Path{
+					Elem:   []*gnmi.PathElem{},
+					Target: target.namespace.Target,
+				},
+				Mode:        gnmi.SubscriptionList_STREAM,
+				UpdatesOnly: stream.updatesOnly,
+				UseModels: []*gnmi.ModelData{{
+					Name:    target.namespace.Name,
+					Version: target.namespace.Version,
+				}},
+			},
+		},
+	}
+	for _, path := range target.paths {
+		request.GetSubscribe().Subscription = append(request.GetSubscribe().Subscription, &gnmi.Subscription{
+			Path:           path,
+			Mode:           stream.mode,
+			SampleInterval: stream.sampleInterval,
+		})
+	}
+
+	subscribeClient, err := o1.gnmiClient.Subscribe(ctx, request)
+	if err != nil {
+		return err
+	}
+	synced := false
+	for {
+		response, err := subscribeClient.Recv()
+		if err != nil {
+			return err
+		}
+		if response.GetSyncResponse() {
+			synced = true
+			continue
+		}
+		update := response.GetUpdate()
+		if update == nil {
+			continue
+		}
+
+		notification, err := stream.build(ctx, update, synced)
+		if err != nil {
+			log.Warnf("Notification of target %s can not be translated: %v", target.namespace.Target, err)
+			continue
+		}
+		if notification == nil {
+			continue
+		}
+		err = session.Notify(notification)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// buildNotification translates a gNMI notification of the target to a NETCONF notification
+// carrying the changed data, the deleted nodes having the delete operation attribute
+func (o1 *o1Controller) buildNotification(ctx context.Context, target subscriptionTarget, update *gnmi.Notification) ([]byte, error) {
+	modelSchema := o1.modelSchema(ctx, target.namespace)
+
+	data, err := updatedXML(modelSchema, target, update)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range update.Delete {
+		element, err := schema.DeletedXML(modelSchema, joinPaths(update.Prefix, path), target.capability)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, element...)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	return xml.Marshal(&Notification{
+		EventTime: eventTime(update.Timestamp),
+		Data:      string(data),
+	})
+}
+
+// updatedXML translates the updates of a gNMI notification of the target to XML data
+func updatedXML(modelSchema *schema.Schema, target subscriptionTarget, update *gnmi.Notification) ([]byte, error) {
+	var data []byte
+	for _, u := range update.Update {
+		value, err := typedValueJSON(u.Val)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+		element, err := schema.JSONToXML(modelSchema, joinPaths(update.Prefix, u.Path), value, target.capability)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, element...)
+	}
+	return data, nil
+}
+
+// eventTime formats the unix timestamp in nanoseconds of an event as a date-and-time, the
+// current time if it is not set
+func eventTime(timestamp int64) string {
+	return notificationTime(timestamp).UTC().Format(time.RFC3339Nano)
+}
+
+// notificationTime returns the time of the unix timestamp in nanoseconds of an event, the
+// current time if it is not set
+func notificationTime(timestamp int64) time.Time {
+	if timestamp > 0 {
+		return time.Unix(0, timestamp)
+	}
+	return time.Now()
+}
+
+// joinPaths returns the path of the prefix followed by the path
+func joinPaths(prefix, path *gnmi.Path) *gnmi.Path {
+	elems := make([]*gnmi.PathElem, 0, len(prefix.GetElem())+len(path.GetElem()))
+	elems = append(elems, prefix.GetElem()...)
+	elems = append(elems, path.GetElem()...)
+	return &gnmi.Path{Elem: elems}
+}
@@ -6,13 +6,14 @@ package controller
 
 import (
 	"bytes"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"strings"
 
-	xj "github.com/basgys/goxml2json"
-
+	"github.com/onosproject/onos-o1t/pkg/schema"
 	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
 
 	gnxi "github.com/google/gnxi/utils/xpath"
 )
@@ -23,7 +24,7 @@ type Namespace struct {
 	Version string
 }
 
-func parseNamespace(ns string) (Namespace, error) {
+func ParseNamespace(ns string) (Namespace, error) {
 
 	nsSplit := strings.Split(ns, "/")
 
@@ -47,7 +48,45 @@ func parseNamespace(ns string) (Namespace, error) {
 	}, nil
 }
 
-func ParseGetConfig(requestXML []byte) (*gnmi.GetRequest, Namespace, error) {
+// parseRPCOperation returns the message-id and the operation name of a rpc, or the name of the
+// root element for other messages (e.g., hello)
+func parseRPCOperation(rawMessage []byte) (string, string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(rawMessage))
+
+	var root *xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", "", fmt.Errorf("malformed message: %v", err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if root == nil {
+			if element.Name.Local != "rpc" {
+				return "", element.Name.Local, nil
+			}
+			root = &element
+			continue
+		}
+
+		messageID := ""
+		for _, attr := range root.Attr {
+			if attr.Name.Local == "message-id" {
+				messageID = attr.Value
+			}
+		}
+		return messageID, element.Name.Local, nil
+	}
+}
+
+// ParseGetConfig translates the get-config to a gNMI GetRequest of the target and model of
+// the namespace of its filter, mapped by the mappings of the namespace if it has any, and
+// parsed from the namespace of a capability otherwise
+func ParseGetConfig(requestXML []byte, mappings []NamespaceMapping) (*gnmi.GetRequest, Namespace, error) {
 	gnmiGet := new(gnmi.GetRequest)
 
 	request := new(GetConfig)
@@ -57,10 +96,16 @@ func ParseGetConfig(requestXML []byte) (*gnmi.GetRequest, Namespace, error) {
 	}
 
 	namespace := request.Filter.XMLNS
-	ns, err := parseNamespace(namespace)
+	ns, mapped, err := mapNamespace(mappings, namespace, xpathKeyValue(request.Filter.Select))
 	if err != nil {
 		return gnmiGet, Namespace{}, err
 	}
+	if !mapped {
+		ns, err = ParseNamespace(namespace)
+		if err != nil {
+			return gnmiGet, Namespace{}, err
+		}
+	}
 
 	if request.Filter.Type != "xpath" {
 		return gnmiGet, ns, fmt.Errorf("get-config filter must be xpath")
@@ -93,24 +138,18 @@ func ParseGetConfig(requestXML []byte) (*gnmi.GetRequest, Namespace, error) {
 	return gnmiGet, ns, nil
 }
 
-func RemoveAttr(n *xj.Node) {
-	prefix := "-"
-	for k, v := range n.Children {
-		if strings.HasPrefix(k, prefix) {
-			delete(n.Children, k)
-		} else {
-			for _, n := range v {
-				RemoveAttr(n)
-			}
-		}
+// getNamespace returns the target and model of a top-level data node of a config, mapped by
+// the mappings of its namespace if it has any, and parsed from the namespace of the
+// capability it contains otherwise
+func getNamespace(node schema.Node, capabilities []string, mappings []NamespaceMapping) (Namespace, error) {
+	ns, mapped, err := mapNamespace(mappings, node.Name.Space, dataLeafValue(node.Data))
+	if mapped {
+		return ns, err
 	}
-}
 
-func getNamespace(request *EditConfig, capabilities []string) (Namespace, error) {
 	var namespace string
-
 	for _, capab := range capabilities {
-		if strings.Contains(request.Config.Config, capab) {
+		if strings.Contains(node.Data, capab) {
 			namespace = capab
 			break
 		}
@@ -120,7 +159,7 @@ func getNamespace(request *EditConfig, capabilities []string) (Namespace, error)
 		return Namespace{}, fmt.Errorf("error namespace of config not in capabilities")
 	}
 
-	ns, err := parseNamespace(namespace)
+	ns, err = ParseNamespace(namespace)
 	if err != nil {
 		return Namespace{}, err
 	}
@@ -128,62 +167,205 @@ func getNamespace(request *EditConfig, capabilities []string) (Namespace, error)
 	return ns, nil
 }
 
-func ParseEditConfig(requestXML []byte, capabilities []string) (*gnmi.SetRequest, Namespace, error) {
-	gnmiSet := new(gnmi.SetRequest)
+const (
+	// TestOptionTestThenSet validates the config before applying it, the default test-option
+	TestOptionTestThenSet = "test-then-set"
+	// TestOptionSet applies the config without validating it against the YANG modules
+	TestOptionSet = "set"
+	// TestOptionTestOnly validates the config without applying it
+	TestOptionTestOnly = "test-only"
+)
+
+// parseTestOption returns the test-option of an edit-config, test-then-set if it is not set
+func parseTestOption(testOption string) (string, error) {
+	switch testOption = strings.TrimSpace(testOption); testOption {
+	case "":
+		return TestOptionTestThenSet, nil
+	case TestOptionTestThenSet, TestOptionSet, TestOptionTestOnly:
+		return testOption, nil
+	}
+	return "", RPCError{
+		Type:     ErrorTypeProtocol,
+		Tag:      ErrorTagInvalidValue,
+		Severity: ErrorSeverityError,
+		Message:  fmt.Sprintf("invalid test-option %s", testOption),
+		Info:     "<error-info><bad-element>test-option</bad-element></error-info>",
+	}
+}
 
+// ParseEditConfig translates the edit-config to the gNMI SetRequests of its parts, a part
+// for each top-level data node of its config. The config is sent as RFC 7951 JSON_IETF when
+// modelSchema returns the schema of the model of its namespace, as untyped JSON otherwise.
+// With a schema, the config is first validated against the YANG modules of the model, the
+// error being a *schema.Error locating the invalid data node, unless the test-option is set
+// without test. The target and model of a part are mapped by the mappings of the namespace of
+// its data node if it has any.
+func ParseEditConfig(requestXML []byte, capabilities []string, mappings []NamespaceMapping, modelSchema func(Namespace) *schema.Schema) (*EditRequest, error) {
 	request := new(EditConfig)
 	err := xml.Unmarshal([]byte(requestXML), request)
 	if err != nil {
-		return gnmiSet, Namespace{}, err
+		return nil, err
+	}
+	testOption, err := parseTestOption(request.TestOption)
+	if err != nil {
+		return nil, err
 	}
-	namespace, err := getNamespace(request, capabilities)
+	errorOption, err := parseErrorOption(request.ErrorOption)
 	if err != nil {
-		return gnmiSet, Namespace{}, err
+		return nil, err
+	}
+	if request.Config == nil {
+		return nil, fmt.Errorf("edit-config has no config")
 	}
 
-	xml := strings.NewReader(request.Config.Config)
+	parts, err := parseConfig(request.Config.Config, request.DefaultOperation, testOption != TestOptionSet, capabilities, mappings, modelSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &EditRequest{
+		Parts:       parts,
+		TestOption:  testOption,
+		ErrorOption: errorOption,
+	}, nil
+}
 
-	// Decode XML document
-	root := &xj.Node{}
-	err = xj.NewDecoder(
-		xml,
-		xj.WithAttrPrefix("-"),
-	).Decode(root)
+// parseConfig translates the config of an edit-config or validate rpc to the gNMI SetRequests
+// of its top-level data nodes, validating them against the schema of their model if validate
+// is set
+func parseConfig(configXML, defaultOperation string, validate bool, capabilities []string, mappings []NamespaceMapping, modelSchema func(Namespace) *schema.Schema) ([]EditPart, error) {
+	nodes, err := schema.SplitNodes(configXML)
 	if err != nil {
-		return nil, Namespace{}, err
+		return nil, err
 	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("config has no data node")
+	}
+
+	parts := make([]EditPart, 0, len(nodes))
+	for _, node := range nodes {
+		part, err := parseConfigNode(node, defaultOperation, validate, capabilities, mappings, modelSchema)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
 
-	RemoveAttr(root)
+// jsonKeyValue returns the string of the JSON value of a list key
+func jsonKeyValue(value json.RawMessage) string {
+	var text string
+	if json.Unmarshal(value, &text) == nil {
+		return text
+	}
+	return string(value)
+}
 
-	// Then encode it in JSON
-	jsonVal := new(bytes.Buffer)
-	e := xj.NewEncoder(jsonVal)
-	err = e.Encode(root)
+// parseConfigNode translates a top-level data node of a config to a gNMI SetRequest deleting,
+// replacing or updating the node according to its operation
+func parseConfigNode(node schema.Node, defaultOperation string, validate bool, capabilities []string, mappings []NamespaceMapping, modelSchema func(Namespace) *schema.Schema) (EditPart, error) {
+	namespace, err := getNamespace(node, capabilities, mappings)
 	if err != nil {
-		return nil, Namespace{}, err
+		return EditPart{}, err
 	}
 
-	elems := []*gnmi.PathElem{}
-	target := namespace.Target
-	gnmiSet.Prefix = &gnmi.Path{
-		Elem:   elems,
-		Target: target,
+	var modelTree *schema.Schema
+	if modelSchema != nil {
+		modelTree = modelSchema(namespace)
+	}
+	if modelTree != nil && validate {
+		err = modelTree.Validate(node.Data, defaultOperation)
+		if err != nil {
+			return EditPart{}, err
+		}
+	}
+
+	var entry *yang.Entry
+	if modelTree != nil {
+		entry = modelTree.Root("", node.Name.Local)
+	}
+
+	operation := node.Operation
+	if operation == "" {
+		operation = defaultOperation
 	}
 
-	update := new(gnmi.Update)
-	update.Path = new(gnmi.Path)
-	update.Path.Elem = []*gnmi.PathElem{}
-	update.Val = new(gnmi.TypedValue)
-	update.Val.Value = &gnmi.TypedValue_JsonVal{JsonVal: jsonVal.Bytes()}
+	part := EditPart{
+		Namespace: namespace,
+		Path:      "/" + node.Name.Local,
+		Request: &gnmi.SetRequest{
+			Prefix: &gnmi.Path{
+				Elem:   []*gnmi.PathElem{},
+				Target: namespace.Target,
+			},
+		},
+	}
+	path := &gnmi.Path{
+		Elem: []*gnmi.PathElem{{Name: node.Name.Local}},
+	}
+	if entry != nil {
+		part.Path = schema.Path(entry)
+	}
 
-	var updates []*gnmi.Update
-	var deletes []*gnmi.Path
+	deleted := operation == "delete" || operation == "remove"
+	if deleted && (entry == nil || !entry.IsList()) {
+		part.Request.Delete = []*gnmi.Path{path}
+		return part, nil
+	}
 
-	updates = append(updates, update)
+	jsonVal, err := schema.XMLToJSON(modelTree, node.Data)
+	if err != nil {
+		return EditPart{}, err
+	}
+	part.Config = jsonVal
+	var object map[string]json.RawMessage
+	err = json.Unmarshal(jsonVal, &object)
+	if err != nil {
+		return EditPart{}, err
+	}
+	for _, value := range object {
+		jsonVal = value
+	}
 
-	gnmiSet.Update = updates
-	gnmiSet.Delete = deletes
+	if entry != nil && entry.IsList() {
+		var entries []map[string]json.RawMessage
+		err = json.Unmarshal(jsonVal, &entries)
+		if err != nil || len(entries) != 1 {
+			return EditPart{}, fmt.Errorf("invalid entry of list %s", node.Name.Local)
+		}
+		path.Elem[0].Key = make(map[string]string)
+		predicates := ""
+		for _, key := range schema.Keys(entry) {
+			value := jsonKeyValue(entries[0][key])
+			path.Elem[0].Key[key] = value
+			predicates += fmt.Sprintf("[%s='%s']", key, value)
+		}
+		part.Path += predicates
+		jsonVal, err = json.Marshal(entries[0])
+		if err != nil {
+			return EditPart{}, err
+		}
+	}
+	if deleted {
+		part.Request.Delete = []*gnmi.Path{path}
+		part.Config = nil
+		return part, nil
+	}
 
-	return gnmiSet, namespace, nil
+	update := &gnmi.Update{
+		Path: path,
+		Val:  &gnmi.TypedValue{},
+	}
+	if modelTree != nil {
+		update.Val.Value = &gnmi.TypedValue_JsonIetfVal{JsonIetfVal: jsonVal}
+	} else {
+		update.Val.Value = &gnmi.TypedValue_JsonVal{JsonVal: jsonVal}
+	}
 
+	if operation == "replace" {
+		part.Request.Replace = []*gnmi.Update{update}
+	} else {
+		part.Request.Update = []*gnmi.Update{update}
+	}
+	return part, nil
 }
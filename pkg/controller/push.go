@@ -0,0 +1,805 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+const (
+	// SubscribedNotificationsNamespace is the namespace of the subscription rpcs and
+	// notifications of RFC 8639
+	SubscribedNotificationsNamespace = "urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications"
+	// YangPushNamespace is the namespace of the datastore subscriptions of RFC 8641
+	YangPushNamespace = "urn:ietf:params:xml:ns:yang:ietf-yang-push"
+	// YangPatchNamespace is the namespace of the changes of a push-change-update
+	YangPatchNamespace = "urn:ietf:params:xml:ns:yang:ietf-yang-patch"
+	// DatastoresNamespace is the namespace of the datastore identities
+	DatastoresNamespace = "urn:ietf:params:xml:ns:yang:ietf-datastores"
+)
+
+type EstablishSubscription struct {
+	RPC
+	Subscription PushParameters `xml:"establish-subscription"`
+}
+
+type ModifySubscription struct {
+	RPC
+	Subscription PushParameters `xml:"modify-subscription"`
+}
+
+type DeleteSubscription struct {
+	RPC
+	ID string `xml:"delete-subscription>id"`
+}
+
+type KillSubscription struct {
+	RPC
+	ID string `xml:"kill-subscription>id"`
+}
+
+// PushParameters are the parameters of a YANG-Push datastore subscription
+type PushParameters struct {
+	ID            string       `xml:"id"`
+	Stream        string       `xml:"stream"`
+	Datastore     string       `xml:"datastore"`
+	SubtreeFilter *EventFilter `xml:"datastore-subtree-filter"`
+	XPathFilter   *XPathFilter `xml:"datastore-xpath-filter"`
+	Periodic      *Periodic    `xml:"periodic"`
+	OnChange      *OnChange    `xml:"on-change"`
+	StopTime      string       `xml:"stop-time"`
+}
+
+// XPathFilter is a datastore xpath filter, its prefixes being declared by its namespace
+// attributes
+type XPathFilter struct {
+	Attrs  []xml.Attr `xml:",any,attr"`
+	Select string     `xml:",chardata"`
+}
+
+// Periodic is the trigger of a periodic subscription, its period being in centiseconds
+type Periodic struct {
+	Period string `xml:"period"`
+}
+
+// OnChange is the trigger of an on-change subscription, its dampening period being in
+// centiseconds
+type OnChange struct {
+	DampeningPeriod string `xml:"dampening-period"`
+	SyncOnStart     string `xml:"sync-on-start"`
+}
+
+type SubscriptionEvent struct {
+	XMLName xml.Name
+	ID      uint32 `xml:"id"`
+	Data    string `xml:",innerxml"`
+}
+
+type PushUpdate struct {
+	XMLName  xml.Name  `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-push push-update"`
+	ID       uint32    `xml:"id"`
+	Contents innerData `xml:"datastore-contents"`
+}
+
+type PushChangeUpdate struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-push push-change-update"`
+	ID      uint32   `xml:"id"`
+	Changes struct {
+		Patch YangPatch `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-patch yang-patch"`
+	} `xml:"datastore-changes"`
+}
+
+type YangPatch struct {
+	PatchID string      `xml:"patch-id"`
+	Edits   []PatchEdit `xml:"edit"`
+}
+
+type PatchEdit struct {
+	EditID    string     `xml:"edit-id"`
+	Operation string     `xml:"operation"`
+	Target    string     `xml:"target"`
+	Value     *innerData `xml:"value,omitempty"`
+}
+
+// innerData is an element whose content is raw XML
+type innerData struct {
+	Data string `xml:",innerxml"`
+}
+
+// pushSubscription is a YANG-Push subscription established by a session
+type pushSubscription struct {
+	id        uint32
+	sessionID string
+	session   NotificationSession
+	targets   []subscriptionTarget
+	// filter is the XML of the filter of the subscription, empty for all the data
+	filter string
+	// period is the period of a periodic subscription in centiseconds, zero for an
+	// on-change subscription
+	period          uint32
+	dampeningPeriod uint32
+	syncOnStart     bool
+	stopTime        time.Time
+	// patches counts the push-change-updates, shared by the streams of the targets
+	patches   *uint64
+	cancel    context.CancelFunc
+	stopTimer *time.Timer
+}
+
+// subscriptionError returns the rpc-error of a subscription rpc, with the identity of the
+// error as error-app-tag
+func subscriptionError(tag, appTag, element, message string) RPCError {
+	rpcErr := RPCError{
+		Type:     ErrorTypeApplication,
+		Tag:      tag,
+		Severity: ErrorSeverityError,
+		AppTag:   appTag,
+		Message:  message,
+	}
+	if element != "" {
+		rpcErr.Info = fmt.Sprintf("<error-info><bad-element>%s</bad-element></error-info>", element)
+	}
+	return rpcErr
+}
+
+func noSuchSubscription(id string) RPCError {
+	return subscriptionError(ErrorTagInvalidValue, "ietf-subscribed-notifications:no-such-subscription", "id",
+		fmt.Sprintf("subscription %s does not exist", id))
+}
+
+// notificationSession returns the session if its transport can send notifications
+func (o1 *o1Controller) notificationSession(sessionID string) (NotificationSession, bool) {
+	o1.mu.RLock()
+	defer o1.mu.RUnlock()
+
+	session, ok := o1.sessions[sessionID].(NotificationSession)
+	return session, ok
+}
+
+// EstablishSubscription establishes a periodic or on-change subscription to the running
+// datastore, the data selected by its filter in each capability namespace being streamed by
+// a gNMI SAMPLE or ON_CHANGE subscription to the target of the namespace
+func (o1 *o1Controller) EstablishSubscription(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(EstablishSubscription)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	session, notifiable := o1.notificationSession(sessionID)
+	if !notifiable {
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagOperationNotSupported,
+			Severity: ErrorSeverityError,
+			Message:  "the transport of the session can not send notifications",
+		})
+	}
+
+	sub, rpcErr := o1.configurePush(pushSubscription{sessionID: sessionID, session: session, patches: new(uint64)}, request.Subscription, true)
+	if rpcErr != nil {
+		return buildErrorReply(request.MessageID, *rpcErr)
+	}
+
+	o1.mu.Lock()
+	o1.lastSubscriptionID++
+	sub.id = o1.lastSubscriptionID
+	o1.startPush(&sub)
+	o1.pushSubscriptions[sub.id] = &sub
+	o1.mu.Unlock()
+	log.Infof("Session %s established subscription %d to %s", sessionID, sub.id, strings.Join(sub.namespaces(), ","))
+
+	err = o1.storePush(ctx, &sub)
+	if err != nil {
+		return nil, err
+	}
+	err = o1.UpdateStoreOperation(ctx, sessionID, "establish-subscription", strings.Join(sub.namespaces(), ","), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	o1.notifyPush(&sub, "subscription-started", sub.parametersXML())
+
+	reply := new(RPCReply)
+	reply.MessageID = request.MessageID
+	reply.Data = fmt.Sprintf(`<id xmlns="%s">%d</id>`, SubscribedNotificationsNamespace, sub.id)
+	return xml.Marshal(reply)
+}
+
+// ModifySubscription changes the filter, the period, the dampening period or the stop time of
+// a subscription of the session, its gNMI subscriptions being opened again
+func (o1 *o1Controller) ModifySubscription(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(ModifySubscription)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	id := strings.TrimSpace(request.Subscription.ID)
+	current, ok := o1.sessionPush(sessionID, id)
+	if !ok {
+		return buildErrorReply(request.MessageID, noSuchSubscription(id))
+	}
+
+	sub, rpcErr := o1.configurePush(current, request.Subscription, false)
+	if rpcErr != nil {
+		return buildErrorReply(request.MessageID, *rpcErr)
+	}
+
+	o1.mu.Lock()
+	if _, ok := o1.pushSubscriptions[sub.id]; !ok {
+		o1.mu.Unlock()
+		return buildErrorReply(request.MessageID, noSuchSubscription(id))
+	}
+	current.stop()
+	o1.startPush(&sub)
+	o1.pushSubscriptions[sub.id] = &sub
+	o1.mu.Unlock()
+	log.Infof("Session %s modified subscription %d", sessionID, sub.id)
+
+	err = o1.storePush(ctx, &sub)
+	if err != nil {
+		return nil, err
+	}
+	err = o1.UpdateStoreOperation(ctx, sessionID, "modify-subscription", strings.Join(sub.namespaces(), ","), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	o1.notifyPush(&sub, "subscription-modified", sub.parametersXML())
+	return buildOkReply(request.MessageID)
+}
+
+// DeleteSubscription ends a subscription of the session
+func (o1 *o1Controller) DeleteSubscription(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(DeleteSubscription)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	id := strings.TrimSpace(request.ID)
+	sub, ok := o1.sessionPush(sessionID, id)
+	if ok {
+		_, ok = o1.endPush(sub.id)
+	}
+	if !ok {
+		return buildErrorReply(request.MessageID, noSuchSubscription(id))
+	}
+	log.Infof("Session %s deleted subscription %d", sessionID, sub.id)
+
+	err = o1.UpdateStoreOperation(ctx, sessionID, "delete-subscription", strings.Join(sub.namespaces(), ","), nil)
+	if err != nil {
+		return nil, err
+	}
+	return buildOkReply(request.MessageID)
+}
+
+// KillSubscription ends a subscription of any session, its session being sent a
+// subscription-terminated notification
+func (o1 *o1Controller) KillSubscription(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(KillSubscription)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	id := strings.TrimSpace(request.ID)
+	var sub *pushSubscription
+	killed, ok := o1.parsePushID(id)
+	if ok {
+		sub, ok = o1.endPush(killed.id)
+	}
+	if !ok {
+		return buildErrorReply(request.MessageID, noSuchSubscription(id))
+	}
+	log.Infof("Session %s killed subscription %d of session %s", sessionID, sub.id, sub.sessionID)
+
+	o1.notifyPush(sub, "subscription-terminated", fmt.Sprintf(`<reason xmlns:sn="%s">sn:no-such-subscription</reason>`, SubscribedNotificationsNamespace))
+
+	err = o1.UpdateStoreOperation(ctx, sessionID, "kill-subscription", strings.Join(sub.namespaces(), ","), nil)
+	if err != nil {
+		return nil, err
+	}
+	return buildOkReply(request.MessageID)
+}
+
+// parsePushID returns the subscription of the id
+func (o1 *o1Controller) parsePushID(id string) (pushSubscription, bool) {
+	value, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return pushSubscription{}, false
+	}
+
+	o1.mu.RLock()
+	defer o1.mu.RUnlock()
+
+	sub, ok := o1.pushSubscriptions[uint32(value)]
+	if !ok {
+		return pushSubscription{}, false
+	}
+	return *sub, true
+}
+
+// sessionPush returns the subscription of the id if it was established by the session
+func (o1 *o1Controller) sessionPush(sessionID, id string) (pushSubscription, bool) {
+	sub, ok := o1.parsePushID(id)
+	if !ok || sub.sessionID != sessionID {
+		return pushSubscription{}, false
+	}
+	return sub, true
+}
+
+// configurePush returns the subscription with the parameters of an establish-subscription or
+// of a modify-subscription, whose parameters not set are left unchanged
+func (o1 *o1Controller) configurePush(sub pushSubscription, params PushParameters, establish bool) (pushSubscription, *RPCError) {
+	if params.Stream != "" {
+		rpcErr := subscriptionError(ErrorTagInvalidValue, "ietf-subscribed-notifications:stream-unavailable", "stream",
+			"only datastore subscriptions are supported, the event streams being subscribed by create-subscription")
+		return sub, &rpcErr
+	}
+	if establish && params.Datastore == "" {
+		rpcErr := subscriptionError(ErrorTagMissingElement, "", "datastore", "establish-subscription has no datastore")
+		return sub, &rpcErr
+	}
+	if params.Datastore != "" {
+		datastore := strings.TrimSpace(params.Datastore)
+		if i := strings.LastIndex(datastore, ":"); i >= 0 {
+			datastore = datastore[i+1:]
+		}
+		if datastore != "running" {
+			rpcErr := subscriptionError(ErrorTagInvalidValue, "ietf-yang-push:datastore-not-subscribable", "datastore",
+				fmt.Sprintf("datastore %s can not be subscribed", datastore))
+			return sub, &rpcErr
+		}
+	}
+
+	if establish || params.SubtreeFilter != nil || params.XPathFilter != nil {
+		targets, filter, rpcErr := o1.datastoreTargets(params.SubtreeFilter, params.XPathFilter)
+		if rpcErr != nil {
+			return sub, rpcErr
+		}
+		sub.targets = targets
+		sub.filter = filter
+	}
+
+	switch {
+	case params.Periodic != nil && params.OnChange != nil:
+		rpcErr := subscriptionError(ErrorTagInvalidValue, "", "on-change", "a subscription is either periodic or on-change")
+		return sub, &rpcErr
+	case params.Periodic != nil:
+		if !establish && sub.period == 0 {
+			rpcErr := subscriptionError(ErrorTagInvalidValue, "", "periodic", "an on-change subscription can not become periodic")
+			return sub, &rpcErr
+		}
+		period, err := strconv.ParseUint(strings.TrimSpace(params.Periodic.Period), 10, 32)
+		if err != nil || period == 0 {
+			rpcErr := subscriptionError(ErrorTagInvalidValue, "ietf-yang-push:period-unsupported", "period",
+				fmt.Sprintf("invalid period %s", params.Periodic.Period))
+			return sub, &rpcErr
+		}
+		sub.period = uint32(period)
+	case params.OnChange != nil:
+		if !establish && sub.period > 0 {
+			rpcErr := subscriptionError(ErrorTagInvalidValue, "", "on-change", "a periodic subscription can not become on-change")
+			return sub, &rpcErr
+		}
+		if establish {
+			sub.syncOnStart = true
+		}
+		if dampening := strings.TrimSpace(params.OnChange.DampeningPeriod); dampening != "" {
+			value, err := strconv.ParseUint(dampening, 10, 32)
+			if err != nil {
+				rpcErr := subscriptionError(ErrorTagInvalidValue, "", "dampening-period",
+					fmt.Sprintf("invalid dampening-period %s", dampening))
+				return sub, &rpcErr
+			}
+			if value != 0 {
+				// the changes are sent as received from onos-config
+				rpcErr := subscriptionError(ErrorTagInvalidValue, "", "dampening-period",
+					fmt.Sprintf("dampening-period %s is not supported, only 0", dampening))
+				return sub, &rpcErr
+			}
+			sub.dampeningPeriod = uint32(value)
+		}
+		if syncOnStart := strings.TrimSpace(params.OnChange.SyncOnStart); syncOnStart != "" {
+			sub.syncOnStart = syncOnStart == "true"
+		}
+	case establish:
+		rpcErr := subscriptionError(ErrorTagMissingElement, "", "periodic", "establish-subscription has neither periodic nor on-change")
+		return sub, &rpcErr
+	}
+
+	if params.StopTime != "" {
+		stopTime, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(params.StopTime))
+		if err != nil || !stopTime.After(time.Now()) {
+			rpcErr := subscriptionError(ErrorTagInvalidValue, "", "stop-time", fmt.Sprintf("invalid stop-time %s", params.StopTime))
+			return sub, &rpcErr
+		}
+		sub.stopTime = stopTime
+	}
+	return sub, nil
+}
+
+// datastoreTargets returns the targets of the data selected by the filter, all the targets
+// of the capabilities without filter, and the XML of the filter
+func (o1 *o1Controller) datastoreTargets(subtree *EventFilter, xpath *XPathFilter) ([]subscriptionTarget, string, *RPCError) {
+	targets := o1.capabilityTargets()
+
+	var paths map[string][]*gnmi.Path
+	var filter, element string
+	var err error
+	switch {
+	case subtree != nil && xpath != nil:
+		rpcErr := subscriptionError(ErrorTagInvalidValue, "", "datastore-xpath-filter", "a subscription has a single filter")
+		return nil, "", &rpcErr
+	case subtree != nil:
+		element = "datastore-subtree-filter"
+		filter = fmt.Sprintf(`<%s xmlns="%s">%s</%s>`, element, YangPushNamespace, subtree.Data, element)
+		paths, err = filterPaths(&EventFilter{Type: "subtree", Data: subtree.Data})
+	case xpath != nil:
+		element = "datastore-xpath-filter"
+		filter = xpath.xml()
+		paths, err = xpathFilterPaths(xpath)
+	default:
+		for i := range targets {
+			targets[i].paths = []*gnmi.Path{{Elem: []*gnmi.PathElem{}}}
+		}
+		return targets, "", nil
+	}
+	if err != nil {
+		rpcErr := subscriptionError(ErrorTagInvalidValue, "ietf-subscribed-notifications:filter-unsupported", element,
+			fmt.Sprintf("invalid filter: %v", err))
+		return nil, "", &rpcErr
+	}
+
+	var filtered []subscriptionTarget
+	for _, target := range targets {
+		if targetPaths, ok := paths[target.capability]; ok {
+			target.paths = targetPaths
+			filtered = append(filtered, target)
+		}
+	}
+	if len(filtered) == 0 {
+		rpcErr := subscriptionError(ErrorTagInvalidValue, "ietf-subscribed-notifications:filter-unavailable", element,
+			"the filter selects no data of the capabilities")
+		return nil, "", &rpcErr
+	}
+	return filtered, filter, nil
+}
+
+// xml returns the XML of the filter, with the declarations of its prefixes
+func (f *XPathFilter) xml() string {
+	var filter strings.Builder
+	fmt.Fprintf(&filter, `<datastore-xpath-filter xmlns="%s"`, YangPushNamespace)
+	for _, attr := range f.Attrs {
+		if attr.Name.Space == "xmlns" {
+			fmt.Fprintf(&filter, ` xmlns:%s="`, attr.Name.Local)
+			_ = xml.EscapeText(&filter, []byte(attr.Value))
+			filter.WriteString(`"`)
+		}
+	}
+	filter.WriteString(">")
+	_ = xml.EscapeText(&filter, []byte(f.Select))
+	filter.WriteString("</datastore-xpath-filter>")
+	return filter.String()
+}
+
+// xpathFilterPaths returns the path selected by a xpath filter by the namespace of its
+// prefixes, which are removed from the path
+func xpathFilterPaths(filter *XPathFilter) (map[string][]*gnmi.Path, error) {
+	prefixes := make(map[string]string)
+	for _, attr := range filter.Attrs {
+		if attr.Name.Space == "xmlns" {
+			prefixes[attr.Name.Local] = attr.Value
+		}
+	}
+
+	var selection strings.Builder
+	namespace := ""
+	quote := rune(0)
+	expression := []rune(strings.TrimSpace(filter.Select))
+	for i := 0; i < len(expression); i++ {
+		c := expression[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ':':
+			// the prefix is the name before the colon
+			name := selection.String()
+			start := strings.LastIndexAny(name, "/[ ")
+			prefix := name[start+1:]
+			ns, ok := prefixes[prefix]
+			if !ok {
+				return nil, fmt.Errorf("prefix %q is not declared", prefix)
+			}
+			if namespace != "" && ns != namespace {
+				return nil, fmt.Errorf("the path selects data of several namespaces")
+			}
+			namespace = ns
+			selection.Reset()
+			selection.WriteString(name[:start+1])
+			continue
+		}
+		selection.WriteRune(c)
+	}
+	if namespace == "" {
+		return nil, fmt.Errorf("the path of the filter is not qualified by a prefix")
+	}
+
+	path, err := filterPath(selection.String())
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]*gnmi.Path{namespace: {path}}, nil
+}
+
+// startPush opens the gNMI subscriptions of the subscription and arms its stop time, with
+// the lock of the controller held
+func (o1 *o1Controller) startPush(sub *pushSubscription) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sub.cancel = cancel
+	sub.stopTimer = nil
+	for _, target := range sub.targets {
+		go o1.subscribeTarget(ctx, sub.session, o1.pushStream(*sub, target))
+	}
+
+	if !sub.stopTime.IsZero() {
+		id := sub.id
+		sub.stopTimer = time.AfterFunc(time.Until(sub.stopTime), func() {
+			ended, ok := o1.endPush(id)
+			if ok {
+				log.Infof("Subscription %d of session %s completed", id, ended.sessionID)
+				o1.notifyPush(ended, "subscription-completed", "")
+			}
+		})
+	}
+}
+
+// stop cancels the gNMI subscriptions and the stop time of the subscription
+func (sub *pushSubscription) stop() {
+	if sub.cancel != nil {
+		sub.cancel()
+	}
+	if sub.stopTimer != nil {
+		sub.stopTimer.Stop()
+	}
+}
+
+// endPush ends the subscription of the id and removes it from the store, returning false if
+// it does not exist
+func (o1 *o1Controller) endPush(id uint32) (*pushSubscription, bool) {
+	o1.mu.Lock()
+	sub, ok := o1.pushSubscriptions[id]
+	delete(o1.pushSubscriptions, id)
+	o1.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	sub.stop()
+	err := o1.Store.Delete(context.Background(), store.Key{SessionID: sub.sessionID, SubscriptionID: strconv.FormatUint(uint64(id), 10)})
+	if err != nil {
+		log.Warnf("Subscription %d can not be removed from the store: %v", id, err)
+	}
+	return sub, true
+}
+
+// cancelPushSubscriptions ends the subscriptions established by the session
+func (o1 *o1Controller) cancelPushSubscriptions(sessionID string) {
+	var ids []uint32
+	o1.mu.RLock()
+	for id, sub := range o1.pushSubscriptions {
+		if sub.sessionID == sessionID {
+			ids = append(ids, id)
+		}
+	}
+	o1.mu.RUnlock()
+
+	for _, id := range ids {
+		log.Infof("Cancel subscription %d of session %s", id, sessionID)
+		o1.endPush(id)
+	}
+}
+
+// storePush puts the subscription in the store, with the key of its session
+func (o1 *o1Controller) storePush(ctx context.Context, sub *pushSubscription) error {
+	value := &store.SubscriptionValue{
+		ID:              sub.id,
+		Datastore:       "running",
+		Namespaces:      sub.namespaces(),
+		Filter:          sub.filter,
+		Period:          sub.period,
+		DampeningPeriod: sub.dampeningPeriod,
+		SyncOnStart:     sub.syncOnStart,
+	}
+	if !sub.stopTime.IsZero() {
+		value.StopTime = uint64(sub.stopTime.UnixNano())
+	}
+	_, err := o1.Store.Put(ctx, store.Key{SessionID: sub.sessionID, SubscriptionID: strconv.FormatUint(uint64(sub.id), 10)}, value)
+	return err
+}
+
+// namespaces returns the target:name:version of the targets of the subscription
+func (sub *pushSubscription) namespaces() []string {
+	var namespaces []string
+	for _, target := range sub.targets {
+		namespaces = append(namespaces, target.name())
+	}
+	return namespaces
+}
+
+// parametersXML returns the XML of the parameters of the subscription, as notified by
+// subscription-started and subscription-modified
+func (sub *pushSubscription) parametersXML() string {
+	var parameters strings.Builder
+	fmt.Fprintf(&parameters, `<datastore xmlns="%s" xmlns:ds="%s">ds:running</datastore>`, YangPushNamespace, DatastoresNamespace)
+	parameters.WriteString(sub.filter)
+	if sub.period > 0 {
+		fmt.Fprintf(&parameters, `<periodic xmlns="%s"><period>%d</period></periodic>`, YangPushNamespace, sub.period)
+	} else {
+		fmt.Fprintf(&parameters, `<on-change xmlns="%s"><dampening-period>%d</dampening-period><sync-on-start>%t</sync-on-start></on-change>`,
+			YangPushNamespace, sub.dampeningPeriod, sub.syncOnStart)
+	}
+	if !sub.stopTime.IsZero() {
+		fmt.Fprintf(&parameters, `<stop-time>%s</stop-time>`, sub.stopTime.UTC().Format(time.RFC3339Nano))
+	}
+	return parameters.String()
+}
+
+// notifyPush sends a subscription state change notification to the session of the
+// subscription
+func (o1 *o1Controller) notifyPush(sub *pushSubscription, event, data string) {
+	content, err := xml.Marshal(&SubscriptionEvent{
+		XMLName: xml.Name{Space: SubscribedNotificationsNamespace, Local: event},
+		ID:      sub.id,
+		Data:    data,
+	})
+	if err == nil {
+		content, err = xml.Marshal(&Notification{
+			EventTime: eventTime(0),
+			Data:      string(content),
+		})
+	}
+	if err == nil {
+		err = sub.session.Notify(content)
+	}
+	if err != nil {
+		log.Warnf("Notification %s of subscription %d can not be sent: %v", event, sub.id, err)
+	}
+}
+
+// pushStream returns the gNMI stream of the data of the target selected by the subscription.
+// A periodic subscription is a SAMPLE subscription, each sample being sent as a push-update.
+// An on-change subscription is an ON_CHANGE subscription, its initial values being sent as a
+// push-update when it syncs on start and the changes as push-change-updates.
+func (o1 *o1Controller) pushStream(sub pushSubscription, target subscriptionTarget) targetStream {
+	stream := targetStream{target: target}
+	if sub.period > 0 {
+		stream.mode = gnmi.SubscriptionMode_SAMPLE
+		stream.sampleInterval = uint64(sub.period) * uint64(10*time.Millisecond)
+	} else {
+		stream.mode = gnmi.SubscriptionMode_ON_CHANGE
+		stream.updatesOnly = !sub.syncOnStart
+	}
+
+	stream.build = func(ctx context.Context, update *gnmi.Notification, synced bool) ([]byte, error) {
+		modelSchema := o1.modelSchema(ctx, target.namespace)
+		data, err := updatedXML(modelSchema, target, update)
+		if err != nil {
+			return nil, err
+		}
+
+		var content interface{}
+		if sub.period > 0 || !synced {
+			if len(data) == 0 {
+				return nil, nil
+			}
+			content = &PushUpdate{ID: sub.id, Contents: innerData{Data: string(data)}}
+		} else {
+			change := &PushChangeUpdate{ID: sub.id}
+			patch := &change.Changes.Patch
+			patch.PatchID = strconv.FormatUint(atomic.AddUint64(sub.patches, 1), 10)
+			if len(data) > 0 {
+				patch.Edits = append(patch.Edits, PatchEdit{Operation: "merge", Target: "/", Value: &innerData{Data: string(data)}})
+			}
+			for _, path := range update.Delete {
+				patch.Edits = append(patch.Edits, PatchEdit{Operation: "delete", Target: patchTarget(modelSchema, target.namespace, joinPaths(update.Prefix, path))})
+			}
+			if len(patch.Edits) == 0 {
+				return nil, nil
+			}
+			for i := range patch.Edits {
+				patch.Edits[i].EditID = strconv.Itoa(i + 1)
+			}
+			content = change
+		}
+
+		data, err = xml.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		return xml.Marshal(&Notification{
+			EventTime: eventTime(update.Timestamp),
+			Data:      string(data),
+		})
+	}
+	return stream
+}
+
+// patchTarget returns the RESTCONF data resource path of a gNMI path of the namespace, the
+// top-level node qualified by the model name and the keys of list entries in the order of the
+// keys of the list in the schema, or in key name order without schema
+func patchTarget(modelSchema *schema.Schema, namespace Namespace, path *gnmi.Path) string {
+	var resource strings.Builder
+	var entry *yang.Entry
+	for i, elem := range path.GetElem() {
+		resource.WriteString("/")
+		if i == 0 {
+			resource.WriteString(namespace.Name + ":")
+		}
+		resource.WriteString(elem.Name)
+
+		if modelSchema != nil {
+			switch {
+			case i == 0:
+				entry = modelSchema.Root(namespace.Name, elem.Name)
+			case entry != nil:
+				entry = schema.Child(entry, elem.Name)
+			}
+		}
+		for j, name := range keyNames(entry, elem.Key) {
+			if j == 0 {
+				resource.WriteString("=")
+			} else {
+				resource.WriteString(",")
+			}
+			resource.WriteString(url.PathEscape(elem.Key[name]))
+		}
+	}
+	if resource.Len() == 0 {
+		return "/"
+	}
+	return resource.String()
+}
+
+// keyNames returns the names of the keys of a list entry in the order of the keys of the list
+// entry of the schema, or in name order if they are not those of the schema
+func keyNames(entry *yang.Entry, keys map[string]string) []string {
+	if entry != nil {
+		names := schema.Keys(entry)
+		found := len(names) == len(keys)
+		for _, name := range names {
+			_, ok := keys[name]
+			found = found && ok
+		}
+		if found {
+			return names
+		}
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
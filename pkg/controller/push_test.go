@@ -0,0 +1,516 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/southbound"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const kpimonNamespace = "http://opennetworking.org/kpimon:ric:1.0.0"
+
+// notifyingSession is the transport of a session able to send notifications, recording them
+// and whether they were flushed
+type notifyingSession struct {
+	*fakeSession
+	notifications chan string
+	flushed       chan struct{}
+}
+
+func newNotifyingSession() *notifyingSession {
+	return &notifyingSession{
+		fakeSession:   newFakeSession(),
+		notifications: make(chan string, 100),
+		flushed:       make(chan struct{}, 1),
+	}
+}
+
+func (s *notifyingSession) Notify(notification []byte) error {
+	s.notifications <- string(notification)
+	return nil
+}
+
+func (s *notifyingSession) Flush(ctx context.Context) error {
+	select {
+	case s.flushed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// next returns the next notification sent to the session
+func (s *notifyingSession) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case notification := <-s.notifications:
+		return notification
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification sent")
+	}
+	return ""
+}
+
+// subscribingClient replies the responses of the test to each gNMI subscription, recording
+// the subscribe requests
+type subscribingClient struct {
+	southbound.GnmiClient
+	responses []*gnmi.SubscribeResponse
+	requests  chan *gnmi.SubscribeRequest
+}
+
+func newSubscribingClient(responses ...*gnmi.SubscribeResponse) *subscribingClient {
+	return &subscribingClient{responses: responses, requests: make(chan *gnmi.SubscribeRequest, 10)}
+}
+
+func (c *subscribingClient) Subscribe(ctx context.Context, request *gnmi.SubscribeRequest) (gnmi.GNMI_SubscribeClient, error) {
+	c.requests <- request
+	return &subscribeStream{ctx: ctx, responses: c.responses}, nil
+}
+
+// next returns the next subscribe request
+func (c *subscribingClient) next(t *testing.T) *gnmi.SubscribeRequest {
+	t.Helper()
+	select {
+	case request := <-c.requests:
+		return request
+	case <-time.After(5 * time.Second):
+		t.Fatal("no gNMI subscription")
+	}
+	return nil
+}
+
+// subscribeStream receives the responses, then blocks until the subscription is canceled
+type subscribeStream struct {
+	gnmi.GNMI_SubscribeClient
+	ctx       context.Context
+	responses []*gnmi.SubscribeResponse
+}
+
+func (s *subscribeStream) Recv() (*gnmi.SubscribeResponse, error) {
+	if len(s.responses) > 0 {
+		response := s.responses[0]
+		s.responses = s.responses[1:]
+		return response, nil
+	}
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+// newPushController returns a controller whose capabilities are those of a kpimon target,
+// with an opened session 1
+func newPushController(t *testing.T, client southbound.GnmiClient) (*o1Controller, *notifyingSession) {
+	t.Helper()
+	topo := rnib.NewFakeClient(rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:1.0.0"})
+	o1 := NewO1Controller(store.NewStore(), topo, client).(*o1Controller)
+	t.Cleanup(o1.Close)
+
+	ctx := context.Background()
+	_, err := o1.Capabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := newNotifyingSession()
+	err = o1.OpenSession(ctx, SessionInfo{SessionID: "1", User: "alice", Transport: "ssh"}, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o1, session
+}
+
+func intervalUpdate(interval string) *gnmi.Notification {
+	return &gnmi.Notification{
+		Timestamp: 1,
+		Update: []*gnmi.Update{{
+			Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "report_period"}, {Name: "interval"}}},
+			Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: interval}},
+		}},
+	}
+}
+
+func TestConfigurePush(t *testing.T) {
+	o1, _ := newPushController(t, newSubscribingClient())
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339Nano)
+	periodic := pushSubscription{period: 100}
+	onChange := pushSubscription{syncOnStart: true}
+
+	tests := []struct {
+		name      string
+		current   pushSubscription
+		params    PushParameters
+		establish bool
+		// tag and appTag are those of the error, want checks the configured subscription
+		tag    string
+		appTag string
+		want   func(pushSubscription) bool
+	}{
+		{
+			name:      "periodic",
+			params:    PushParameters{Datastore: "ds:running", Periodic: &Periodic{Period: "50"}, StopTime: future},
+			establish: true,
+			want: func(sub pushSubscription) bool {
+				return sub.period == 50 && !sub.stopTime.IsZero() && len(sub.targets) == 1 && sub.filter == ""
+			},
+		},
+		{
+			name:      "on-change syncing on start by default",
+			params:    PushParameters{Datastore: "running", OnChange: &OnChange{DampeningPeriod: "0"}},
+			establish: true,
+			want: func(sub pushSubscription) bool {
+				return sub.period == 0 && sub.dampeningPeriod == 0 && sub.syncOnStart
+			},
+		},
+		{
+			name:      "on-change with dampening",
+			params:    PushParameters{Datastore: "running", OnChange: &OnChange{DampeningPeriod: "20"}},
+			establish: true,
+			tag:       ErrorTagInvalidValue,
+		},
+		{
+			name:      "on-change without sync on start",
+			params:    PushParameters{Datastore: "running", OnChange: &OnChange{SyncOnStart: "false"}},
+			establish: true,
+			want: func(sub pushSubscription) bool {
+				return !sub.syncOnStart
+			},
+		},
+		{
+			name:      "subtree filter",
+			params:    PushParameters{Datastore: "running", Periodic: &Periodic{Period: "50"}, SubtreeFilter: &EventFilter{Data: `<report_period xmlns="` + kpimonNamespace + `"/>`}},
+			establish: true,
+			want: func(sub pushSubscription) bool {
+				return len(sub.targets) == 1 && len(sub.targets[0].paths) == 1 && strings.Contains(sub.filter, "report_period")
+			},
+		},
+		{
+			name:    "modify keeping the trigger",
+			current: periodic,
+			params:  PushParameters{StopTime: future},
+			want: func(sub pushSubscription) bool {
+				return sub.period == 100 && !sub.stopTime.IsZero()
+			},
+		},
+		{
+			name:      "event stream",
+			params:    PushParameters{Stream: "NETCONF"},
+			establish: true,
+			tag:       ErrorTagInvalidValue,
+			appTag:    "ietf-subscribed-notifications:stream-unavailable",
+		},
+		{
+			name:      "no datastore",
+			params:    PushParameters{Periodic: &Periodic{Period: "50"}},
+			establish: true,
+			tag:       ErrorTagMissingElement,
+		},
+		{
+			name:      "operational datastore",
+			params:    PushParameters{Datastore: "ds:operational", Periodic: &Periodic{Period: "50"}},
+			establish: true,
+			tag:       ErrorTagInvalidValue,
+			appTag:    "ietf-yang-push:datastore-not-subscribable",
+		},
+		{
+			name:      "no trigger",
+			params:    PushParameters{Datastore: "running"},
+			establish: true,
+			tag:       ErrorTagMissingElement,
+		},
+		{
+			name:      "periodic and on-change",
+			params:    PushParameters{Datastore: "running", Periodic: &Periodic{Period: "50"}, OnChange: &OnChange{}},
+			establish: true,
+			tag:       ErrorTagInvalidValue,
+		},
+		{
+			name:      "zero period",
+			params:    PushParameters{Datastore: "running", Periodic: &Periodic{Period: "0"}},
+			establish: true,
+			tag:       ErrorTagInvalidValue,
+			appTag:    "ietf-yang-push:period-unsupported",
+		},
+		{
+			name:      "stop time passed",
+			params:    PushParameters{Datastore: "running", Periodic: &Periodic{Period: "50"}, StopTime: past},
+			establish: true,
+			tag:       ErrorTagInvalidValue,
+		},
+		{
+			name:      "filter of another namespace",
+			params:    PushParameters{Datastore: "running", Periodic: &Periodic{Period: "50"}, SubtreeFilter: &EventFilter{Data: `<cells xmlns="http://opennetworking.org/mho:ric:1.0.0"/>`}},
+			establish: true,
+			tag:       ErrorTagInvalidValue,
+			appTag:    "ietf-subscribed-notifications:filter-unavailable",
+		},
+		{
+			name:    "periodic becoming on-change",
+			current: periodic,
+			params:  PushParameters{OnChange: &OnChange{}},
+			tag:     ErrorTagInvalidValue,
+		},
+		{
+			name:    "on-change becoming periodic",
+			current: onChange,
+			params:  PushParameters{Periodic: &Periodic{Period: "50"}},
+			tag:     ErrorTagInvalidValue,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sub, rpcErr := o1.configurePush(test.current, test.params, test.establish)
+			if test.tag == "" {
+				if rpcErr != nil {
+					t.Fatal(rpcErr.Message)
+				}
+				if !test.want(sub) {
+					t.Errorf("unexpected subscription %+v", sub)
+				}
+				return
+			}
+			if rpcErr == nil {
+				t.Fatalf("subscription %+v configured, want %s", sub, test.tag)
+			}
+			if rpcErr.Tag != test.tag || rpcErr.AppTag != test.appTag {
+				t.Errorf("error %s %s, want %s %s", rpcErr.Tag, rpcErr.AppTag, test.tag, test.appTag)
+			}
+		})
+	}
+}
+
+// pathString formats the path as its element names, with their keys
+func pathString(path *gnmi.Path) string {
+	var elems []string
+	for _, elem := range path.Elem {
+		name := elem.Name
+		for key, value := range elem.Key {
+			name += "[" + key + "=" + value + "]"
+		}
+		elems = append(elems, name)
+	}
+	return strings.Join(elems, "/")
+}
+
+func TestXPathFilterPaths(t *testing.T) {
+	prefix := xml.Attr{Name: xml.Name{Space: "xmlns", Local: "k"}, Value: kpimonNamespace}
+	other := xml.Attr{Name: xml.Name{Space: "xmlns", Local: "m"}, Value: "http://opennetworking.org/mho:ric:1.0.0"}
+	tests := []struct {
+		name      string
+		attrs     []xml.Attr
+		selection string
+		path      string
+		err       string
+	}{
+		{name: "qualified", attrs: []xml.Attr{prefix}, selection: "/k:report_period/k:interval", path: "report_period/interval"},
+		{name: "quoted colon", attrs: []xml.Attr{prefix}, selection: "/k:cells/k:cell[k:id='a:b']", path: "cells/cell[id=a:b]"},
+		{name: "undeclared prefix", attrs: []xml.Attr{prefix}, selection: "/x:report_period", err: `prefix "x" is not declared`},
+		{name: "several namespaces", attrs: []xml.Attr{prefix, other}, selection: "/k:report_period/m:cells", err: "several namespaces"},
+		{name: "unqualified", attrs: []xml.Attr{prefix}, selection: "/report_period", err: "not qualified"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paths, err := xpathFilterPaths(&XPathFilter{Attrs: test.attrs, Select: test.selection})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 || len(paths[kpimonNamespace]) != 1 {
+				t.Fatalf("paths %v, want a path of %s", paths, kpimonNamespace)
+			}
+			if got := pathString(paths[kpimonNamespace][0]); got != test.path {
+				t.Errorf("path %s, want %s", got, test.path)
+			}
+		})
+	}
+}
+
+func TestPatchTarget(t *testing.T) {
+	namespace := Namespace{Target: "kpimon", Name: "ric", Version: "1.0.0"}
+	path := &gnmi.Path{Elem: []*gnmi.PathElem{
+		{Name: "cells"},
+		{Name: "cell", Key: map[string]string{"plmn": "a/b", "id": "1"}},
+		{Name: "name"},
+	}}
+	if got := patchTarget(nil, namespace, path); got != "/ric:cells/cell=1,a%2Fb/name" {
+		t.Errorf("target %s", got)
+	}
+	if got := patchTarget(nil, namespace, &gnmi.Path{}); got != "/" {
+		t.Errorf("target %s of the root", got)
+	}
+
+	// the keys are in the order of the list of the schema
+	ricSchema, ok := ricRegistry(t).Schema(context.Background(), "ric", "1.0.0")
+	if !ok {
+		t.Fatal("no schema of the ric model")
+	}
+	path = &gnmi.Path{Elem: []*gnmi.PathElem{
+		{Name: "ric"},
+		{Name: "cell", Key: map[string]string{"cid": "7", "plmn": "001-01"}},
+	}}
+	if got := patchTarget(ricSchema, namespace, path); got != "/ric:ric/cell=001-01,7" {
+		t.Errorf("target %s", got)
+	}
+}
+
+func TestPushPeriodic(t *testing.T) {
+	client := newSubscribingClient(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: intervalUpdate("7")}})
+	o1, session := newPushController(t, client)
+	ctx := context.Background()
+
+	reply, err := o1.EstablishSubscription(ctx, "1", []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.1" message-id="1"><establish-subscription xmlns="`+SubscribedNotificationsNamespace+`">`+
+		`<datastore xmlns:ds="`+DatastoresNamespace+`">ds:running</datastore><periodic xmlns="`+YangPushNamespace+`"><period>50</period></periodic>`+
+		`</establish-subscription></rpc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(reply), `<id xmlns="`+SubscribedNotificationsNamespace+`">1</id>`) {
+		t.Fatalf("unexpected reply %s", reply)
+	}
+	if notification := session.next(t); !strings.Contains(notification, "subscription-started") || !strings.Contains(notification, "<period>50</period>") {
+		t.Errorf("unexpected notification %s", notification)
+	}
+
+	entry, err := o1.Store.Get(ctx, store.Key{SessionID: "1", SubscriptionID: "1"})
+	if err != nil {
+		t.Fatalf("subscription not stored: %v", err)
+	}
+	if value := entry.Value.(*store.SubscriptionValue); value.Period != 50 || len(value.Namespaces) != 1 || value.Namespaces[0] != "kpimon:ric:1.0.0" {
+		t.Errorf("stored subscription %+v", value)
+	}
+
+	// a period of 50 centiseconds samples every 500ms
+	request := client.next(t).GetSubscribe()
+	if request.Prefix.Target != "kpimon" || len(request.Subscription) != 1 {
+		t.Fatalf("unexpected subscribe request %v", request)
+	}
+	if subscription := request.Subscription[0]; subscription.Mode != gnmi.SubscriptionMode_SAMPLE || subscription.SampleInterval != uint64(500*time.Millisecond) {
+		t.Errorf("subscription mode %s interval %d", subscription.Mode, subscription.SampleInterval)
+	}
+	if notification := session.next(t); !strings.Contains(notification, "<push-update") || !strings.Contains(notification, "<interval>7</interval>") {
+		t.Errorf("unexpected push-update %s", notification)
+	}
+
+	// another session can neither modify nor delete the subscription
+	otherSession := newNotifyingSession()
+	err = o1.OpenSession(ctx, SessionInfo{SessionID: "2", User: "bob", Transport: "ssh"}, otherSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rpc := range []string{
+		`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.1" message-id="2"><modify-subscription xmlns="` + SubscribedNotificationsNamespace + `"><id>1</id><periodic xmlns="` + YangPushNamespace + `"><period>10</period></periodic></modify-subscription></rpc>`,
+		`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.1" message-id="3"><delete-subscription xmlns="` + SubscribedNotificationsNamespace + `"><id>1</id></delete-subscription></rpc>`,
+	} {
+		var reply []byte
+		if strings.Contains(rpc, "modify-subscription") {
+			reply, err = o1.ModifySubscription(ctx, "2", []byte(rpc))
+		} else {
+			reply, err = o1.DeleteSubscription(ctx, "2", []byte(rpc))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(reply), "no-such-subscription") {
+			t.Errorf("unexpected reply %s", reply)
+		}
+	}
+
+	// the subscription is modified by its session, its gNMI subscription opened again
+	reply, err = o1.ModifySubscription(ctx, "1", []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.1" message-id="4"><modify-subscription xmlns="`+SubscribedNotificationsNamespace+`">`+
+		`<id>1</id><periodic xmlns="`+YangPushNamespace+`"><period>10</period></periodic></modify-subscription></rpc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(reply), "<ok/>") {
+		t.Fatalf("unexpected reply %s", reply)
+	}
+	if notification := session.next(t); !strings.Contains(notification, "subscription-modified") || !strings.Contains(notification, "<period>10</period>") {
+		t.Errorf("unexpected notification %s", notification)
+	}
+	if interval := client.next(t).GetSubscribe().Subscription[0].SampleInterval; interval != uint64(100*time.Millisecond) {
+		t.Errorf("modified interval %d", interval)
+	}
+	session.next(t)
+
+	// the subscription is killed by another session, its session being notified
+	reply, err = o1.KillSubscription(ctx, "2", []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.1" message-id="5"><kill-subscription xmlns="`+SubscribedNotificationsNamespace+`"><id>1</id></kill-subscription></rpc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(reply), "<ok/>") {
+		t.Fatalf("unexpected reply %s", reply)
+	}
+	if notification := session.next(t); !strings.Contains(notification, "subscription-terminated") {
+		t.Errorf("unexpected notification %s", notification)
+	}
+	if _, err := o1.Store.Get(ctx, store.Key{SessionID: "1", SubscriptionID: "1"}); err == nil {
+		t.Error("killed subscription still stored")
+	}
+}
+
+func TestPushOnChange(t *testing.T) {
+	deleted := &gnmi.Notification{
+		Timestamp: 1,
+		Delete:    []*gnmi.Path{{Elem: []*gnmi.PathElem{{Name: "report_period"}}}},
+	}
+	client := newSubscribingClient(
+		&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: intervalUpdate("7")}},
+		&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}},
+		&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: intervalUpdate("9")}},
+		&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: deleted}},
+	)
+	o1, session := newPushController(t, client)
+	ctx := context.Background()
+
+	stopTime := time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano)
+	_, err := o1.EstablishSubscription(ctx, "1", []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.1" message-id="1"><establish-subscription xmlns="`+SubscribedNotificationsNamespace+`">`+
+		`<datastore xmlns:ds="`+DatastoresNamespace+`">ds:running</datastore><on-change xmlns="`+YangPushNamespace+`"/>`+
+		`<stop-time>`+stopTime+`</stop-time></establish-subscription></rpc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.next(t)
+
+	request := client.next(t).GetSubscribe()
+	if request.UpdatesOnly || request.Subscription[0].Mode != gnmi.SubscriptionMode_ON_CHANGE {
+		t.Errorf("subscription updates-only %t mode %s", request.UpdatesOnly, request.Subscription[0].Mode)
+	}
+
+	// the initial values are a push-update, the changes push-change-updates
+	if notification := session.next(t); !strings.Contains(notification, "<push-update") || !strings.Contains(notification, "<interval>7</interval>") {
+		t.Errorf("unexpected initial values %s", notification)
+	}
+	notification := session.next(t)
+	for _, want := range []string{"<push-change-update", "<patch-id>1</patch-id>", "<operation>merge</operation>", "<interval>9</interval>"} {
+		if !strings.Contains(notification, want) {
+			t.Errorf("no %s in change %s", want, notification)
+		}
+	}
+	notification = session.next(t)
+	for _, want := range []string{"<patch-id>2</patch-id>", "<operation>delete</operation>", "<target>/ric:report_period</target>"} {
+		if !strings.Contains(notification, want) {
+			t.Errorf("no %s in change %s", want, notification)
+		}
+	}
+
+	// the subscription completes at its stop time
+	if notification := session.next(t); !strings.Contains(notification, "subscription-completed") {
+		t.Errorf("unexpected notification %s", notification)
+	}
+	if _, err := o1.Store.Get(ctx, store.Key{SessionID: "1", SubscriptionID: "1"}); err == nil {
+		t.Error("completed subscription still stored")
+	}
+}
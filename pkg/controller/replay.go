@@ -0,0 +1,366 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/replay"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// NetmodNotificationNamespace is the namespace of the event streams and of the replay
+// notifications of RFC 5277
+const NetmodNotificationNamespace = "urn:ietf:params:xml:ns:netmod:notification"
+
+// WithReplayLog persists the notifications of the event streams in the replay log, from which
+// the subscriptions with a start time are replayed
+func WithReplayLog(replayLog *replay.Log) Option {
+	return func(o1 *o1Controller) {
+		o1.replayLog = replayLog
+	}
+}
+
+type ReplayComplete struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netmod:notification replayComplete"`
+}
+
+type NotificationComplete struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netmod:notification notificationComplete"`
+}
+
+// replayWindow parses the startTime and stopTime of a create-subscription, zero if they are
+// not set
+func (o1 *o1Controller) replayWindow(startTime, stopTime string) (time.Time, time.Time, *RPCError) {
+	startTime = strings.TrimSpace(startTime)
+	stopTime = strings.TrimSpace(stopTime)
+	invalid := func(element, message string) (time.Time, time.Time, *RPCError) {
+		return time.Time{}, time.Time{}, &RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagInvalidValue,
+			Severity: ErrorSeverityError,
+			Message:  message,
+			Info:     fmt.Sprintf("<error-info><bad-element>%s</bad-element></error-info>", element),
+		}
+	}
+
+	if startTime == "" {
+		if stopTime != "" {
+			return time.Time{}, time.Time{}, &RPCError{
+				Type:     ErrorTypeProtocol,
+				Tag:      ErrorTagMissingElement,
+				Severity: ErrorSeverityError,
+				Message:  "stopTime requires a startTime",
+				Info:     "<error-info><bad-element>startTime</bad-element></error-info>",
+			}
+		}
+		return time.Time{}, time.Time{}, nil
+	}
+	if o1.replayLog == nil {
+		return time.Time{}, time.Time{}, &RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagOperationNotSupported,
+			Severity: ErrorSeverityError,
+			Message:  "replay is not supported",
+			Info:     "<error-info><bad-element>startTime</bad-element></error-info>",
+		}
+	}
+
+	start, err := time.Parse(time.RFC3339Nano, startTime)
+	if err != nil {
+		return invalid("startTime", fmt.Sprintf("invalid startTime %s", startTime))
+	}
+	if start.After(time.Now()) {
+		return invalid("startTime", "startTime is in the future")
+	}
+	if stopTime == "" {
+		return start, time.Time{}, nil
+	}
+	stop, err := time.Parse(time.RFC3339Nano, stopTime)
+	if err != nil {
+		return invalid("stopTime", fmt.Sprintf("invalid stopTime %s", stopTime))
+	}
+	if !stop.After(start) {
+		return invalid("stopTime", "stopTime must be later than startTime")
+	}
+	return start, stop, nil
+}
+
+// replayBatch is the number of replayed notifications queued to a session before waiting for
+// them to be sent, below the number of queued notifications closing the session
+const replayBatch = 100
+
+// replaySubscription sends the notifications of the replay log selected by the subscription
+// from its start time, followed by replayComplete, by batches sent before the next is queued. The subscription then receives the live
+// notifications, until its stop time where it ends with notificationComplete.
+func (o1 *o1Controller) replaySubscription(ctx context.Context, sub *subscription, targets []subscriptionTarget, start, stop time.Time) {
+	end := time.Now()
+	if !stop.IsZero() && stop.Before(end) {
+		end = stop
+	}
+	replayed := 0
+	err := o1.replayLog.Read(start, end, func(record replay.Record) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if !replays(record, targets, sub.events) {
+			return true
+		}
+		if sub.session.Notify([]byte(record.Notification)) != nil {
+			return false
+		}
+		replayed++
+		if replayed%replayBatch == 0 && sub.session.Flush(ctx) != nil {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		log.Warnf("Replay of session %s failed: %v", sub.sessionID, err)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	log.Infof("Replayed %d notifications to session %s", replayed, sub.sessionID)
+	o1.notifySubscription(sub, &ReplayComplete{})
+
+	if !stop.IsZero() && !stop.After(time.Now()) {
+		o1.completeSubscription(sub)
+		return
+	}
+
+	o1.mu.Lock()
+	sub.live = true
+	o1.mu.Unlock()
+	for _, target := range targets {
+		go o1.subscribeTarget(ctx, sub.session, o1.changeStream(target))
+	}
+	if stop.IsZero() {
+		return
+	}
+
+	timer := time.NewTimer(time.Until(stop))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+		o1.completeSubscription(sub)
+	}
+}
+
+// completeSubscription ends the subscription at its stop time with notificationComplete
+func (o1 *o1Controller) completeSubscription(sub *subscription) {
+	o1.mu.Lock()
+	current, ok := o1.subscriptions[sub.sessionID]
+	if ok && current == sub {
+		delete(o1.subscriptions, sub.sessionID)
+	}
+	o1.mu.Unlock()
+	if current != sub {
+		return
+	}
+
+	sub.cancel()
+	log.Infof("Subscription of session %s completed", sub.sessionID)
+	o1.notifySubscription(sub, &NotificationComplete{})
+}
+
+// notifySubscription sends a notification of the subscription to its session
+func (o1 *o1Controller) notifySubscription(sub *subscription, event interface{}) {
+	data, err := xml.Marshal(event)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+	notification, err := xml.Marshal(&Notification{
+		EventTime: eventTime(0),
+		Data:      string(data),
+	})
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+	err = sub.session.Notify(notification)
+	if err != nil {
+		log.Debugf("Notification of session %s can not be sent: %v", sub.sessionID, err)
+	}
+}
+
+// replays checks the subscription to the targets and events selects the record, the changes
+// of a target being selected when one of their paths is in or contains a path of the target
+func replays(record replay.Record, targets []subscriptionTarget, events eventSelection) bool {
+	if record.Event != "" {
+		return events.selects(record.Event)
+	}
+	for _, target := range targets {
+		if target.name() != record.Target {
+			continue
+		}
+		for _, path := range target.paths {
+			for _, changed := range record.Paths {
+				if pathsOverlap(path.GetElem(), changed) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// pathsOverlap checks the shorter path is a prefix of the other, the keys missing from an
+// element or with the value * matching any entry
+func pathsOverlap(selected []*gnmi.PathElem, changed []replay.PathElem) bool {
+	for i := 0; i < len(selected) && i < len(changed); i++ {
+		if selected[i].Name != changed[i].Name && selected[i].Name != "*" {
+			return false
+		}
+		for name, value := range selected[i].Key {
+			changedValue, ok := changed[i].Key[name]
+			if ok && value != "*" && value != changedValue {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// recordEvent appends an event notification of the NETCONF stream to the replay log
+func (o1 *o1Controller) recordEvent(name string, at time.Time, notification []byte) {
+	if o1.replayLog == nil {
+		return
+	}
+	err := o1.replayLog.Append(replay.Record{
+		EventTime:    at,
+		Event:        name,
+		Notification: string(notification),
+	})
+	if err != nil {
+		log.Warnf("Notification %s can not be recorded: %v", name, err)
+	}
+}
+
+// recordTargets keeps a gNMI ON_CHANGE subscription to all the data of each target of the
+// capabilities, whose changes are appended to the replay log whether a session subscribed to
+// them or not, until the controller is closed
+func (o1 *o1Controller) recordTargets() {
+	if o1.replayLog == nil {
+		return
+	}
+	targets := o1.capabilityTargets()
+
+	o1.mu.Lock()
+	defer o1.mu.Unlock()
+
+	if o1.ctx.Err() != nil {
+		return
+	}
+
+	current := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		name := target.name()
+		current[name] = true
+		if _, ok := o1.recorders[name]; ok {
+			continue
+		}
+		target.paths = []*gnmi.Path{{Elem: []*gnmi.PathElem{}}}
+		ctx, cancel := context.WithCancel(o1.ctx)
+		o1.recorders[name] = cancel
+		log.Infof("Recording the changes of %s in the replay log", name)
+		// no notification is sent to a session, the stream appending them to the log
+		o1.wg.Add(1)
+		go func(target subscriptionTarget) {
+			defer o1.wg.Done()
+			o1.subscribeTarget(ctx, nil, o1.recordStream(target))
+		}(target)
+	}
+	for name, cancel := range o1.recorders {
+		if !current[name] {
+			cancel()
+			delete(o1.recorders, name)
+		}
+	}
+}
+
+// recordStream returns the ON_CHANGE stream of the changes of the target appended to the
+// replay log
+func (o1 *o1Controller) recordStream(target subscriptionTarget) targetStream {
+	return targetStream{
+		target:      target,
+		mode:        gnmi.SubscriptionMode_ON_CHANGE,
+		updatesOnly: true,
+		build: func(ctx context.Context, update *gnmi.Notification, synced bool) ([]byte, error) {
+			notification, err := o1.buildNotification(ctx, target, update)
+			if err != nil || notification == nil {
+				return nil, err
+			}
+			err = o1.replayLog.Append(replay.Record{
+				EventTime:    notificationTime(update.Timestamp),
+				Target:       target.name(),
+				Paths:        changedPaths(update),
+				Notification: string(notification),
+			})
+			if err != nil {
+				log.Warnf("Change of target %s can not be recorded: %v", target.namespace.Target, err)
+			}
+			return nil, nil
+		},
+	}
+}
+
+// changedPaths returns the paths of the updated and deleted data of a gNMI notification
+func changedPaths(update *gnmi.Notification) [][]replay.PathElem {
+	var paths [][]replay.PathElem
+	add := func(path *gnmi.Path) {
+		var elems []replay.PathElem
+		for _, elem := range joinPaths(update.Prefix, path).Elem {
+			elems = append(elems, replay.PathElem{Name: elem.Name, Key: elem.Key})
+		}
+		paths = append(paths, elems)
+	}
+	for _, u := range update.Update {
+		add(u.Path)
+	}
+	for _, path := range update.Delete {
+		add(path)
+	}
+	return paths
+}
+
+// eventStreams returns the event streams, the NETCONF stream followed by a stream for each
+// target of the capabilities
+func (o1 *o1Controller) eventStreams() []EventStream {
+	stream := func(name, description string) EventStream {
+		s := EventStream{
+			Name:          name,
+			Description:   description,
+			ReplaySupport: o1.replayLog != nil,
+		}
+		if o1.replayLog != nil {
+			s.ReplayLogCreationTime = o1.replayLog.CreationTime().UTC().Format(time.RFC3339Nano)
+		}
+		return s
+	}
+
+	streams := []EventStream{stream(NetconfStream, "default NETCONF event stream, with the changes of all the targets")}
+	for _, target := range o1.capabilityTargets() {
+		streams = append(streams, stream(target.name(), fmt.Sprintf("changes of target %s", target.namespace.Target)))
+	}
+	return streams
+}
+
+// stopRecording stops recording the changes of the targets in the replay log
+func (o1 *o1Controller) stopRecording() {
+	o1.mu.Lock()
+	defer o1.mu.Unlock()
+
+	for name, cancel := range o1.recorders {
+		cancel()
+		delete(o1.recorders, name)
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/replay"
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// boundedSession is the transport of a session whose notifications are queued until flushed,
+// the notifications sent beyond replayBatch queued ones failing
+type boundedSession struct {
+	*fakeSession
+	mu            sync.Mutex
+	queued        int
+	notifications []string
+}
+
+func (s *boundedSession) Notify(notification []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queued >= replayBatch {
+		return errors.New("notification queue full")
+	}
+	s.queued++
+	s.notifications = append(s.notifications, string(notification))
+	return nil
+}
+
+func (s *boundedSession) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queued = 0
+	return nil
+}
+
+func TestReplayByBatches(t *testing.T) {
+	replayLog, err := replay.Open(replay.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayLog.Close()
+	start := time.Now().Add(-time.Minute)
+	total := 2*replayBatch + 1
+	for i := 0; i < total; i++ {
+		err := replayLog.Append(replay.Record{
+			EventTime:    start.Add(time.Duration(i) * time.Millisecond),
+			Event:        "netconf-config-change",
+			Notification: fmt.Sprint(i),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o1 := NewO1Controller(store.NewStore(), rnib.NewFakeClient(), nil, WithReplayLog(replayLog)).(*o1Controller)
+	defer o1.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := &boundedSession{fakeSession: newFakeSession()}
+	sub := &subscription{sessionID: "1", session: session, events: eventSelection{selected: true}, cancel: cancel}
+	o1.replaySubscription(ctx, sub, nil, start, time.Now())
+
+	// the replay waits for each batch to be sent, so that no notification overflows the queue
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if len(session.notifications) != total+1 {
+		t.Fatalf("%d notifications sent, want the %d of the replay log and replayComplete", len(session.notifications), total)
+	}
+	for i := 0; i < total; i++ {
+		if session.notifications[i] != fmt.Sprint(i) {
+			t.Fatalf("notification %d is %s", i, session.notifications[i])
+		}
+	}
+	if !strings.Contains(session.notifications[total], "<replayComplete") {
+		t.Errorf("unexpected last notification %s", session.notifications[total])
+	}
+}
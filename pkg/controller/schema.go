@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onosproject/onos-o1t/pkg/schema"
+)
+
+// SchemaProvider provides the schema of the YANG modules of the models of the targets
+type SchemaProvider interface {
+	Schema(ctx context.Context, name, version string) (*schema.Schema, bool)
+	// ModuleInfos returns the YANG modules and submodules whose source is provided
+	ModuleInfos() []schema.ModuleInfo
+	// Source returns the YANG source of a module or submodule, of the given revision if not
+	// empty
+	Source(name, revision string) (string, bool)
+}
+
+// WithSchemas sets the provider of the schemas used to translate the data of the rpcs, the
+// data being translated without schema if it is not set
+func WithSchemas(schemas SchemaProvider) Option {
+	return func(o1 *o1Controller) {
+		o1.schemas = schemas
+	}
+}
+
+// modelSchema returns the schema of the model of the namespace, nil if it is not known
+func (o1 *o1Controller) modelSchema(ctx context.Context, namespace Namespace) *schema.Schema {
+	if o1.schemas == nil {
+		return nil
+	}
+	s, ok := o1.schemas.Schema(ctx, namespace.Name, namespace.Version)
+	if !ok {
+		return nil
+	}
+	return s
+}
+
+// schemaRPCError returns the rpc-error of an error of the data of a request against its
+// schema
+func schemaRPCError(err *schema.Error) RPCError {
+	rpcErr := RPCError{
+		Type:     ErrorTypeApplication,
+		Tag:      err.Tag,
+		Severity: ErrorSeverityError,
+		AppTag:   err.AppTag,
+		Path:     err.Path,
+		Message:  err.Message,
+	}
+	if err.Tag == schema.TagMalformedMessage {
+		rpcErr.Type = ErrorTypeRPC
+	}
+	if err.BadElement != "" {
+		rpcErr.Info = fmt.Sprintf("<error-info><bad-element>%s</bad-element></error-info>", err.BadElement)
+	}
+	return rpcErr
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-o1t/pkg/metrics"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// Session is the transport side of a NETCONF session handled by the controller
+type Session interface {
+	// Close closes the transport of the session, after the reply of an in-flight rpc is sent
+	Close() error
+}
+
+// SessionInfo describes a NETCONF session and the transport carrying it
+type SessionInfo struct {
+	SessionID    string
+	User         string
+	SSHSessionID string
+	SourceHost   string
+	// Transport is the transport of the session, ssh or tls
+	Transport string
+}
+
+// OpenSession registers a new session and creates its entry in the store
+func (o1 *o1Controller) OpenSession(ctx context.Context, info SessionInfo, session Session) error {
+	session = o1.countSession(info.SessionID, session)
+	o1.mu.Lock()
+	o1.sessions[info.SessionID] = session
+	o1.mu.Unlock()
+
+	metrics.SessionOpened()
+	err := o1.CreateStoreOperation(ctx, info)
+	if err != nil {
+		return err
+	}
+	o1.publishSessionStart(info)
+	return nil
+}
+
+// EndSession marks the session as not alive in the store with the given reason, releases
+// its resources and closes its transport. Ending an already ended session has no effect.
+func (o1 *o1Controller) EndSession(ctx context.Context, sessionID string, reason store.EndReason) error {
+	return o1.endSession(ctx, sessionID, reason, "")
+}
+
+// endSession ends the session, killedBy being the session that killed it
+func (o1 *o1Controller) endSession(ctx context.Context, sessionID string, reason store.EndReason, killedBy string) error {
+	o1.mu.Lock()
+	session, registered := o1.sessions[sessionID]
+	delete(o1.sessions, sessionID)
+	o1.mu.Unlock()
+
+	o1.releaseSession(sessionID)
+
+	err := o1.markSessionEnded(ctx, sessionID, reason, killedBy)
+	if registered {
+		o1.uncountSession(sessionID, reason)
+	}
+
+	if registered {
+		closeErr := session.Close()
+		if closeErr != nil {
+			log.Debugf("Error closing session %s: %v", sessionID, closeErr)
+		}
+	}
+
+	return err
+}
+
+// markSessionEnded marks the session as not alive in the store with the given reason and
+// publishes its netconf-session-end
+func (o1 *o1Controller) markSessionEnded(ctx context.Context, sessionID string, reason store.EndReason, killedBy string) error {
+	var ended *store.SessionValue
+	err := o1.updateSession(ctx, sessionID, func(value *store.SessionValue) bool {
+		if !value.Alive {
+			return false
+		}
+		value.Alive = false
+		value.EndReason = reason
+		value.KilledBy = killedBy
+		value.EndTime = uint64(time.Now().UnixNano())
+		ended = value
+		return true
+	})
+	if err == nil && ended != nil {
+		log.Infof("Session %s ended: %s", sessionID, reason)
+		metrics.SessionEnded(reason.String())
+		o1.publishSessionEnd(sessionID, ended)
+	}
+	return err
+}
+
+// releaseSession releases the subscriptions of the session
+func (o1 *o1Controller) releaseSession(sessionID string) {
+	o1.cancelSubscription(sessionID)
+	o1.cancelPushSubscriptions(sessionID)
+
+	o1.mu.Lock()
+	defer o1.mu.Unlock()
+
+	delete(o1.helloCapabilities, sessionID)
+}
+
+// TerminateSessions ends all the alive sessions of the store with the shutdown reason. The
+// sessions are marked as ended first, publishing their netconf-session-end, and closed once the
+// notifications queued for them are sent, or when the context is done.
+func (o1 *o1Controller) TerminateSessions(ctx context.Context) error {
+	ch := make(chan *store.Entry)
+	done := make(chan []string)
+
+	go func() {
+		alive := []string{}
+		for entry := range ch {
+			if value, ok := entry.Value.(*store.SessionValue); ok && value.Alive {
+				alive = append(alive, entry.Key.SessionID)
+			}
+		}
+		done <- alive
+	}()
+
+	err := o1.Store.Entries(ctx, ch)
+	alive := <-done
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	for _, sessionID := range alive {
+		err = o1.markSessionEnded(ctx, sessionID, store.EndReasonShutdown, "")
+		if err != nil {
+			return err
+		}
+	}
+	o1.flushSessions(ctx)
+
+	for _, sessionID := range alive {
+		err = o1.EndSession(ctx, sessionID, store.EndReasonShutdown)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sessionPruneInterval is the interval at which the entries of the ended sessions are pruned
+var sessionPruneInterval = time.Minute
+
+// flushSessions waits for the notifications queued for the registered sessions to be sent,
+// until the context is done
+func (o1 *o1Controller) flushSessions(ctx context.Context) {
+	var sessions []NotificationSession
+	o1.mu.RLock()
+	for _, session := range o1.sessions {
+		if notifiable, ok := session.(NotificationSession); ok {
+			sessions = append(sessions, notifiable)
+		}
+	}
+	o1.mu.RUnlock()
+
+	for _, session := range sessions {
+		err := session.Flush(ctx)
+		if err != nil {
+			log.Warnf("Notifications of the sessions not sent before the shutdown: %v", err)
+			return
+		}
+	}
+}
+
+// startSessionPruning prunes the entries of the ended sessions once their retention elapsed
+func (o1 *o1Controller) startSessionPruning(ctx context.Context) {
+	o1.wg.Add(1)
+	go func() {
+		defer o1.wg.Done()
+		ticker := time.NewTicker(sessionPruneInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				o1.pruneSessions(ctx, now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// pruneSessions deletes from the store the entries of the sessions that ended longer than the
+// session retention before now
+func (o1 *o1Controller) pruneSessions(ctx context.Context, now time.Time) {
+	ch := make(chan *store.Entry)
+	done := make(chan []string)
+
+	endedBefore := uint64(now.Add(-o1.currentSettings().SessionRetention).UnixNano())
+	go func() {
+		expired := []string{}
+		for entry := range ch {
+			value, ok := entry.Value.(*store.SessionValue)
+			if ok && !value.Alive && value.EndTime <= endedBefore {
+				expired = append(expired, entry.Key.SessionID)
+			}
+		}
+		done <- expired
+	}()
+
+	err := o1.Store.Entries(ctx, ch)
+	expired := <-done
+	if err != nil {
+		return
+	}
+
+	for _, sessionID := range expired {
+		err = o1.DeleteStoreOperation(ctx, sessionID)
+		if err != nil {
+			log.Warnf("Unable to delete the entry of session %s: %v", sessionID, err)
+		}
+	}
+}
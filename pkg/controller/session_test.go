@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// fakeSession is the transport of a session, recording whether it was closed
+type fakeSession struct {
+	closed chan struct{}
+}
+
+func newFakeSession() *fakeSession {
+	return &fakeSession{closed: make(chan struct{})}
+}
+
+func (s *fakeSession) Close() error {
+	close(s.closed)
+	return nil
+}
+
+// waitEnded waits for the entry of the session to be marked not alive in the store
+func waitEnded(t *testing.T, sessionStore store.Store, sessionID string) *store.SessionValue {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entry, err := sessionStore.Get(context.Background(), store.Key{SessionID: sessionID})
+		if err != nil {
+			t.Fatalf("entry of session %s deleted: %v", sessionID, err)
+		}
+		if value := entry.Value.(*store.SessionValue); !value.Alive {
+			return value
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("entry of session %s not ended", sessionID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEndedSessionsRetained(t *testing.T) {
+	sessionStore := store.NewStore()
+	o1 := NewO1Controller(sessionStore, rnib.NewFakeClient(), nil).(*o1Controller)
+	defer o1.Close()
+
+	ctx := context.Background()
+	for _, sessionID := range []string{"1", "restconf-1"} {
+		session := newFakeSession()
+		err := o1.OpenSession(ctx, SessionInfo{SessionID: sessionID, User: "alice", Transport: "ssh"}, session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = o1.UpdateStoreOperation(ctx, sessionID, "get-config", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = o1.EndSession(ctx, sessionID, store.EndReasonClosed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-session.closed
+
+		value := waitEnded(t, sessionStore, sessionID)
+		if value.EndReason != store.EndReasonClosed {
+			t.Errorf("session %s end reason %s, expected %s", sessionID, value.EndReason, store.EndReasonClosed)
+		}
+		if len(value.Operations) != 1 {
+			t.Errorf("session %s has %d operations, expected 1", sessionID, len(value.Operations))
+		}
+
+		// the entry is kept until the retention elapsed
+		endTime := time.Unix(0, int64(value.EndTime))
+		o1.pruneSessions(ctx, endTime.Add(time.Minute))
+		if _, err := sessionStore.Get(ctx, store.Key{SessionID: sessionID}); err != nil {
+			t.Errorf("entry of session %s pruned before the retention elapsed", sessionID)
+		}
+		o1.pruneSessions(ctx, endTime.Add(DefaultSettings().SessionRetention))
+		if _, err := sessionStore.Get(ctx, store.Key{SessionID: sessionID}); err == nil {
+			t.Errorf("entry of session %s not pruned after the retention elapsed", sessionID)
+		}
+
+		// an operation of the pruned session does not store it again
+		err = o1.UpdateStoreOperation(ctx, sessionID, "get-config", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sessionStore.Get(ctx, store.Key{SessionID: sessionID}); err == nil {
+			t.Errorf("entry of pruned session %s stored again", sessionID)
+		}
+	}
+}
+
+func TestKilledSessionRetained(t *testing.T) {
+	sessionStore := store.NewStore()
+	o1 := NewO1Controller(sessionStore, rnib.NewFakeClient(), nil).(*o1Controller)
+	defer o1.Close()
+
+	ctx := context.Background()
+	killer, killed := newFakeSession(), newFakeSession()
+	for sessionID, session := range map[string]*fakeSession{"1": killer, "2": killed} {
+		err := o1.OpenSession(ctx, SessionInfo{SessionID: sessionID, User: "alice", Transport: "ssh"}, session)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := o1.KillSession(ctx, "1", []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.1" message-id="101"><kill-session><session-id>2</session-id></kill-session></rpc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-killed.closed
+
+	value := waitEnded(t, sessionStore, "2")
+	if value.EndReason != store.EndReasonKilled || value.KilledBy != "1" {
+		t.Errorf("session 2 ended %s by %q, expected killed by 1", value.EndReason, value.KilledBy)
+	}
+}
+
+func TestTerminateSessions(t *testing.T) {
+	sessionStore := store.NewStore()
+	o1 := NewO1Controller(sessionStore, rnib.NewFakeClient(), nil).(*o1Controller)
+	defer o1.Close()
+
+	ctx := context.Background()
+	subscriber, other := newNotifyingSession(), newFakeSession()
+	err := o1.OpenSession(ctx, SessionInfo{SessionID: "1", User: "alice", Transport: "ssh"}, subscriber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := o1.CreateSubscription(ctx, "1", []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.1" message-id="101">`+
+		`<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"/></rpc>`))
+	if err != nil || !strings.Contains(string(reply), "<ok/>") {
+		t.Fatalf("subscription not created: %s %v", reply, err)
+	}
+	err = o1.OpenSession(ctx, SessionInfo{SessionID: "2", User: "bob", Transport: "ssh"}, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notification := subscriber.next(t); !strings.Contains(notification, "<netconf-session-start") || !strings.Contains(notification, "<session-id>2</session-id>") {
+		t.Errorf("unexpected notification %s", notification)
+	}
+
+	// the sessions are closed once the netconf-session-end queued for the subscriber is
+	// flushed, without waiting for the deadline
+	terminateCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	err = o1.TerminateSessions(terminateCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sessions terminated in %s", elapsed)
+	}
+	<-subscriber.closed
+	<-other.closed
+	select {
+	case <-subscriber.flushed:
+	default:
+		t.Error("notifications of session 1 not flushed before it was closed")
+	}
+
+	ended := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		notification := subscriber.next(t)
+		if !strings.Contains(notification, "<netconf-session-end") || !strings.Contains(notification, "<termination-reason>other</termination-reason>") {
+			t.Errorf("unexpected notification %s", notification)
+		}
+		for _, sessionID := range []string{"1", "2"} {
+			if strings.Contains(notification, "<session-id>"+sessionID+"</session-id>") {
+				ended[sessionID] = true
+			}
+		}
+	}
+	if !ended["1"] || !ended["2"] {
+		t.Errorf("netconf-session-end published for sessions %v, want 1 and 2", ended)
+	}
+	for _, sessionID := range []string{"1", "2"} {
+		if value := waitEnded(t, sessionStore, sessionID); value.EndReason != store.EndReasonShutdown {
+			t.Errorf("session %s end reason %s, expected %s", sessionID, value.EndReason, store.EndReasonShutdown)
+		}
+	}
+}
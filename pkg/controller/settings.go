@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Features enables the optional NETCONF operations of the controller
+type Features struct {
+	KillSession bool
+	Monitoring  bool
+}
+
+// Settings are the settings of the controller that can be changed while it runs
+type Settings struct {
+	// NamespacePrefix prefixes the names of the configurables in the capabilities
+	NamespacePrefix string
+	// GnmiTimeout bounds the gNMI requests sent to the southbound
+	GnmiTimeout time.Duration
+	// SessionRetention is the time the entries of the ended sessions are kept in the store
+	SessionRetention time.Duration
+	Features         Features
+	// NamespaceMappings map the namespaces of YANG modules to targets and models, before the
+	// mappings of the onos-topo entities
+	NamespaceMappings []NamespaceMapping
+}
+
+// DefaultSettings returns the settings used by a controller created without WithSettings
+func DefaultSettings() Settings {
+	return Settings{
+		NamespacePrefix:  ONF_CAPABILITY_PREFIX,
+		GnmiTimeout:      3 * time.Second,
+		SessionRetention: 10 * time.Minute,
+		Features: Features{
+			KillSession: true,
+			Monitoring:  true,
+		},
+	}
+}
+
+// WithSettings sets the initial settings of the controller
+func WithSettings(settings Settings) Option {
+	return func(o1 *o1Controller) {
+		o1.settings = settings
+	}
+}
+
+// Configure replaces the settings of the controller, they apply to the next rpcs
+func (o1 *o1Controller) Configure(settings Settings) {
+	o1.mu.Lock()
+	defer o1.mu.Unlock()
+
+	o1.settings = settings
+}
+
+func (o1 *o1Controller) currentSettings() Settings {
+	o1.mu.RLock()
+	defer o1.mu.RUnlock()
+
+	return o1.settings
+}
+
+// gnmiContext bounds the context of a gNMI request with the gNMI timeout
+func (o1 *o1Controller) gnmiContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, o1.currentSettings().GnmiTimeout)
+}
+
+// enabled checks the feature providing the operation is enabled
+func (f Features) enabled(operation string) bool {
+	switch operation {
+	case "kill-session":
+		return f.KillSession
+	case "get", "get-schema":
+		return f.Monitoring
+	}
+	return true
+}
+
+func featureDisabledError(operation string) RPCError {
+	return RPCError{
+		Type:     ErrorTypeProtocol,
+		Tag:      ErrorTagOperationNotSupported,
+		Severity: ErrorSeverityError,
+		Message:  fmt.Sprintf("operation %s is disabled", operation),
+	}
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sync/atomic"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// base capabilities, one of which is announced by the hello of a client
+const (
+	baseCapability10 = "urn:ietf:params:netconf:base:1.0"
+	baseCapability11 = "urn:ietf:params:netconf:base:1.1"
+)
+
+// counters are the rpc and notification counters of ietf-netconf-monitoring, of a session or
+// of the server, updated atomically
+type counters struct {
+	inRPCs           uint32
+	inBadRPCs        uint32
+	outRPCErrors     uint32
+	outNotifications uint32
+}
+
+// rpc counts a rpc received, bad if it is malformed and failed if its reply has an rpc-error
+func (c *counters) rpc(bad, failed bool) {
+	atomic.AddUint32(&c.inRPCs, 1)
+	if bad {
+		atomic.AddUint32(&c.inBadRPCs, 1)
+	}
+	if failed {
+		atomic.AddUint32(&c.outRPCErrors, 1)
+	}
+}
+
+func (c *counters) notified() {
+	atomic.AddUint32(&c.outNotifications, 1)
+}
+
+// load returns a copy of the counters
+func (c *counters) load() counters {
+	return counters{
+		inRPCs:           atomic.LoadUint32(&c.inRPCs),
+		inBadRPCs:        atomic.LoadUint32(&c.inBadRPCs),
+		outRPCErrors:     atomic.LoadUint32(&c.outRPCErrors),
+		outNotifications: atomic.LoadUint32(&c.outNotifications),
+	}
+}
+
+// statistics are the global statistics of ietf-netconf-monitoring
+type statistics struct {
+	startTime       time.Time
+	inBadHellos     uint32
+	inSessions      uint32
+	droppedSessions uint32
+	counters
+}
+
+// countedSession counts the notifications sent to a session
+type countedSession struct {
+	NotificationSession
+	counters *counters
+	stats    *statistics
+}
+
+func (s *countedSession) Notify(notification []byte) error {
+	err := s.NotificationSession.Notify(notification)
+	if err == nil {
+		s.counters.notified()
+		s.stats.notified()
+	}
+	return err
+}
+
+// countSession registers the counters of a new session, returning the session to register in
+// place of a session that can send notifications
+func (o1 *o1Controller) countSession(sessionID string, session Session) Session {
+	atomic.AddUint32(&o1.stats.inSessions, 1)
+
+	c := &counters{}
+	o1.mu.Lock()
+	o1.sessionCounters[sessionID] = c
+	o1.mu.Unlock()
+
+	if notifiable, ok := session.(NotificationSession); ok {
+		return &countedSession{NotificationSession: notifiable, counters: c, stats: &o1.stats}
+	}
+	return session
+}
+
+// uncountSession removes the counters of an ended session, counting it as dropped if it was
+// not ended by a rpc or a shutdown
+func (o1 *o1Controller) uncountSession(sessionID string, reason store.EndReason) {
+	o1.mu.Lock()
+	delete(o1.sessionCounters, sessionID)
+	o1.mu.Unlock()
+
+	if reason == store.EndReasonTransportError || reason == store.EndReasonIdleTimeout {
+		atomic.AddUint32(&o1.stats.droppedSessions, 1)
+	}
+}
+
+// countRPC counts a rpc of the session from the error-tag of its reply, a malformed rpc
+// having no reply
+func (o1 *o1Controller) countRPC(sessionID, errorTag string) {
+	bad := errorTag == ErrorTagMalformedMessage
+	failed := errorTag != "" && !bad
+	o1.stats.rpc(bad, failed)
+
+	o1.mu.RLock()
+	c, ok := o1.sessionCounters[sessionID]
+	o1.mu.RUnlock()
+	if ok {
+		c.rpc(bad, failed)
+	}
+}
+
+// countersOf returns a copy of the counters of the session
+func (o1 *o1Controller) countersOf(sessionID string) counters {
+	o1.mu.RLock()
+	c, ok := o1.sessionCounters[sessionID]
+	o1.mu.RUnlock()
+	if !ok {
+		return counters{}
+	}
+	return c.load()
+}
+
+// checkHello counts the hello of a client as bad if it announces no base capability
+func (o1 *o1Controller) checkHello(sessionID string, rawMessage []byte) {
+	hello := struct {
+		Capabilities []string `xml:"capabilities>capability"`
+	}{}
+	err := xml.NewDecoder(bytes.NewReader(rawMessage)).Decode(&hello)
+	if err == nil {
+		for _, capability := range hello.Capabilities {
+			switch capability {
+			case baseCapability10, baseCapability11:
+				return
+			}
+		}
+	}
+	log.Warnf("Session %s received a hello without base capability", sessionID)
+	atomic.AddUint32(&o1.stats.inBadHellos, 1)
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+)
+
+const (
+	// topoRetryInterval is the delay before watching onos-topo again after the watch ended,
+	// doubled after each failure up to topoMaxRetryInterval
+	topoRetryInterval    = time.Second
+	topoMaxRetryInterval = 30 * time.Second
+)
+
+// topoCache holds the o1t entities of onos-topo by id, synced once it is filled by a list of
+// the entities and while the watch of their changes runs
+type topoCache struct {
+	mu       sync.RWMutex
+	synced   bool
+	entities map[string]rnib.O1tEntity
+}
+
+func newTopoCache() *topoCache {
+	return &topoCache{entities: make(map[string]rnib.O1tEntity)}
+}
+
+// reset replaces the entities of the cache, which is synced
+func (c *topoCache) reset(entities []rnib.O1tEntity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entities = make(map[string]rnib.O1tEntity, len(entities))
+	for _, entity := range entities {
+		c.entities[entity.ID] = entity
+	}
+	c.synced = true
+}
+
+// apply applies the change of an entity, returning false if it changed nothing
+func (c *topoCache) apply(event rnib.O1tEntityEvent) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, ok := c.entities[event.Entity.ID]
+	if event.Type == rnib.EntityRemoved {
+		delete(c.entities, event.Entity.ID)
+		return ok
+	}
+	c.entities[event.Entity.ID] = event.Entity
+	return !ok || !sameEntity(current, event.Entity)
+}
+
+// unsync marks the cache as missing the changes of the entities
+func (c *topoCache) unsync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.synced = false
+}
+
+// snapshot returns the entities of the cache sorted by id, and whether the cache is synced
+func (c *topoCache) snapshot() ([]rnib.O1tEntity, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entities := make([]rnib.O1tEntity, 0, len(c.entities))
+	for _, entity := range c.entities {
+		entities = append(entities, entity)
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].ID < entities[j].ID
+	})
+	return entities, c.synced
+}
+
+func sameEntity(a, b rnib.O1tEntity) bool {
+	if a.Configurable != b.Configurable || len(a.Mappings) != len(b.Mappings) {
+		return false
+	}
+	for i := range a.Mappings {
+		if a.Mappings[i] != b.Mappings[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// watchTopo keeps the topo cache synced with the o1t entities of onos-topo, watching them
+// again after the watch ended, and updates the capabilities on each change
+func (o1 *o1Controller) watchTopo(ctx context.Context) {
+	retry := topoRetryInterval
+	for {
+		if o1.syncTopo(ctx) {
+			retry = topoRetryInterval
+		}
+		o1.topo.unsync()
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Infof("Watching the o1t entities of onos-topo again in %s", retry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry *= 2
+		if retry > topoMaxRetryInterval {
+			retry = topoMaxRetryInterval
+		}
+	}
+}
+
+// syncTopo fills the topo cache with the o1t entities, then applies their changes until the
+// watch ends, returning false if the cache could not be filled. The watch is started first so
+// that no change is missed, the entities it replays being already in the cache.
+func (o1 *o1Controller) syncTopo(ctx context.Context) bool {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan rnib.O1tEntityEvent)
+	err := o1.rnibClient.Watch(watchCtx, ch)
+	if err != nil {
+		log.Warnf("Watch of the o1t entities of onos-topo failed: %v", err)
+		return false
+	}
+
+	entities, err := o1.rnibClient.GetO1tEntities(ctx)
+	if err != nil {
+		log.Warnf("List of the o1t entities of onos-topo failed: %v", err)
+		cancel()
+		for range ch {
+		}
+		return false
+	}
+	o1.topo.reset(entities)
+	log.Infof("Synced %d o1t entities of onos-topo", len(entities))
+	o1.refreshCapabilities(ctx)
+
+	for event := range ch {
+		if o1.topo.apply(event) {
+			log.Infof("Entity %s of configurable %s %s", event.Entity.ID, event.Entity.Configurable, event.Type)
+			o1.refreshCapabilities(ctx)
+		}
+	}
+	log.Warn("Watch of the o1t entities of onos-topo ended")
+	return true
+}
+
+// refreshCapabilities updates the capabilities from the topo cache
+func (o1 *o1Controller) refreshCapabilities(ctx context.Context) {
+	_, err := o1.Capabilities(ctx)
+	if err != nil {
+		log.Warn(err)
+	}
+}
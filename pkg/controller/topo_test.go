@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// waitCapabilities waits for the capabilities of the configurables of the controller to be
+// the given ones
+func waitCapabilities(t *testing.T, o1 *o1Controller, want []string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := []string{}
+		for _, capability := range o1.currentCapabilities() {
+			if strings.HasPrefix(capability, DefaultSettings().NamespacePrefix) {
+				got = append(got, capability)
+			}
+		}
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("capabilities %v, want %v", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTopoCacheFollowsEntities(t *testing.T) {
+	topo := rnib.NewFakeClient(rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:1.0.0"})
+	o1 := NewO1Controller(store.NewStore(), topo, nil).(*o1Controller)
+	defer o1.Close()
+
+	kpimon := "http://opennetworking.org/kpimon:ric:1.0.0"
+	waitCapabilities(t, o1, []string{kpimon})
+
+	topo.SetEntity(rnib.O1tEntity{ID: "mho", Configurable: "mho:ric:1.0.0"})
+	mho := "http://opennetworking.org/mho:ric:1.0.0"
+	waitCapabilities(t, o1, []string{kpimon, mho})
+
+	topo.SetEntity(rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:2.0.0"})
+	kpimon2 := "http://opennetworking.org/kpimon:ric:2.0.0"
+	waitCapabilities(t, o1, []string{kpimon2, mho})
+
+	topo.RemoveEntity("mho")
+	waitCapabilities(t, o1, []string{kpimon2})
+
+	entities, synced := o1.topo.snapshot()
+	if !synced {
+		t.Error("topo cache not synced")
+	}
+	if len(entities) != 1 || entities[0].ID != "kpimon" || entities[0].Configurable != "kpimon:ric:2.0.0" {
+		t.Errorf("unexpected entities %v in the topo cache", entities)
+	}
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/onosproject/onos-o1t/pkg/southbound"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
+)
+
+// Validate validates the config of the source of a validate rpc without applying it. An
+// inline config is translated, validated against the YANG modules of its model and checked by
+// onos-config as the config of a test-only edit-config. The running datastore is valid, its
+// changes being validated by onos-config.
+func (o1 *o1Controller) Validate(ctx context.Context, sessionID string, requestXML []byte) ([]byte, error) {
+	request := new(Validate)
+	err := xml.Unmarshal(requestXML, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Source == nil {
+		return buildErrorReply(request.MessageID, RPCError{
+			Type:     ErrorTypeProtocol,
+			Tag:      ErrorTagMissingElement,
+			Severity: ErrorSeverityError,
+			Message:  "validate has no source",
+			Info:     "<error-info><bad-element>source</bad-element></error-info>",
+		})
+	}
+
+	if request.Source.Config == nil {
+		datastore := request.Source.Name()
+		if datastore != "running" {
+			return buildErrorReply(request.MessageID, RPCError{
+				Type:     ErrorTypeProtocol,
+				Tag:      ErrorTagInvalidValue,
+				Severity: ErrorSeverityError,
+				Message:  fmt.Sprintf("datastore %q not supported", datastore),
+			})
+		}
+		err = o1.UpdateStoreOperation(ctx, sessionID, store.OperationValidate, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		return buildOkReply(request.MessageID)
+	}
+
+	_, span := tracing.Start(ctx, "controller.ParseValidate")
+	parts, err := parseConfig(request.Source.Config.Config, "", true, o1.sessionCapabilities(sessionID), o1.currentMappings(), func(namespace Namespace) *schema.Schema {
+		return o1.modelSchema(ctx, namespace)
+	})
+	tracing.End(span, err)
+	if err != nil {
+		return buildErrorReply(request.MessageID, gnmiRPCError(err))
+	}
+
+	edit := &EditRequest{Parts: parts}
+	ns := strings.Join(edit.Namespaces(), ",")
+	record := auditRecordFrom(ctx)
+	auditSet(record, ns, edit.SetRequest())
+	tracing.SetAttributes(ctx, tracing.NamespaceKey.String(ns), tracing.TargetKey.String(strings.Join(record.Targets, ",")))
+
+	results := o1.testEdit(ctx, edit)
+	err = o1.UpdateStoreEdit(ctx, sessionID, store.OperationValidate, edit, results)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcErrors := editErrors(edit, results)
+	if len(rpcErrors) > 0 {
+		return buildErrorReply(request.MessageID, rpcErrors...)
+	}
+	return buildOkReply(request.MessageID)
+}
+
+// dryRun checks onos-config would accept the config of the part, without applying it. The
+// config is validated by the model plugin of its model, the part failing if the plugin can not
+// be reached.
+func (o1 *o1Controller) dryRun(ctx context.Context, part EditPart) error {
+	validator, ok := o1.gnmiClient.(southbound.ConfigValidator)
+	if !ok || part.Config == nil {
+		return nil
+	}
+	namespace := part.Namespace
+
+	gnmiCtx, cancel := o1.gnmiContext(ctx)
+	defer cancel()
+
+	valid, message, err := validator.ValidateConfig(gnmiCtx, namespace.Name, namespace.Version, part.Config)
+	if err != nil {
+		log.Warnf("Model plugin of %s %s can not validate the config: %v", namespace.Name, namespace.Version, err)
+		message = fmt.Sprintf("the model plugin of %s %s can not validate the config: %v", namespace.Name, namespace.Version, err)
+	}
+	if err != nil || !valid {
+		return RPCError{
+			Type:     ErrorTypeApplication,
+			Tag:      ErrorTagOperationFailed,
+			Severity: ErrorSeverityError,
+			Message:  message,
+		}
+	}
+	return nil
+}
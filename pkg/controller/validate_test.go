@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/southbound"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// validatingClient is a southbound whose model plugin validates the configs
+type validatingClient struct {
+	southbound.GnmiClient
+	valid   bool
+	message string
+	err     error
+}
+
+func (c *validatingClient) ValidateConfig(ctx context.Context, name, version string, config []byte) (bool, string, error) {
+	return c.valid, c.message, c.err
+}
+
+func TestDryRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *validatingClient
+		message string
+	}{
+		{name: "valid", client: &validatingClient{valid: true}},
+		{name: "invalid", client: &validatingClient{message: "interval out of range"}, message: "interval out of range"},
+		{name: "plugin unreachable", client: &validatingClient{err: errors.New("connection refused")}, message: "connection refused"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o1 := NewO1Controller(store.NewStore(), rnib.NewFakeClient(), test.client).(*o1Controller)
+			defer o1.Close()
+
+			part := EditPart{
+				Namespace: Namespace{Target: "kpimon", Name: "ric", Version: "1.0.0"},
+				Config:    []byte(`{"interval":7}`),
+			}
+			err := o1.dryRun(context.Background(), part)
+			if test.message == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			rpcErr, ok := err.(RPCError)
+			if !ok {
+				t.Fatalf("error %v is not an rpc-error", err)
+			}
+			if rpcErr.Tag != ErrorTagOperationFailed || !strings.Contains(rpcErr.Message, test.message) {
+				t.Errorf("rpc-error %s %q, want %s with %q", rpcErr.Tag, rpcErr.Message, ErrorTagOperationFailed, test.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/onosproject/onos-o1t/pkg/schema"
+)
+
+const (
+	YangLibraryNamespace = "urn:ietf:params:xml:ns:yang:ietf-yang-library"
+	// yangLibraryCapability announces the ietf-yang-library of RFC 8526, followed by the
+	// content-id of the library
+	yangLibraryCapability = "urn:ietf:params:netconf:capability:yang-library:1.1?revision=2019-01-04&content-id="
+
+	// serverModuleSet is the module-set of the modules implemented by onos-o1t itself, the
+	// module-sets of the targets being named by their target:name:version
+	serverModuleSet = "onos-o1t"
+	// librarySchema is the schema of the running datastore, made of all the module-sets
+	librarySchema = "running"
+)
+
+// serverModules are the modules implemented by onos-o1t itself
+var serverModules = []LibraryModule{
+	{Name: "ietf-netconf-monitoring", Revision: "2010-10-04", Namespace: NetconfMonitoringNamespace},
+	{Name: "ietf-netconf-notifications", Revision: "2012-02-06", Namespace: NetconfNotificationsNamespace},
+	{Name: "ietf-subscribed-notifications", Revision: "2019-09-09", Namespace: SubscribedNotificationsNamespace},
+	{Name: "ietf-yang-push", Revision: "2019-09-09", Namespace: YangPushNamespace, Features: []string{"on-change"}},
+	{Name: "ietf-yang-library", Revision: "2019-01-04", Namespace: YangLibraryNamespace},
+}
+
+type YangLibrary struct {
+	XMLName    xml.Name           `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-library yang-library"`
+	ModuleSets []ModuleSet        `xml:"module-set"`
+	Schemas    []LibrarySchema    `xml:"schema"`
+	Datastores []LibraryDatastore `xml:"datastore"`
+	ContentID  string             `xml:"content-id,omitempty"`
+}
+
+type ModuleSet struct {
+	Name              string             `xml:"name"`
+	Modules           []LibraryModule    `xml:"module"`
+	ImportOnlyModules []ImportOnlyModule `xml:"import-only-module"`
+}
+
+type LibraryModule struct {
+	Name       string             `xml:"name"`
+	Revision   string             `xml:"revision,omitempty"`
+	Namespace  string             `xml:"namespace"`
+	Submodules []LibrarySubmodule `xml:"submodule"`
+	Features   []string           `xml:"feature"`
+	Deviations []string           `xml:"deviation"`
+}
+
+// ImportOnlyModule is a module of a module-set whose definitions are only imported, its
+// revision being empty if it has none
+type ImportOnlyModule struct {
+	Name       string             `xml:"name"`
+	Revision   string             `xml:"revision"`
+	Namespace  string             `xml:"namespace"`
+	Submodules []LibrarySubmodule `xml:"submodule"`
+}
+
+type LibrarySubmodule struct {
+	Name     string `xml:"name"`
+	Revision string `xml:"revision,omitempty"`
+}
+
+type LibrarySchema struct {
+	Name       string   `xml:"name"`
+	ModuleSets []string `xml:"module-set"`
+}
+
+type LibraryDatastore struct {
+	Name   DatastoreIdentity `xml:"name"`
+	Schema string            `xml:"schema"`
+}
+
+// DatastoreIdentity is a datastore identity of ietf-datastores
+type DatastoreIdentity struct {
+	Prefix string `xml:"xmlns:ds,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// yangLibrary returns the yang-library of the modules of onos-o1t and of the models of the
+// targets of the namespaces, a target whose model has no known schema having no module-set.
+// The content-id is a digest of the module-sets.
+func (o1 *o1Controller) yangLibrary(ctx context.Context, namespaces []Namespace) *YangLibrary {
+	library := &YangLibrary{
+		ModuleSets: []ModuleSet{{Name: serverModuleSet, Modules: serverModules}},
+	}
+
+	if o1.schemas != nil {
+		infos := make(map[string]schema.ModuleInfo)
+		for _, info := range o1.schemas.ModuleInfos() {
+			infos[info.Name] = info
+		}
+		for _, namespace := range namespaces {
+			s, ok := o1.schemas.Schema(ctx, namespace.Name, namespace.Version)
+			if !ok {
+				continue
+			}
+			set := moduleSet(fmt.Sprintf("%s:%s:%s", namespace.Target, namespace.Name, namespace.Version), s.Modules(), infos)
+			library.ModuleSets = append(library.ModuleSets, set)
+		}
+	}
+
+	running := LibrarySchema{Name: librarySchema}
+	for _, set := range library.ModuleSets {
+		running.ModuleSets = append(running.ModuleSets, set.Name)
+	}
+	library.Schemas = []LibrarySchema{running}
+	library.Datastores = []LibraryDatastore{{
+		Name:   DatastoreIdentity{Prefix: DatastoresNamespace, Value: "ds:running"},
+		Schema: librarySchema,
+	}}
+
+	content, err := xml.Marshal(library)
+	if err != nil {
+		log.Warn(err)
+	}
+	digest := sha256.Sum256(content)
+	library.ContentID = hex.EncodeToString(digest[:8])
+	return library
+}
+
+// moduleSet returns the module-set of the modules of a model, with the modules they import
+// as import-only modules
+func moduleSet(name string, modules []string, infos map[string]schema.ModuleInfo) ModuleSet {
+	set := ModuleSet{Name: name}
+	submodules := func(info schema.ModuleInfo) []LibrarySubmodule {
+		var libSubmodules []LibrarySubmodule
+		for _, submodule := range info.Submodules {
+			libSubmodules = append(libSubmodules, LibrarySubmodule{Name: submodule, Revision: infos[submodule].Revision})
+		}
+		return libSubmodules
+	}
+
+	implemented := make(map[string]bool, len(modules))
+	var imports []string
+	for _, module := range modules {
+		info, ok := infos[module]
+		if !ok {
+			continue
+		}
+		implemented[module] = true
+		set.Modules = append(set.Modules, LibraryModule{
+			Name:       info.Name,
+			Revision:   info.Revision,
+			Namespace:  info.Namespace,
+			Submodules: submodules(info),
+			Features:   info.Features,
+			Deviations: info.Deviations,
+		})
+		imports = append(imports, info.Imports...)
+	}
+
+	imported := make(map[string]bool)
+	for len(imports) > 0 {
+		module := imports[0]
+		imports = imports[1:]
+		info, ok := infos[module]
+		if !ok || implemented[module] || imported[module] {
+			continue
+		}
+		imported[module] = true
+		set.ImportOnlyModules = append(set.ImportOnlyModules, ImportOnlyModule{
+			Name:       info.Name,
+			Revision:   info.Revision,
+			Namespace:  info.Namespace,
+			Submodules: submodules(info),
+		})
+		imports = append(imports, info.Imports...)
+	}
+	// the modules are sorted for the content-id not to depend on the order they were loaded in
+	sort.Slice(set.Modules, func(i, j int) bool {
+		return set.Modules[i].Name < set.Modules[j].Name
+	})
+	sort.Slice(set.ImportOnlyModules, func(i, j int) bool {
+		return set.ImportOnlyModules[i].Name < set.ImportOnlyModules[j].Name
+	})
+	return set
+}
+
+// libraryCapabilities returns the module capabilities of RFC 6020 of the modules of the
+// module-sets of the targets, followed by the yang-library capability with its content-id
+func libraryCapabilities(library *YangLibrary) []string {
+	var capabilities []string
+	seen := make(map[string]bool)
+	add := func(capability string) {
+		if !seen[capability] {
+			seen[capability] = true
+			capabilities = append(capabilities, capability)
+		}
+	}
+	for _, set := range library.ModuleSets {
+		if set.Name == serverModuleSet {
+			continue
+		}
+		for _, module := range set.Modules {
+			add(moduleCapability(module.Namespace, module.Name, module.Revision, module.Features, module.Deviations))
+		}
+		for _, module := range set.ImportOnlyModules {
+			add(moduleCapability(module.Namespace, module.Name, module.Revision, nil, nil))
+		}
+	}
+	return append(capabilities, yangLibraryCapability+library.ContentID)
+}
+
+// moduleCapability returns the capability of a module, e.g.
+// urn:example?module=example&revision=2022-01-01&features=a,b&deviations=example-dev
+func moduleCapability(namespace, name, revision string, features, deviations []string) string {
+	capability := namespace + "?module=" + name
+	if revision != "" {
+		capability += "&revision=" + revision
+	}
+	if len(features) > 0 {
+		capability += "&features=" + strings.Join(features, ",")
+	}
+	if len(deviations) > 0 {
+		capability += "&deviations=" + strings.Join(deviations, ",")
+	}
+	return capability
+}
+
+// currentLibrary returns the yang-library of the last hello or capabilities request, with the
+// children selected by a filter, all of them if nil
+func (o1 *o1Controller) currentLibrary(children map[string]bool) *YangLibrary {
+	library := YangLibrary{}
+	o1.mu.RLock()
+	if o1.library != nil {
+		library = *o1.library
+	}
+	o1.mu.RUnlock()
+
+	if children == nil {
+		return &library
+	}
+	if !children["module-set"] {
+		library.ModuleSets = nil
+	}
+	if !children["schema"] {
+		library.Schemas = nil
+	}
+	if !children["datastore"] {
+		library.Datastores = nil
+	}
+	if !children["content-id"] {
+		library.ContentID = ""
+	}
+	return &library
+}
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+// ricModules are the modules of the ric:1.0.0 model by file, the imported module being in
+// a directory of its own
+var ricModules = map[string]string{
+	"ric-1.0.0/ric.yang": `module ric {
+  namespace "urn:onf:ric";
+  prefix ric;
+  import ric-types { prefix rt; }
+  include ric-cells;
+  revision 2022-01-01;
+  feature handover;
+  container ric {
+    leaf report_period { type rt:period; }
+    leaf-list tags { type string; }
+    list cell {
+      key "plmn cid";
+      leaf cid { type uint32; }
+      leaf plmn { type string; }
+      leaf pci { type uint32; }
+    }
+  }
+}
+`,
+	"ric-1.0.0/ric-cells.yang": `submodule ric-cells {
+  belongs-to ric { prefix ric; }
+  revision 2022-02-01;
+}
+`,
+	"ric-1.0.0/ric-dev.yang": `module ric-dev {
+  namespace "urn:onf:ric-dev";
+  prefix rd;
+  import ric { prefix ric; }
+  revision 2022-03-01;
+  deviation /ric:ric/ric:tags { deviate not-supported; }
+}
+`,
+	"common/ric-types.yang": `module ric-types {
+  namespace "urn:onf:ric-types";
+  prefix rt;
+  revision 2022-01-01;
+  typedef period { type uint32; }
+}
+`,
+}
+
+// ricRegistry loads the modules of the ric model
+func ricRegistry(t *testing.T) *schema.Registry {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range ricModules {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(source), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry, err := schema.NewRegistry([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+// ricLibrary returns the yang-library and the capabilities of a controller of targets of the
+// ric model
+func ricLibrary(t *testing.T, registry *schema.Registry, entities ...rnib.O1tEntity) (*YangLibrary, []string) {
+	t.Helper()
+	o1 := NewO1Controller(store.NewStore(), rnib.NewFakeClient(entities...), nil, WithSchemas(registry)).(*o1Controller)
+	defer o1.Close()
+	capabilities, err := o1.Capabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o1.currentLibrary(nil), capabilities
+}
+
+func TestModuleCapability(t *testing.T) {
+	tests := []struct {
+		name       string
+		revision   string
+		features   []string
+		deviations []string
+		want       string
+	}{
+		{name: "no revision", want: "urn:onf:ric?module=ric"},
+		{name: "revision", revision: "2022-01-01", want: "urn:onf:ric?module=ric&revision=2022-01-01"},
+		{name: "features", revision: "2022-01-01", features: []string{"a", "b"}, want: "urn:onf:ric?module=ric&revision=2022-01-01&features=a,b"},
+		{name: "deviations", features: []string{"a"}, deviations: []string{"ric-dev"}, want: "urn:onf:ric?module=ric&features=a&deviations=ric-dev"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := moduleCapability("urn:onf:ric", "ric", test.revision, test.features, test.deviations); got != test.want {
+				t.Errorf("capability %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestYangLibrary(t *testing.T) {
+	kpimon := rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:1.0.0"}
+	library, capabilities := ricLibrary(t, ricRegistry(t), kpimon)
+
+	if len(library.ModuleSets) != 2 || library.ModuleSets[0].Name != serverModuleSet {
+		t.Fatalf("module-sets %+v", library.ModuleSets)
+	}
+	want := ModuleSet{
+		Name: "kpimon:ric:1.0.0",
+		Modules: []LibraryModule{
+			{
+				Name:       "ric",
+				Revision:   "2022-01-01",
+				Namespace:  "urn:onf:ric",
+				Submodules: []LibrarySubmodule{{Name: "ric-cells", Revision: "2022-02-01"}},
+				Features:   []string{"handover"},
+				Deviations: []string{"ric-dev"},
+			},
+			{Name: "ric-dev", Revision: "2022-03-01", Namespace: "urn:onf:ric-dev"},
+		},
+		ImportOnlyModules: []ImportOnlyModule{{Name: "ric-types", Revision: "2022-01-01", Namespace: "urn:onf:ric-types"}},
+	}
+	if !reflect.DeepEqual(library.ModuleSets[1], want) {
+		t.Errorf("module-set %+v, want %+v", library.ModuleSets[1], want)
+	}
+	if len(library.Schemas) != 1 || !reflect.DeepEqual(library.Schemas[0].ModuleSets, []string{serverModuleSet, "kpimon:ric:1.0.0"}) {
+		t.Errorf("schemas %+v", library.Schemas)
+	}
+
+	// the modules of the targets are advertised, the server modules by their own capabilities
+	advertised := make(map[string]bool)
+	for _, capability := range capabilities {
+		advertised[capability] = true
+	}
+	for _, capability := range []string{
+		"urn:onf:ric?module=ric&revision=2022-01-01&features=handover&deviations=ric-dev",
+		"urn:onf:ric-dev?module=ric-dev&revision=2022-03-01",
+		"urn:onf:ric-types?module=ric-types&revision=2022-01-01",
+		yangLibraryCapability + library.ContentID,
+	} {
+		if !advertised[capability] {
+			t.Errorf("capability %s not advertised", capability)
+		}
+	}
+	if advertised["urn:ietf:params:xml:ns:yang:ietf-yang-library?module=ietf-yang-library&revision=2019-01-04"] {
+		t.Error("module capability of the yang-library advertised")
+	}
+
+	// the content-id is that of the modules, whatever the order they were loaded in
+	for i := 0; i < 5; i++ {
+		if other, _ := ricLibrary(t, ricRegistry(t), kpimon); other.ContentID != library.ContentID {
+			t.Fatalf("content-id %s of the same modules, want %s", other.ContentID, library.ContentID)
+		}
+	}
+	other, _ := ricLibrary(t, ricRegistry(t), kpimon, rnib.O1tEntity{ID: "mho", Configurable: "mho:ric:1.0.0"})
+	if other.ContentID == library.ContentID {
+		t.Error("content-id unchanged by the module-set of another target")
+	}
+}
+
+func TestCurrentLibraryChildren(t *testing.T) {
+	o1 := NewO1Controller(store.NewStore(), rnib.NewFakeClient(), nil).(*o1Controller)
+	defer o1.Close()
+	_, err := o1.Capabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	library := o1.currentLibrary(map[string]bool{"content-id": true})
+	if library.ContentID == "" || library.ModuleSets != nil || library.Schemas != nil || library.Datastores != nil {
+		t.Errorf("library %+v, want its content-id only", library)
+	}
+	if full := o1.currentLibrary(nil); len(full.ModuleSets) != 1 || full.ContentID != library.ContentID {
+		t.Errorf("library %+v", full)
+	}
+}
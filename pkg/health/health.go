@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	healthgrpc "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var log = logging.GetLogger("health")
+
+// Components whose health is checked
+const (
+	ComponentConfig   = "onos-config"
+	ComponentTopo     = "onos-topo"
+	ComponentNetconf  = "netconf-ssh"
+	ComponentRESTCONF = "restconf"
+)
+
+// Policies gating the acceptance of new NETCONF sessions on readiness
+const (
+	// PolicyReady accepts new sessions only when all the components are up
+	PolicyReady = "ready"
+	// PolicySouthbound accepts new sessions only when onos-config is up
+	PolicySouthbound = "southbound"
+	// PolicyAlways accepts new sessions whatever the health of the components
+	PolicyAlways = "always"
+)
+
+const (
+	DefaultCheckInterval = 5 * time.Second
+	DefaultCheckTimeout  = 2 * time.Second
+
+	// grpcServicePrefix prefixes the component names in the gRPC health service, the
+	// empty service name reporting the readiness of onos-o1t
+	grpcServicePrefix = "onos.o1t."
+)
+
+// Checker checks the health of a component
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is a function checking the health of a component
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ComponentStatus is the last checked health of a component
+type ComponentStatus struct {
+	Name      string
+	Up        bool
+	Error     string
+	CheckedAt time.Time
+}
+
+// Monitor periodically checks the health of the components of onos-o1t
+type Monitor struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	mu       sync.RWMutex
+	checkers map[string]Checker
+	statuses map[string]ComponentStatus
+	// policy is the readiness policy, PolicyReady by default
+	policy string
+
+	grpcServer *healthgrpc.Server
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewMonitor creates a monitor checking the components at the given interval, all the
+// components being down until checked
+func NewMonitor(interval, timeout time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+
+	m := &Monitor{
+		interval:   interval,
+		timeout:    timeout,
+		checkers:   make(map[string]Checker),
+		statuses:   make(map[string]ComponentStatus),
+		policy:     PolicyReady,
+		grpcServer: healthgrpc.NewServer(),
+	}
+	m.grpcServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return m
+}
+
+// Register adds a component checked by the monitor
+func (m *Monitor) Register(name string, checker Checker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checkers[name] = checker
+	m.statuses[name] = ComponentStatus{
+		Name:  name,
+		Error: "not checked yet",
+	}
+	m.grpcServer.SetServingStatus(grpcServicePrefix+name, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// Start checks the components once, then periodically until the monitor is stopped
+func (m *Monitor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	m.checkAll(ctx)
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.checkAll(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops checking the components and reports onos-o1t as not serving
+func (m *Monitor) Stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	<-m.done
+	m.cancel = nil
+	m.grpcServer.Shutdown()
+}
+
+func (m *Monitor) checkAll(ctx context.Context) {
+	m.mu.RLock()
+	checkers := make(map[string]Checker, len(m.checkers))
+	for name, checker := range m.checkers {
+		checkers[name] = checker
+	}
+	m.mu.RUnlock()
+
+	for name, checker := range checkers {
+		m.check(ctx, name, checker)
+	}
+	m.updateReadiness()
+}
+
+func (m *Monitor) check(ctx context.Context, name string, checker Checker) {
+	checkCtx, cancel := context.WithTimeout(ctx, m.timeout)
+	err := checker.Check(checkCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+
+	status := ComponentStatus{
+		Name:      name,
+		Up:        err == nil,
+		CheckedAt: time.Now(),
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	m.mu.Lock()
+	previous := m.statuses[name]
+	m.statuses[name] = status
+	m.mu.Unlock()
+
+	if previous.Up != status.Up || previous.CheckedAt.IsZero() {
+		if status.Up {
+			log.Infof("Component %s is up", name)
+			m.grpcServer.SetServingStatus(grpcServicePrefix+name, healthpb.HealthCheckResponse_SERVING)
+		} else {
+			log.Warnf("Component %s is down: %s", name, status.Error)
+			m.grpcServer.SetServingStatus(grpcServicePrefix+name, healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+	}
+}
+
+func (m *Monitor) updateReadiness() {
+	if m.checkReady() == nil {
+		m.grpcServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	} else {
+		m.grpcServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// Statuses returns the last checked health of the components, sorted by name
+func (m *Monitor) Statuses() []ComponentStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]ComponentStatus, 0, len(m.statuses))
+	for _, status := range m.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Ready checks all the components are up
+func (m *Monitor) Ready() bool {
+	return len(m.Degraded()) == 0
+}
+
+// Degraded returns the components that are down
+func (m *Monitor) Degraded() []ComponentStatus {
+	degraded := []ComponentStatus{}
+	for _, status := range m.Statuses() {
+		if !status.Up {
+			degraded = append(degraded, status)
+		}
+	}
+	return degraded
+}
+
+// componentUp checks a component is up, a component that is not registered is considered up
+func (m *Monitor) componentUp(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status, ok := m.statuses[name]
+	return !ok || status.Up
+}
+
+// SetPolicy sets the policy the readiness of onos-o1t is checked against, gating the
+// acceptance of new NETCONF sessions
+func (m *Monitor) SetPolicy(policy string) error {
+	switch policy {
+	case PolicyReady, PolicySouthbound, PolicyAlways:
+	case "":
+		policy = PolicyReady
+	default:
+		return fmt.Errorf("invalid readiness policy %q, expected %s, %s or %s", policy, PolicyReady, PolicySouthbound, PolicyAlways)
+	}
+
+	m.mu.Lock()
+	m.policy = policy
+	m.mu.Unlock()
+	m.updateReadiness()
+	return nil
+}
+
+// Gate returns the function checking new NETCONF sessions can be accepted according to
+// the policy
+func (m *Monitor) Gate() func() error {
+	return m.checkReady
+}
+
+// checkReady checks onos-o1t is ready according to the policy
+func (m *Monitor) checkReady() error {
+	m.mu.RLock()
+	policy := m.policy
+	m.mu.RUnlock()
+
+	switch policy {
+	case PolicyAlways:
+		return nil
+	case PolicySouthbound:
+		if !m.componentUp(ComponentConfig) {
+			return fmt.Errorf("onos-o1t is not ready: %s is down", ComponentConfig)
+		}
+		return nil
+	default:
+		degraded := m.Degraded()
+		if len(degraded) > 0 {
+			return fmt.Errorf("onos-o1t is not ready: %s is down", degraded[0].Name)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestMonitor returns a monitor whose components were checked once, the components named
+// by down failing their check
+func newTestMonitor(t *testing.T, down ...string) *Monitor {
+	m := NewMonitor(time.Hour, time.Second)
+	for _, name := range []string{ComponentConfig, ComponentTopo, ComponentNetconf} {
+		var err error
+		for _, d := range down {
+			if d == name {
+				err = errors.New("unreachable")
+			}
+		}
+		m.Register(name, CheckerFunc(func(ctx context.Context) error {
+			return err
+		}))
+	}
+	m.Start()
+	t.Cleanup(m.Stop)
+	return m
+}
+
+func TestGate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		down   []string
+		ready  bool
+	}{
+		{name: "ready, all up", policy: PolicyReady, ready: true},
+		{name: "ready, topo down", policy: PolicyReady, down: []string{ComponentTopo}, ready: false},
+		{name: "default, topo down", policy: "", down: []string{ComponentTopo}, ready: false},
+		{name: "southbound, topo down", policy: PolicySouthbound, down: []string{ComponentTopo}, ready: true},
+		{name: "southbound, config down", policy: PolicySouthbound, down: []string{ComponentConfig}, ready: false},
+		{name: "always, config down", policy: PolicyAlways, down: []string{ComponentConfig, ComponentTopo}, ready: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := newTestMonitor(t, test.down...)
+			if err := m.SetPolicy(test.policy); err != nil {
+				t.Fatal(err)
+			}
+			err := m.Gate()()
+			if ready := err == nil; ready != test.ready {
+				t.Errorf("gate error %v, want ready %t", err, test.ready)
+			}
+
+			recorder := httptest.NewRecorder()
+			m.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+			wantCode := http.StatusOK
+			if !test.ready {
+				wantCode = http.StatusServiceUnavailable
+			}
+			if recorder.Code != wantCode {
+				t.Errorf("/readyz status %d, want %d", recorder.Code, wantCode)
+			}
+			readiness := readinessJSON{}
+			if err := json.NewDecoder(recorder.Body).Decode(&readiness); err != nil {
+				t.Fatal(err)
+			}
+			if readiness.Ready != test.ready || len(readiness.Components) != 3 {
+				t.Errorf("/readyz reports ready %t with %d components, want %t with 3", readiness.Ready, len(readiness.Components), test.ready)
+			}
+			for _, component := range readiness.Components {
+				up := true
+				for _, name := range test.down {
+					up = up && name != component.Name
+				}
+				if component.Up != up {
+					t.Errorf("/readyz reports %s up %t, want %t", component.Name, component.Up, up)
+				}
+			}
+		})
+	}
+}
+
+func TestGateFollowsPolicy(t *testing.T) {
+	m := newTestMonitor(t, ComponentTopo)
+	gate := m.Gate()
+	if err := gate(); err == nil {
+		t.Error("sessions accepted with onos-topo down and the default policy")
+	}
+	if err := m.SetPolicy(PolicySouthbound); err != nil {
+		t.Fatal(err)
+	}
+	if err := gate(); err != nil {
+		t.Errorf("sessions rejected with onos-config up and the southbound policy: %v", err)
+	}
+	if err := m.SetPolicy("never"); err == nil {
+		t.Error("invalid policy accepted")
+	}
+	if err := gate(); err != nil {
+		t.Errorf("policy changed by an invalid policy: %v", err)
+	}
+}
+
+func TestNotCheckedYet(t *testing.T) {
+	m := NewMonitor(time.Hour, time.Second)
+	m.Register(ComponentConfig, CheckerFunc(func(ctx context.Context) error {
+		return nil
+	}))
+	if err := m.Gate()(); err == nil {
+		t.Error("sessions accepted before the components are checked")
+	}
+}
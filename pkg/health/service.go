@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging/service"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// NewService returns the gRPC health service reporting the health of the monitor
+func NewService(monitor *Monitor) service.Service {
+	return &Service{
+		monitor: monitor,
+	}
+}
+
+// Service is the gRPC health service
+type Service struct {
+	monitor *Monitor
+}
+
+func (s Service) Register(r *grpc.Server) {
+	healthpb.RegisterHealthServer(r, s.monitor.grpcServer)
+}
+
+type componentJSON struct {
+	Name  string `json:"name"`
+	Up    bool   `json:"up"`
+	Error string `json:"error,omitempty"`
+}
+
+type readinessJSON struct {
+	Ready      bool            `json:"ready"`
+	Components []componentJSON `json:"components"`
+}
+
+// LivenessHandler returns the HTTP handler of the liveness probe, replying while onos-o1t
+// is serving whatever the health of its components
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
+
+// ReadinessHandler returns the HTTP handler of the readiness probe, failing when onos-o1t is
+// not ready according to the policy, with the components that are down
+func (m *Monitor) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readiness := readinessJSON{
+			Ready:      m.checkReady() == nil,
+			Components: []componentJSON{},
+		}
+		for _, status := range m.Statuses() {
+			readiness.Components = append(readiness.Components, componentJSON{
+				Name:  status.Name,
+				Up:    status.Up,
+				Error: status.Error,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !readiness.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(readiness)
+	})
+}
@@ -5,10 +5,24 @@
 package manager
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/onosproject/onos-o1t/pkg/audit"
+	"github.com/onosproject/onos-o1t/pkg/config"
+	"github.com/onosproject/onos-o1t/pkg/health"
+	"github.com/onosproject/onos-o1t/pkg/metrics"
 	"github.com/onosproject/onos-o1t/pkg/northbound/cli"
+	"github.com/onosproject/onos-o1t/pkg/northbound/restconf"
 	"github.com/onosproject/onos-o1t/pkg/northbound/ssh"
+	"github.com/onosproject/onos-o1t/pkg/replay"
 	"github.com/onosproject/onos-o1t/pkg/southbound"
 	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
 	"google.golang.org/grpc"
 
 	"github.com/onosproject/onos-o1t/pkg/controller"
@@ -21,49 +35,198 @@ import (
 var log = logging.GetLogger()
 
 type Config struct {
-	CAPath       string
-	KeyPath      string
-	CertPath     string
-	GRPCPort     int
-	ConfigPath   string
-	NetconfPort  int
-	GnmiEndpoint string
+	CAPath   string
+	KeyPath  string
+	CertPath string
+	GRPCPort int
+	// ConfigPath is the configuration file, which must exist if set
+	ConfigPath string
+	// Version is the version of onos-o1t registered in onos-topo
+	Version string
+	// TopoClient is used instead of connecting to onos-topo if set, such as a rnib.FakeClient
+	TopoClient rnib.TopoClient
+	// Overrides applies the settings given on the command line over the configuration file,
+	// when it is loaded and reloaded
+	Overrides func(*config.Config)
 }
 
 type Manager struct {
-	sshServer  ssh.SSHServer
-	controller controller.O1Controller
-	config     Config
-	confStore  store.Store
-	rnibClient rnib.TopoClient
+	sshServer ssh.SSHServer
+	// restconfServer is nil if RESTCONF is disabled
+	restconfServer restconf.Server
+	controller     controller.O1Controller
+	config         Config
+	confStore      store.Store
+	rnibClient     rnib.TopoClient
+	nbiServer      *northbound.Server
+	httpServer     *http.Server
+	monitor        *health.Monitor
+	auditor        audit.Auditor
+	// replayLog is nil if replay is disabled
+	replayLog   *replay.Log
+	stopTracing tracing.ShutdownFunc
+	// registration is nil if onos-o1t is not registered in onos-topo
+	registration *registration
+
+	// settingsMu guards settings, the effective configuration loaded from the config file
+	settingsMu sync.RWMutex
+	settings   *config.Config
 }
 
-func NewManager(config Config, opts ...grpc.DialOption) (*Manager, error) {
-	confStore := store.NewStore()
+func NewManager(cfg Config, opts ...grpc.DialOption) (*Manager, error) {
+	settings, err := loadConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	settings.ApplyLogging()
 
-	rnibClient, err := rnib.NewClient()
+	auditConfig, err := auditConfig(settings)
 	if err != nil {
 		return nil, err
 	}
 
-	gnmiClient, err := southbound.NewGNMIClient(config.GnmiEndpoint, opts...)
+	sshOptions, err := liveSSHOptions(settings)
 	if err != nil {
 		return nil, err
 	}
+	hostKeys, err := ssh.LoadHostKeys(settings.SSH.HostKeys)
+	if err != nil {
+		return nil, err
+	}
+	listeners, err := sshListeners(cfg, settings)
+	if err != nil {
+		return nil, err
+	}
+	// cleanups release what is created when the manager can not be created, in reverse order
+	cleanups := []func(){func() {
+		closeListeners(listeners)
+	}}
+	fail := func(err error) (*Manager, error) {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+		return nil, err
+	}
 
-	controller := controller.NewO1Controller(confStore, rnibClient, gnmiClient)
+	stopTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Endpoint:    settings.Tracing.Endpoint,
+		Insecure:    settings.Tracing.Insecure,
+		SampleRatio: settings.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return fail(err)
+	}
+	cleanups = append(cleanups, func() { _ = stopTracing(context.Background()) })
+
+	confStore := store.NewStore()
+	monitor := health.NewMonitor(settings.Health.Interval.Duration(), 0)
 
-	sshServer, err := ssh.NewSSHServer(config.NetconfPort, controller)
+	err = monitor.SetPolicy(settings.Health.ReadinessPolicy)
 	if err != nil {
-		return nil, err
+		return fail(err)
+	}
+	sessionGate := monitor.Gate()
+
+	rnibClient := cfg.TopoClient
+	if rnibClient == nil {
+		rnibClient, err = rnib.NewClient(settings.Topo.Kind)
+		if err != nil {
+			return fail(err)
+		}
+		if closer, ok := rnibClient.(io.Closer); ok {
+			cleanups = append(cleanups, func() { _ = closer.Close() })
+		}
+	}
+	if checker, ok := rnibClient.(health.Checker); ok {
+		monitor.Register(health.ComponentTopo, checker)
+	}
+
+	gnmiClient, err := southbound.NewGNMIClient(settings.Southbound.GnmiEndpoint, opts...)
+	if err != nil {
+		return fail(err)
+	}
+	if closer, ok := gnmiClient.(io.Closer); ok {
+		cleanups = append(cleanups, func() { _ = closer.Close() })
+	}
+	if checker, ok := gnmiClient.(health.Checker); ok {
+		monitor.Register(health.ComponentConfig, checker)
+	}
+
+	auditor, err := audit.NewAuditor(auditConfig)
+	if err != nil {
+		return fail(err)
+	}
+	cleanups = append(cleanups, func() { _ = auditor.Close() })
+
+	registry, err := schemaRegistry(settings, gnmiClient)
+	if err != nil {
+		return fail(err)
+	}
+
+	var replayLog *replay.Log
+	if settings.Replay.Dir != "" {
+		replayLog, err = replay.Open(replay.Config{
+			Dir:       settings.Replay.Dir,
+			MaxAge:    settings.Replay.MaxAge.Duration(),
+			MaxSizeMB: settings.Replay.MaxSizeMB,
+		})
+		if err != nil {
+			return fail(err)
+		}
+		cleanups = append(cleanups, func() { _ = replayLog.Close() })
+	}
+
+	controllerOptions := []controller.Option{
+		controller.WithAuditor(auditor),
+		controller.WithHealth(monitor),
+		controller.WithSettings(controllerSettings(settings)),
+	}
+	if registry != nil {
+		controllerOptions = append(controllerOptions, controller.WithSchemas(registry))
+	}
+	if replayLog != nil {
+		controllerOptions = append(controllerOptions, controller.WithReplayLog(replayLog))
+	}
+	controller := controller.NewO1Controller(confStore, rnibClient, gnmiClient, controllerOptions...)
+	cleanups = append(cleanups, controller.Close)
+
+	sshOptions = append(sshOptions,
+		ssh.WithListeners(listeners...),
+		ssh.WithCallHome(callHomeClients(settings)...),
+		ssh.WithHostKeys(hostKeys),
+		ssh.WithSessionGate(sessionGate))
+	sshServer, err := ssh.NewSSHServer(controller, sshOptions...)
+	if err != nil {
+		return fail(err)
+	}
+	monitor.Register(health.ComponentNetconf, sshServer)
+
+	var restconfServer restconf.Server
+	if settings.RESTCONF.Port != 0 {
+		restconfOptions, err := restconfOptions(cfg, settings)
+		if err != nil {
+			return fail(err)
+		}
+		restconfServer, err = restconf.NewServer(controller, append(restconfOptions, restconf.WithSessionGate(sessionGate))...)
+		if err != nil {
+			return fail(err)
+		}
+		monitor.Register(health.ComponentRESTCONF, restconfServer)
 	}
 
 	return &Manager{
-		sshServer:  sshServer,
-		controller: controller,
-		confStore:  confStore,
-		config:     config,
-		rnibClient: rnibClient,
+		sshServer:      sshServer,
+		restconfServer: restconfServer,
+		controller:     controller,
+		confStore:      confStore,
+		config:         cfg,
+		rnibClient:     rnibClient,
+		auditor:        auditor,
+		replayLog:      replayLog,
+		stopTracing:    stopTracing,
+		monitor:        monitor,
+		settings:       settings,
+		registration:   newRegistration(cfg, settings, listeners),
 	}, nil
 }
 
@@ -77,31 +240,103 @@ func (m *Manager) startNorthboundServer() error {
 		northbound.SecurityConfig{}))
 
 	s.AddService(cli.NewService(m.confStore))
+	s.AddService(health.NewService(m.monitor))
+	s.AddService(cli.NewAdminService(m.EffectiveConfig, m.confStore))
 
-	doneCh := make(chan error)
+	doneCh := make(chan error, 1)
 	go func() {
-		err := s.Serve(func(started string) {
-			log.Info("Started NBI on ", started)
-			close(doneCh)
+		started := false
+		err := s.Serve(func(addr string) {
+			log.Info("Started NBI on ", addr)
+			started = true
+			doneCh <- nil
 		})
 		if err != nil {
+			if started {
+				log.Errorf("NBI stopped serving: %v", err)
+				return
+			}
 			doneCh <- err
 		}
 	}()
-	return <-doneCh
+
+	err := <-doneCh
+	if err != nil {
+		return err
+	}
+	m.nbiServer = s
+	return nil
 }
 
-// func (m *Manager) registerO1TtoRnib() error {
-// 	return m.rnibClient.AddO1TEntity(context.Background(), uint32(m.config.NetconfPort))
-// }
+func (m *Manager) stopNorthboundServer(ctx context.Context) {
+	if m.nbiServer == nil {
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		m.nbiServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Warn("NBI did not stop gracefully before the deadline, forcing it to stop")
+		m.nbiServer.Stop()
+	}
+	log.Info("Stopped NBI")
+}
+
+// startHTTPServer serves the Prometheus metrics on /metrics, the liveness probe on /healthz
+// and the readiness probe on /readyz, unless the HTTP port is 0
+func (m *Manager) startHTTPServer() error {
+	port := m.EffectiveConfig().HTTP.Port
+	if port == 0 {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", health.LivenessHandler())
+	mux.Handle("/readyz", m.monitor.ReadinessHandler())
+	m.httpServer = &http.Server{Handler: mux}
+
+	go func() {
+		err := m.httpServer.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Errorf("HTTP server stopped serving: %v", err)
+		}
+	}()
+	log.Infof("Started HTTP server on %s", listener.Addr())
+	return nil
+}
+
+func (m *Manager) stopHTTPServer(ctx context.Context) {
+	if m.httpServer == nil {
+		return
+	}
+
+	err := m.httpServer.Shutdown(ctx)
+	if err != nil {
+		log.Warnf("Error when stopping the HTTP server: %v", err)
+	}
+	log.Info("Stopped HTTP server")
+}
 
 func (m *Manager) start() error {
-	// err := m.registerO1TtoRnib()
-	// if err != nil {
-	// 	return err
-	// }
+	err := m.startHTTPServer()
+	if err != nil {
+		log.Warn(err)
+		return err
+	}
 
-	err := m.startNorthboundServer()
+	err = m.startNorthboundServer()
 	if err != nil {
 		log.Warn(err)
 		return err
@@ -113,12 +348,95 @@ func (m *Manager) start() error {
 		return err
 	}
 
+	if m.restconfServer != nil {
+		err = m.restconfServer.Start()
+		if err != nil {
+			log.Warn(err)
+			return err
+		}
+	}
+
+	m.monitor.Start()
+	m.startRegistration()
 	return nil
 }
 
-func (m *Manager) Run() {
+// Run starts onos-o1t and blocks until the given context is done, then stops it gracefully
+func (m *Manager) Run(ctx context.Context) error {
 	err := m.start()
 	if err != nil {
 		log.Errorf("Error when starting O1T: %v", err)
+		stopCtx, cancel := context.WithTimeout(context.Background(), m.EffectiveConfig().Timeouts.Shutdown.Duration())
+		defer cancel()
+		_ = m.Stop(stopCtx)
+		return err
+	}
+
+	log.Info("Started onos-o1t")
+	<-ctx.Done()
+
+	log.Info("Stopping onos-o1t")
+	stopCtx, cancel := context.WithTimeout(context.Background(), m.EffectiveConfig().Timeouts.Shutdown.Duration())
+	defer cancel()
+	return m.Stop(stopCtx)
+}
+
+// Stop removes the registration of onos-o1t from onos-topo and reports onos-o1t as not ready, then
+// drains the NETCONF server and stops the RESTCONF server, so no new session or rpc is accepted,
+// terminates the remaining sessions in the store, closes the NETCONF connections, stops the
+// controller, the NBI and the HTTP server, closes the audit trail and the replay log and flushes
+// the traces
+func (m *Manager) Stop(ctx context.Context) error {
+	m.stopRegistration(ctx)
+	m.monitor.Stop()
+
+	err := m.sshServer.Drain(ctx)
+	if err != nil {
+		log.Warnf("Error when draining the Netconf SSH server: %v", err)
+	}
+
+	if m.restconfServer != nil {
+		restconfErr := m.restconfServer.Stop(ctx)
+		if restconfErr != nil {
+			log.Warnf("Error when stopping the RESTCONF server: %v", restconfErr)
+		}
+	}
+
+	terminateErr := m.controller.TerminateSessions(ctx)
+	if terminateErr != nil {
+		log.Warnf("Error when terminating sessions: %v", terminateErr)
+		if err == nil {
+			err = terminateErr
+		}
+	}
+
+	sshErr := m.sshServer.Stop(ctx)
+	if sshErr != nil {
+		log.Warnf("Error when stopping the Netconf SSH server: %v", sshErr)
+		if err == nil {
+			err = sshErr
+		}
+	}
+
+	m.controller.Close()
+	m.stopNorthboundServer(ctx)
+	m.stopHTTPServer(ctx)
+
+	auditErr := m.auditor.Close()
+	if auditErr != nil {
+		log.Warnf("Error when closing the audit trail: %v", auditErr)
+	}
+
+	if m.replayLog != nil {
+		replayErr := m.replayLog.Close()
+		if replayErr != nil {
+			log.Warnf("Error when closing the replay log: %v", replayErr)
+		}
+	}
+
+	tracingErr := m.stopTracing(ctx)
+	if tracingErr != nil {
+		log.Warnf("Error when flushing the traces: %v", tracingErr)
 	}
+	return err
 }
@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package manager
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/config"
+	"github.com/onosproject/onos-o1t/pkg/northbound/ssh"
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+)
+
+// leaseIntervals is the number of refresh intervals after which the lease of the entity
+// registering onos-o1t expires
+const leaseIntervals = 3
+
+// registration keeps onos-o1t registered in onos-topo while it runs
+type registration struct {
+	id        string
+	interval  time.Duration
+	version   string
+	endpoints []rnib.Endpoint
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// newRegistration returns the registration of onos-o1t with the endpoints of its listeners,
+// or nil if the registration is disabled
+func newRegistration(cfg Config, settings *config.Config, listeners []ssh.Listener) *registration {
+	if settings.Topo.Registration.ID == "" {
+		return nil
+	}
+
+	host := settings.Topo.Registration.Address
+	if host == "" {
+		var err error
+		host, err = os.Hostname()
+		if err != nil {
+			log.Warnf("Unable to get the hostname advertised in onos-topo: %v", err)
+		}
+	}
+
+	endpoints := make([]rnib.Endpoint, 0, len(listeners)+1)
+	for _, listener := range listeners {
+		address := listener.Address
+		if listener.NetListener != nil {
+			address = listener.NetListener.Addr().String()
+		}
+		transport := rnib.TransportSSH
+		if listener.TLS != nil {
+			transport = rnib.TransportTLS
+		}
+		endpoints = append(endpoints, endpoint(rnib.ProtocolNetconf, transport, host, address))
+	}
+	if settings.RESTCONF.Port != 0 {
+		transport := rnib.TransportHTTPS
+		if settings.RESTCONF.Insecure {
+			transport = rnib.TransportHTTP
+		}
+		address := net.JoinHostPort(settings.RESTCONF.Address, strconv.Itoa(settings.RESTCONF.Port))
+		endpoints = append(endpoints, endpoint(rnib.ProtocolRESTCONF, transport, host, address))
+	}
+
+	return &registration{
+		id:        settings.Topo.Registration.ID,
+		interval:  settings.Topo.Registration.RefreshInterval.Duration(),
+		version:   cfg.Version,
+		endpoints: endpoints,
+	}
+}
+
+// endpoint returns the endpoint of a listened address, advertised with the given host
+func endpoint(protocol, transport, host, address string) rnib.Endpoint {
+	e := rnib.Endpoint{Protocol: protocol, Transport: transport, Address: host}
+	_, port, err := net.SplitHostPort(address)
+	if err == nil {
+		e.Port, _ = strconv.Atoi(port)
+	}
+	return e
+}
+
+// register registers onos-o1t in onos-topo with its current capabilities
+func (m *Manager) register(ctx context.Context) error {
+	r := m.registration
+	ctx, cancel := context.WithTimeout(ctx, r.interval)
+	defer cancel()
+
+	capabilities, err := m.controller.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	server := rnib.Server{
+		Version:      r.version,
+		Endpoints:    r.endpoints,
+		Capabilities: capabilities,
+	}
+	return m.rnibClient.Register(ctx, r.id, server, leaseIntervals*r.interval)
+}
+
+// startRegistration registers onos-o1t in onos-topo, then refreshes its registration at each
+// interval, a failed registration being retried at the next one
+func (m *Manager) startRegistration() {
+	r := m.registration
+	if r == nil {
+		return
+	}
+
+	err := m.register(context.Background())
+	if err != nil {
+		log.Warnf("Unable to register onos-o1t in onos-topo: %v", err)
+	} else {
+		log.Infof("Registered onos-o1t in onos-topo as %s", r.id)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		failed := err != nil
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := m.register(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Warnf("Unable to refresh the registration of onos-o1t in onos-topo: %v", err)
+				}
+				failed = true
+				continue
+			}
+			if failed {
+				log.Infof("Registered onos-o1t in onos-topo as %s", r.id)
+				failed = false
+			}
+		}
+	}()
+}
+
+// stopRegistration stops refreshing the registration of onos-o1t and removes it from onos-topo
+func (m *Manager) stopRegistration(ctx context.Context) {
+	r := m.registration
+	if r == nil || r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+
+	err := m.rnibClient.Unregister(ctx, r.id)
+	if err != nil {
+		log.Warnf("Unable to remove the registration of onos-o1t from onos-topo: %v", err)
+		return
+	}
+	log.Infof("Removed the registration of onos-o1t from onos-topo")
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+const (
+	registrationID       = "onos-o1t-1"
+	registrationInterval = 50 * time.Millisecond
+)
+
+// newRegisteringManager returns a manager registering itself in the fake topo client
+func newRegisteringManager(topo *rnib.FakeClient) *Manager {
+	return &Manager{
+		controller: controller.NewO1Controller(store.NewStore(), topo, nil),
+		rnibClient: topo,
+		registration: &registration{
+			id:        registrationID,
+			interval:  registrationInterval,
+			version:   "v1.0.0",
+			endpoints: []rnib.Endpoint{endpoint(rnib.ProtocolNetconf, rnib.TransportSSH, "o1t", ":830")},
+		},
+	}
+}
+
+// waitRegistration waits for the registration of onos-o1t to satisfy the condition
+func waitRegistration(t *testing.T, topo *rnib.FakeClient, condition func(rnib.Registration, bool) bool) rnib.Registration {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		registration, ok := topo.Registration(registrationID)
+		if condition(registration, ok) {
+			return registration
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected registration %v, registered %t", registration, ok)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	topo := rnib.NewFakeClient(rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:1.0.0"})
+	m := newRegisteringManager(topo)
+	defer m.controller.Close()
+
+	m.startRegistration()
+	registration, ok := topo.Registration(registrationID)
+	if !ok {
+		t.Fatal("onos-o1t not registered while onos-topo is up")
+	}
+	server := registration.Server
+	if server.Version != "v1.0.0" {
+		t.Errorf("registered version %q, want v1.0.0", server.Version)
+	}
+	if len(server.Endpoints) != 1 || server.Endpoints[0].Port != 830 || server.Endpoints[0].Address != "o1t" {
+		t.Errorf("unexpected registered endpoints %v", server.Endpoints)
+	}
+	if len(server.Capabilities) == 0 {
+		t.Error("no registered capabilities")
+	}
+	if lease := registration.Expiration.Sub(server.Updated); lease != leaseIntervals*registrationInterval {
+		t.Errorf("lease %s, want %s", lease, leaseIntervals*registrationInterval)
+	}
+
+	// a refresh updates the registration
+	waitRegistration(t, topo, func(r rnib.Registration, ok bool) bool {
+		return ok && r.Server.Updated.After(server.Updated)
+	})
+
+	m.stopRegistration(context.Background())
+	if _, ok := topo.Registration(registrationID); ok {
+		t.Error("registration not removed when stopped")
+	}
+}
+
+func TestRegistrationRetry(t *testing.T) {
+	topo := rnib.NewFakeClient(rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:1.0.0"})
+	m := newRegisteringManager(topo)
+	defer m.controller.Close()
+
+	topo.SetError(errors.New("onos-topo unavailable"))
+	m.startRegistration()
+	defer m.stopRegistration(context.Background())
+	if _, ok := topo.Registration(registrationID); ok {
+		t.Fatal("onos-o1t registered while onos-topo is down")
+	}
+
+	// the failed registration is retried at the next interval
+	topo.SetError(nil)
+	waitRegistration(t, topo, func(r rnib.Registration, ok bool) bool {
+		return ok
+	})
+}
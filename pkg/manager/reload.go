@@ -0,0 +1,318 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/onosproject/onos-o1t/pkg/audit"
+	"github.com/onosproject/onos-o1t/pkg/config"
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/northbound/restconf"
+	"github.com/onosproject/onos-o1t/pkg/northbound/ssh"
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/onosproject/onos-o1t/pkg/southbound"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// loadConfig loads the configuration file, applies the command line overrides and
+// validates the result
+func loadConfig(cfg Config) (*config.Config, error) {
+	settings, err := config.Load(cfg.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Overrides != nil {
+		cfg.Overrides(settings)
+	}
+	err = settings.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Reload reloads the configuration file and applies its live settings. The current
+// configuration is kept if the file is invalid, a change of the settings requiring a
+// restart is reported and ignored.
+func (m *Manager) Reload() error {
+	next, err := loadConfig(m.config)
+	if err != nil {
+		log.Errorf("Error reloading the configuration, keeping the current one: %v", err)
+		return err
+	}
+
+	sshOptions, err := liveSSHOptions(next)
+	if err != nil {
+		log.Errorf("Error reloading the configuration, keeping the current one: %v", err)
+		return err
+	}
+	err = m.monitor.SetPolicy(next.Health.ReadinessPolicy)
+	if err != nil {
+		log.Errorf("Error reloading the configuration, keeping the current one: %v", err)
+		return err
+	}
+
+	m.settingsMu.Lock()
+	settings, restart := m.settings.Reload(next)
+	m.settings = settings
+	m.settingsMu.Unlock()
+
+	settings.ApplyLogging()
+	m.sshServer.Reconfigure(sshOptions...)
+	if m.restconfServer != nil {
+		m.restconfServer.Reconfigure(restconf.WithUsers(settings.SSH.Users))
+	}
+	m.controller.Configure(controllerSettings(settings))
+
+	if len(restart) > 0 {
+		log.Warnf("Changes of %s require a restart of onos-o1t and are not applied", strings.Join(restart, ", "))
+	}
+	log.Info("Reloaded the configuration")
+	return nil
+}
+
+// EffectiveConfig returns the configuration in effect, without its secrets
+func (m *Manager) EffectiveConfig() *config.Config {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+
+	return m.settings.Redacted()
+}
+
+// liveSSHOptions returns the options of the NETCONF SSH server that can change while it runs
+func liveSSHOptions(settings *config.Config) ([]ssh.Option, error) {
+	var authorizedKeys []gossh.PublicKey
+	if settings.SSH.AuthorizedKeys != "" {
+		keys, err := ssh.LoadAuthorizedKeys(settings.SSH.AuthorizedKeys)
+		if err != nil {
+			return nil, err
+		}
+		authorizedKeys = keys
+	}
+
+	return []ssh.Option{
+		ssh.WithAuth(authorizedKeys, settings.SSH.Users),
+		ssh.WithIdleTimeout(settings.Timeouts.Idle.Duration()),
+		ssh.WithRPCTimeout(settings.Timeouts.RPC.Duration()),
+		ssh.WithKeepalive(settings.SSH.KeepaliveInterval.Duration(), settings.SSH.KeepaliveCountMax),
+	}, nil
+}
+
+// sshListeners returns the listeners of the NETCONF SSH server, opening the inherited sockets
+// and loading the certificates of the TLS listeners, by default those of the command line
+func sshListeners(cfg Config, settings *config.Config) ([]ssh.Listener, error) {
+	listeners := make([]ssh.Listener, 0, len(settings.Listeners))
+	for i, listener := range settings.Listeners {
+		var tlsConfig *ssh.TLSConfig
+		if listener.TLS != nil {
+			var err error
+			tlsConfig, err = listenerTLSConfig(cfg, listener.TLS)
+			if err != nil {
+				closeListeners(listeners)
+				return nil, fmt.Errorf("listeners[%d].tls: %v", i, err)
+			}
+		}
+
+		if listener.FD == 0 {
+			listeners = append(listeners, ssh.Listener{
+				Address:     net.JoinHostPort(listener.Address, strconv.Itoa(listener.Port)),
+				AuthMethods: listener.Auth,
+				TLS:         tlsConfig,
+			})
+			continue
+		}
+
+		file := os.NewFile(uintptr(listener.FD), fmt.Sprintf("fd%d", listener.FD))
+		netListener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			closeListeners(listeners)
+			return nil, fmt.Errorf("file descriptor %d is not a listening socket: %v", listener.FD, err)
+		}
+		listeners = append(listeners, ssh.Listener{
+			NetListener: netListener,
+			AuthMethods: listener.Auth,
+			TLS:         tlsConfig,
+		})
+	}
+	return listeners, nil
+}
+
+// closeListeners closes the inherited sockets of the listeners
+func closeListeners(listeners []ssh.Listener) {
+	for _, listener := range listeners {
+		if listener.NetListener != nil {
+			_ = listener.NetListener.Close()
+		}
+	}
+}
+
+// listenerTLSConfig loads the server certificate and the CA of the client certificates of a
+// NETCONF over TLS listener and parses its cert-to-name rules
+func listenerTLSConfig(cfg Config, listenerTLS *config.ListenerTLS) (*ssh.TLSConfig, error) {
+	certPath, keyPath, caPath := listenerTLS.CertPath, listenerTLS.KeyPath, listenerTLS.CAPath
+	if certPath == "" && keyPath == "" {
+		certPath, keyPath = cfg.CertPath, cfg.KeyPath
+	}
+	if caPath == "" {
+		caPath = cfg.CAPath
+	}
+	if certPath == "" || keyPath == "" || caPath == "" {
+		return nil, fmt.Errorf("a certificate, its key and a CA are required")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in %s", caPath)
+	}
+
+	rules := make([]ssh.CertToName, 0, len(listenerTLS.CertToName))
+	for i, rule := range listenerTLS.CertToName {
+		var fingerprint ssh.Fingerprint
+		if rule.Fingerprint != "" {
+			fingerprint, err = ssh.ParseFingerprint(rule.Fingerprint)
+			if err != nil {
+				return nil, fmt.Errorf("certToName[%d].fingerprint: %v", i, err)
+			}
+		}
+		rules = append(rules, ssh.CertToName{
+			Fingerprint: fingerprint,
+			MapType:     rule.MapType,
+			Name:        rule.Name,
+		})
+	}
+
+	return &ssh.TLSConfig{
+		Config: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientCAs:    clientCAs,
+			MinVersion:   tls.VersionTLS12,
+		},
+		CertToName: rules,
+	}, nil
+}
+
+// restconfOptions returns the options of the RESTCONF server, loading its certificate, by
+// default that of the command line, unless it is insecure
+func restconfOptions(cfg Config, settings *config.Config) ([]restconf.Option, error) {
+	options := []restconf.Option{
+		restconf.WithAddress(net.JoinHostPort(settings.RESTCONF.Address, strconv.Itoa(settings.RESTCONF.Port))),
+		restconf.WithUsers(settings.SSH.Users),
+		restconf.WithAnonymous(settings.RESTCONF.AllowAnonymous),
+	}
+	if settings.RESTCONF.Insecure {
+		return options, nil
+	}
+
+	certPath, keyPath := settings.RESTCONF.CertPath, settings.RESTCONF.KeyPath
+	if certPath == "" && keyPath == "" {
+		certPath, keyPath = cfg.CertPath, cfg.KeyPath
+	}
+	if certPath == "" || keyPath == "" {
+		return nil, fmt.Errorf("restconf: a certificate and its key are required unless insecure")
+	}
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("restconf: %v", err)
+	}
+
+	return append(options, restconf.WithTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})), nil
+}
+
+// callHomeClients returns the clients the NETCONF SSH server connects to with SSH Call Home
+func callHomeClients(settings *config.Config) []ssh.CallHomeClient {
+	clients := make([]ssh.CallHomeClient, 0, len(settings.CallHome))
+	for _, client := range settings.CallHome {
+		clients = append(clients, ssh.CallHomeClient{
+			Name:           client.Name,
+			Address:        client.HostPort(),
+			Mode:           client.Mode,
+			Period:         client.Period.Duration(),
+			InitialBackoff: client.InitialBackoff.Duration(),
+			MaxBackoff:     client.MaxBackoff.Duration(),
+			AuthMethods:    client.Auth,
+		})
+	}
+	return clients
+}
+
+// schemaRegistry loads the YANG modules of the directories of the yang section, nil if no
+// directory is configured, the modules of the models being those listed by their model plugins
+// registered in onos-config
+func schemaRegistry(settings *config.Config, gnmiClient southbound.GnmiClient) (*schema.Registry, error) {
+	if len(settings.YANG.Dirs) == 0 {
+		return nil, nil
+	}
+
+	var lister schema.ModelLister
+	if modelLister, ok := gnmiClient.(southbound.ModelLister); ok {
+		lister = func(ctx context.Context) ([]schema.Model, error) {
+			models, err := modelLister.ListModels(ctx)
+			if err != nil {
+				return nil, err
+			}
+			schemaModels := make([]schema.Model, 0, len(models))
+			for _, model := range models {
+				schemaModels = append(schemaModels, schema.Model{
+					Name:    model.Name,
+					Version: model.Version,
+					Modules: model.Modules,
+				})
+			}
+			return schemaModels, nil
+		}
+	}
+	return schema.NewRegistry(settings.YANG.Dirs, lister)
+}
+
+func controllerSettings(settings *config.Config) controller.Settings {
+	mappings := make([]controller.NamespaceMapping, 0, len(settings.Namespace.Mappings))
+	for _, mapping := range settings.Namespace.Mappings {
+		mappings = append(mappings, controller.NamespaceMapping(mapping))
+	}
+	return controller.Settings{
+		NamespacePrefix:  settings.Namespace.Prefix,
+		GnmiTimeout:      settings.Timeouts.Gnmi.Duration(),
+		SessionRetention: settings.Timeouts.SessionRetention.Duration(),
+		Features: controller.Features{
+			KillSession: settings.Features.KillSession,
+			Monitoring:  settings.Features.Monitoring,
+		},
+		NamespaceMappings: mappings,
+	}
+}
+
+func auditConfig(settings *config.Config) (audit.Config, error) {
+	network, address, err := config.ParseSyslogURL(settings.Audit.Syslog)
+	if err != nil {
+		return audit.Config{}, err
+	}
+	return audit.Config{
+		File:       settings.Audit.File,
+		MaxSizeMB:  settings.Audit.MaxSizeMB,
+		MaxBackups: settings.Audit.MaxBackups,
+		Syslog:     audit.SyslogConfig{Network: network, Address: address},
+		Redact:     settings.Audit.Redact,
+	}, nil
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	namespace = "onos"
+	subsystem = "o1t"
+)
+
+var (
+	sessionsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "sessions_active",
+		Help:      "Number of alive NETCONF sessions.",
+	})
+	sessionsOpened = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "sessions_opened_total",
+		Help:      "Number of NETCONF sessions opened.",
+	})
+	sessionsClosed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "sessions_closed_total",
+		Help:      "Number of NETCONF sessions ended, by end reason.",
+	}, []string{"reason"})
+
+	rpcs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "rpcs_total",
+		Help:      "Number of NETCONF rpcs handled, by operation, target and outcome.",
+	}, []string{"operation", "target", "outcome"})
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "rpc_duration_seconds",
+		Help:      "Duration of the NETCONF rpcs, by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	gnmiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "gnmi_requests_total",
+		Help:      "Number of gNMI requests sent to onos-config, by method and status code.",
+	}, []string{"method", "code"})
+	gnmiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "gnmi_request_duration_seconds",
+		Help:      "Duration of the gNMI requests sent to onos-config, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	topoRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "topo_requests_total",
+		Help:      "Number of requests sent to onos-topo, by method and status code.",
+	}, []string{"method", "code"})
+	topoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "topo_request_duration_seconds",
+		Help:      "Duration of the requests sent to onos-topo, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	framingErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "framing_errors_total",
+		Help:      "Number of NETCONF messages received with a chunked framing error.",
+	})
+	authFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "auth_failures_total",
+		Help:      "Number of SSH connections closed after failing to authenticate.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		sessionsActive,
+		sessionsOpened,
+		sessionsClosed,
+		rpcs,
+		rpcDuration,
+		gnmiRequests,
+		gnmiDuration,
+		topoRequests,
+		topoDuration,
+		framingErrors,
+		authFailures,
+	)
+}
+
+// Handler returns the HTTP handler exposing the metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// SessionOpened counts a new alive NETCONF session
+func SessionOpened() {
+	sessionsOpened.Inc()
+	sessionsActive.Inc()
+}
+
+// SessionEnded counts an ended NETCONF session
+func SessionEnded(reason string) {
+	sessionsClosed.WithLabelValues(reason).Inc()
+	sessionsActive.Dec()
+}
+
+// ObserveRPC records a handled NETCONF rpc
+func ObserveRPC(operation, target, outcome string, duration time.Duration) {
+	rpcs.WithLabelValues(operation, target, outcome).Inc()
+	rpcDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// ObserveGnmi records a gNMI request and the status code of its error
+func ObserveGnmi(method string, err error, duration time.Duration) {
+	gnmiRequests.WithLabelValues(method, code(err)).Inc()
+	gnmiDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// ObserveTopo records a onos-topo request and the status code of its error
+func ObserveTopo(method string, err error, duration time.Duration) {
+	topoRequests.WithLabelValues(method, code(err)).Inc()
+	topoDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// code returns the status code of an error, carrying either a gRPC status or a onos typed error
+func code(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return st.Code().String()
+	}
+	return errors.Status(err).Code().String()
+}
+
+// FramingError counts a NETCONF message received with a chunked framing error
+func FramingError() {
+	framingErrors.Inc()
+}
+
+// AuthFailure counts a SSH connection failing to authenticate
+func AuthFailure() {
+	authFailures.Inc()
+}
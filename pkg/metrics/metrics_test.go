@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestObserveRPC(t *testing.T) {
+	ok := rpcs.WithLabelValues("edit-config", "kpimon", "ok")
+	failed := rpcs.WithLabelValues("edit-config", "kpimon", "error")
+	before, beforeFailed := testutil.ToFloat64(ok), testutil.ToFloat64(failed)
+
+	ObserveRPC("edit-config", "kpimon", "ok", 20*time.Millisecond)
+	ObserveRPC("edit-config", "kpimon", "ok", 30*time.Millisecond)
+	ObserveRPC("edit-config", "kpimon", "error", time.Millisecond)
+
+	if got := testutil.ToFloat64(ok) - before; got != 2 {
+		t.Errorf("%v successful rpcs counted, want 2", got)
+	}
+	if got := testutil.ToFloat64(failed) - beforeFailed; got != 1 {
+		t.Errorf("%v failed rpcs counted, want 1", got)
+	}
+
+	// the duration is observed by operation, whatever the target and outcome
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+	if !strings.Contains(string(body), `onos_o1t_rpc_duration_seconds_count{operation="edit-config"} 3`) {
+		t.Errorf("rpc durations not exposed:\n%s", body)
+	}
+}
+
+func TestFramingError(t *testing.T) {
+	before := testutil.ToFloat64(framingErrors)
+	FramingError()
+	FramingError()
+	if got := testutil.ToFloat64(framingErrors) - before; got != 2 {
+		t.Errorf("%v framing errors counted, want 2", got)
+	}
+}
+
+func TestObserveGnmi(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{nil, "OK"},
+		{status.Error(codes.Unavailable, "onos-config is down"), "Unavailable"},
+		{errors.NewNotFound("no target"), "NotFound"},
+	}
+	for _, test := range tests {
+		counter := gnmiRequests.WithLabelValues("Get", test.code)
+		before := testutil.ToFloat64(counter)
+		ObserveGnmi("Get", test.err, time.Millisecond)
+		if got := testutil.ToFloat64(counter) - before; got != 1 {
+			t.Errorf("gNMI request with error %v not counted with code %s", test.err, test.code)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging/service"
+	"github.com/onosproject/onos-o1t/pkg/config"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// AdminServiceName is the full name of the gRPC service administrating onos-o1t
+const AdminServiceName = "onos.o1t.admin.O1TAdmin"
+
+// ConfigProvider returns the effective configuration of onos-o1t, without its secrets
+type ConfigProvider func() *config.Config
+
+// NewAdminService returns the gRPC service administrating onos-o1t
+func NewAdminService(configProvider ConfigProvider, o1tStore store.Store) service.Service {
+	return &AdminService{
+		configProvider: configProvider,
+		o1tStore:       o1tStore,
+	}
+}
+
+// AdminService is the gRPC service administrating onos-o1t. Its messages are the well-known
+// protobuf types, its description is written by hand as onos-api does not define it.
+type AdminService struct {
+	service.Service
+	configProvider ConfigProvider
+	o1tStore       store.Store
+}
+
+func (s AdminService) Register(r *grpc.Server) {
+	r.RegisterService(&adminServiceDesc, &AdminServer{
+		configProvider: s.configProvider,
+		o1tStore:       s.o1tStore,
+	})
+}
+
+// AdminServer implements the admin gRPC service
+type AdminServer struct {
+	configProvider ConfigProvider
+	o1tStore       store.Store
+}
+
+// GetConfig returns the effective configuration, in the JSON form of the config.json file
+func (s *AdminServer) GetConfig(ctx context.Context, request *emptypb.Empty) (*structpb.Struct, error) {
+	return toStruct(s.configProvider())
+}
+
+// CallHomeStatus is the status of the connection to a call home client
+type CallHomeStatus struct {
+	Name          string `json:"name"`
+	Address       string `json:"address"`
+	Mode          string `json:"mode"`
+	State         string `json:"state"`
+	Failures      int    `json:"failures"`
+	LastError     string `json:"lastError,omitempty"`
+	Connections   int    `json:"connections"`
+	LastConnected string `json:"lastConnected,omitempty"`
+	NextAttempt   string `json:"nextAttempt,omitempty"`
+}
+
+// ListCallHome returns the status of the connections to the call home clients, as a list of
+// CallHomeStatus in the clients field
+func (s *AdminServer) ListCallHome(ctx context.Context, request *emptypb.Empty) (*structpb.Struct, error) {
+	ch := make(chan *store.Entry)
+	done := make(chan []CallHomeStatus)
+
+	go func() {
+		clients := []CallHomeStatus{}
+		for entry := range ch {
+			value, ok := entry.Value.(*store.CallHomeValue)
+			if !ok {
+				continue
+			}
+			clients = append(clients, CallHomeStatus{
+				Name:          entry.Key.CallHome,
+				Address:       value.Address,
+				Mode:          value.Mode,
+				State:         value.State.String(),
+				Failures:      value.Failures,
+				LastError:     value.LastError,
+				Connections:   value.Connections,
+				LastConnected: formatTimestamp(value.LastConnected),
+				NextAttempt:   formatTimestamp(value.NextAttempt),
+			})
+		}
+		sort.Slice(clients, func(i, j int) bool {
+			return clients[i].Name < clients[j].Name
+		})
+		done <- clients
+	}()
+
+	err := s.o1tStore.Entries(ctx, ch)
+	clients := <-done
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, errors.Status(err).Err()
+	}
+
+	return toStruct(map[string]interface{}{
+		"clients": clients,
+	})
+}
+
+// SubscriptionStatus is a YANG-Push subscription of a session
+type SubscriptionStatus struct {
+	ID              uint32   `json:"id"`
+	SessionID       string   `json:"sessionId"`
+	Datastore       string   `json:"datastore"`
+	Namespaces      []string `json:"namespaces"`
+	Filter          string   `json:"filter,omitempty"`
+	Trigger         string   `json:"trigger"`
+	Period          uint32   `json:"period,omitempty"`
+	DampeningPeriod uint32   `json:"dampeningPeriod,omitempty"`
+	SyncOnStart     bool     `json:"syncOnStart,omitempty"`
+	StopTime        string   `json:"stopTime,omitempty"`
+}
+
+// ListSubscriptions returns the YANG-Push subscriptions of the sessions, as a list of
+// SubscriptionStatus in the subscriptions field
+func (s *AdminServer) ListSubscriptions(ctx context.Context, request *emptypb.Empty) (*structpb.Struct, error) {
+	ch := make(chan *store.Entry)
+	done := make(chan []SubscriptionStatus)
+
+	go func() {
+		subscriptions := []SubscriptionStatus{}
+		for entry := range ch {
+			value, ok := entry.Value.(*store.SubscriptionValue)
+			if !ok {
+				continue
+			}
+			subscription := SubscriptionStatus{
+				ID:              value.ID,
+				SessionID:       entry.Key.SessionID,
+				Datastore:       value.Datastore,
+				Namespaces:      value.Namespaces,
+				Filter:          value.Filter,
+				Trigger:         "on-change",
+				Period:          value.Period,
+				DampeningPeriod: value.DampeningPeriod,
+				SyncOnStart:     value.SyncOnStart,
+				StopTime:        formatTimestamp(value.StopTime),
+			}
+			if value.Period > 0 {
+				subscription.Trigger = "periodic"
+			}
+			subscriptions = append(subscriptions, subscription)
+		}
+		sort.Slice(subscriptions, func(i, j int) bool {
+			return subscriptions[i].ID < subscriptions[j].ID
+		})
+		done <- subscriptions
+	}()
+
+	err := s.o1tStore.Entries(ctx, ch)
+	subscriptions := <-done
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, errors.Status(err).Err()
+	}
+
+	return toStruct(map[string]interface{}{
+		"subscriptions": subscriptions,
+	})
+}
+
+// formatTimestamp formats a unix timestamp in nanoseconds in RFC 3339, zero being empty
+func formatTimestamp(timestamp uint64) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.Unix(0, int64(timestamp)).UTC().Format(time.RFC3339Nano)
+}
+
+// toStruct converts a value to a protobuf struct through its JSON form
+func toStruct(value interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	fields := make(map[string]interface{})
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return result, nil
+}
+
+func adminGetConfigHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(*AdminServer).GetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + AdminServiceName + "/GetConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*AdminServer).GetConfig(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func adminListCallHomeHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(*AdminServer).ListCallHome(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + AdminServiceName + "/ListCallHome",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*AdminServer).ListCallHome(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func adminListSubscriptionsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(*AdminServer).ListSubscriptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + AdminServiceName + "/ListSubscriptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*AdminServer).ListSubscriptions(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: AdminServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetConfig",
+			Handler:    adminGetConfigHandler,
+		},
+		{
+			MethodName: "ListCallHome",
+			Handler:    adminListCallHomeHandler,
+		},
+		{
+			MethodName: "ListSubscriptions",
+			Handler:    adminListSubscriptionsHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// AdminClient is the client of the admin gRPC service
+type AdminClient struct {
+	conn grpc.ClientConnInterface
+}
+
+// NewAdminClient creates a client of the admin gRPC service
+func NewAdminClient(conn grpc.ClientConnInterface) *AdminClient {
+	return &AdminClient{
+		conn: conn,
+	}
+}
+
+// GetConfig returns the effective configuration of onos-o1t
+func (c *AdminClient) GetConfig(ctx context.Context, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	err := c.conn.Invoke(ctx, "/"+AdminServiceName+"/GetConfig", new(emptypb.Empty), out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCallHome returns the status of the connections to the call home clients
+func (c *AdminClient) ListCallHome(ctx context.Context, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	err := c.conn.Invoke(ctx, "/"+AdminServiceName+"/ListCallHome", new(emptypb.Empty), out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSubscriptions returns the YANG-Push subscriptions of the sessions
+func (c *AdminClient) ListSubscriptions(ctx context.Context, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	err := c.conn.Invoke(ctx, "/"+AdminServiceName+"/ListSubscriptions", new(emptypb.Empty), out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -53,6 +53,9 @@ func (s *Server) List(ctx context.Context, request *o1tapi.GetRequest) (*o1tapi.
 	go func(ch chan *store.Entry, done chan bool, sessions map[string]*o1tapi.Session) {
 
 		for entry := range ch {
+			if _, ok := entry.Value.(*store.SessionValue); !ok {
+				continue
+			}
 			session := parseEntry(entry)
 			sessions[entry.Key.SessionID] = session
 		}
@@ -86,6 +89,9 @@ func (s *Server) Watch(request *o1tapi.GetRequest, server o1tapi.NetconfSessions
 		sessions := make(map[string]*o1tapi.Session)
 
 		entry := event.Value.(*store.Entry)
+		if _, ok := entry.Value.(*store.SessionValue); !ok {
+			continue
+		}
 		session := parseEntry(entry)
 		sessions[entry.Key.SessionID] = session
 
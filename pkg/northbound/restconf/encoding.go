@@ -0,0 +1,342 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/schema"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const (
+	MediaTypeYangDataJSON = "application/yang-data+json"
+	MediaTypeYangDataXML  = "application/yang-data+xml"
+
+	// RestconfNamespace is the namespace of the ietf-restconf module
+	RestconfNamespace = "urn:ietf:params:xml:ns:yang:ietf-restconf"
+
+	// maxBodySize bounds the size of the request bodies
+	maxBodySize = 4 << 20
+)
+
+// encoding is the JSON or XML encoding of the YANG data of the messages
+type encoding int
+
+const (
+	encodingJSON encoding = iota
+	encodingXML
+)
+
+func (e encoding) mediaType() string {
+	if e == encodingXML {
+		return MediaTypeYangDataXML
+	}
+	return MediaTypeYangDataJSON
+}
+
+// mediaTypeEncoding returns the encoding of a media type, either a YANG data media type or
+// the generic JSON and XML media types
+func mediaTypeEncoding(mediaType string) (encoding, bool) {
+	switch mediaType {
+	case MediaTypeYangDataJSON, "application/json":
+		return encodingJSON, true
+	case MediaTypeYangDataXML, "application/xml", "text/xml":
+		return encodingXML, true
+	}
+	return encodingJSON, false
+}
+
+// negotiate returns the encoding of the response from the Accept header of the request, the
+// first acceptable media type being used and JSON if the header accepts any media type
+func negotiate(r *http.Request) (encoding, bool) {
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return encodingJSON, true
+	}
+
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		if e, ok := mediaTypeEncoding(mediaType); ok {
+			return e, true
+		}
+		if mediaType == "*/*" || mediaType == "application/*" {
+			return encodingJSON, true
+		}
+	}
+	return encodingJSON, false
+}
+
+// httpError is a RESTCONF error replied with a given status code
+type httpError struct {
+	status   int
+	rpcError controller.RPCError
+}
+
+func (e *httpError) Error() string {
+	return e.rpcError.Message
+}
+
+func newHTTPError(status int, tag, message string) *httpError {
+	return &httpError{
+		status: status,
+		rpcError: controller.RPCError{
+			Type:     controller.ErrorTypeProtocol,
+			Tag:      tag,
+			Severity: controller.ErrorSeverityError,
+			Message:  message,
+		},
+	}
+}
+
+// errorStatus returns the status code of the error-tag (RFC 8040, section 7)
+func errorStatus(tag string) int {
+	switch tag {
+	case controller.ErrorTagInUse, controller.ErrorTagLockDenied, controller.ErrorTagResourceDenied,
+		controller.ErrorTagDataExists:
+		return http.StatusConflict
+	case controller.ErrorTagInvalidValue, controller.ErrorTagMalformedMessage:
+		return http.StatusBadRequest
+	case controller.ErrorTagDataMissing:
+		return http.StatusNotFound
+	case controller.ErrorTagAccessDenied:
+		return http.StatusForbidden
+	case controller.ErrorTagOperationNotSupported:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// toHTTPError returns the status code and the error of the reply of a failed request
+func toHTTPError(err error) *httpError {
+	switch e := err.(type) {
+	case *httpError:
+		return e
+	case controller.RPCError:
+		return &httpError{status: errorStatus(e.Tag), rpcError: e}
+	}
+	return newHTTPError(http.StatusInternalServerError, controller.ErrorTagOperationFailed, err.Error())
+}
+
+type jsonError struct {
+	Type    string `json:"error-type"`
+	Tag     string `json:"error-tag"`
+	Path    string `json:"error-path,omitempty"`
+	Message string `json:"error-message,omitempty"`
+}
+
+type xmlErrors struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-restconf errors"`
+	Errors  []xmlError
+}
+
+type xmlError struct {
+	XMLName xml.Name `xml:"error"`
+	Type    string   `xml:"error-type"`
+	Tag     string   `xml:"error-tag"`
+	Path    string   `xml:"error-path,omitempty"`
+	Message string   `xml:"error-message,omitempty"`
+	Info    string   `xml:",innerxml"`
+}
+
+// writeError replies the errors container of ietf-restconf
+func writeError(w http.ResponseWriter, e encoding, err error) {
+	httpErr := toHTTPError(err)
+	rpcErr := httpErr.rpcError
+
+	var body []byte
+	var marshalErr error
+	if e == encodingXML {
+		body, marshalErr = xml.Marshal(xmlErrors{
+			Errors: []xmlError{{
+				Type:    rpcErr.Type,
+				Tag:     rpcErr.Tag,
+				Path:    rpcErr.Path,
+				Message: rpcErr.Message,
+				Info:    rpcErr.Info,
+			}},
+		})
+	} else {
+		body, marshalErr = json.Marshal(map[string]interface{}{
+			"ietf-restconf:errors": map[string]interface{}{
+				"error": []jsonError{{
+					Type:    rpcErr.Type,
+					Tag:     rpcErr.Tag,
+					Path:    rpcErr.Path,
+					Message: rpcErr.Message,
+				}},
+			},
+		})
+	}
+	if marshalErr != nil {
+		log.Warn(marshalErr)
+		w.WriteHeader(httpErr.status)
+		return
+	}
+
+	writeBody(w, httpErr.status, e, body)
+}
+
+func writeBody(w http.ResponseWriter, status int, e encoding, body []byte) {
+	w.Header().Set("Content-Type", e.mediaType())
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	if err != nil {
+		log.Debugf("Error writing RESTCONF reply: %v", err)
+	}
+}
+
+// encodeNode encodes the value of a data node, in the namespace of its module
+func encodeNode(e encoding, module, name, namespace string, value []byte) ([]byte, error) {
+	if e == encodingXML {
+		return schema.JSONToXML(nil, &gnmi.Path{Elem: []*gnmi.PathElem{{Name: name}}}, value, namespace)
+	}
+	return json.Marshal(map[string]json.RawMessage{
+		module + ":" + name: value,
+	})
+}
+
+// dataNode is the data node of the body of a request
+type dataNode struct {
+	// Module is the module of the node, empty if the node is not qualified by its module
+	Module string
+	Name   string
+	// Value is the JSON value of the node, without the module prefixes of its members
+	Value []byte
+}
+
+// decodeBody decodes the data node of the body of the request, in the encoding of its
+// Content-Type
+func decodeBody(r *http.Request) (*dataNode, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, newHTTPError(http.StatusUnsupportedMediaType, controller.ErrorTagInvalidValue,
+			"the content type of the body is missing or invalid")
+	}
+	e, ok := mediaTypeEncoding(mediaType)
+	if !ok {
+		return nil, newHTTPError(http.StatusUnsupportedMediaType, controller.ErrorTagInvalidValue,
+			fmt.Sprintf("content type %s is not supported", mediaType))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, controller.ErrorTagMalformedMessage, err.Error())
+	}
+	if len(body) > maxBodySize {
+		return nil, newHTTPError(http.StatusRequestEntityTooLarge, "too-big", "the body is too large")
+	}
+
+	var node *dataNode
+	if e == encodingXML {
+		node, err = decodeXMLNode(body)
+	} else {
+		node, err = decodeJSONNode(body)
+	}
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, controller.ErrorTagMalformedMessage, err.Error())
+	}
+	return node, nil
+}
+
+func decodeJSONNode(body []byte) (*dataNode, error) {
+	var members map[string]json.RawMessage
+	err := json.Unmarshal(body, &members)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) != 1 {
+		return nil, fmt.Errorf("the body must contain one data node, found %d", len(members))
+	}
+
+	for name, raw := range members {
+		value, err := stripModules(raw)
+		if err != nil {
+			return nil, err
+		}
+		module, local := splitQualifiedName(name)
+		return &dataNode{Module: module, Name: local, Value: value}, nil
+	}
+	return nil, nil
+}
+
+func decodeXMLNode(body []byte) (*dataNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	var root xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("the body must contain one data node: %v", err)
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			root = element
+			break
+		}
+	}
+
+	jsonVal, err := schema.XMLToJSON(nil, string(body))
+	if err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	err = json.Unmarshal(jsonVal, &members)
+	if err != nil {
+		return nil, err
+	}
+
+	module := ""
+	if namespace, err := controller.ParseNamespace(root.Name.Space); err == nil {
+		module = namespace.Name
+	}
+	return &dataNode{Module: module, Name: root.Name.Local, Value: members[root.Name.Local]}, nil
+}
+
+// stripModules removes the module prefixes of the names of the members of the JSON value
+func stripModules(value []byte) ([]byte, error) {
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	err := decoder.Decode(&tree)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(stripTree(tree))
+}
+
+func stripTree(tree interface{}) interface{} {
+	switch node := tree.(type) {
+	case map[string]interface{}:
+		stripped := make(map[string]interface{}, len(node))
+		for name, child := range node {
+			_, local := splitQualifiedName(name)
+			stripped[local] = stripTree(child)
+		}
+		return stripped
+	case []interface{}:
+		for i, child := range node {
+			node[i] = stripTree(child)
+		}
+	}
+	return tree
+}
+
+// splitQualifiedName splits a name qualified by its module, as module:name
+func splitQualifiedName(name string) (string, string) {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
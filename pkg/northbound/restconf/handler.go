@@ -0,0 +1,344 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/metrics"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
+)
+
+const (
+	// RootPath is the RESTCONF root resource, discovered with the host-meta resource
+	RootPath     = "/restconf"
+	hostMetaPath = "/.well-known/host-meta"
+
+	// YangLibraryVersion is the revision of the ietf-yang-library module
+	YangLibraryVersion = "2019-01-04"
+
+	// RestconfMonitoringModule is the module of the capabilities of the server
+	RestconfMonitoringModule    = "ietf-restconf-monitoring"
+	RestconfMonitoringNamespace = "urn:ietf:params:xml:ns:yang:ietf-restconf-monitoring"
+
+	// targetParameter selects the target of a module implemented by several targets, the
+	// other query parameters of RFC 8040 are not supported
+	targetParameter = "target"
+
+	dataAllow = "GET, HEAD, PUT, PATCH, POST, DELETE, OPTIONS"
+)
+
+var (
+	// RestconfCapabilities are the RESTCONF capabilities advertised with the module capabilities
+	RestconfCapabilities = []string{
+		"urn:ietf:params:restconf:capability:defaults:1.0?basic-mode=explicit",
+	}
+)
+
+// ServeHTTP serves the RESTCONF resources, the data resources being handled by the O1
+// controller in the session of the connection of the client
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == hostMetaPath {
+		serveHostMeta(w, r)
+		return
+	}
+
+	e, ok := negotiate(r)
+	if !ok {
+		writeError(w, encodingJSON, newHTTPError(http.StatusNotAcceptable, controller.ErrorTagInvalidValue,
+			fmt.Sprintf("none of the media types %s is supported", r.Header.Get("Accept"))))
+		return
+	}
+
+	if r.URL.Path != RootPath && !strings.HasPrefix(r.URL.Path, RootPath+"/") {
+		writeError(w, e, newHTTPError(http.StatusNotFound, controller.ErrorTagInvalidValue, "unknown resource"))
+		return
+	}
+
+	user, ok := s.authenticate(r)
+	if !ok {
+		metrics.AuthFailure()
+		log.Warnf("RESTCONF client %s rejected", r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+		writeError(w, e, newHTTPError(http.StatusUnauthorized, controller.ErrorTagAccessDenied, "authentication failed"))
+		return
+	}
+
+	sessionID, err := s.session(r.Context(), r, user)
+	if err != nil {
+		writeError(w, e, err)
+		return
+	}
+
+	ctx, span := tracing.Start(r.Context(), "restconf.request",
+		tracing.SessionIDKey.String(sessionID),
+		tracing.UserKey.String(user),
+		tracing.TransportKey.String(TransportRESTCONF),
+		tracing.OperationKey.String(r.Method))
+
+	err = s.handle(ctx, w, r, sessionID, e)
+	if err != nil {
+		httpErr := toHTTPError(err)
+		tracing.SetAttributes(ctx, tracing.ErrorTagKey.String(httpErr.rpcError.Tag))
+		writeError(w, e, httpErr)
+	}
+	tracing.End(span, err)
+}
+
+func (s *server) handle(ctx context.Context, w http.ResponseWriter, r *http.Request, sessionID string, e encoding) error {
+	for parameter := range r.URL.Query() {
+		if parameter != targetParameter {
+			return newHTTPError(http.StatusBadRequest, controller.ErrorTagInvalidValue,
+				fmt.Sprintf("query parameter %s is not supported", parameter))
+		}
+	}
+
+	resourcePath := strings.TrimPrefix(r.URL.EscapedPath(), RootPath)
+	switch {
+	case resourcePath == "" || resourcePath == "/":
+		return serveStatic(w, r, e, "ietf-restconf", "restconf", RestconfNamespace, map[string]interface{}{
+			"data":                 map[string]interface{}{},
+			"operations":           map[string]interface{}{},
+			"yang-library-version": YangLibraryVersion,
+		})
+	case resourcePath == "/yang-library-version":
+		return serveStatic(w, r, e, "ietf-restconf", "yang-library-version", RestconfNamespace, YangLibraryVersion)
+	case resourcePath == "/operations":
+		return serveStatic(w, r, e, "ietf-restconf", "operations", RestconfNamespace, map[string]interface{}{})
+	case strings.HasPrefix(resourcePath, "/operations/"):
+		return newHTTPError(http.StatusNotFound, controller.ErrorTagInvalidValue, "no operation is supported")
+	case resourcePath == "/data" || strings.HasPrefix(resourcePath, "/data/"):
+		resource, err := parseResource(strings.TrimPrefix(resourcePath, "/data"))
+		if err != nil {
+			return err
+		}
+		resource.Target = r.URL.Query().Get(targetParameter)
+		if resource.Module == RestconfMonitoringModule {
+			return s.serveMonitoring(ctx, w, r, e, resource)
+		}
+		return s.serveData(ctx, w, r, sessionID, e, resource)
+	}
+	return newHTTPError(http.StatusNotFound, controller.ErrorTagInvalidValue, "unknown resource")
+}
+
+// serveData maps the methods on a data resource to the data operations of the controller
+func (s *server) serveData(ctx context.Context, w http.ResponseWriter, r *http.Request, sessionID string, e encoding, resource controller.DataResource) error {
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", dataAllow)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	if len(resource.Path) == 0 && r.Method != http.MethodPost {
+		return newHTTPError(http.StatusMethodNotAllowed, controller.ErrorTagOperationNotSupported,
+			fmt.Sprintf("method %s on the datastore resource is not supported, a data resource is required", r.Method))
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		value, err := s.controller.GetData(ctx, sessionID, resource)
+		if err != nil {
+			return err
+		}
+		node := resource.Path[len(resource.Path)-1]
+		body, err := encodeNode(e, resource.Module, node.Name, value.Namespace, value.Value)
+		if err != nil {
+			return err
+		}
+		writeBody(w, http.StatusOK, e, body)
+		return nil
+
+	case http.MethodPut, http.MethodPatch:
+		node, err := decodeResourceNode(r, resource)
+		if err != nil {
+			return err
+		}
+		operation := controller.DataReplace
+		if r.Method == http.MethodPatch {
+			operation = controller.DataMerge
+		}
+		created, err := s.controller.EditData(ctx, sessionID, operation, resource, node.Value)
+		if err != nil {
+			return err
+		}
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return nil
+
+	case http.MethodPost:
+		node, err := decodeBody(r)
+		if err != nil {
+			return err
+		}
+		location := r.URL.EscapedPath()
+		if len(resource.Path) == 0 {
+			if node.Module == "" {
+				return newHTTPError(http.StatusBadRequest, controller.ErrorTagInvalidValue,
+					fmt.Sprintf("the module of the top-level data node %s is missing", node.Name))
+			}
+			resource.Module = node.Module
+			location = strings.TrimSuffix(location, "/") + "/" + url.PathEscape(node.Module) + ":" + url.PathEscape(node.Name)
+		} else {
+			location = strings.TrimSuffix(location, "/") + "/" + url.PathEscape(node.Name)
+		}
+		resource.Path = append(resource.Path, controller.PathSegment{Name: node.Name})
+
+		_, err = s.controller.EditData(ctx, sessionID, controller.DataCreate, resource, node.Value)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusCreated)
+		return nil
+
+	case http.MethodDelete:
+		_, err := s.controller.EditData(ctx, sessionID, controller.DataDelete, resource, nil)
+		if err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	w.Header().Set("Allow", dataAllow)
+	return newHTTPError(http.StatusMethodNotAllowed, controller.ErrorTagOperationNotSupported,
+		fmt.Sprintf("method %s is not supported", r.Method))
+}
+
+// decodeResourceNode decodes the body of a request replacing or merging the data resource,
+// which must be the data node of the resource
+func decodeResourceNode(r *http.Request, resource controller.DataResource) (*dataNode, error) {
+	node, err := decodeBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	target := resource.Path[len(resource.Path)-1]
+	if node.Name != target.Name || (node.Module != "" && len(resource.Path) == 1 && node.Module != resource.Module) {
+		return nil, newHTTPError(http.StatusBadRequest, controller.ErrorTagInvalidValue,
+			fmt.Sprintf("the data node of the body must be %s", target.Name))
+	}
+	return node, nil
+}
+
+// serveMonitoring serves the capabilities of the restconf-state container of
+// ietf-restconf-monitoring, the module capabilities being those of the targets of onos-topo
+func (s *server) serveMonitoring(ctx context.Context, w http.ResponseWriter, r *http.Request, e encoding, resource controller.DataResource) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		return newHTTPError(http.StatusMethodNotAllowed, controller.ErrorTagOperationNotSupported,
+			fmt.Sprintf("%s is read-only", RestconfMonitoringModule))
+	}
+
+	modules, err := s.controller.ModuleCapabilities(ctx)
+	if err != nil {
+		return newHTTPError(http.StatusServiceUnavailable, controller.ErrorTagOperationFailed, err.Error())
+	}
+	capabilities := map[string]interface{}{
+		"capability": append(append([]string{}, RestconfCapabilities...), modules...),
+	}
+
+	names := make([]string, 0, len(resource.Path))
+	for _, segment := range resource.Path {
+		names = append(names, segment.Name)
+	}
+	switch strings.Join(names, "/") {
+	case "restconf-state":
+		return serveStatic(w, r, e, RestconfMonitoringModule, "restconf-state", RestconfMonitoringNamespace,
+			map[string]interface{}{"capabilities": capabilities})
+	case "restconf-state/capabilities":
+		return serveStatic(w, r, e, RestconfMonitoringModule, "capabilities", RestconfMonitoringNamespace, capabilities)
+	}
+	return newHTTPError(http.StatusNotFound, controller.ErrorTagInvalidValue,
+		fmt.Sprintf("unknown resource of %s", RestconfMonitoringModule))
+}
+
+// serveStatic replies the value of a read-only resource of the server
+func serveStatic(w http.ResponseWriter, r *http.Request, e encoding, module, name, namespace string, value interface{}) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		return newHTTPError(http.StatusMethodNotAllowed, controller.ErrorTagOperationNotSupported,
+			fmt.Sprintf("method %s is not supported", r.Method))
+	}
+
+	jsonVal, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	body, err := encodeNode(e, module, name, namespace, jsonVal)
+	if err != nil {
+		return err
+	}
+	writeBody(w, http.StatusOK, e, body)
+	return nil
+}
+
+// serveHostMeta replies the link to the RESTCONF root resource (RFC 6415)
+func serveHostMeta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xrd+xml")
+	w.WriteHeader(http.StatusOK)
+	_, err := fmt.Fprintf(w, "<XRD xmlns=\"http://docs.oasis-open.org/ns/xri/xrd-1.0\"><Link rel=\"restconf\" href=\"%s\"/></XRD>", RootPath)
+	if err != nil {
+		log.Debugf("Error writing host-meta: %v", err)
+	}
+}
+
+// parseResource parses the escaped path of a data resource identifier, relative to the
+// datastore resource, such as /module:container/list=key1,key2/leaf
+func parseResource(escapedPath string) (controller.DataResource, error) {
+	resource := controller.DataResource{}
+
+	escapedPath = strings.Trim(escapedPath, "/")
+	if escapedPath == "" {
+		return resource, nil
+	}
+
+	for i, escaped := range strings.Split(escapedPath, "/") {
+		segment := controller.PathSegment{}
+		identifier := escaped
+		if j := strings.Index(escaped, "="); j >= 0 {
+			identifier = escaped[:j]
+			for _, escapedKey := range strings.Split(escaped[j+1:], ",") {
+				key, err := url.PathUnescape(escapedKey)
+				if err != nil {
+					return resource, newHTTPError(http.StatusBadRequest, controller.ErrorTagInvalidValue,
+						fmt.Sprintf("invalid key %s: %v", escapedKey, err))
+				}
+				segment.Keys = append(segment.Keys, key)
+			}
+		}
+
+		identifier, err := url.PathUnescape(identifier)
+		if err != nil || identifier == "" {
+			return resource, newHTTPError(http.StatusBadRequest, controller.ErrorTagInvalidValue,
+				fmt.Sprintf("invalid data node %s", escaped))
+		}
+		module, name := splitQualifiedName(identifier)
+		if i == 0 {
+			if module == "" {
+				return resource, newHTTPError(http.StatusBadRequest, controller.ErrorTagInvalidValue,
+					fmt.Sprintf("the top-level data node %s must be qualified by its module", name))
+			}
+			resource.Module = module
+		}
+		segment.Name = name
+		resource.Path = append(resource.Path, segment)
+	}
+	return resource, nil
+}
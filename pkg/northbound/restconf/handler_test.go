@@ -0,0 +1,308 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const kpimonNamespace = "http://opennetworking.org/kpimon:ric:1.0.0"
+
+// fakeController replies the data operations with the value or the error of the test,
+// recording the edits
+type fakeController struct {
+	controller.O1Controller
+
+	mu      sync.Mutex
+	value   string
+	err     error
+	created bool
+	edits   []edit
+}
+
+type edit struct {
+	operation controller.DataOperation
+	resource  controller.DataResource
+	value     string
+}
+
+func (c *fakeController) OpenSession(ctx context.Context, info controller.SessionInfo, session controller.Session) error {
+	return nil
+}
+
+func (c *fakeController) EndSession(ctx context.Context, sessionID string, reason store.EndReason) error {
+	return nil
+}
+
+func (c *fakeController) ModuleCapabilities(ctx context.Context) ([]string, error) {
+	return []string{kpimonNamespace + "?module=ric&revision=1.0.0"}, nil
+}
+
+func (c *fakeController) GetData(ctx context.Context, sessionID string, resource controller.DataResource) (*controller.DataValue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &controller.DataValue{Namespace: kpimonNamespace, Value: []byte(c.value)}, nil
+}
+
+func (c *fakeController) EditData(ctx context.Context, sessionID string, operation controller.DataOperation, resource controller.DataResource, value []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.edits = append(c.edits, edit{operation: operation, resource: resource, value: string(value)})
+	return c.created, c.err
+}
+
+// startServer starts a RESTCONF server of the controller on a port of the loopback address,
+// returning its URL
+func startServer(t *testing.T, ctrl controller.O1Controller, opts ...Option) (Server, string) {
+	t.Helper()
+	opts = append([]Option{WithAddress("127.0.0.1:0")}, opts...)
+	srv, err := NewServer(ctrl, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = srv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Stop(ctx)
+	})
+	return srv, "http://" + srv.(*server).listener.Addr().String()
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   string
+}
+
+func request(t *testing.T, method, url string, header map[string]string, body string) response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response{status: resp.StatusCode, header: resp.Header, body: string(data)}
+}
+
+// errorTag returns the error-tag of the JSON errors container of the body
+func errorTag(t *testing.T, body string) string {
+	t.Helper()
+	var errs struct {
+		Errors struct {
+			Error []jsonError `json:"error"`
+		} `json:"ietf-restconf:errors"`
+	}
+	err := json.Unmarshal([]byte(body), &errs)
+	if err != nil || len(errs.Errors.Error) != 1 {
+		t.Fatalf("no errors container in %s", body)
+	}
+	return errs.Errors.Error[0].Tag
+}
+
+func TestStatusCodes(t *testing.T) {
+	jsonBody := map[string]string{"Content-Type": MediaTypeYangDataJSON}
+	resource := RootPath + "/data/ric:report_period"
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		body   string
+		err    error
+		// created is the reply of the edit of the controller
+		created bool
+		status  int
+		tag     string
+	}{
+		{name: "get", method: http.MethodGet, path: resource, status: http.StatusOK},
+		{name: "unknown resource", method: http.MethodGet, path: "/other", status: http.StatusNotFound, tag: controller.ErrorTagInvalidValue},
+		{name: "unknown operation", method: http.MethodPost, path: RootPath + "/operations/reset", status: http.StatusNotFound, tag: controller.ErrorTagInvalidValue},
+		{name: "unqualified top-level node", method: http.MethodGet, path: RootPath + "/data/report_period", status: http.StatusBadRequest, tag: controller.ErrorTagInvalidValue},
+		{name: "query parameter", method: http.MethodGet, path: resource + "?depth=1", status: http.StatusBadRequest, tag: controller.ErrorTagInvalidValue},
+		{name: "media type not acceptable", method: http.MethodGet, path: resource, header: map[string]string{"Accept": "text/plain"}, status: http.StatusNotAcceptable, tag: controller.ErrorTagInvalidValue},
+		{name: "get of the datastore", method: http.MethodGet, path: RootPath + "/data", status: http.StatusMethodNotAllowed, tag: controller.ErrorTagOperationNotSupported},
+		{name: "put of a read-only resource", method: http.MethodPut, path: RootPath + "/yang-library-version", status: http.StatusMethodNotAllowed, tag: controller.ErrorTagOperationNotSupported},
+		{name: "missing data", method: http.MethodGet, path: resource, err: controller.RPCError{Type: controller.ErrorTypeApplication, Tag: controller.ErrorTagDataMissing}, status: http.StatusNotFound, tag: controller.ErrorTagDataMissing},
+		{name: "access denied", method: http.MethodGet, path: resource, err: controller.RPCError{Type: controller.ErrorTypeApplication, Tag: controller.ErrorTagAccessDenied}, status: http.StatusForbidden, tag: controller.ErrorTagAccessDenied},
+		{name: "failed", method: http.MethodGet, path: resource, err: errors.New("onos-config unavailable"), status: http.StatusInternalServerError, tag: controller.ErrorTagOperationFailed},
+		{name: "put creating", method: http.MethodPut, path: resource, header: jsonBody, body: `{"ric:report_period":{"interval":7}}`, created: true, status: http.StatusCreated},
+		{name: "put replacing", method: http.MethodPut, path: resource, header: jsonBody, body: `{"ric:report_period":{"interval":7}}`, status: http.StatusNoContent},
+		{name: "put without content type", method: http.MethodPut, path: resource, body: `{"ric:report_period":{}}`, status: http.StatusUnsupportedMediaType, tag: controller.ErrorTagInvalidValue},
+		{name: "put of another node", method: http.MethodPut, path: resource, header: jsonBody, body: `{"ric:interval":7}`, status: http.StatusBadRequest, tag: controller.ErrorTagInvalidValue},
+		{name: "put of a malformed body", method: http.MethodPut, path: resource, header: jsonBody, body: `{"ric:report_period":`, status: http.StatusBadRequest, tag: controller.ErrorTagMalformedMessage},
+		{name: "post of an existing node", method: http.MethodPost, path: RootPath + "/data", header: jsonBody, body: `{"ric:report_period":{}}`, err: controller.RPCError{Type: controller.ErrorTypeApplication, Tag: controller.ErrorTagDataExists}, status: http.StatusConflict, tag: controller.ErrorTagDataExists},
+		{name: "patch of an unsupported node", method: http.MethodPatch, path: resource, header: jsonBody, body: `{"ric:report_period":{}}`, err: controller.RPCError{Type: controller.ErrorTypeProtocol, Tag: controller.ErrorTagOperationNotSupported}, status: http.StatusNotImplemented, tag: controller.ErrorTagOperationNotSupported},
+		{name: "delete", method: http.MethodDelete, path: resource, status: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := &fakeController{value: `{"interval":7}`, err: test.err, created: test.created}
+			_, url := startServer(t, ctrl, WithAnonymous(true))
+
+			resp := request(t, test.method, url+test.path, test.header, test.body)
+			if resp.status != test.status {
+				t.Fatalf("status %d, want %d: %s", resp.status, test.status, resp.body)
+			}
+			if test.tag == "" {
+				return
+			}
+			if got := resp.header.Get("Content-Type"); got != MediaTypeYangDataJSON {
+				t.Errorf("error of content type %s", got)
+			}
+			if got := errorTag(t, resp.body); got != test.tag {
+				t.Errorf("error-tag %s, want %s", got, test.tag)
+			}
+		})
+	}
+}
+
+func TestDataBodies(t *testing.T) {
+	ctrl := &fakeController{value: `{"interval":7}`}
+	_, url := startServer(t, ctrl, WithAnonymous(true))
+	resource := url + RootPath + "/data/ric:report_period"
+
+	resp := request(t, http.MethodGet, resource, nil, "")
+	if resp.body != `{"ric:report_period":{"interval":7}}` {
+		t.Errorf("JSON body %s", resp.body)
+	}
+	resp = request(t, http.MethodGet, resource, map[string]string{"Accept": MediaTypeYangDataXML}, "")
+	if got := resp.header.Get("Content-Type"); got != MediaTypeYangDataXML {
+		t.Errorf("XML body of content type %s", got)
+	}
+	if !strings.Contains(resp.body, `<report_period xmlns="`+kpimonNamespace+`"><interval>7</interval></report_period>`) {
+		t.Errorf("XML body %s", resp.body)
+	}
+
+	// the module prefixes of the body are removed and the location of a created node returned
+	resp = request(t, http.MethodPost, url+RootPath+"/data", map[string]string{"Content-Type": MediaTypeYangDataJSON},
+		`{"ric:report_period":{"ric:interval":9}}`)
+	if resp.status != http.StatusCreated {
+		t.Fatalf("status %d: %s", resp.status, resp.body)
+	}
+	if got := resp.header.Get("Location"); got != RootPath+"/data/ric:report_period" {
+		t.Errorf("location %s", got)
+	}
+	resp = request(t, http.MethodPut, resource, map[string]string{"Content-Type": MediaTypeYangDataXML},
+		`<report_period xmlns="`+kpimonNamespace+`"><interval>11</interval></report_period>`)
+	if resp.status != http.StatusNoContent {
+		t.Fatalf("status %d: %s", resp.status, resp.body)
+	}
+
+	want := []edit{
+		{operation: controller.DataCreate, value: `{"interval":9}`},
+		{operation: controller.DataReplace, value: `{"interval":"11"}`},
+	}
+	if len(ctrl.edits) != len(want) {
+		t.Fatalf("%d edits, want %d", len(ctrl.edits), len(want))
+	}
+	for i, e := range ctrl.edits {
+		if e.operation != want[i].operation || e.value != want[i].value {
+			t.Errorf("edit %d %s of %s, want %s of %s", i, e.operation, e.value, want[i].operation, want[i].value)
+		}
+		if e.resource.Module != "ric" || len(e.resource.Path) != 1 || e.resource.Path[0].Name != "report_period" {
+			t.Errorf("edit %d of resource %+v", i, e.resource)
+		}
+	}
+
+	// the module capabilities are those of the targets
+	resp = request(t, http.MethodGet, url+RootPath+"/data/"+RestconfMonitoringModule+":restconf-state/capabilities", nil, "")
+	if !strings.Contains(resp.body, RestconfCapabilities[0]) || !strings.Contains(resp.body, "module=ric") {
+		t.Errorf("capabilities %s", resp.body)
+	}
+
+	// the errors are encoded as the response
+	resp = request(t, http.MethodGet, url+"/other", map[string]string{"Accept": MediaTypeYangDataXML}, "")
+	if !strings.Contains(resp.body, `<errors xmlns="`+RestconfNamespace+`"><error><error-type>protocol</error-type><error-tag>invalid-value</error-tag>`) {
+		t.Errorf("XML errors %s", resp.body)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	_, err := NewServer(&fakeController{}, WithAddress("127.0.0.1:0"))
+	if err == nil {
+		t.Fatal("server without user nor anonymous clients")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, url := startServer(t, &fakeController{value: `{}`}, WithUsers(map[string]string{"alice": string(hash)}))
+	resource := url + RootPath + "/data/ric:report_period"
+
+	for _, credentials := range []map[string]string{
+		nil,
+		{"Authorization": "Basic YWxpY2U6d3Jvbmc="}, // alice:wrong
+	} {
+		resp := request(t, http.MethodGet, resource, credentials, "")
+		if resp.status != http.StatusUnauthorized {
+			t.Fatalf("status %d, want %d", resp.status, http.StatusUnauthorized)
+		}
+		if resp.header.Get("WWW-Authenticate") == "" {
+			t.Error("no challenge")
+		}
+		if got := errorTag(t, resp.body); got != controller.ErrorTagAccessDenied {
+			t.Errorf("error-tag %s", got)
+		}
+	}
+
+	alice := map[string]string{"Authorization": "Basic YWxpY2U6c2VjcmV0"} // alice:secret
+	if resp := request(t, http.MethodGet, resource, alice, ""); resp.status != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.status, resp.body)
+	}
+
+	// the anonymous clients are those without credentials, whose user is never trusted
+	srv.Reconfigure(WithAnonymous(true))
+	if resp := request(t, http.MethodGet, resource, nil, ""); resp.status != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.status, resp.body)
+	}
+	bob := map[string]string{"Authorization": "Basic Ym9iOnNlY3JldA=="} // bob:secret
+	if resp := request(t, http.MethodGet, resource, bob, ""); resp.status != http.StatusUnauthorized {
+		t.Fatalf("status %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+
+	// a new session is refused once the gate is closed
+	srv.Reconfigure(WithSessionGate(func() error {
+		return errors.New("onos-config unavailable")
+	}))
+	http.DefaultClient.CloseIdleConnections()
+	resp := request(t, http.MethodGet, resource, alice, "")
+	if resp.status != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want %d", resp.status, http.StatusServiceUnavailable)
+	}
+	if got := errorTag(t, resp.body); got != controller.ErrorTagResourceDenied {
+		t.Errorf("error-tag %s", got)
+	}
+}
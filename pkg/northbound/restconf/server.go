@@ -0,0 +1,382 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restconf
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var log = logging.GetLogger("restconf")
+
+const (
+	// TransportRESTCONF is the transport of the sessions of the RESTCONF clients in the store
+	TransportRESTCONF = "restconf"
+
+	// anonymousUser is the user of the requests without credentials, accepted when anonymous
+	// clients are allowed
+	anonymousUser = "anonymous"
+	realm         = "onos-o1t"
+
+	readHeaderTimeout = 10 * time.Second
+)
+
+// Server is a RESTCONF server (RFC 8040) exposing the data of the targets of the O1 controller
+type Server interface {
+	// Start listens on the address of the server and serves the RESTCONF clients
+	Start() error
+	// Stop stops listening, waits for the in-flight requests until the context is done and
+	// ends the sessions of the clients
+	Stop(ctx context.Context) error
+	// Reconfigure applies the options to the next requests
+	Reconfigure(opts ...Option)
+	// Check reports an error if the server is not listening
+	Check(ctx context.Context) error
+}
+
+// Option configures the RESTCONF server
+type Option func(*server)
+
+// WithAddress sets the host:port the server listens on
+func WithAddress(address string) Option {
+	return func(s *server) {
+		s.address = address
+	}
+}
+
+// WithTLS serves RESTCONF over HTTPS with the TLS configuration, the server serving HTTP
+// without it
+func WithTLS(config *tls.Config) Option {
+	return func(s *server) {
+		s.tlsConfig = config
+	}
+}
+
+// WithUsers sets the bcrypt password hashes of the users accepted with HTTP basic
+// authentication
+func WithUsers(users map[string]string) Option {
+	return func(s *server) {
+		s.passwords = make(map[string][]byte, len(users))
+		for user, hash := range users {
+			s.passwords[user] = []byte(hash)
+		}
+	}
+}
+
+// WithAnonymous accepts the requests without credentials as those of the anonymous user,
+// the requests with credentials being still checked against the users
+func WithAnonymous(allow bool) Option {
+	return func(s *server) {
+		s.anonymous = allow
+	}
+}
+
+// WithSessionGate sets the function checking a new session can be accepted
+func WithSessionGate(gate func() error) Option {
+	return func(s *server) {
+		s.sessionGate = gate
+	}
+}
+
+type server struct {
+	mu sync.RWMutex
+
+	controller  controller.O1Controller
+	address     string
+	tlsConfig   *tls.Config
+	passwords   map[string][]byte
+	anonymous   bool
+	sessionGate func() error
+
+	httpServer *http.Server
+	listener   net.Listener
+	// stopping is set while the server stops, the connections closed ending their sessions
+	// with the shutdown reason
+	stopping bool
+	// clients are the connections of the RESTCONF clients, each carrying a session
+	clients map[net.Conn]*client
+	// lastSessionID numbers the sessions of the server
+	lastSessionID uint32
+}
+
+// NewServer creates a RESTCONF server handling the requests with the O1 controller
+func NewServer(o1tControl controller.O1Controller, opts ...Option) (Server, error) {
+	s := &server{
+		controller: o1tControl,
+		passwords:  make(map[string][]byte),
+		clients:    make(map[net.Conn]*client),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.address == "" {
+		return nil, errors.New("restconf server has no address")
+	}
+	if len(s.passwords) == 0 && !s.anonymous {
+		return nil, errors.New("restconf server has no user and does not allow anonymous clients")
+	}
+	return s, nil
+}
+
+func (s *server) Reconfigure(opts ...Option) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, opt := range opts {
+		opt(s)
+	}
+}
+
+func (s *server) Start() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	httpServer := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ConnContext:       s.connContext,
+		ConnState:         s.connState,
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.httpServer = httpServer
+	s.mu.Unlock()
+
+	go func() {
+		err := httpServer.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Errorf("RESTCONF server stopped serving: %v", err)
+		}
+	}()
+
+	scheme := "https"
+	if s.tlsConfig == nil {
+		scheme = "http"
+	}
+	log.Infof("Started RESTCONF server on %s://%s", scheme, listener.Addr())
+	return nil
+}
+
+func (s *server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	httpServer := s.httpServer
+	s.httpServer = nil
+	s.listener = nil
+	s.stopping = httpServer != nil
+	s.mu.Unlock()
+
+	if httpServer == nil {
+		return nil
+	}
+
+	err := httpServer.Shutdown(ctx)
+	if err != nil {
+		// close the connections of the requests still in-flight
+		_ = httpServer.Close()
+	}
+
+	s.mu.Lock()
+	clients := make([]*client, 0, len(s.clients))
+	for conn, c := range s.clients {
+		clients = append(clients, c)
+		delete(s.clients, conn)
+	}
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		c.end(ctx, s.controller, store.EndReasonShutdown)
+	}
+
+	log.Info("Stopped RESTCONF server")
+	return err
+}
+
+func (s *server) Check(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.listener == nil {
+		return fmt.Errorf("not listening on %s", s.address)
+	}
+	return nil
+}
+
+// authenticate returns the user of the request, checking its password against the bcrypt
+// hash of the user. A request without credentials is the anonymous user's if anonymous
+// clients are allowed.
+func (s *server) authenticate(r *http.Request) (string, bool) {
+	user, password, ok := r.BasicAuth()
+
+	s.mu.RLock()
+	hash, found := s.passwords[user]
+	anonymous := s.anonymous
+	s.mu.RUnlock()
+
+	if !ok {
+		return anonymousUser, anonymous
+	}
+	if !found {
+		return "", false
+	}
+	return user, bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+type clientKey struct{}
+
+// client is the connection of a RESTCONF client and the session it carries, opened by its
+// first authenticated request
+type client struct {
+	conn net.Conn
+	// opening serializes the opening of the session by concurrent requests
+	opening sync.Mutex
+
+	mu        sync.Mutex
+	sessionID string
+	user      string
+	// released is set when the session is ended without closing the connection
+	released bool
+}
+
+// Close closes the connection of the session when the session is killed
+func (c *client) Close() error {
+	c.mu.Lock()
+	released := c.released
+	c.mu.Unlock()
+
+	if released {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// end ends the session of the client, if any
+func (c *client) end(ctx context.Context, o1tControl controller.O1Controller, reason store.EndReason) {
+	c.mu.Lock()
+	sessionID := c.sessionID
+	c.sessionID = ""
+	c.released = true
+	c.mu.Unlock()
+
+	if sessionID == "" {
+		return
+	}
+	err := o1tControl.EndSession(ctx, sessionID, reason)
+	if err != nil {
+		log.Warnf("Error ending RESTCONF session %s: %v", sessionID, err)
+	}
+}
+
+func (s *server) connContext(ctx context.Context, conn net.Conn) context.Context {
+	c := &client{conn: conn}
+
+	s.mu.Lock()
+	s.clients[conn] = c
+	s.mu.Unlock()
+
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+func (s *server) connState(conn net.Conn, state http.ConnState) {
+	if state != http.StateClosed && state != http.StateHijacked {
+		return
+	}
+
+	s.mu.Lock()
+	c, ok := s.clients[conn]
+	delete(s.clients, conn)
+	reason := store.EndReasonClosed
+	if s.stopping {
+		reason = store.EndReasonShutdown
+	}
+	s.mu.Unlock()
+
+	if ok {
+		c.end(context.Background(), s.controller, reason)
+	}
+}
+
+// session returns the session of the connection of the request, opening a new session for
+// its first request or when the user changes
+func (s *server) session(ctx context.Context, r *http.Request, user string) (string, error) {
+	c, ok := ctx.Value(clientKey{}).(*client)
+	if !ok {
+		return "", errors.New("request without connection")
+	}
+	c.opening.Lock()
+	defer c.opening.Unlock()
+
+	c.mu.Lock()
+	sessionID, sessionUser := c.sessionID, c.user
+	c.mu.Unlock()
+
+	if sessionID != "" && sessionUser == user {
+		return sessionID, nil
+	}
+	if sessionID != "" {
+		c.end(ctx, s.controller, store.EndReasonClosed)
+	}
+
+	s.mu.RLock()
+	gate := s.sessionGate
+	s.mu.RUnlock()
+	if gate != nil {
+		if err := gate(); err != nil {
+			log.Warnf("Rejecting new session of %s: %v", r.RemoteAddr, err)
+			return "", &httpError{
+				status: http.StatusServiceUnavailable,
+				rpcError: controller.RPCError{
+					Type:     controller.ErrorTypeProtocol,
+					Tag:      controller.ErrorTagResourceDenied,
+					Severity: controller.ErrorSeverityError,
+					Message:  err.Error(),
+				},
+			}
+		}
+	}
+
+	sessionID = TransportRESTCONF + "-" + strconv.FormatUint(uint64(atomic.AddUint32(&s.lastSessionID, 1)), 10)
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	c.mu.Lock()
+	c.sessionID = sessionID
+	c.user = user
+	c.released = false
+	c.mu.Unlock()
+
+	log.Infof("starting restconf session - user %s session %s from %s", user, sessionID, host)
+	err = s.controller.OpenSession(ctx, controller.SessionInfo{
+		SessionID:  sessionID,
+		User:       user,
+		SourceHost: host,
+		Transport:  TransportRESTCONF,
+	}, c)
+	if err != nil {
+		return "", err
+	}
+	return sessionID, nil
+}
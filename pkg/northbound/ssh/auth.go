@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+// LoadHostKeys reads the host private keys from PEM files
+func LoadHostKeys(paths []string) ([]ssh.Signer, error) {
+	signers := []ssh.Signer{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading host key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing host key %s: %v", path, err)
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+// LoadAuthorizedKeys reads the public keys of a file in the authorized_keys format
+func LoadAuthorizedKeys(path string) ([]ssh.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading authorized keys: %v", err)
+	}
+
+	keys := []ssh.PublicKey{}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing authorized keys %s:%d: %v", path, i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// authorizePublicKey checks the public key is authorized, any key being authorized if no
+// authorized keys are configured and no user has a password
+func (srv *sshServer) authorizePublicKey(key ssh.PublicKey) bool {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+
+	if srv.authorizedKeys == nil {
+		return len(srv.passwords) == 0
+	}
+	return srv.authorizedKeys[string(key.Marshal())]
+}
+
+// authorizePassword checks the password of the user against its bcrypt hash
+func (srv *sshServer) authorizePassword(user, password string) bool {
+	srv.mu.RLock()
+	hash, ok := srv.passwords[user]
+	srv.mu.RUnlock()
+
+	if !ok {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
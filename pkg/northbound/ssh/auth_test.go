@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLoadAuthorizedKeys(t *testing.T) {
+	first := string(ssh.MarshalAuthorizedKey(newSigner(t).PublicKey()))
+	second := string(ssh.MarshalAuthorizedKey(newSigner(t).PublicKey()))
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+
+	err := os.WriteFile(path, []byte("# alice\n"+first+"\n"+second), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadAuthorizedKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("%d keys loaded, want 2", len(keys))
+	}
+
+	// the error names the line of the invalid key, comments and blank lines included
+	err = os.WriteFile(path, []byte("# alice\n"+first+"\nssh-ed25519 invalid\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadAuthorizedKeys(path)
+	if err == nil || !strings.Contains(err.Error(), path+":4:") {
+		t.Errorf("error %v, want an error of line 4", err)
+	}
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/store"
+)
+
+const (
+	// CallHomePort is the port assigned by IANA to NETCONF Call Home over SSH (RFC 8071)
+	CallHomePort = 4334
+
+	// CallHomePersistent keeps a connection to the client at all times, reconnecting when it
+	// is closed
+	CallHomePersistent = "persistent"
+	// CallHomePeriodic connects to the client once per period, the connection being closed when
+	// its last NETCONF session ends
+	CallHomePeriodic = "periodic"
+
+	DefaultCallHomeInitialBackoff = 1 * time.Second
+	DefaultCallHomeMaxBackoff     = 2 * time.Minute
+
+	callHomeDialTimeout = 10 * time.Second
+)
+
+// CallHomeClient is a NETCONF client the server connects to with SSH Call Home, the server
+// being the SSH server of the established connection
+type CallHomeClient struct {
+	Name string
+	// Address is the host:port of the client
+	Address string
+	// Mode is CallHomePersistent or CallHomePeriodic, Period the time between the connections
+	// of the periodic mode
+	Mode   string
+	Period time.Duration
+	// InitialBackoff is the delay before connecting again after a failed attempt or a closed
+	// persistent connection, doubled after each consecutive failure up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// AuthMethods are the authentication methods accepted from the client, all of them if empty
+	AuthMethods []string
+}
+
+// WithCallHome sets the clients the server connects to with SSH Call Home
+func WithCallHome(clients ...CallHomeClient) Option {
+	return func(srv *sshServer) {
+		srv.callHomeClients = clients
+	}
+}
+
+// startCallHome connects to the call home clients until the server stops
+func (srv *sshServer) startCallHome() {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv.mu.Lock()
+	srv.stopCallHome = cancel
+	srv.mu.Unlock()
+
+	for _, client := range srv.callHomeClients {
+		if client.InitialBackoff <= 0 {
+			client.InitialBackoff = DefaultCallHomeInitialBackoff
+		}
+		if client.MaxBackoff < client.InitialBackoff {
+			client.MaxBackoff = DefaultCallHomeMaxBackoff
+			if client.MaxBackoff < client.InitialBackoff {
+				client.MaxBackoff = client.InitialBackoff
+			}
+		}
+		srv.callHomeWg.Add(1)
+		go srv.callHome(ctx, client)
+	}
+}
+
+// callHome connects to the client and serves the connection, then connects again after the
+// backoff or the period of the client, until the context is done
+func (srv *sshServer) callHome(ctx context.Context, client CallHomeClient) {
+	defer srv.callHomeWg.Done()
+
+	listener := Listener{
+		Address:     client.Address,
+		AuthMethods: client.AuthMethods,
+	}
+	status := store.CallHomeValue{
+		Address: client.Address,
+		Mode:    client.Mode,
+	}
+	dialer := &net.Dialer{
+		Timeout: callHomeDialTimeout,
+	}
+	backoff := client.InitialBackoff
+
+	for {
+		status.State = store.CallHomeConnecting
+		status.NextAttempt = 0
+		srv.reportCallHome(client.Name, status)
+
+		var delay time.Duration
+		conn, err := dialer.DialContext(ctx, "tcp", client.Address)
+		if ctx.Err() != nil {
+			if conn != nil {
+				conn.Close()
+			}
+			break
+		}
+
+		if err != nil {
+			log.Warnf("Call home to %s at %s failed: %v", client.Name, client.Address, err)
+			status.Failures++
+			status.LastError = err.Error()
+			delay = backoff
+			backoff *= 2
+			if backoff > client.MaxBackoff {
+				backoff = client.MaxBackoff
+			}
+		} else {
+			log.Infof("Call home connection to %s at %s established", client.Name, client.Address)
+			status.State = store.CallHomeConnected
+			status.Failures = 0
+			status.LastError = ""
+			status.Connections++
+			status.LastConnected = uint64(time.Now().UnixNano())
+			srv.reportCallHome(client.Name, status)
+
+			srv.serveConn(listener, conn, client.Mode == CallHomePeriodic)
+			log.Infof("Call home connection to %s at %s closed", client.Name, client.Address)
+
+			backoff = client.InitialBackoff
+			delay = client.InitialBackoff
+			if client.Mode == CallHomePeriodic {
+				delay = client.Period
+			}
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+		status.State = store.CallHomeWaiting
+		status.NextAttempt = uint64(time.Now().Add(delay).UnixNano())
+		srv.reportCallHome(client.Name, status)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	status.State = store.CallHomeStopped
+	status.NextAttempt = 0
+	srv.reportCallHome(client.Name, status)
+}
+
+func (srv *sshServer) reportCallHome(name string, status store.CallHomeValue) {
+	err := srv.controller.UpdateCallHome(context.Background(), name, status)
+	if err != nil {
+		log.Warnf("Error storing the call home status of %s: %v", name, err)
+	}
+}
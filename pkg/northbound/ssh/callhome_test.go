@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"golang.org/x/crypto/ssh"
+)
+
+// watchCallHome returns the statuses of the call home client stored from now on
+func watchCallHome(t *testing.T, sessionStore store.Store, name string) <-chan store.CallHomeValue {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	events := make(chan store.Event)
+	err := sessionStore.Watch(ctx, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(chan store.CallHomeValue, 100)
+	go func() {
+		for event := range events {
+			if event.Key.(store.Key).CallHome != name {
+				continue
+			}
+			statuses <- *event.Value.(*store.Entry).Value.(*store.CallHomeValue)
+		}
+	}()
+	return statuses
+}
+
+// nextCallHome returns the next status of the call home client in the state
+func nextCallHome(t *testing.T, statuses <-chan store.CallHomeValue, state store.CallHomeState) store.CallHomeValue {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case status := <-statuses:
+			if status.State == state {
+				return status
+			}
+		case <-timeout:
+			t.Fatalf("call home status not %s", state)
+		}
+	}
+}
+
+// acceptCallHome accepts the call home connection of the server, returning the SSH client of
+// the connection
+func acceptCallHome(t *testing.T, listener net.Listener) *ssh.Client {
+	t.Helper()
+	_ = listener.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("no call home connection: %v", err)
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, conn.RemoteAddr().String(), clientConfig(t))
+	if err != nil {
+		conn.Close()
+		t.Fatal(err)
+	}
+	client := ssh.NewClient(clientConn, chans, reqs)
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
+
+func closeSession(t *testing.T, ch *netconfChannel) {
+	t.Helper()
+	err := send(ch.in, `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.1"><close-session/></rpc>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := receive(ch.out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallHomeBackoff(t *testing.T) {
+	// the address of a client not listening
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	srv, sessionStore := newTestServer(t, WithCallHome(CallHomeClient{
+		Name:           "smo",
+		Address:        address,
+		Mode:           CallHomePersistent,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     40 * time.Millisecond,
+	}))
+	statuses := watchCallHome(t, sessionStore, "smo")
+	err = srv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the delays are 10, 20, 40 then 40ms, the next attempts spaced by the delay and the
+	// time of the failed attempt
+	var attempts []store.CallHomeValue
+	for i := 0; i < 5; i++ {
+		nextCallHome(t, statuses, store.CallHomeConnecting)
+		status := nextCallHome(t, statuses, store.CallHomeWaiting)
+		if status.Failures != i+1 {
+			t.Errorf("attempt %d counted %d failures", i+1, status.Failures)
+		}
+		if status.LastError == "" {
+			t.Errorf("no error of failed attempt %d", i+1)
+		}
+		attempts = append(attempts, status)
+	}
+	for i, delay := range []time.Duration{20, 40, 40, 40} {
+		delay *= time.Millisecond
+		spacing := time.Duration(attempts[i+1].NextAttempt - attempts[i].NextAttempt)
+		if spacing < delay {
+			t.Errorf("attempt %d after %s, backoff %s", i+3, spacing, delay)
+		}
+		if spacing >= 4*delay {
+			t.Errorf("attempt %d after %s, backoff %s not bounded", i+3, spacing, delay)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = srv.Stop(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := nextCallHome(t, statuses, store.CallHomeStopped)
+	if status.NextAttempt != 0 {
+		t.Error("next attempt of a stopped call home")
+	}
+}
+
+func TestCallHomePersistent(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	srv, sessionStore := newTestServer(t, WithCallHome(CallHomeClient{
+		Name:           "smo",
+		Address:        listener.Addr().String(),
+		Mode:           CallHomePersistent,
+		InitialBackoff: 10 * time.Millisecond,
+	}))
+	statuses := watchCallHome(t, sessionStore, "smo")
+	err = srv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := acceptCallHome(t, listener)
+	status := nextCallHome(t, statuses, store.CallHomeConnected)
+	if status.Connections != 1 || status.Failures != 0 {
+		t.Errorf("first connection counted %d connections and %d failures", status.Connections, status.Failures)
+	}
+
+	// the connection is kept once its last session ended
+	ch, _ := openNetconf(t, client)
+	closeSession(t, ch)
+	_, _ = openNetconf(t, client)
+
+	// the client closing the connection, the server connects again
+	client.Close()
+	status = nextCallHome(t, statuses, store.CallHomeWaiting)
+	if status.NextAttempt == 0 {
+		t.Error("no next attempt after the connection closed")
+	}
+	client = acceptCallHome(t, listener)
+	status = nextCallHome(t, statuses, store.CallHomeConnected)
+	if status.Connections != 2 {
+		t.Errorf("second connection counted %d connections", status.Connections)
+	}
+	_, _ = openNetconf(t, client)
+}
+
+func TestCallHomePeriodic(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	period := 200 * time.Millisecond
+	srv, sessionStore := newTestServer(t, WithCallHome(CallHomeClient{
+		Name:           "smo",
+		Address:        listener.Addr().String(),
+		Mode:           CallHomePeriodic,
+		Period:         period,
+		InitialBackoff: 10 * time.Millisecond,
+	}))
+	statuses := watchCallHome(t, sessionStore, "smo")
+	err = srv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := acceptCallHome(t, listener)
+	nextCallHome(t, statuses, store.CallHomeConnected)
+	first, _ := openNetconf(t, client)
+	second, _ := openNetconf(t, client)
+
+	// the connection is closed by the server when its last session ends
+	closeSession(t, first)
+	closeSession(t, second)
+	closed := make(chan error, 1)
+	go func() {
+		closed <- client.Wait()
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed after its last session ended")
+	}
+	closedAt := time.Now()
+
+	// the server connects again after the period
+	status := nextCallHome(t, statuses, store.CallHomeWaiting)
+	if time.Duration(status.NextAttempt-status.LastConnected) < period {
+		t.Errorf("next attempt within the period of the last connection")
+	}
+	client = acceptCallHome(t, listener)
+	if elapsed := time.Since(closedAt); elapsed < period/2 {
+		t.Errorf("connected again after %s, period %s", elapsed, period)
+	}
+	status = nextCallHome(t, statuses, store.CallHomeConnected)
+	if status.Connections != 2 {
+		t.Errorf("second connection counted %d connections", status.Connections)
+	}
+	_, _ = openNetconf(t, client)
+}
@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	// the hash functions of the fingerprints
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// The map types of the cert-to-name rules, as defined by the ietf-x509-cert-to-name module (RFC 7407)
+const (
+	MapTypeSpecified     = "specified"
+	MapTypeSANRFC822Name = "san-rfc822-name"
+	MapTypeSANDNSName    = "san-dns-name"
+	MapTypeSANIPAddress  = "san-ip-address"
+	MapTypeSANAny        = "san-any"
+	MapTypeCommonName    = "common-name"
+)
+
+// fingerprintHashes are the hash functions of the fingerprints by their TLS HashAlgorithm value
+var fingerprintHashes = map[byte]crypto.Hash{
+	2: crypto.SHA1,
+	4: crypto.SHA256,
+	5: crypto.SHA384,
+	6: crypto.SHA512,
+}
+
+// Fingerprint is the tls-fingerprint of a certificate: the TLS HashAlgorithm value of the hash
+// function followed by the hash of the DER encoded certificate
+type Fingerprint []byte
+
+// ParseFingerprint parses a fingerprint written as hex bytes separated by colons, such as
+// 04:a3:...:5f for a SHA-256 fingerprint
+func ParseFingerprint(fingerprint string) (Fingerprint, error) {
+	data, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprint %s: %v", fingerprint, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid fingerprint %s: empty", fingerprint)
+	}
+	hash, ok := fingerprintHashes[data[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid fingerprint %s: unsupported hash algorithm %d, expected 2 (sha1), 4 (sha256), 5 (sha384) or 6 (sha512)", fingerprint, data[0])
+	}
+	if len(data) != 1+hash.Size() {
+		return nil, fmt.Errorf("invalid fingerprint %s: %d bytes of %s hash, expected %d", fingerprint, len(data)-1, hash, hash.Size())
+	}
+	return Fingerprint(data), nil
+}
+
+// Matches checks the fingerprint is the one of the certificate
+func (f Fingerprint) Matches(cert *x509.Certificate) bool {
+	if len(f) == 0 {
+		return false
+	}
+	hash := fingerprintHashes[f[0]].New()
+	hash.Write(cert.Raw)
+	return bytes.Equal(f[1:], hash.Sum(nil))
+}
+
+// CertToName is a rule mapping a client certificate to a NETCONF username
+type CertToName struct {
+	// Fingerprint selects the rule when it is the one of the client certificate or of a CA
+	// certificate of its chain, an empty fingerprint selecting it for any certificate
+	Fingerprint Fingerprint
+	// MapType is the way the username is derived from the client certificate, Name the
+	// username of the MapTypeSpecified map type
+	MapType string
+	Name    string
+}
+
+// ErrNoCertToName is returned when no rule maps the client certificate to a username
+var ErrNoCertToName = errors.New("no cert-to-name rule maps the client certificate to a username")
+
+// mapCertToName returns the username of the client certificate given by the first rule of
+// the list selected by the verified chain of the certificate that can derive a username
+func mapCertToName(rules []CertToName, chain []*x509.Certificate) (string, error) {
+	if len(chain) == 0 {
+		return "", errors.New("no client certificate")
+	}
+
+	for _, rule := range rules {
+		if !rule.selects(chain) {
+			continue
+		}
+		if name := rule.name(chain[0]); name != "" {
+			return name, nil
+		}
+	}
+	return "", ErrNoCertToName
+}
+
+func (r CertToName) selects(chain []*x509.Certificate) bool {
+	if len(r.Fingerprint) == 0 {
+		return true
+	}
+	for _, cert := range chain {
+		if r.Fingerprint.Matches(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// name derives the username from the certificate, empty if the certificate lacks the field
+// of the map type
+func (r CertToName) name(cert *x509.Certificate) string {
+	switch r.MapType {
+	case MapTypeSpecified:
+		return r.Name
+	case MapTypeSANRFC822Name:
+		return rfc822Name(cert)
+	case MapTypeSANDNSName:
+		return dnsName(cert)
+	case MapTypeSANIPAddress:
+		return ipAddress(cert)
+	case MapTypeSANAny:
+		if name := rfc822Name(cert); name != "" {
+			return name
+		}
+		if name := dnsName(cert); name != "" {
+			return name
+		}
+		return ipAddress(cert)
+	case MapTypeCommonName:
+		return cert.Subject.CommonName
+	}
+	return ""
+}
+
+// rfc822Name returns the first email address of the certificate, with its domain in lowercase
+func rfc822Name(cert *x509.Certificate) string {
+	if len(cert.EmailAddresses) == 0 {
+		return ""
+	}
+	address := cert.EmailAddresses[0]
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		address = address[:i] + strings.ToLower(address[i:])
+	}
+	return address
+}
+
+// dnsName returns the first DNS name of the certificate, in lowercase
+func dnsName(cert *x509.Certificate) string {
+	if len(cert.DNSNames) == 0 {
+		return ""
+	}
+	return strings.ToLower(cert.DNSNames[0])
+}
+
+// ipAddress returns the first IP address of the certificate, in dotted decimal for IPv4 and as
+// 32 lowercase hex digits for IPv6
+func ipAddress(cert *x509.Certificate) string {
+	if len(cert.IPAddresses) == 0 {
+		return ""
+	}
+	ip := cert.IPAddresses[0]
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String()
+	}
+	return hex.EncodeToString(ip.To16())
+}
@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCertificate creates a certificate of the template signed by the parent, self-signed
+// without parent
+func newCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	return newCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+}
+
+// sha256Fingerprint returns the SHA-256 fingerprint of the certificate as written in the
+// configuration
+func sha256Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := []string{"04"}
+	for _, b := range sum {
+		parts = append(parts, fmt.Sprintf("%02x", b))
+	}
+	return strings.Join(parts, ":")
+}
+
+func mustParseFingerprint(t *testing.T, fingerprint string) Fingerprint {
+	t.Helper()
+	f, err := ParseFingerprint(fingerprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestParseFingerprint(t *testing.T) {
+	sha1 := "02:" + strings.Repeat("ab:", 19) + "ab"
+	tests := []struct {
+		name        string
+		fingerprint string
+		err         string
+	}{
+		{name: "sha1", fingerprint: sha1},
+		{name: "sha256", fingerprint: "04" + strings.Repeat(":0F", 32)},
+		{name: "not hex", fingerprint: "04:zz", err: "invalid byte"},
+		{name: "empty", fingerprint: "", err: "empty"},
+		{name: "md5", fingerprint: "01" + strings.Repeat(":00", 16), err: "unsupported hash algorithm 1"},
+		{name: "truncated", fingerprint: "04" + strings.Repeat(":00", 20), err: "20 bytes of SHA-256 hash, expected 32"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseFingerprint(test.fingerprint)
+			if test.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestMapCertToName(t *testing.T) {
+	ca, caKey := newCA(t, "ca")
+	otherCA, _ := newCA(t, "other-ca")
+	client, _ := newCertificate(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "alice"},
+		EmailAddresses: []string{"Alice@Example.COM"},
+		DNSNames:       []string{"Client.Example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("192.0.2.1")},
+	}, ca, caKey)
+	ipv6Client, _ := newCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "bob"},
+		DNSNames:    []string{"bob.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("2001:db8::1")},
+	}, ca, caKey)
+	bareClient, _ := newCertificate(t, &x509.Certificate{}, ca, caKey)
+
+	clientFingerprint := mustParseFingerprint(t, sha256Fingerprint(client))
+	caFingerprint := mustParseFingerprint(t, sha256Fingerprint(ca))
+	otherFingerprint := mustParseFingerprint(t, sha256Fingerprint(otherCA))
+
+	tests := []struct {
+		name  string
+		rules []CertToName
+		chain []*x509.Certificate
+		user  string
+		err   error
+	}{
+		{
+			name:  "specified by the client fingerprint",
+			rules: []CertToName{{Fingerprint: clientFingerprint, MapType: MapTypeSpecified, Name: "admin"}},
+			chain: []*x509.Certificate{client, ca},
+			user:  "admin",
+		},
+		{
+			name:  "specified by the CA fingerprint",
+			rules: []CertToName{{Fingerprint: caFingerprint, MapType: MapTypeSpecified, Name: "operator"}},
+			chain: []*x509.Certificate{client, ca},
+			user:  "operator",
+		},
+		{
+			name: "fingerprint of another CA",
+			rules: []CertToName{
+				{Fingerprint: otherFingerprint, MapType: MapTypeSpecified, Name: "admin"},
+				{Fingerprint: caFingerprint, MapType: MapTypeCommonName},
+			},
+			chain: []*x509.Certificate{client, ca},
+			user:  "alice",
+		},
+		{
+			name:  "san-rfc822-name",
+			rules: []CertToName{{MapType: MapTypeSANRFC822Name}},
+			chain: []*x509.Certificate{client, ca},
+			user:  "Alice@example.com",
+		},
+		{
+			name:  "san-dns-name",
+			rules: []CertToName{{MapType: MapTypeSANDNSName}},
+			chain: []*x509.Certificate{client, ca},
+			user:  "client.example.com",
+		},
+		{
+			name:  "san-ip-address of IPv4",
+			rules: []CertToName{{MapType: MapTypeSANIPAddress}},
+			chain: []*x509.Certificate{client, ca},
+			user:  "192.0.2.1",
+		},
+		{
+			name:  "san-ip-address of IPv6",
+			rules: []CertToName{{MapType: MapTypeSANIPAddress}},
+			chain: []*x509.Certificate{ipv6Client, ca},
+			user:  "20010db8000000000000000000000001",
+		},
+		{
+			name:  "san-any of an email address",
+			rules: []CertToName{{MapType: MapTypeSANAny}},
+			chain: []*x509.Certificate{client, ca},
+			user:  "Alice@example.com",
+		},
+		{
+			name:  "san-any without email address",
+			rules: []CertToName{{MapType: MapTypeSANAny}},
+			chain: []*x509.Certificate{ipv6Client, ca},
+			user:  "bob.example.com",
+		},
+		{
+			name:  "common-name",
+			rules: []CertToName{{Fingerprint: caFingerprint, MapType: MapTypeCommonName}},
+			chain: []*x509.Certificate{client, ca},
+			user:  "alice",
+		},
+		{
+			name: "field of the map type missing",
+			rules: []CertToName{
+				{MapType: MapTypeSANRFC822Name},
+				{MapType: MapTypeCommonName},
+				{MapType: MapTypeSpecified, Name: "guest"},
+			},
+			chain: []*x509.Certificate{bareClient, ca},
+			user:  "guest",
+		},
+		{
+			name:  "no rule",
+			rules: []CertToName{{Fingerprint: otherFingerprint, MapType: MapTypeCommonName}},
+			chain: []*x509.Certificate{client, ca},
+			err:   ErrNoCertToName,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user, err := mapCertToName(test.rules, test.chain)
+			if err != test.err {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			if user != test.user {
+				t.Errorf("user %q, want %q", user, test.user)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+const (
+	// NetconfPort is the port assigned by IANA to NETCONF over SSH (RFC 6242)
+	NetconfPort = 830
+	// privilegedPorts are the ports below which binding requires the CAP_NET_BIND_SERVICE
+	// capability
+	privilegedPorts = 1024
+
+	AuthPublicKey = "publickey"
+	AuthPassword  = "password"
+)
+
+// Listener is an address the Netconf SSH server listens on
+type Listener struct {
+	// Address is the host:port listened on, an empty host listening on all the IPv4 and IPv6
+	// addresses
+	Address string
+	// NetListener is a pre-opened listener served instead of listening on Address
+	NetListener net.Listener
+	// AuthMethods are the authentication methods accepted on the listener, AuthPublicKey or
+	// AuthPassword, all of them if empty
+	AuthMethods []string
+	// TLS makes the listener serve NETCONF over TLS, the client being authenticated by its
+	// certificate
+	TLS *TLSConfig
+}
+
+// name identifies the listener in the logs and health checks
+func (l Listener) name() string {
+	if l.NetListener != nil {
+		return l.NetListener.Addr().String()
+	}
+	return l.Address
+}
+
+func (l Listener) listen() (net.Listener, error) {
+	if l.NetListener != nil {
+		return l.NetListener, nil
+	}
+	netListener, err := net.Listen("tcp", l.Address)
+	if errors.Is(err, syscall.EACCES) {
+		_, port, _ := net.SplitHostPort(l.Address)
+		if number, _ := strconv.Atoi(port); number > 0 && number < privilegedPorts {
+			return nil, fmt.Errorf("listening on %s requires the CAP_NET_BIND_SERVICE capability: %v", l.Address, err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %v", l.Address, err)
+	}
+	return netListener, nil
+}
+
+// accepts checks the authentication method is accepted on the listener
+func (l Listener) accepts(method string) bool {
+	if len(l.AuthMethods) == 0 {
+		return true
+	}
+	for _, accepted := range l.AuthMethods {
+		if accepted == method {
+			return true
+		}
+	}
+	return false
+}
@@ -6,14 +6,29 @@ package ssh
 
 import (
 	"context"
+	"errors"
 	"io"
+	"sync"
 	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/config"
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
 )
 
 var (
-	netconfTimeout = 1
+	// errSend is returned when the reply of a rpc could not be sent
+	errSend = errors.New("error sending rpc reply")
+	// errNotificationOverflow is returned when a notification is sent to a session whose client
+	// does not read the previous ones
+	errNotificationOverflow = errors.New("too many notifications not written")
 )
 
+// maxQueuedNotifications is the number of notifications queued for a session beyond which
+// the session is closed
+var maxQueuedNotifications = 1000
+
 type NetconfServer interface {
 	Serve() error
 }
@@ -22,15 +37,38 @@ type netconfSubsystem struct {
 	ctx Context
 	srv SSHServer
 	*serverConn
+
+	// sessionID is the NETCONF session-id, distinct for each netconf subsystem channel
+	// of the SSH connection identified by ctx.SessionID()
+	sessionID string
+
+	rpcTimeout  time.Duration
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+
+	mu sync.Mutex
+	// busy is set while a rpc is handled, closing when the session must be closed once
+	// the reply of that rpc is sent
+	busy    bool
+	closing bool
+	closed  bool
+	// queue holds the notifications not written yet, written in order by the notification
+	// writer while no rpc is handled so that a notification sent while a rpc is handled
+	// follows its reply. wake is signaled when the writer may write, drained closed once
+	// the queue is empty and no notification is being written.
+	queue   [][]byte
+	writing bool
+	wake    chan struct{}
+	drained chan struct{}
 }
 
 func Hello(n *netconfSubsystem) error {
 	helloRequest := "<request-hello"
 
-	netconfCtx, cancel := context.WithTimeout(context.Background(), time.Duration(netconfTimeout)*time.Second)
+	netconfCtx, cancel := context.WithTimeout(context.Background(), n.rpcTimeout)
 	defer cancel()
 
-	hello, err := n.srv.Handle(netconfCtx, n.ctx.SessionID(), []byte(helloRequest))
+	hello, err := n.srv.Handle(netconfCtx, n.sessionID, []byte(helloRequest))
 	if err != nil {
 		log.Errorf("error create hello request: %v", err)
 		return err
@@ -45,16 +83,231 @@ func Hello(n *netconfSubsystem) error {
 	return nil
 }
 
+// Close closes the transport of the session, deferring it until the reply is sent if
+// a rpc is being handled
+func (n *netconfSubsystem) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.busy {
+		n.closing = true
+		return nil
+	}
+	return n.closeLocked()
+}
+
+func (n *netconfSubsystem) closeLocked() error {
+	if n.closed {
+		return nil
+	}
+	n.closed = true
+	n.queue = nil
+	n.signalLocked()
+	return n.serverConn.Close()
+}
+
+// Notify queues a notification for the channel of the session without blocking, the framing
+// of the messages being serialized with that of the rpc replies. A notification sent while a
+// rpc is handled follows its reply. The session is closed if its client does not read the
+// notifications queued before.
+func (n *netconfSubsystem) Notify(notification []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return io.ErrClosedPipe
+	}
+	if len(n.queue) >= maxQueuedNotifications {
+		log.Warnf("Closing netconf session %s, %d notifications not written", n.sessionID, len(n.queue))
+		err := n.closeLocked()
+		if err != nil {
+			log.Debugf("conn close error: %s", err)
+		}
+		return errNotificationOverflow
+	}
+	n.queue = append(n.queue, notification)
+	n.signalLocked()
+	return nil
+}
+
+// Flush waits for the notifications queued for the session to be written, until the session
+// is closed or the context is done
+func (n *netconfSubsystem) Flush(ctx context.Context) error {
+	n.mu.Lock()
+	if n.closed || (len(n.queue) == 0 && !n.writing) {
+		n.mu.Unlock()
+		return nil
+	}
+	if n.drained == nil {
+		n.drained = make(chan struct{})
+	}
+	drained := n.drained
+	n.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// signalLocked wakes the notification writer up, and the flushes up once the queue is drained
+func (n *netconfSubsystem) signalLocked() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+	if n.drained != nil && (n.closed || (len(n.queue) == 0 && !n.writing)) {
+		close(n.drained)
+		n.drained = nil
+	}
+}
+
+// writeNotifications writes the queued notifications while no rpc is handled, until the
+// session is closed
+func (n *netconfSubsystem) writeNotifications() {
+	for {
+		n.mu.Lock()
+		for !n.closed && (n.busy || len(n.queue) == 0) {
+			n.mu.Unlock()
+			<-n.wake
+			n.mu.Lock()
+		}
+		if n.closed {
+			n.mu.Unlock()
+			return
+		}
+		notification := n.queue[0]
+		n.queue = n.queue[1:]
+		n.writing = true
+		n.mu.Unlock()
+
+		err := n.serverConn.send(notification)
+
+		n.mu.Lock()
+		n.writing = false
+		if err != nil {
+			log.Debugf("notification write error: %s", err)
+			n.queue = nil
+		}
+		n.signalLocked()
+		n.mu.Unlock()
+	}
+}
+
+// begin marks the session as busy handling a rpc, returning false if it is closed
+func (n *netconfSubsystem) begin() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return false
+	}
+	n.busy = true
+	if n.idleTimer != nil {
+		n.idleTimer.Stop()
+	}
+	return true
+}
+
+// end marks the session as not busy, returning false if it must be closed
+func (n *netconfSubsystem) end() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.busy = false
+	n.signalLocked()
+	if n.closing {
+		err := n.closeLocked()
+		if err != nil {
+			log.Debugf("conn close error: %s", err)
+		}
+		return false
+	}
+	if n.idleTimer != nil {
+		n.idleTimer.Reset(n.idleTimeout)
+	}
+	return true
+}
+
+func (n *netconfSubsystem) idleExpired() {
+	n.mu.Lock()
+	busy := n.busy
+	n.mu.Unlock()
+	if busy {
+		return
+	}
+
+	log.Infof("netconf session %s idle for more than %s", n.sessionID, n.idleTimeout)
+	err := n.srv.EndSession(context.Background(), n.sessionID, store.EndReasonIdleTimeout)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	err = n.closeLocked()
+	if err != nil {
+		log.Debugf("conn close error: %s", err)
+	}
+}
+
 func (n *netconfSubsystem) Serve() error {
 
-	log.Infof("starting netconf subsystem - user %s session %s", n.ctx.User(), n.ctx.SessionID())
+	log.Infof("starting netconf subsystem - user %s session %s %s session %s", n.ctx.User(), n.sessionID, n.ctx.Transport(), n.ctx.SessionID())
+
+	ctx, span := tracing.Start(context.Background(), "netconf.session",
+		tracing.SessionIDKey.String(n.sessionID),
+		tracing.UserKey.String(n.ctx.User()),
+		tracing.SSHSessionIDKey.String(n.ctx.SessionID()),
+		tracing.SourceHostKey.String(n.ctx.SourceHost()),
+		tracing.TransportKey.String(n.ctx.Transport()))
+	defer span.End()
+
+	info := controller.SessionInfo{
+		SessionID:    n.sessionID,
+		User:         n.ctx.User(),
+		SSHSessionID: n.ctx.SessionID(),
+		SourceHost:   n.ctx.SourceHost(),
+		Transport:    n.ctx.Transport(),
+	}
+	go n.writeNotifications()
+	defer func() {
+		n.mu.Lock()
+		err := n.closeLocked()
+		n.mu.Unlock()
+		if err != nil {
+			log.Debugf("conn close error: %s", err)
+		}
+	}()
+
+	err := n.srv.OpenSession(ctx, info, n)
+	if err != nil {
+		log.Errorf("error netconf open session: %v", err)
+		return err
+	}
+	// the session ends here if it was not already ended by close-session, kill-session or idle timeout
+	defer func() {
+		err := n.srv.EndSession(ctx, n.sessionID, store.EndReasonTransportError)
+		if err != nil {
+			log.Warn(err)
+		}
+	}()
 
-	err := Hello(n)
+	err = Hello(n)
 	if err != nil {
 		log.Errorf("error netconf hello: %v", err)
 		return err
 	}
 
+	if n.idleTimeout > 0 {
+		n.mu.Lock()
+		n.idleTimer = time.AfterFunc(n.idleTimeout, n.idleExpired)
+		n.mu.Unlock()
+		defer n.idleTimer.Stop()
+	}
+
 	for {
 		data, err := n.serverConn.receive()
 		if err != nil {
@@ -62,36 +315,59 @@ func (n *netconfSubsystem) Serve() error {
 			return err
 		}
 
-		netconfCtx, cancel := context.WithTimeout(context.Background(), time.Duration(netconfTimeout)*time.Second)
-		defer cancel()
-
-		reply, err := n.srv.Handle(netconfCtx, n.ctx.SessionID(), data)
-		if err != nil {
-			log.Infof("Serve decode error: %s", err)
-			return err
+		if !n.begin() {
+			break
 		}
 
-		if reply != nil {
-			err = n.serverConn.send(reply)
-			if err != nil {
-				log.Debugf("handler write error: %s", err)
+		err = n.handle(ctx, data)
+		if err != nil {
+			n.end()
+			if err == errSend {
 				break
 			}
+			return err
 		}
 
+		if !n.end() {
+			break
+		}
 	}
 
-	log.Info("finishing netconf subsystem - session id %s", n.ctx.SessionID())
+	log.Infof("finishing netconf subsystem - session id %s", n.sessionID)
+	return nil
+}
+
+// handle handles a rpc of the session and sends its reply, in its own span
+func (n *netconfSubsystem) handle(ctx context.Context, data []byte) error {
+	ctx, span := tracing.Start(ctx, "netconf.rpc", tracing.SessionIDKey.String(n.sessionID))
 
-	err = n.serverConn.Close()
+	netconfCtx, cancel := context.WithTimeout(ctx, n.rpcTimeout)
+	reply, err := n.srv.Handle(netconfCtx, n.sessionID, data)
+	cancel()
 	if err != nil {
-		log.Debugf("conn close error: %s", err)
+		log.Infof("Serve decode error: %s", err)
+		tracing.End(span, err)
+		return err
 	}
 
+	if reply != nil {
+		err = n.serverConn.send(reply)
+		if err != nil {
+			log.Debugf("handler write error: %s", err)
+			tracing.End(span, err)
+			return errSend
+		}
+	}
+
+	span.End()
 	return nil
 }
 
-func NewNetconfServer(ctx Context, srv SSHServer, rwc io.ReadWriteCloser) NetconfServer {
+func NewNetconfServer(ctx Context, srv SSHServer, sessionID string, rwc io.ReadWriteCloser) NetconfServer {
+	return newNetconfSubsystem(ctx, srv, sessionID, rwc)
+}
+
+func newNetconfSubsystem(ctx Context, srv SSHServer, sessionID string, rwc io.ReadWriteCloser) *netconfSubsystem {
 	svrConn := &serverConn{
 		conn: conn{
 			Reader:      rwc,
@@ -103,6 +379,9 @@ func NewNetconfServer(ctx Context, srv SSHServer, rwc io.ReadWriteCloser) Netcon
 		ctx:        ctx,
 		srv:        srv,
 		serverConn: svrConn,
+		sessionID:  sessionID,
+		rpcTimeout: config.DefaultRPCTimeout,
+		wake:       make(chan struct{}, 1),
 	}
 	return ns
 }
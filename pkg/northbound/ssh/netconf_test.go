@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/southbound"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const kpimonNamespace = "http://opennetworking.org/kpimon:ric:1.0.0"
+
+// gnmiServer replies to the gNMI requests, recording the trace context they carry
+type gnmiServer struct {
+	gnmi.UnimplementedGNMIServer
+
+	mu           sync.Mutex
+	traceparents []string
+}
+
+func (s *gnmiServer) record(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.traceparents = append(s.traceparents, md.Get("traceparent")...)
+}
+
+func (s *gnmiServer) Get(ctx context.Context, request *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	s.record(ctx)
+	return &gnmi.GetResponse{}, nil
+}
+
+func (s *gnmiServer) Set(ctx context.Context, request *gnmi.SetRequest) (*gnmi.SetResponse, error) {
+	s.record(ctx)
+	return &gnmi.SetResponse{}, nil
+}
+
+// sessionContext is the context of the SSH connection of a netconf session
+type sessionContext struct {
+	context.Context
+	sync.Mutex
+}
+
+func (c *sessionContext) User() string      { return "alice" }
+func (c *sessionContext) SessionID() string { return "ssh-1" }
+func (c *sessionContext) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4000}
+}
+func (c *sessionContext) SourceHost() string            { return "127.0.0.1" }
+func (c *sessionContext) Transport() string             { return TransportSSH }
+func (c *sessionContext) Permissions() *ssh.Permissions { return &ssh.Permissions{} }
+func (c *sessionContext) SetValue(key, value interface{}) {
+	c.Context = context.WithValue(c.Context, key, value)
+}
+
+func startGnmiServer(t *testing.T) (*gnmiServer, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	gnmiSrv := &gnmiServer{}
+	gnmi.RegisterGNMIServer(server, gnmiSrv)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return gnmiSrv, listener.Addr().String()
+}
+
+func TestRPCSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.InitWithExporter(exporter)
+	defer func() {
+		_ = shutdown(context.Background())
+	}()
+
+	gnmiSrv, endpoint := startGnmiServer(t)
+	gnmiClient, err := southbound.NewGNMIClient(endpoint, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	topo := rnib.NewFakeClient(rnib.O1tEntity{ID: "kpimon", Configurable: "kpimon:ric:1.0.0"})
+	ctrl := controller.NewO1Controller(store.NewStore(), topo, gnmiClient)
+	defer ctrl.Close()
+
+	srv, err := NewSSHServer(ctrl,
+		WithListeners(Listener{Address: "127.0.0.1:0"}),
+		WithHostKeys([]ssh.Signer{newSigner(t)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, server := net.Pipe()
+	session := NewNetconfServer(&sessionContext{Context: context.Background()}, srv, "1", server)
+	served := make(chan error, 1)
+	go func() {
+		served <- session.Serve()
+	}()
+
+	_ = client.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := receive(client); err != nil {
+		t.Fatalf("hello not received: %v", err)
+	}
+	err = send(client, `<rpc message-id="42" xmlns="urn:ietf:params:xml:ns:netconf:base:1.1"><edit-config><target><running/></target>`+
+		`<config><report_period xmlns="`+kpimonNamespace+`"><interval>7</interval></report_period></config></edit-config></rpc>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := receive(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply, "<ok/>") {
+		t.Fatalf("unexpected reply %s", reply)
+	}
+	client.Close()
+	<-served
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+	sessionSpan, ok := byName["netconf.session"]
+	if !ok {
+		t.Fatalf("no netconf.session span in %d spans", len(spans))
+	}
+	rpcSpan, ok := byName["netconf.rpc"]
+	if !ok {
+		t.Fatal("no netconf.rpc span")
+	}
+	parseSpan, ok := byName["controller.ParseEditConfig"]
+	if !ok {
+		t.Fatal("no controller.ParseEditConfig span")
+	}
+	setSpan, ok := byName["gnmi.gNMI/Set"]
+	if !ok {
+		t.Fatal("no gnmi.gNMI/Set span")
+	}
+
+	attributes := func(span tracetest.SpanStub) map[attribute.Key]string {
+		values := make(map[attribute.Key]string)
+		for _, kv := range span.Attributes {
+			values[kv.Key] = kv.Value.Emit()
+		}
+		return values
+	}
+	if got := attributes(sessionSpan)[tracing.SessionIDKey]; got != "1" {
+		t.Errorf("session span session-id %q, want 1", got)
+	}
+	if got := attributes(sessionSpan)[tracing.UserKey]; got != "alice" {
+		t.Errorf("session span user %q, want alice", got)
+	}
+	rpcAttributes := attributes(rpcSpan)
+	if got := rpcAttributes[tracing.SessionIDKey]; got != "1" {
+		t.Errorf("rpc span session-id %q, want 1", got)
+	}
+	if got := rpcAttributes[tracing.MessageIDKey]; got != "42" {
+		t.Errorf("rpc span message-id %q, want 42", got)
+	}
+	if got := rpcAttributes[tracing.OperationKey]; got != "edit-config" {
+		t.Errorf("rpc span operation %q, want edit-config", got)
+	}
+
+	if rpcSpan.Parent.SpanID() != sessionSpan.SpanContext.SpanID() {
+		t.Error("rpc span is not a child of the session span")
+	}
+	if parseSpan.Parent.SpanID() != rpcSpan.SpanContext.SpanID() {
+		t.Error("parse span is not a child of the rpc span")
+	}
+	if setSpan.Parent.SpanID() != rpcSpan.SpanContext.SpanID() {
+		t.Error("gNMI set span is not a child of the rpc span")
+	}
+	if setSpan.SpanContext.TraceID() != sessionSpan.SpanContext.TraceID() {
+		t.Error("gNMI set span is not in the trace of the session")
+	}
+
+	// the trace context of the gNMI requests is propagated to onos-config
+	gnmiSrv.mu.Lock()
+	traceparents := gnmiSrv.traceparents
+	gnmiSrv.mu.Unlock()
+	propagated := false
+	for _, traceparent := range traceparents {
+		if strings.Contains(traceparent, setSpan.SpanContext.TraceID().String()) {
+			propagated = true
+		}
+	}
+	if !propagated {
+		t.Errorf("trace %s not propagated in %v", setSpan.SpanContext.TraceID(), traceparents)
+	}
+}
+
+func TestNotificationQueue(t *testing.T) {
+	defer func(max int) {
+		maxQueuedNotifications = max
+	}(maxQueuedNotifications)
+	maxQueuedNotifications = 2
+
+	client, server := net.Pipe()
+	defer client.Close()
+	n := newNetconfSubsystem(&sessionContext{Context: context.Background()}, nil, "1", server)
+	go n.writeNotifications()
+
+	// the notifications are written in order while the client reads them
+	notifications := []string{"<notification>1</notification>", "<notification>2</notification>"}
+	for _, notification := range notifications {
+		err := n.Notify([]byte(notification))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = client.SetDeadline(time.Now().Add(10 * time.Second))
+	for _, want := range notifications {
+		got, err := receive(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(got, want) {
+			t.Fatalf("notification %s, want %s", got, want)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := n.Flush(ctx)
+	if err != nil {
+		t.Fatalf("notifications not flushed: %v", err)
+	}
+
+	// the session of a client not reading its notifications is closed without blocking
+	for i := 0; i <= maxQueuedNotifications+1 && err == nil; i++ {
+		err = n.Notify([]byte("<notification>3</notification>"))
+	}
+	if err != errNotificationOverflow {
+		t.Fatalf("notify error %v, want %v", err, errNotificationOverflow)
+	}
+	err = n.Notify([]byte("<notification>4</notification>"))
+	if err != io.ErrClosedPipe {
+		t.Errorf("notify error %v after the overflow, want %v", err, io.ErrClosedPipe)
+	}
+	err = n.Flush(ctx)
+	if err != nil {
+		t.Errorf("flush of a closed session failed: %v", err)
+	}
+}
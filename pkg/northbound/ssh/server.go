@@ -9,13 +9,20 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-o1t/pkg/config"
 	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/metrics"
+	"github.com/onosproject/onos-o1t/pkg/store"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -24,9 +31,15 @@ var log = logging.GetLogger()
 const (
 	// sshNetconfSubsystem sets the SSH subsystem to NETCONF
 	sshNetconfSubsystem = "netconf"
+
+	// sshKeepaliveRequest is the global request sent to check the client is alive
+	sshKeepaliveRequest = "keepalive@openssh.com"
 )
 
 var (
+	// ErrServerClosed is returned when the server is stopping and no longer accepts new rpcs
+	ErrServerClosed = errors.New("netconf ssh server closed")
+
 	ContextKeyUser = &contextKey{"user"}
 
 	ContextKeySessionID = &contextKey{"session-id"}
@@ -36,6 +49,10 @@ var (
 	ContextKeyServer = &contextKey{"ssh-server"}
 
 	ContextKeyPermissions = &contextKey{"permissions"}
+
+	ContextKeyRemoteAddr = &contextKey{"remote-addr"}
+
+	ContextKeyTransport = &contextKey{"transport"}
 )
 
 type contextKey struct {
@@ -52,6 +69,15 @@ type Context interface {
 	// SessionID returns the session hash.
 	SessionID() string
 
+	// RemoteAddr returns the address of the client.
+	RemoteAddr() net.Addr
+
+	// SourceHost returns the host of the client address.
+	SourceHost() string
+
+	// Transport returns the transport of the connection, TransportSSH or TransportTLS.
+	Transport() string
+
 	// Permissions returns the Permissions object used for this connection.
 	Permissions() *ssh.Permissions
 
@@ -72,6 +98,26 @@ func (ctx *sshContext) SessionID() string {
 	return ctx.Value(ContextKeySessionID).(string)
 }
 
+func (ctx *sshContext) RemoteAddr() net.Addr {
+	return ctx.Value(ContextKeyRemoteAddr).(net.Addr)
+}
+
+func (ctx *sshContext) SourceHost() string {
+	addr := ctx.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func (ctx *sshContext) Transport() string {
+	if transport, ok := ctx.Value(ContextKeyTransport).(string); ok {
+		return transport
+	}
+	return TransportSSH
+}
+
 func (ctx *sshContext) Permissions() *ssh.Permissions {
 	return ctx.Value(ContextKeyPermissions).(*ssh.Permissions)
 }
@@ -86,6 +132,7 @@ func fillContext(ctx Context, conn ssh.ConnMetadata) {
 	}
 	ctx.SetValue(ContextKeySessionID, hex.EncodeToString(conn.SessionID()))
 	ctx.SetValue(ContextKeyUser, conn.User())
+	ctx.SetValue(ContextKeyRemoteAddr, conn.RemoteAddr())
 }
 
 func newContext(srv *sshServer) (*sshContext, context.CancelFunc) {
@@ -98,10 +145,14 @@ func newContext(srv *sshServer) (*sshContext, context.CancelFunc) {
 
 type PublicKeyHandler func(ctx Context, key ssh.PublicKey) bool
 type PasswordHandler func(ctx Context, password string) bool
-type SubsystemHandler func(ctx Context, srv *sshServer, sshCh ssh.Channel) error
+type SubsystemHandler func(ctx Context, srv *sshServer, sshCh io.ReadWriteCloser) error
 
-func NetconfHandler(ctx Context, srv *sshServer, sshCh ssh.Channel) error {
-	ns := NewNetconfServer(ctx, srv, sshCh)
+func NetconfHandler(ctx Context, srv *sshServer, sshCh io.ReadWriteCloser) error {
+	ns := newNetconfSubsystem(ctx, srv, srv.newSessionID(), sshCh)
+	srv.mu.RLock()
+	ns.idleTimeout = srv.idleTimeout
+	ns.rpcTimeout = srv.rpcTimeout
+	srv.mu.RUnlock()
 	err := ns.Serve()
 	return err
 }
@@ -112,23 +163,127 @@ var DefaultSubsystemHandlers = map[string]SubsystemHandler{
 
 type SSHServer interface {
 	Start() error
+	// Drain stops listening and waits for the in-flight rpcs, the netconf sessions being kept
+	// open until Stop
+	Drain(ctx context.Context) error
 	Stop(ctx context.Context) error
 	Handle(context.Context, string, []byte) ([]byte, error)
+	OpenSession(context.Context, controller.SessionInfo, controller.Session) error
+	EndSession(context.Context, string, store.EndReason) error
+	Check(context.Context) error
+	// Reconfigure applies the options to the new connections and sessions
+	Reconfigure(opts ...Option)
+}
+
+// Option configures the Netconf SSH server
+type Option func(*sshServer)
+
+// WithIdleTimeout closes netconf sessions that do not receive any message during the timeout, zero disables it
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(srv *sshServer) {
+		srv.idleTimeout = timeout
+	}
+}
+
+// WithKeepalive sends keepalive requests to clients at the interval and closes their connection
+// after countMax requests without reply, a zero interval disables keepalives
+func WithKeepalive(interval time.Duration, countMax int) Option {
+	return func(srv *sshServer) {
+		srv.keepaliveInterval = interval
+		srv.keepaliveCountMax = countMax
+	}
+}
+
+// WithListeners sets the addresses the server listens on
+func WithListeners(listeners ...Listener) Option {
+	return func(srv *sshServer) {
+		srv.listeners = listeners
+	}
+}
+
+// WithRPCTimeout sets the time given to the handling of a rpc
+func WithRPCTimeout(timeout time.Duration) Option {
+	return func(srv *sshServer) {
+		srv.rpcTimeout = timeout
+	}
+}
+
+// WithHostKeys sets the host keys of the server, a RSA host key is generated if none is set
+func WithHostKeys(signers []ssh.Signer) Option {
+	return func(srv *sshServer) {
+		srv.HostSigners = signers
+	}
+}
+
+// WithAuth sets the public keys accepted for all users and the bcrypt password hashes of the
+// users accepted with password authentication. Any public key is accepted if authorizedKeys
+// is nil and no user has a password.
+func WithAuth(authorizedKeys []ssh.PublicKey, users map[string]string) Option {
+	return func(srv *sshServer) {
+		srv.authorizedKeys = nil
+		if authorizedKeys != nil {
+			srv.authorizedKeys = make(map[string]bool, len(authorizedKeys))
+			for _, key := range authorizedKeys {
+				srv.authorizedKeys[string(key.Marshal())] = true
+			}
+		}
+		srv.passwords = make(map[string][]byte, len(users))
+		for user, hash := range users {
+			srv.passwords[user] = []byte(hash)
+		}
+	}
+}
+
+// WithSessionGate rejects the SSH channels of new netconf sessions while the gate returns an error
+func WithSessionGate(gate func() error) Option {
+	return func(srv *sshServer) {
+		srv.sessionGate = gate
+	}
 }
 
 type sshServer struct {
 	mu sync.RWMutex
 
-	netconfPort int
-	Version     string
+	listeners []Listener
+	Version   string
 
 	subsystemHandlers map[string]SubsystemHandler
 	HostSigners       []ssh.Signer
 
 	PasswordHandler  PasswordHandler
 	PublicKeyHandler PublicKeyHandler
+	// authorizedKeys are the marshaled public keys accepted for all users, passwords the
+	// bcrypt password hashes of the users
+	authorizedKeys map[string]bool
+	passwords      map[string][]byte
 
 	controller controller.O1Controller
+
+	idleTimeout       time.Duration
+	rpcTimeout        time.Duration
+	keepaliveInterval time.Duration
+	keepaliveCountMax int
+	sessionGate       func() error
+
+	// lastSessionID is the last NETCONF session-id given to a netconf subsystem channel
+	lastSessionID uint32
+
+	// netListeners are the listeners being served, by address of their Listener
+	netListeners map[string]net.Listener
+	// callHomeClients are connected to by the call home goroutines, until stopCallHome is called
+	callHomeClients []CallHomeClient
+	stopCallHome    context.CancelFunc
+	callHomeWg      sync.WaitGroup
+	conns           map[io.Closer]struct{}
+	// handshakes are the connections whose SSH handshake is in progress
+	handshakes map[net.Conn]struct{}
+	// closing is set once the server is drained, stopped once its connections are closed
+	closing bool
+	stopped bool
+	// rpcs tracks the in-flight rpcs, connWg the goroutines serving connections and their
+	// netconf sessions
+	rpcs   sync.WaitGroup
+	connWg sync.WaitGroup
 }
 
 func (srv *sshServer) addHostKey(key ssh.Signer) {
@@ -161,35 +316,220 @@ func (srv *sshServer) createHostKeyFile() error {
 	return nil
 }
 
-func NewSSHServer(netconfPort int, o1tControl controller.O1Controller) (SSHServer, error) {
+// NewSSHServer creates a Netconf SSH server listening on the addresses set with WithListeners
+func NewSSHServer(o1tControl controller.O1Controller, opts ...Option) (SSHServer, error) {
 	srv := &sshServer{
-		netconfPort: netconfPort,
+		netListeners:      make(map[string]net.Listener),
+		conns:             make(map[io.Closer]struct{}),
+		handshakes:        make(map[net.Conn]struct{}),
+		rpcTimeout:        config.DefaultRPCTimeout,
+		keepaliveInterval: config.DefaultKeepaliveInterval,
+		keepaliveCountMax: config.DefaultKeepaliveCountMax,
+	}
+	for _, opt := range opts {
+		opt(srv)
 	}
 	srv.subsystemHandlers = DefaultSubsystemHandlers
 	srv.controller = o1tControl
 
+	if len(srv.listeners) == 0 && len(srv.callHomeClients) == 0 {
+		return nil, errors.New("netconf ssh server has no listener nor call home client")
+	}
+	for _, listener := range srv.listeners {
+		for _, method := range listener.AuthMethods {
+			if method != AuthPublicKey && method != AuthPassword {
+				return nil, fmt.Errorf("unknown authentication method %q of listener %s", method, listener.name())
+			}
+		}
+	}
+
 	srv.PublicKeyHandler = func(ctx Context, key ssh.PublicKey) bool {
-		return true //TODO For now allow all keys, later use ssh.KeysEqual() to compare against known keys
+		return srv.authorizePublicKey(key)
+	}
+	srv.PasswordHandler = func(ctx Context, password string) bool {
+		return srv.authorizePassword(ctx.User(), password)
 	}
 
-	err := srv.createHostKeyFile()
-	if err != nil {
-		return nil, err
+	if len(srv.HostSigners) == 0 {
+		err := srv.createHostKeyFile()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return srv, nil
 }
 
+func (srv *sshServer) Reconfigure(opts ...Option) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	for _, opt := range opts {
+		opt(srv)
+	}
+}
+
 func (srv *sshServer) Handle(ctx context.Context, sessionID string, request []byte) ([]byte, error) {
+	if !srv.beginRPC() {
+		return nil, ErrServerClosed
+	}
+	defer srv.rpcs.Done()
+
 	reply, err := srv.controller.Handler(ctx, sessionID, request)
 	return reply, err
 }
 
-func (srv *sshServer) Stop(ctx context.Context) error {
+func (srv *sshServer) OpenSession(ctx context.Context, info controller.SessionInfo, session controller.Session) error {
+	if !srv.beginRPC() {
+		return ErrServerClosed
+	}
+	defer srv.rpcs.Done()
+
+	return srv.controller.OpenSession(ctx, info, session)
+}
+
+// newSessionID returns a new NETCONF session-id, unique among the sessions of the server
+func (srv *sshServer) newSessionID() string {
+	return strconv.FormatUint(uint64(atomic.AddUint32(&srv.lastSessionID, 1)), 10)
+}
+
+func (srv *sshServer) EndSession(ctx context.Context, sessionID string, reason store.EndReason) error {
+	return srv.controller.EndSession(ctx, sessionID, reason)
+}
+
+// beginRPC registers an in-flight rpc, unless the server is stopping
+func (srv *sshServer) beginRPC() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.closing {
+		return false
+	}
+	srv.rpcs.Add(1)
+	return true
+}
+
+// trackConn registers a served connection, unless the server is stopping
+func (srv *sshServer) trackConn(conn io.Closer) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.closing {
+		return false
+	}
+	srv.conns[conn] = struct{}{}
+	srv.connWg.Add(1)
+	return true
+}
+
+func (srv *sshServer) untrackConn(conn io.Closer) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	delete(srv.conns, conn)
+	srv.connWg.Done()
+}
+
+// Check checks the server is listening for new connections on all its listeners
+func (srv *sshServer) Check(ctx context.Context) error {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+
+	for _, listener := range srv.listeners {
+		if _, ok := srv.netListeners[listener.name()]; !ok {
+			return fmt.Errorf("netconf ssh server is not listening on %s", listener.name())
+		}
+	}
+	return nil
+}
+
+// Drain closes the listeners and stops the call home clients, so no new connection or rpc is
+// accepted, then waits for the in-flight rpcs until the context deadline
+func (srv *sshServer) Drain(ctx context.Context) error {
+	srv.mu.Lock()
+	if srv.closing {
+		srv.mu.Unlock()
+		return nil
+	}
+	srv.closing = true
+	listeners := make([]net.Listener, 0, len(srv.netListeners))
+	for _, listener := range srv.netListeners {
+		listeners = append(listeners, listener)
+	}
+	stopCallHome := srv.stopCallHome
+	srv.mu.Unlock()
+
+	if stopCallHome != nil {
+		stopCallHome()
+	}
+
+	for _, listener := range listeners {
+		err := listener.Close()
+		if err != nil {
+			log.Warn(err)
+		}
+	}
+
+	if !waitUntil(ctx, &srv.rpcs) {
+		log.Warn("Deadline exceeded waiting for in-flight rpcs to complete")
+		return ctx.Err()
+	}
 	return nil
 }
 
-func (srv *sshServer) config(ctx Context) *ssh.ServerConfig {
+// Stop drains the server if it is not drained yet, then closes all the connections, and so the
+// netconf sessions running on them
+func (srv *sshServer) Stop(ctx context.Context) error {
+	err := srv.Drain(ctx)
+
+	srv.mu.Lock()
+	if srv.stopped {
+		srv.mu.Unlock()
+		return err
+	}
+	srv.stopped = true
+	for conn := range srv.handshakes {
+		conn.Close()
+	}
+	log.Infof("Closing %d Netconf connections", len(srv.conns))
+	for conn := range srv.conns {
+		closeErr := conn.Close()
+		if closeErr != nil {
+			log.Debug(closeErr)
+		}
+	}
+	srv.mu.Unlock()
+
+	if !waitUntil(ctx, &srv.connWg) {
+		log.Warn("Deadline exceeded waiting for Netconf SSH connections to close")
+		err = ctx.Err()
+	}
+	if !waitUntil(ctx, &srv.callHomeWg) {
+		log.Warn("Deadline exceeded waiting for call home connections to stop")
+		err = ctx.Err()
+	}
+
+	log.Info("Netconf SSH server stopped")
+	return err
+}
+
+// waitUntil waits for the wait group, returning false if the context is done before
+func waitUntil(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (srv *sshServer) config(ctx Context, listener Listener) *ssh.ServerConfig {
 	srv.mu.RLock()
 	defer srv.mu.RUnlock()
 
@@ -205,7 +545,7 @@ func (srv *sshServer) config(ctx Context) *ssh.ServerConfig {
 	if srv.Version != "" {
 		config.ServerVersion = "SSH-2.0-" + srv.Version
 	}
-	if srv.PasswordHandler != nil {
+	if srv.PasswordHandler != nil && len(srv.passwords) > 0 && listener.accepts(AuthPassword) {
 		config.PasswordCallback = func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
 			fillContext(ctx, conn)
 			if ok := srv.PasswordHandler(ctx, string(password)); !ok {
@@ -214,7 +554,7 @@ func (srv *sshServer) config(ctx Context) *ssh.ServerConfig {
 			return ctx.Permissions(), nil
 		}
 	}
-	if srv.PublicKeyHandler != nil {
+	if srv.PublicKeyHandler != nil && listener.accepts(AuthPublicKey) {
 		config.PublicKeyCallback = func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
 			fillContext(ctx, conn)
 			if ok := srv.PublicKeyHandler(ctx, key); !ok {
@@ -227,43 +567,166 @@ func (srv *sshServer) config(ctx Context) *ssh.ServerConfig {
 	return config
 }
 
+// Start listens on all the listeners of the server, none is left open if one fails
 func (srv *sshServer) Start() error {
-	address := "0.0.0.0:" + strconv.Itoa(srv.netconfPort)
-	listener, err := net.Listen("tcp", address)
+	netListeners := make([]net.Listener, 0, len(srv.listeners))
+	for _, listener := range srv.listeners {
+		netListener, err := listener.listen()
+		if err != nil {
+			log.Error(err)
+			for _, opened := range netListeners {
+				opened.Close()
+			}
+			return err
+		}
+		netListeners = append(netListeners, netListener)
+	}
 
-	if err != nil {
-		log.Error(err)
-		return err
+	srv.mu.Lock()
+	if srv.closing {
+		srv.mu.Unlock()
+		for _, netListener := range netListeners {
+			netListener.Close()
+		}
+		return ErrServerClosed
+	}
+	for i, listener := range srv.listeners {
+		srv.netListeners[listener.name()] = netListeners[i]
+	}
+	srv.mu.Unlock()
 
+	for i, listener := range srv.listeners {
+		log.Infof("Netconf SSH server listening on %s", netListeners[i].Addr())
+		go srv.serve(listener, netListeners[i])
 	}
 
-	log.Infof("Netconf SSH server listening on %s", address)
+	srv.startCallHome()
+	return nil
+}
+
+func (srv *sshServer) serve(listener Listener, netListener net.Listener) {
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.netListeners, listener.name())
+		srv.mu.Unlock()
+	}()
 
 	for {
-		conn, err := listener.Accept()
+		conn, err := netListener.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				log.Infof("Netconf SSH server stopped listening on %s", netListener.Addr())
+				return
+			}
 			log.Error(err)
-			return err
+			return
 		}
 
-		ctx, _ := newContext(srv)
-		config := srv.config(ctx)
+		go srv.serveConn(listener, conn, false)
+	}
+}
 
-		srvConn, chans, reqs, err := ssh.NewServerConn(conn, config)
-		if err != nil {
-			log.Error(err)
-			return err
+// serveConn runs the SSH server side of the connection until it is closed, closing it when its
+// last channel is closed if closeWhenIdle is set
+func (srv *sshServer) serveConn(listener Listener, conn net.Conn, closeWhenIdle bool) {
+	if listener.TLS != nil {
+		srv.serveTLSConn(listener, conn)
+		return
+	}
+
+	ctx, cancel := newContext(srv)
+	defer cancel()
+	config := srv.config(ctx, listener)
+
+	srv.mu.Lock()
+	if srv.closing {
+		srv.mu.Unlock()
+		conn.Close()
+		return
+	}
+	srv.handshakes[conn] = struct{}{}
+	srv.mu.Unlock()
+
+	srvConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+
+	srv.mu.Lock()
+	delete(srv.handshakes, conn)
+	srv.mu.Unlock()
+	if err != nil {
+		var authErr *ssh.ServerAuthError
+		if errors.As(err, &authErr) {
+			metrics.AuthFailure()
 		}
+		log.Warnf("SSH handshake with %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
 
-		fillContext(ctx, srvConn)
-		go ssh.DiscardRequests(reqs)
-		go srv.handleServerConn(ctx, chans)
+	if !srv.trackConn(srvConn) {
+		srvConn.Close()
+		return
+	}
+	defer srv.untrackConn(srvConn)
+
+	fillContext(ctx, srvConn)
+	go ssh.DiscardRequests(reqs)
+	srv.mu.RLock()
+	interval, countMax := srv.keepaliveInterval, srv.keepaliveCountMax
+	srv.mu.RUnlock()
+	if interval > 0 {
+		go srv.keepalive(ctx, srvConn, interval, countMax)
 	}
+	srv.handleServerConn(ctx, srvConn, chans, closeWhenIdle)
 }
 
-func (srv *sshServer) handleServerConn(ctx Context, chans <-chan ssh.NewChannel) {
+// keepalive sends keepalive requests to the client and closes the connection when
+// keepaliveCountMax consecutive requests are not replied, any reply counts as alive
+func (srv *sshServer) keepalive(ctx context.Context, conn *ssh.ServerConn, interval time.Duration, countMax int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	replies := make(chan error, 1)
+	pending := false
+	missed := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-replies:
+			pending = false
+			if err != nil {
+				log.Infof("SSH keepalive to %s failed: %v", conn.RemoteAddr(), err)
+				conn.Close()
+				return
+			}
+			missed = 0
+		case <-ticker.C:
+			if pending {
+				missed++
+				if missed >= countMax {
+					log.Infof("SSH client %s did not reply to %d keepalives, closing connection", conn.RemoteAddr(), missed)
+					conn.Close()
+					return
+				}
+				continue
+			}
+			pending = true
+			go func() {
+				_, _, err := conn.SendRequest(sshKeepaliveRequest, true, nil)
+				replies <- err
+			}()
+		}
+	}
+}
+
+func (srv *sshServer) handleServerConn(ctx Context, conn *ssh.ServerConn, chans <-chan ssh.NewChannel, closeWhenIdle bool) {
 	log.Info("Handling Connection")
 
+	// channels counts the open channels of the connection
+	var channelsMu sync.Mutex
+	channels := 0
+
 	for newChan := range chans {
 		log.Infof("Handling Channel %s", newChan.ChannelType())
 
@@ -275,15 +738,49 @@ func (srv *sshServer) handleServerConn(ctx Context, chans <-chan ssh.NewChannel)
 			continue
 		}
 
+		srv.mu.RLock()
+		gate := srv.sessionGate
+		srv.mu.RUnlock()
+		if gate != nil {
+			if err := gate(); err != nil {
+				log.Warnf("Rejecting new session: %v", err)
+				err = newChan.Reject(ssh.ResourceShortage, err.Error())
+				if err != nil {
+					log.Warn(err)
+				}
+				continue
+			}
+		}
+
 		ch, reqs, err := newChan.Accept()
 		if err != nil {
 			log.Infof("Handling Channel error %s", err)
 			continue
 		}
 
+		channelsMu.Lock()
+		channels++
+		channelsMu.Unlock()
+
+		// the channels are counted as their connection, which is tracked until here
+		srv.connWg.Add(1)
 		go func(sshCh ssh.Channel, in <-chan *ssh.Request) {
+			defer srv.connWg.Done()
+			defer func() {
+				channelsMu.Lock()
+				channels--
+				idle := channels == 0
+				channelsMu.Unlock()
+				if idle && closeWhenIdle {
+					log.Infof("Closing connection of %s without channel", conn.RemoteAddr())
+					conn.Close()
+				}
+			}()
 			defer sshCh.Close()
 
+			// a channel runs at most one subsystem, and so one netconf session
+			subsystemStarted := false
+
 			for req := range in {
 				log.Infof("Handling Request %s", req.Type)
 
@@ -291,14 +788,14 @@ func (srv *sshServer) handleServerConn(ctx Context, chans <-chan ssh.NewChannel)
 
 				case "subsystem":
 					var payload = struct{ Value string }{}
-					err = ssh.Unmarshal(req.Payload, &payload)
+					err := ssh.Unmarshal(req.Payload, &payload)
 					if err != nil {
 						log.Warn(err)
 						return
 					}
 
 					handler, ok := srv.subsystemHandlers[payload.Value]
-					if !ok {
+					if !ok || subsystemStarted {
 						err = req.Reply(false, nil)
 						if err != nil {
 							log.Warn(err)
@@ -307,8 +804,17 @@ func (srv *sshServer) handleServerConn(ctx Context, chans <-chan ssh.NewChannel)
 						continue
 					}
 
+					err = req.Reply(true, nil)
+					if err != nil {
+						log.Warn(err)
+						return
+					}
+
 					log.Infof("Handling subsystem %s", payload.Value)
+					subsystemStarted = true
+					srv.connWg.Add(1)
 					go func() {
+						defer srv.connWg.Done()
 						defer sshCh.Close()
 						err := handler(ctx, srv, sshCh)
 						if err != nil {
@@ -317,14 +823,8 @@ func (srv *sshServer) handleServerConn(ctx Context, chans <-chan ssh.NewChannel)
 						}
 					}()
 
-					err = req.Reply(true, nil)
-					if err != nil {
-						log.Warn(err)
-						return
-					}
-
 				default:
-					err = req.Reply(false, nil)
+					err := req.Reply(false, nil)
 					if err != nil {
 						log.Warn(err)
 						return
@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-o1t/pkg/controller"
+	"github.com/onosproject/onos-o1t/pkg/rnib"
+	"github.com/onosproject/onos-o1t/pkg/store"
+	"golang.org/x/crypto/ssh"
+)
+
+// helloSessionID matches the session-id of a hello
+var helloSessionID = regexp.MustCompile(`<session-id>(\d+)</session-id>`)
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// newTestServer creates a server of a controller with an empty store, stopped with the test
+func newTestServer(t *testing.T, opts ...Option) (*sshServer, store.Store) {
+	t.Helper()
+	sessionStore := store.NewStore()
+	ctrl := controller.NewO1Controller(sessionStore, rnib.NewFakeClient(), nil)
+	t.Cleanup(ctrl.Close)
+
+	opts = append([]Option{WithHostKeys([]ssh.Signer{newSigner(t)})}, opts...)
+	srv, err := NewSSHServer(ctrl, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Stop(ctx)
+	})
+	return srv.(*sshServer), sessionStore
+}
+
+// startTestServer starts a server of a controller with an empty store, listening on an
+// ephemeral port and stopped with the test, returning the address listened on
+func startTestServer(t *testing.T, opts ...Option) (*sshServer, store.Store, string) {
+	t.Helper()
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append([]Option{WithListeners(Listener{NetListener: netListener})}, opts...)
+	srv, sessionStore := newTestServer(t, opts...)
+	err = srv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, sessionStore, netListener.Addr().String()
+}
+
+func clientConfig(t *testing.T) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User:            "alice",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(newSigner(t))},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+}
+
+func dial(t *testing.T, address string) *ssh.Client {
+	t.Helper()
+	client, err := ssh.Dial("tcp", address, clientConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
+
+// netconfChannel is the netconf subsystem of a SSH channel on the client side
+type netconfChannel struct {
+	session *ssh.Session
+	in      io.WriteCloser
+	out     io.Reader
+}
+
+// openNetconf opens a channel running the netconf subsystem, returning its session-id
+// read from the hello of the server
+func openNetconf(t *testing.T, client *ssh.Client) (*netconfChannel, string) {
+	t.Helper()
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err := session.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := session.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.RequestSubsystem(sshNetconfSubsystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := &netconfChannel{session: session, in: in, out: out}
+
+	hello, err := receive(ch.out)
+	if err != nil {
+		t.Fatalf("hello not received: %v", err)
+	}
+	match := helloSessionID.FindStringSubmatch(hello)
+	if match == nil {
+		t.Fatalf("no session-id in hello %s", hello)
+	}
+	return ch, match[1]
+}
+
+// send sends a message with the chunked framing of NETCONF 1.1
+func send(w io.Writer, message string) error {
+	_, err := fmt.Fprintf(w, "\n#%d\n%s\n##\n", len(message), message)
+	return err
+}
+
+// receive receives a message with the chunked framing of NETCONF 1.1
+func receive(r io.Reader) (string, error) {
+	var message bytes.Buffer
+	buf := make([]byte, 4096)
+	for !bytes.HasSuffix(message.Bytes(), []byte("\n##\n")) {
+		n, err := r.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		message.Write(buf[:n])
+	}
+	return message.String(), nil
+}
+
+func sessionValue(t *testing.T, sessionStore store.Store, sessionID string) *store.SessionValue {
+	t.Helper()
+	entry, err := sessionStore.Get(context.Background(), store.Key{SessionID: sessionID})
+	if err != nil {
+		t.Fatalf("no entry for session %s: %v", sessionID, err)
+	}
+	return entry.Value.(*store.SessionValue)
+}
+
+func TestSessionPerChannel(t *testing.T) {
+	_, sessionStore, address := startTestServer(t)
+	client := dial(t, address)
+
+	first, firstID := openNetconf(t, client)
+	_, secondID := openNetconf(t, client)
+	if firstID == secondID {
+		t.Fatalf("channels of the connection share session-id %s", firstID)
+	}
+
+	firstValue := sessionValue(t, sessionStore, firstID)
+	secondValue := sessionValue(t, sessionStore, secondID)
+	if !firstValue.Alive || !secondValue.Alive {
+		t.Error("sessions of the channels are not alive")
+	}
+	if firstValue.SSHSessionID == "" || firstValue.SSHSessionID != secondValue.SSHSessionID {
+		t.Errorf("sessions of one connection have SSH sessions %q and %q", firstValue.SSHSessionID, secondValue.SSHSessionID)
+	}
+
+	// a channel runs a single netconf subsystem
+	if err := first.session.RequestSubsystem(sshNetconfSubsystem); err == nil {
+		t.Error("second netconf subsystem accepted on a channel")
+	}
+
+	// closing a channel ends its session only
+	err := send(first.in, `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.1"><close-session/></rpc>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := receive(first.out); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for sessionValue(t, sessionStore, firstID).Alive {
+		if time.Now().After(deadline) {
+			t.Fatalf("session %s not ended", firstID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !sessionValue(t, sessionStore, secondID).Alive {
+		t.Errorf("session %s ended with the other channel", secondID)
+	}
+}
+
+func TestSessionGate(t *testing.T) {
+	srv, _, address := startTestServer(t)
+	client := dial(t, address)
+	_, _ = openNetconf(t, client)
+
+	srv.Reconfigure(WithSessionGate(func() error {
+		return errors.New("onos-config unavailable")
+	}))
+	_, err := client.NewSession()
+	var openErr *ssh.OpenChannelError
+	if !errors.As(err, &openErr) || openErr.Reason != ssh.ResourceShortage {
+		t.Fatalf("channel opened with the gate closed: %v", err)
+	}
+
+	srv.Reconfigure(WithSessionGate(func() error {
+		return nil
+	}))
+	_, _ = openNetconf(t, client)
+}
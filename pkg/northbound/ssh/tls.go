@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"crypto/tls"
+	"net"
+
+	"github.com/onosproject/onos-o1t/pkg/metrics"
+)
+
+const (
+	// NetconfTLSPort is the port assigned by IANA to NETCONF over TLS (RFC 7589)
+	NetconfTLSPort = 6513
+
+	TransportSSH = "ssh"
+	TransportTLS = "tls"
+)
+
+// TLSConfig makes a listener serve NETCONF over TLS instead of SSH
+type TLSConfig struct {
+	// Config holds the server certificate and the CAs of the client certificates, the
+	// client certificate being always required and verified
+	Config *tls.Config
+	// CertToName are the rules mapping the client certificates to the NETCONF usernames
+	CertToName []CertToName
+}
+
+// serveTLSConn runs the NETCONF session of a TLS connection, the username of the session being
+// mapped from the client certificate
+func (srv *sshServer) serveTLSConn(listener Listener, conn net.Conn) {
+	config := listener.TLS.Config.Clone()
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if config.MinVersion < tls.VersionTLS12 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	tlsConn := tls.Server(conn, config)
+
+	srv.mu.Lock()
+	if srv.closing {
+		srv.mu.Unlock()
+		conn.Close()
+		return
+	}
+	srv.handshakes[conn] = struct{}{}
+	srv.mu.Unlock()
+
+	err := tlsConn.Handshake()
+
+	srv.mu.Lock()
+	delete(srv.handshakes, conn)
+	srv.mu.Unlock()
+
+	if err != nil {
+		metrics.AuthFailure()
+		log.Warnf("TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+
+	user, err := mapCertToName(listener.TLS.CertToName, tlsConn.ConnectionState().VerifiedChains[0])
+	if err != nil {
+		metrics.AuthFailure()
+		log.Warnf("TLS client %s rejected: %v", conn.RemoteAddr(), err)
+		tlsConn.Close()
+		return
+	}
+
+	if !srv.trackConn(tlsConn) {
+		tlsConn.Close()
+		return
+	}
+	defer srv.untrackConn(tlsConn)
+
+	srv.mu.RLock()
+	gate := srv.sessionGate
+	srv.mu.RUnlock()
+	if gate != nil {
+		if err := gate(); err != nil {
+			log.Warnf("Rejecting new session of %s: %v", conn.RemoteAddr(), err)
+			tlsConn.Close()
+			return
+		}
+	}
+
+	ctx, cancel := newContext(srv)
+	defer cancel()
+	ctx.SetValue(ContextKeySessionID, "")
+	ctx.SetValue(ContextKeyUser, user)
+	ctx.SetValue(ContextKeyRemoteAddr, conn.RemoteAddr())
+	ctx.SetValue(ContextKeyTransport, TransportTLS)
+
+	err = NetconfHandler(ctx, srv, tlsConn)
+	if err != nil {
+		log.Warn(err)
+	}
+	tlsConn.Close()
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTLSCertToName(t *testing.T) {
+	ca, caKey := newCA(t, "ca")
+	otherCA, otherKey := newCA(t, "other-ca")
+	serverCert, serverKey := newCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "onos-o1t"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	clientTemplate := func() *x509.Certificate {
+		return &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "alice"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+	}
+	clientCert, clientKey := newCertificate(t, clientTemplate(), ca, caKey)
+	otherCert, otherClientKey := newCertificate(t, clientTemplate(), otherCA, otherKey)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+	clientCAs.AddCert(otherCA)
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, sessionStore := newTestServer(t, WithListeners(Listener{
+		NetListener: netListener,
+		TLS: &TLSConfig{
+			Config: &tls.Config{
+				Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+				ClientCAs:    clientCAs,
+			},
+			// the clients of the other CA have no username
+			CertToName: []CertToName{{Fingerprint: mustParseFingerprint(t, sha256Fingerprint(ca)), MapType: MapTypeCommonName}},
+		},
+	}))
+	err = srv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca)
+	dial := func(certificates ...tls.Certificate) *tls.Conn {
+		t.Helper()
+		conn, err := tls.Dial("tcp", netListener.Addr().String(), &tls.Config{
+			RootCAs:      rootCAs,
+			Certificates: certificates,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			conn.Close()
+		})
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+
+	conn := dial(tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey})
+	hello, err := receive(conn)
+	if err != nil {
+		t.Fatalf("hello not received: %v", err)
+	}
+	match := helloSessionID.FindStringSubmatch(hello)
+	if match == nil {
+		t.Fatalf("no session-id in hello %s", hello)
+	}
+	value := sessionValue(t, sessionStore, match[1])
+	if value.User != "alice" || value.Transport != TransportTLS {
+		t.Errorf("session of user %q over %q, want alice over %s", value.User, value.Transport, TransportTLS)
+	}
+
+	// the certificate of the other CA is verified but not mapped to a username
+	conn = dial(tls.Certificate{Certificate: [][]byte{otherCert.Raw}, PrivateKey: otherClientKey})
+	if hello, err := receive(conn); err == nil {
+		t.Errorf("session of an unmapped certificate: %s", hello)
+	}
+
+	// a client certificate is required
+	conn = dial()
+	if hello, err := receive(conn); err == nil {
+		t.Errorf("session without client certificate: %s", hello)
+	}
+}
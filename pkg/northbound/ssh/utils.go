@@ -15,6 +15,8 @@ import (
 	"io"
 	"strconv"
 	"sync"
+
+	"github.com/onosproject/onos-o1t/pkg/metrics"
 )
 
 const (
@@ -62,7 +64,12 @@ func (c *conn) receive() ([]byte, error) {
 		return nil, err
 	}
 
-	return framing(b)
+	msg, err := framing(b)
+	if err != nil {
+		metrics.FramingError()
+		return nil, err
+	}
+	return msg, nil
 }
 
 func (c *conn) receiveUntil(separator []byte) ([]byte, error) {
@@ -115,6 +122,9 @@ func framing(b []byte) ([]byte, error) {
 	for scanner.Scan() {
 		got = append(got, scanner.Bytes()...)
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 	return got, nil
 }
 
@@ -0,0 +1,341 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("replay")
+
+const (
+	DefaultMaxAge    = 24 * time.Hour
+	DefaultMaxSizeMB = 100
+
+	// segments is the number of segments the size of the log is divided in, the oldest
+	// segment being removed once the log exceeds its size
+	segments = 8
+
+	segmentPrefix = "notifications-"
+	segmentSuffix = ".log"
+	// createdFile holds the creation time of the log
+	createdFile = "created"
+)
+
+// Record is a notification of the replay log
+type Record struct {
+	EventTime time.Time `json:"eventTime"`
+	// Target is the target:name:version of the target whose change is notified, empty for the
+	// event notifications of the NETCONF stream
+	Target string `json:"target,omitempty"`
+	// Event is the name of an event notification of the NETCONF stream
+	Event string `json:"event,omitempty"`
+	// Paths are the paths of the data changed in the target
+	Paths        [][]PathElem `json:"paths,omitempty"`
+	Notification string       `json:"notification"`
+}
+
+// PathElem is an element of the path of a changed data node, with the keys of a list entry
+type PathElem struct {
+	Name string            `json:"name"`
+	Key  map[string]string `json:"key,omitempty"`
+}
+
+// Config configures the replay log
+type Config struct {
+	// Dir is the directory of the files of the log, created if missing
+	Dir string
+	// MaxAge is the age after which the notifications are removed
+	MaxAge time.Duration
+	// MaxSizeMB is the size of the files of the log above which the oldest notifications
+	// are removed
+	MaxSizeMB int
+}
+
+// segment is a file of the log, holding the records appended from its start time
+type segment struct {
+	path  string
+	start time.Time
+	// last is the time the last record was appended
+	last time.Time
+	size int64
+}
+
+// Log is a bounded log of notifications persisted as JSON lines in segment files, a new
+// segment being started when the current one reaches its share of the size of the log
+type Log struct {
+	config      Config
+	segmentSize int64
+	created     time.Time
+
+	mu       sync.Mutex
+	segments []*segment
+	file     *os.File
+	closed   bool
+}
+
+// Open opens the replay log in its directory, with the records it already holds
+func Open(config Config) (*Log, error) {
+	if config.MaxAge <= 0 {
+		config.MaxAge = DefaultMaxAge
+	}
+	if config.MaxSizeMB <= 0 {
+		config.MaxSizeMB = DefaultMaxSizeMB
+	}
+	err := os.MkdirAll(config.Dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		config:      config,
+		segmentSize: int64(config.MaxSizeMB) * 1024 * 1024 / segments,
+	}
+	l.created, err = creationTime(filepath.Join(config.Dir, createdFile))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		nanos, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		l.segments = append(l.segments, &segment{
+			path:  filepath.Join(config.Dir, name),
+			start: time.Unix(0, nanos),
+			last:  info.ModTime(),
+			size:  info.Size(),
+		})
+	}
+	sort.Slice(l.segments, func(i, j int) bool {
+		return l.segments[i].start.Before(l.segments[j].start)
+	})
+
+	l.prune(time.Now())
+	log.Infof("Opened replay log %s created at %s with %d segments", config.Dir, l.created.Format(time.RFC3339), len(l.segments))
+	return l, nil
+}
+
+// creationTime reads the creation time of the log from its file, the file being written with
+// the current time if it does not exist
+func creationTime(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		created, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid creation time in %s: %v", path, err)
+		}
+		return created, nil
+	}
+	if !os.IsNotExist(err) {
+		return time.Time{}, err
+	}
+
+	created := time.Now().UTC()
+	err = os.WriteFile(path, []byte(created.Format(time.RFC3339Nano)+"\n"), 0600)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return created, nil
+}
+
+// CreationTime returns the time the log was created
+func (l *Log) CreationTime() time.Time {
+	return l.created
+}
+
+// Append appends the record to the log, removing the records beyond its age and size
+func (l *Log) Append(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return os.ErrClosed
+	}
+	now := time.Now()
+	current := l.current()
+	if current == nil || l.file == nil || (current.size > 0 && current.size+int64(len(line)) > l.segmentSize) {
+		err = l.startSegment(now)
+		if err != nil {
+			return err
+		}
+		current = l.current()
+	}
+
+	n, err := l.file.Write(line)
+	current.size += int64(n)
+	current.last = now
+	l.prune(now)
+	return err
+}
+
+// current returns the segment records are appended to, nil if there is none
+func (l *Log) current() *segment {
+	if len(l.segments) == 0 {
+		return nil
+	}
+	return l.segments[len(l.segments)-1]
+}
+
+// startSegment closes the current segment and starts a new one
+func (l *Log) startSegment(now time.Time) error {
+	if l.file != nil {
+		err := l.file.Close()
+		l.file = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(l.config.Dir, fmt.Sprintf("%s%020d%s", segmentPrefix, now.UnixNano(), segmentSuffix))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	l.segments = append(l.segments, &segment{path: path, start: now, last: now})
+	return nil
+}
+
+// prune removes the oldest segments while the log exceeds its size or their records exceed
+// its age, the current segment being kept
+func (l *Log) prune(now time.Time) {
+	var size int64
+	for _, s := range l.segments {
+		size += s.size
+	}
+	maxSize := int64(l.config.MaxSizeMB) * 1024 * 1024
+	for len(l.segments) > 1 {
+		oldest := l.segments[0]
+		if size <= maxSize && now.Sub(oldest.last) <= l.config.MaxAge {
+			return
+		}
+		err := os.Remove(oldest.path)
+		if err != nil && !os.IsNotExist(err) {
+			log.Warnf("Replay log segment %s can not be removed: %v", oldest.path, err)
+			return
+		}
+		log.Debugf("Removed replay log segment %s", oldest.path)
+		size -= oldest.size
+		l.segments = l.segments[1:]
+	}
+}
+
+// Read calls fn with the records of the log whose event time is between start and stop, a
+// zero stop reading up to the last record, until fn returns false. The records are read in
+// the order they were appended, the records beyond the age of the log being skipped.
+func (l *Log) Read(start, stop time.Time, fn func(Record) bool) error {
+	type part struct {
+		path string
+		size int64
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return os.ErrClosed
+	}
+	// the event time of a record precedes the time it is appended at, so a segment started
+	// after the stop time may still hold records before it
+	var parts []part
+	for _, s := range l.segments {
+		if s.last.Before(start) {
+			continue
+		}
+		parts = append(parts, part{path: s.path, size: s.size})
+	}
+	l.mu.Unlock()
+
+	oldest := time.Now().Add(-l.config.MaxAge)
+	if oldest.After(start) {
+		start = oldest
+	}
+	for _, p := range parts {
+		more, err := readSegment(p.path, p.size, start, stop, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readSegment reads the first size bytes of the segment, returning false if fn stopped the read
+func readSegment(path string, size int64, start, stop time.Time, fn func(Record) bool) (bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// the segment was removed after the read started
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(io.LimitReader(file, size))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		record := Record{}
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			// a record partially written before a crash
+			log.Debugf("Invalid record in replay log segment %s: %v", path, err)
+			continue
+		}
+		if record.EventTime.Before(start) || (!stop.IsZero() && record.EventTime.After(stop)) {
+			continue
+		}
+		if !fn(record) {
+			return false, nil
+		}
+	}
+	return true, scanner.Err()
+}
+
+// Close closes the current segment of the log
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
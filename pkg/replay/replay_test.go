@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package replay
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openLog(t *testing.T, config Config) *Log {
+	t.Helper()
+	l, err := Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+	return l
+}
+
+// readAll returns the notifications of the records read between start and stop
+func readAll(t *testing.T, l *Log, start, stop time.Time) []string {
+	t.Helper()
+	var notifications []string
+	err := l.Read(start, stop, func(record Record) bool {
+		notifications = append(notifications, record.Notification)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notifications
+}
+
+// segmentFiles returns the names of the segment files of the directory
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, segmentPrefix+"*"+segmentSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestReadTimeRange(t *testing.T) {
+	l := openLog(t, Config{Dir: t.TempDir()})
+	base := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		err := l.Append(Record{EventTime: base.Add(time.Duration(i) * time.Second), Notification: fmt.Sprint(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		start, stop time.Time
+		want        string
+	}{
+		{name: "all", want: "0,1,2,3,4"},
+		{name: "from the start time", start: base.Add(2 * time.Second), want: "2,3,4"},
+		{name: "up to the stop time", stop: base.Add(time.Second), want: "0,1"},
+		{name: "between", start: base.Add(time.Second), stop: base.Add(3 * time.Second), want: "1,2,3"},
+		{name: "after the last record", start: base.Add(time.Minute), want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := strings.Join(readAll(t, l, test.start, test.stop), ","); got != test.want {
+				t.Errorf("records %s, want %s", got, test.want)
+			}
+		})
+	}
+
+	// the read stops when fn returns false
+	count := 0
+	err := l.Read(time.Time{}, time.Time{}, func(Record) bool {
+		count++
+		return count < 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("%d records read, want 2", count)
+	}
+}
+
+func TestReadSkipsExpiredRecords(t *testing.T) {
+	l := openLog(t, Config{Dir: t.TempDir(), MaxAge: time.Hour})
+	for _, record := range []Record{
+		{EventTime: time.Now().Add(-2 * time.Hour), Notification: "expired"},
+		{EventTime: time.Now(), Notification: "recent"},
+	} {
+		err := l.Append(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readAll(t, l, time.Time{}, time.Time{}); len(got) != 1 || got[0] != "recent" {
+		t.Errorf("records %v, want [recent]", got)
+	}
+}
+
+func TestPruneBySize(t *testing.T) {
+	dir := t.TempDir()
+	l := openLog(t, Config{Dir: dir, MaxSizeMB: 1})
+
+	// 40 records of 64KiB fill 20 segments of 128KiB
+	notification := strings.Repeat("x", 64*1024)
+	for i := 0; i < 40; i++ {
+		err := l.Append(Record{EventTime: time.Now(), Event: fmt.Sprint(i), Notification: notification})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var size int64
+	for _, path := range segmentFiles(t, dir) {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+	if size > 1024*1024 {
+		t.Errorf("log of %d bytes exceeds 1MiB", size)
+	}
+
+	var events []string
+	err := l.Read(time.Time{}, time.Time{}, func(record Record) bool {
+		events = append(events, record.Event)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || events[len(events)-1] != "39" {
+		t.Fatalf("last records %v lost", events)
+	}
+	if events[0] == "0" {
+		t.Error("oldest records kept beyond the size of the log")
+	}
+}
+
+func TestPruneByAge(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	oldSegment := filepath.Join(dir, fmt.Sprintf("%s%020d%s", segmentPrefix, old.UnixNano(), segmentSuffix))
+	recentSegment := filepath.Join(dir, fmt.Sprintf("%s%020d%s", segmentPrefix, time.Now().UnixNano(), segmentSuffix))
+	for _, path := range []string{oldSegment, recentSegment} {
+		err := os.WriteFile(path, []byte(`{"eventTime":"`+time.Now().UTC().Format(time.RFC3339Nano)+`","notification":"n"}`+"\n"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.Chtimes(oldSegment, old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openLog(t, Config{Dir: dir, MaxAge: time.Hour})
+	if _, err := os.Stat(oldSegment); !os.IsNotExist(err) {
+		t.Error("segment older than the age of the log kept")
+	}
+	if _, err := os.Stat(recentSegment); err != nil {
+		t.Errorf("recent segment removed: %v", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := l.CreationTime()
+	err = l.Append(Record{EventTime: time.Now(), Notification: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append(Record{EventTime: time.Now()}); err != os.ErrClosed {
+		t.Errorf("append to a closed log: %v", err)
+	}
+
+	// a record partially written before a crash is skipped
+	segments := segmentFiles(t, dir)
+	if len(segments) != 1 {
+		t.Fatalf("%d segments, want 1", len(segments))
+	}
+	file, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.WriteString(`{"eventTime":`)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l = openLog(t, Config{Dir: dir})
+	if !l.CreationTime().Equal(created) {
+		t.Errorf("creation time %s, want %s", l.CreationTime(), created)
+	}
+	err = l.Append(Record{EventTime: time.Now(), Notification: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, l, time.Time{}, time.Time{}); strings.Join(got, ",") != "first,second" {
+		t.Errorf("records %v, want [first second]", got)
+	}
+}
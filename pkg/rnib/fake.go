@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rnib
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fakeWatchBuffer is the number of events queued for a watch of the fake client
+const fakeWatchBuffer = 64
+
+// FakeClient is an in-memory TopoClient for tests, holding the o1t entities and the
+// registrations of onos-o1t
+type FakeClient struct {
+	mu            sync.Mutex
+	entities      map[string]O1tEntity
+	registrations map[string]Registration
+	watches       map[chan O1tEntityEvent]struct{}
+	// err is returned by the calls of the client when set
+	err error
+}
+
+// Registration is the entity registering onos-o1t in the fake client
+type Registration struct {
+	Server     Server
+	Expiration time.Time
+}
+
+// NewFakeClient creates a fake client holding the given o1t entities
+func NewFakeClient(entities ...O1tEntity) *FakeClient {
+	f := &FakeClient{
+		entities:      make(map[string]O1tEntity),
+		registrations: make(map[string]Registration),
+		watches:       make(map[chan O1tEntityEvent]struct{}),
+	}
+	for _, entity := range entities {
+		f.entities[entity.ID] = entity
+	}
+	return f
+}
+
+// SetError makes the calls of the client fail with the error, or succeed again if nil
+func (f *FakeClient) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+}
+
+// SetEntity adds or updates an o1t entity, sending the change to the watches
+func (f *FakeClient) SetEntity(entity O1tEntity) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	eventType := EntityUpdated
+	if _, ok := f.entities[entity.ID]; !ok {
+		eventType = EntityAdded
+	}
+	f.entities[entity.ID] = entity
+	f.notify(O1tEntityEvent{Type: eventType, Entity: entity})
+}
+
+// RemoveEntity removes an o1t entity, sending the change to the watches
+func (f *FakeClient) RemoveEntity(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entity, ok := f.entities[id]
+	if !ok {
+		return
+	}
+	delete(f.entities, id)
+	f.notify(O1tEntityEvent{Type: EntityRemoved, Entity: entity})
+}
+
+// notify queues the event to the watches, dropping it for a watch whose queue is full
+func (f *FakeClient) notify(event O1tEntityEvent) {
+	for queue := range f.watches {
+		select {
+		case queue <- event:
+		default:
+			log.Warnf("Dropping the event of entity %s of a fake watch", event.Entity.ID)
+		}
+	}
+}
+
+// Registration returns the registration of onos-o1t with the given id
+func (f *FakeClient) Registration(id string) (Registration, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	registration, ok := f.registrations[id]
+	return registration, ok
+}
+
+func (f *FakeClient) GetO1tConfigurables(ctx context.Context) ([]string, error) {
+	entities, err := f.GetO1tEntities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	configurables := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		configurables = append(configurables, entity.Configurable)
+	}
+	return configurables, nil
+}
+
+// GetO1tEntities returns the o1t entities sorted by id
+func (f *FakeClient) GetO1tEntities(ctx context.Context) ([]O1tEntity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.sortedEntities(), nil
+}
+
+func (f *FakeClient) sortedEntities() []O1tEntity {
+	entities := make([]O1tEntity, 0, len(f.entities))
+	for _, entity := range f.entities {
+		entities = append(entities, entity)
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].ID < entities[j].ID
+	})
+	return entities
+}
+
+// Watch replays the o1t entities as added, then sends their changes until the context is done
+func (f *FakeClient) Watch(ctx context.Context, ch chan<- O1tEntityEvent) error {
+	f.mu.Lock()
+	if f.err != nil {
+		err := f.err
+		f.mu.Unlock()
+		close(ch)
+		return err
+	}
+	queue := make(chan O1tEntityEvent, fakeWatchBuffer)
+	for _, entity := range f.sortedEntities() {
+		select {
+		case queue <- O1tEntityEvent{Type: EntityAdded, Entity: entity}:
+		default:
+		}
+	}
+	f.watches[queue] = struct{}{}
+	f.mu.Unlock()
+
+	go func() {
+		defer close(ch)
+		defer func() {
+			f.mu.Lock()
+			delete(f.watches, queue)
+			f.mu.Unlock()
+		}()
+		for {
+			select {
+			case event := <-queue:
+				select {
+				case ch <- event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (f *FakeClient) Register(ctx context.Context, id string, server Server, lease time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	server.Updated = time.Now().UTC()
+	f.registrations[id] = Registration{Server: server, Expiration: server.Updated.Add(lease)}
+	return nil
+}
+
+func (f *FakeClient) Unregister(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	delete(f.registrations, id)
+	return nil
+}
+
+var _ TopoClient = &FakeClient{}
@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rnib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-o1t/pkg/metrics"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
+	toposdk "github.com/onosproject/onos-ric-sdk-go/pkg/topo"
+	"github.com/onosproject/onos-ric-sdk-go/pkg/utils/creds"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	// ServerKind is the kind of the entity registering onos-o1t, distinct from the kind of the
+	// o1t entities of its targets
+	ServerKind = "onos-o1t"
+	// ServerAspect is the type of the aspect of the entity registering onos-o1t, its value
+	// being the JSON of the Server
+	ServerAspect = "onos.o1t.Server"
+)
+
+// Endpoint protocols and transports
+const (
+	ProtocolNetconf  = "netconf"
+	ProtocolRESTCONF = "restconf"
+
+	TransportSSH   = "ssh"
+	TransportTLS   = "tls"
+	TransportHTTP  = "http"
+	TransportHTTPS = "https"
+)
+
+// Server describes onos-o1t to the other components discovering it in onos-topo
+type Server struct {
+	Version      string     `json:"version"`
+	Endpoints    []Endpoint `json:"endpoints"`
+	Capabilities []string   `json:"capabilities"`
+	// Updated is the time of the last registration, set by Register
+	Updated time.Time `json:"updated"`
+}
+
+// Endpoint is an address onos-o1t serves NETCONF or RESTCONF on
+type Endpoint struct {
+	Protocol  string `json:"protocol"`
+	Transport string `json:"transport"`
+	Address   string `json:"address"`
+	Port      int    `json:"port"`
+}
+
+// dialTopo connects to onos-topo as the topo SDK client does
+func dialTopo() (*grpc.ClientConn, error) {
+	tlsConfig, err := creds.GetClientCredentials()
+	if err != nil {
+		return nil, err
+	}
+	address := fmt.Sprintf("%s:%d", toposdk.DefaultServiceHost, toposdk.DefaultServicePort)
+	return grpc.Dial(address, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+}
+
+func (c *Client) Register(ctx context.Context, id string, server Server, lease time.Duration) error {
+	ctx, span := tracing.Start(ctx, "topo.Register")
+	err := c.register(ctx, id, server, lease)
+	tracing.End(span, err)
+	return err
+}
+
+// register updates the entity, or creates it if it does not exist
+func (c *Client) register(ctx context.Context, id string, server Server, lease time.Duration) error {
+	start := time.Now()
+	object, err := c.client.Get(ctx, topoapi.ID(id))
+	metrics.ObserveTopo("Get", err, time.Since(start))
+	create := errors.IsNotFound(err)
+	if err != nil && !create {
+		return err
+	}
+	if create {
+		object = &topoapi.Object{
+			ID:   topoapi.ID(id),
+			Type: topoapi.Object_ENTITY,
+			Obj: &topoapi.Object_Entity{
+				Entity: &topoapi.Entity{KindID: ServerKind},
+			},
+		}
+	}
+
+	err = setServerAspects(object, server, lease)
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
+	if create {
+		err = c.client.Create(ctx, object)
+		metrics.ObserveTopo("Create", err, time.Since(start))
+		return err
+	}
+	err = c.client.Update(ctx, object)
+	metrics.ObserveTopo("Update", err, time.Since(start))
+	return err
+}
+
+// setServerAspects sets the server aspect and the lease aspect of the entity registering
+// onos-o1t
+func setServerAspects(object *topoapi.Object, server Server, lease time.Duration) error {
+	server.Updated = time.Now().UTC()
+	value, err := json.Marshal(server)
+	if err != nil {
+		return err
+	}
+	err = object.SetAspectBytes(ServerAspect, value)
+	if err != nil {
+		return err
+	}
+	expiration := server.Updated.Add(lease)
+	return object.SetAspect(&topoapi.Lease{Expiration: &expiration})
+}
+
+func (c *Client) Unregister(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "topo.Delete")
+	start := time.Now()
+	_, err := c.topo.Delete(ctx, &topoapi.DeleteRequest{ID: topoapi.ID(id)})
+	err = errors.FromGRPC(err)
+	metrics.ObserveTopo("Delete", err, time.Since(start))
+	tracing.End(span, err)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
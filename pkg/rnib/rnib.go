@@ -6,24 +6,100 @@ package rnib
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
+	"time"
 
 	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-o1t/pkg/metrics"
+	"github.com/onosproject/onos-o1t/pkg/tracing"
 	toposdk "github.com/onosproject/onos-ric-sdk-go/pkg/topo"
+	"google.golang.org/grpc"
 )
 
+var log = logging.GetLogger("rnib")
+
+// NamespaceMappingsAspect is the type of the aspect of the o1t entities mapping namespaces of
+// YANG modules to their configurable, its value being the JSON array of the mappings
+const NamespaceMappingsAspect = "onos.o1t.NamespaceMappings"
+
 type TopoClient interface {
 	GetO1tConfigurables(ctx context.Context) ([]string, error)
+	// GetO1tEntities returns the o1t entities with their configurable and namespace mappings
+	GetO1tEntities(ctx context.Context) ([]O1tEntity, error)
+	// Watch sends the changes of the o1t entities to the channel, closed when the watch ends
+	Watch(ctx context.Context, ch chan<- O1tEntityEvent) error
+	// Register creates or updates the entity registering onos-o1t, its lease expiring after
+	// the given duration unless it is registered again
+	Register(ctx context.Context, id string, server Server, lease time.Duration) error
+	// Unregister deletes the entity registering onos-o1t
+	Unregister(ctx context.Context, id string) error
+}
+
+// O1tEntity is an entity of the o1t kind, whose configurable is a capability of onos-o1t
+type O1tEntity struct {
+	ID string
+	// Configurable is the target:type:version of the configurable aspect of the entity
+	Configurable string
+	Mappings     []NamespaceMapping
+}
+
+// EventType is the type of a change of an o1t entity
+type EventType int
+
+const (
+	EntityAdded EventType = iota
+	EntityUpdated
+	EntityRemoved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EntityAdded:
+		return "added"
+	case EntityRemoved:
+		return "removed"
+	}
+	return "updated"
+}
+
+// O1tEntityEvent is a change of an o1t entity
+type O1tEntityEvent struct {
+	Type   EventType
+	Entity O1tEntity
+}
+
+// NamespaceMapping maps the data of the namespace of a YANG module to the target and model of
+// the configurable of an o1t entity, the aspect holding the namespace and the leaf selecting
+// the mapping by its value: equal to Value, starting with Prefix, or any value if both are
+// empty
+type NamespaceMapping struct {
+	Namespace string `json:"namespace"`
+	Target    string `json:"-"`
+	Model     string `json:"-"`
+	Version   string `json:"-"`
+	Leaf      string `json:"leaf,omitempty"`
+	Value     string `json:"value,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
 }
 
-// NewClient creates a new topo SDK client
-func NewClient() (TopoClient, error) {
+// NewClient creates a new topo SDK client listing the entities of the given kind
+func NewClient(kind string) (TopoClient, error) {
 	sdkClient, err := toposdk.NewClient()
 	if err != nil {
 		return &Client{}, err
 	}
+	// the topo SDK client can not delete objects
+	conn, err := dialTopo()
+	if err != nil {
+		return &Client{}, err
+	}
 	cl := &Client{
 		client: sdkClient,
+		conn:   conn,
+		topo:   topoapi.NewTopoClient(conn),
+		kind:   kind,
 	}
 	return cl, nil
 }
@@ -31,35 +107,133 @@ func NewClient() (TopoClient, error) {
 // Client topo SDK client
 type Client struct {
 	client toposdk.Client
+	// conn is the connection of the topo API client
+	conn *grpc.ClientConn
+	topo topoapi.TopoClient
+	kind string
+}
+
+// Close closes the connection of the topo API client
+func (c *Client) Close() error {
+	return c.conn.Close()
 }
 
 func (c *Client) GetO1tConfigurables(ctx context.Context) ([]string, error) {
-	O1tConfigurables := make([]string, 0)
-	objects, err := c.client.List(ctx, toposdk.WithListFilters(getO1tFilter()))
+	entities, err := c.GetO1tEntities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	O1tConfigurables := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		O1tConfigurables = append(O1tConfigurables, entity.Configurable)
+	}
+	return O1tConfigurables, nil
+}
+
+func (c *Client) GetO1tEntities(ctx context.Context) ([]O1tEntity, error) {
+	ctx, span := tracing.Start(ctx, "topo.List")
+	start := time.Now()
+	objects, err := c.client.List(ctx, toposdk.WithListFilters(getO1tFilter(c.kind)))
+	metrics.ObserveTopo("List", err, time.Since(start))
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
 
+	entities := make([]O1tEntity, 0, len(objects))
 	for _, object := range objects {
-		configurableObject := &topoapi.Configurable{}
-		err = object.GetAspect(configurableObject)
+		entity, err := o1tEntity(object)
 		if err != nil {
 			return nil, err
 		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+func (c *Client) Watch(ctx context.Context, ch chan<- O1tEntityEvent) error {
+	events := make(chan topoapi.Event)
+	start := time.Now()
+	err := c.client.Watch(ctx, events, toposdk.WithWatchFilters(getO1tFilter(c.kind)))
+	metrics.ObserveTopo("Watch", err, time.Since(start))
+	if err != nil {
+		close(ch)
+		return err
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range events {
+			if ctx.Err() != nil {
+				// the events are drained until the watch stream ends
+				continue
+			}
+			entity, err := o1tEntity(event.Object)
+			if err != nil {
+				log.Warnf("Ignoring entity %s: %v", event.Object.ID, err)
+				continue
+			}
+			eventType := EntityUpdated
+			switch event.Type {
+			case topoapi.EventType_NONE, topoapi.EventType_ADDED:
+				// the existing entities are replayed first with no event type
+				eventType = EntityAdded
+			case topoapi.EventType_REMOVED:
+				eventType = EntityRemoved
+			}
+			select {
+			case ch <- O1tEntityEvent{Type: eventType, Entity: entity}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return nil
+}
 
-		configurable := strings.Join([]string{configurableObject.Target, configurableObject.Type, configurableObject.Version}, ":")
-		O1tConfigurables = append(O1tConfigurables, configurable)
+// o1tEntity returns the o1t entity of a topo object, the target and model of its namespace
+// mappings being those of its configurable
+func o1tEntity(object topoapi.Object) (O1tEntity, error) {
+	configurableObject := &topoapi.Configurable{}
+	err := object.GetAspect(configurableObject)
+	if err != nil {
+		return O1tEntity{}, err
+	}
+	entity := O1tEntity{
+		ID:           string(object.ID),
+		Configurable: strings.Join([]string{configurableObject.Target, configurableObject.Type, configurableObject.Version}, ":"),
+	}
 
+	value, err := object.GetAspectBytes(NamespaceMappingsAspect)
+	if err != nil {
+		// the entity maps no namespace
+		return entity, nil
 	}
-	return O1tConfigurables, nil
+	err = json.Unmarshal(value, &entity.Mappings)
+	if err != nil {
+		log.Warnf("Invalid aspect %s of entity %s: %v", NamespaceMappingsAspect, object.ID, err)
+		entity.Mappings = nil
+		return entity, nil
+	}
+	for i := range entity.Mappings {
+		entity.Mappings[i].Target = configurableObject.Target
+		entity.Mappings[i].Model = configurableObject.Type
+		entity.Mappings[i].Version = configurableObject.Version
+	}
+	return entity, nil
+}
+
+// Check checks onos-topo is reachable
+func (c *Client) Check(ctx context.Context) error {
+	_, err := c.client.List(ctx, toposdk.WithListFilters(getO1tFilter(c.kind)))
+	return err
 }
 
-func getO1tFilter() *topoapi.Filters {
+func getO1tFilter(kind string) *topoapi.Filters {
 	controlRelationFilter := &topoapi.Filters{
 		KindFilter: &topoapi.Filter{
 			Filter: &topoapi.Filter_Equal_{
 				Equal_: &topoapi.EqualFilter{
-					Value: "o1t",
+					Value: kind,
 				},
 			},
 		},
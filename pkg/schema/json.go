@@ -0,0 +1,269 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// JSONToXML converts the JSON value of the data node at the path to the XML elements of
+// the top-level data nodes containing it, the list entries of the path carrying their keys.
+// The top-level elements are in the given namespace, or in the namespace of their module if
+// it is empty. With a schema, the value is read as RFC 7951 JSON: list keys come first,
+// identities are qualified by the prefix of their module and augmented nodes are in the
+// namespace of their module. Without a schema, or for the nodes the schema does not define,
+// objects become nested elements and arrays repeated elements.
+func JSONToXML(s *Schema, path *gnmi.Path, value []byte, namespace string) ([]byte, error) {
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	err := decoder.Decode(&tree)
+	if err != nil {
+		return nil, err
+	}
+
+	elems := path.GetElem()
+	for i := len(elems) - 1; i >= 0; i-- {
+		elem := elems[i]
+		if len(elem.Key) > 0 {
+			object, ok := tree.(map[string]interface{})
+			if !ok {
+				object = make(map[string]interface{})
+			}
+			for key, keyValue := range elem.Key {
+				if _, ok := object[key]; !ok {
+					object[key] = keyValue
+				}
+			}
+			tree = []interface{}{object}
+		}
+		tree = map[string]interface{}{elem.Name: tree}
+	}
+
+	object, ok := tree.(map[string]interface{})
+	if !ok {
+		// a value that is not a data node, e.g. a scalar at the root
+		object = map[string]interface{}{"data": tree}
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := xml.NewEncoder(buf)
+	for _, name := range sortedNames(object) {
+		module, local := splitName(name)
+		var entry *yang.Entry
+		if s != nil {
+			entry = s.Root(module, local)
+		}
+
+		elementNamespace := namespace
+		if elementNamespace == "" && entry != nil {
+			elementNamespace = entry.Namespace().Name
+		}
+		start := xml.StartElement{Name: xml.Name{Local: local}}
+		if elementNamespace != "" {
+			start.Attr = []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: elementNamespace}}
+		}
+		err = s.encodeNode(encoder, start, entry, object[name])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = encoder.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeNode encodes the value of the data node of the entry, nil if the node is not
+// defined by the schema
+func (s *Schema) encodeNode(encoder *xml.Encoder, start xml.StartElement, entry *yang.Entry, node interface{}) error {
+	if items, ok := node.([]interface{}); ok && (entry == nil || entry.IsList() || entry.IsLeafList()) {
+		for _, item := range items {
+			err := s.encodeNode(encoder, start, entry, item)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if object, ok := node.(map[string]interface{}); ok {
+		err := encoder.EncodeToken(start)
+		if err != nil {
+			return err
+		}
+		err = s.encodeMembers(encoder, entry, object)
+		if err != nil {
+			return err
+		}
+		return encoder.EncodeToken(start.End())
+	}
+
+	text := scalarText(node)
+	if entry != nil && entry.Type != nil {
+		leafText, ns, err := s.encodeLeaf(entry, entry.Type, node)
+		if err == nil {
+			text = leafText
+			if ns != nil {
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + ns.prefix}, Value: ns.namespace})
+			}
+		}
+	}
+	err := encoder.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	if text != "" {
+		err = encoder.EncodeToken(xml.CharData(text))
+		if err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// encodeMembers encodes the members of an object, the members defined by the schema first
+// in the order of the children of the entry
+func (s *Schema) encodeMembers(encoder *xml.Encoder, entry *yang.Entry, object map[string]interface{}) error {
+	members := make(map[string]string, len(object))
+	for name := range object {
+		_, local := splitName(name)
+		members[local] = name
+	}
+
+	if entry != nil && entry.IsDir() {
+		module := Module(entry)
+		for _, child := range Children(entry) {
+			name, ok := members[child.Name]
+			if !ok {
+				continue
+			}
+			delete(members, child.Name)
+
+			start := xml.StartElement{Name: xml.Name{Local: child.Name}}
+			if childModule := Module(child); childModule != module {
+				start.Attr = []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: child.Namespace().Name}}
+			}
+			err := s.encodeNode(encoder, start, child, object[name])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	locals := make([]string, 0, len(members))
+	for local := range members {
+		locals = append(locals, local)
+	}
+	sort.Strings(locals)
+	for _, local := range locals {
+		err := s.encodeNode(encoder, xml.StartElement{Name: xml.Name{Local: local}}, nil, object[members[local]])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedNames(object map[string]interface{}) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitName splits the name of a JSON member qualified by its module, as module:name
+func splitName(name string) (string, string) {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// DeletedXML returns the XML elements of the top-level data nodes containing the data node
+// at the path, as JSONToXML, the node being empty and marked by the edit-config delete
+// operation attribute
+func DeletedXML(s *Schema, path *gnmi.Path, namespace string) ([]byte, error) {
+	elems := path.GetElem()
+	if len(elems) == 0 {
+		return nil, nil
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := xml.NewEncoder(buf)
+
+	var entry *yang.Entry
+	var ends []xml.EndElement
+	for i, elem := range elems {
+		module, local := splitName(elem.Name)
+		parent := entry
+		switch {
+		case s == nil:
+		case parent == nil:
+			entry = s.Root(module, local)
+		default:
+			entry = Child(parent, local)
+		}
+
+		start := xml.StartElement{Name: xml.Name{Local: local}}
+		switch {
+		case i == 0 && namespace != "":
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: namespace})
+		case entry != nil && (parent == nil || Module(entry) != Module(parent)):
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: entry.Namespace().Name})
+		}
+		if i == len(elems)-1 {
+			start.Attr = append(start.Attr,
+				xml.Attr{Name: xml.Name{Local: "xmlns:nc"}, Value: NetconfBaseNamespace},
+				xml.Attr{Name: xml.Name{Local: "nc:operation"}, Value: "delete"})
+		}
+		err := encoder.EncodeToken(start)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []string
+		if entry != nil {
+			keys = Keys(entry)
+		}
+		if len(keys) != len(elem.Key) {
+			keys = make([]string, 0, len(elem.Key))
+			for key := range elem.Key {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		}
+		for _, key := range keys {
+			keyStart := xml.StartElement{Name: xml.Name{Local: key}}
+			err = encoder.EncodeElement(elem.Key[key], keyStart)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ends = append(ends, start.End())
+	}
+
+	for i := len(ends) - 1; i >= 0; i-- {
+		err := encoder.EncodeToken(ends[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := encoder.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
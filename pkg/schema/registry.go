@@ -0,0 +1,350 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+var log = logging.GetLogger("schema")
+
+const (
+	// yangExtension is the extension of the files of the YANG modules
+	yangExtension = ".yang"
+
+	// listInterval bounds how often the models of onos-config are listed to map a model
+	// that is not mapped yet
+	listInterval = 30 * time.Second
+)
+
+// Model is a model of the targets and the YANG modules it is made of
+type Model struct {
+	Name    string
+	Version string
+	Modules []string
+}
+
+// ModelLister lists the models known by onos-config with their YANG modules
+type ModelLister func(ctx context.Context) ([]Model, error)
+
+// Registry loads the YANG modules found in its directories and provides the schema of the
+// models of the targets. The modules of a model are, in order of preference, the modules
+// listed for the model by onos-config, the modules of a directory named <name>-<version>
+// or <name>, or the module named as the model.
+type Registry struct {
+	modules *yang.Modules
+	// entries are the entries of the modules by module name
+	entries map[string]*yang.Entry
+	// dirModules are the names of the modules found in each directory by directory name
+	dirModules map[string][]string
+	// files are the files of the modules and submodules by name
+	files  map[string]string
+	lister ModelLister
+
+	mu sync.Mutex
+	// models are the modules of the models listed by onos-config by name and version
+	models  map[string][]string
+	listed  time.Time
+	schemas map[string]*Schema
+}
+
+// NewRegistry loads the YANG modules of the files found in the directories, the lister
+// being optional
+func NewRegistry(dirs []string, lister ModelLister) (*Registry, error) {
+	r := &Registry{
+		modules:    yang.NewModules(),
+		entries:    make(map[string]*yang.Entry),
+		dirModules: make(map[string][]string),
+		files:      make(map[string]string),
+		lister:     lister,
+		models:     make(map[string][]string),
+		schemas:    make(map[string]*Schema),
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				// the modules import the modules of their own directory
+				r.modules.AddPath(path)
+				return nil
+			}
+			if filepath.Ext(path) == yangExtension {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error searching YANG modules in %s: %v", dir, err)
+		}
+	}
+
+	for _, file := range files {
+		known := make(map[string]bool, len(r.modules.Modules))
+		for name := range r.modules.Modules {
+			known[name] = true
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		statements, err := yang.Parse(string(data), file)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing YANG file %s: %v", file, err)
+		}
+		if len(statements) == 0 {
+			continue
+		}
+		name := statements[0].Argument
+		r.files[name] = file
+		err = r.modules.Parse(string(data), file)
+		if err != nil {
+			// the module may have been read already as the import of another module
+			if _, ok := r.modules.SubModules[name]; known[name] || ok {
+				log.Debugf("Skipping YANG file %s of module %s read already: %v", file, name, err)
+				continue
+			}
+			return nil, fmt.Errorf("error loading YANG module %s of %s: %v", name, file, err)
+		}
+		dir := filepath.Base(filepath.Dir(file))
+		for name, module := range r.modules.Modules {
+			if !known[name] && name == module.Name {
+				r.dirModules[dir] = append(r.dirModules[dir], module.Name)
+			}
+		}
+	}
+
+	errs := r.modules.Process()
+	if len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		return nil, fmt.Errorf("error processing YANG modules: %s", strings.Join(messages, "; "))
+	}
+
+	for name, module := range r.modules.Modules {
+		if name == module.Name {
+			r.entries[name] = yang.ToEntry(module)
+		}
+	}
+	log.Infof("Loaded %d YANG modules", len(r.entries))
+	return r, nil
+}
+
+// ModuleNames returns the names of the modules of the registry
+func (r *Registry) ModuleNames() []string {
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Module returns the module of the registry with the given name
+func (r *Registry) Module(name string) (*yang.Module, bool) {
+	if _, ok := r.entries[name]; !ok {
+		return nil, false
+	}
+	return r.modules.Modules[name], true
+}
+
+// ModuleInfo describes a YANG module or submodule of the registry
+type ModuleInfo struct {
+	Name     string
+	Revision string
+	// Namespace is the namespace of a module, that of the module a submodule belongs to
+	Namespace string
+	Submodule bool
+	// Features are the features defined by the module, Deviations the modules deviating it
+	Features   []string
+	Deviations []string
+	// Imports are the modules imported by the module, Submodules the submodules it includes
+	Imports    []string
+	Submodules []string
+}
+
+// ModuleInfos returns the modules and submodules of the registry loaded from a file, sorted
+// by name
+func (r *Registry) ModuleInfos() []ModuleInfo {
+	deviations := r.deviations()
+	var infos []ModuleInfo
+	add := func(module *yang.Module, submodule bool) {
+		if _, ok := r.files[module.Name]; !ok {
+			return
+		}
+		info := ModuleInfo{Name: module.Name, Revision: module.Current(), Submodule: submodule}
+		for _, feature := range module.Feature {
+			info.Features = append(info.Features, feature.Name)
+		}
+		for _, imported := range module.Import {
+			info.Imports = append(info.Imports, imported.Name)
+		}
+		for _, included := range module.Include {
+			info.Submodules = append(info.Submodules, included.Name)
+		}
+		info.Deviations = deviations[module.Name]
+		if module.Namespace != nil {
+			info.Namespace = module.Namespace.Name
+		} else if module.BelongsTo != nil {
+			if parent, ok := r.modules.Modules[module.BelongsTo.Name]; ok && parent.Namespace != nil {
+				info.Namespace = parent.Namespace.Name
+			}
+		}
+		infos = append(infos, info)
+	}
+	for name, module := range r.modules.Modules {
+		if name == module.Name {
+			add(module, false)
+		}
+	}
+	for name, module := range r.modules.SubModules {
+		if name == module.Name {
+			add(module, true)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// deviations returns the names of the modules deviating each module by module name, the
+// deviated module being that of the prefix of the first node of the target of a deviation
+func (r *Registry) deviations() map[string][]string {
+	deviations := make(map[string][]string)
+	for name, module := range r.modules.Modules {
+		if name != module.Name {
+			continue
+		}
+		deviated := make(map[string]bool)
+		for _, deviation := range module.Deviation {
+			node := strings.SplitN(strings.TrimPrefix(deviation.Name, "/"), "/", 2)[0]
+			i := strings.Index(node, ":")
+			if i < 0 {
+				continue
+			}
+			prefix := node[:i]
+			for _, imported := range module.Import {
+				if imported.Prefix != nil && imported.Prefix.Name == prefix && !deviated[imported.Name] {
+					deviated[imported.Name] = true
+					deviations[imported.Name] = append(deviations[imported.Name], module.Name)
+				}
+			}
+		}
+	}
+	for _, names := range deviations {
+		sort.Strings(names)
+	}
+	return deviations
+}
+
+// Source returns the YANG source of the module or submodule, false if it is not loaded or
+// if its current revision is not the given revision
+func (r *Registry) Source(name, revision string) (string, bool) {
+	file, ok := r.files[name]
+	if !ok {
+		return "", false
+	}
+	module, ok := r.modules.Modules[name]
+	if !ok || module.Name != name {
+		module, ok = r.modules.SubModules[name]
+	}
+	if !ok || (revision != "" && module.Current() != revision) {
+		return "", false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		log.Warnf("Error reading the source of YANG module %s: %v", name, err)
+		return "", false
+	}
+	return string(data), true
+}
+
+// Schema returns the schema of the model, false if none of the modules of the model is loaded
+func (r *Registry) Schema(ctx context.Context, name, version string) (*Schema, bool) {
+	key := name + ":" + version
+
+	r.mu.Lock()
+	if schema, ok := r.schemas[key]; ok {
+		r.mu.Unlock()
+		return schema, true
+	}
+	_, ok := r.models[key]
+	list := !ok && r.lister != nil && time.Since(r.listed) > listInterval
+	if list {
+		r.listed = time.Now()
+	}
+	r.mu.Unlock()
+
+	// the models are listed without the lock, the schemas of the other models being
+	// available meanwhile
+	if list {
+		r.listModels(ctx)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if schema, ok := r.schemas[key]; ok {
+		return schema, true
+	}
+
+	modules, ok := r.models[key]
+	if !ok {
+		modules, ok = r.dirModules[name+"-"+version]
+	}
+	if !ok {
+		modules, ok = r.dirModules[name]
+	}
+	if !ok {
+		modules = []string{name}
+	}
+
+	entries := make([]*yang.Entry, 0, len(modules))
+	for _, module := range modules {
+		if entry, ok := r.entries[module]; ok {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, false
+	}
+
+	schema := newSchema(r.modules, entries)
+	r.schemas[key] = schema
+	return schema, true
+}
+
+// listModels updates the modules of the models listed by onos-config, locking the registry
+// only to store them
+func (r *Registry) listModels(ctx context.Context) {
+	models, err := r.lister(ctx)
+	if err != nil {
+		log.Warnf("Error listing the models of onos-config: %v", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, model := range models {
+		r.models[model.Name+":"+model.Version] = model.Modules
+	}
+}
@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// Schema is the schema tree of the YANG modules of a model
+type Schema struct {
+	modules *yang.Modules
+	// roots are the entries of the modules of the model
+	roots []*yang.Entry
+}
+
+func newSchema(modules *yang.Modules, roots []*yang.Entry) *Schema {
+	return &Schema{modules: modules, roots: roots}
+}
+
+// Modules returns the names of the modules of the model
+func (s *Schema) Modules() []string {
+	names := make([]string, 0, len(s.roots))
+	for _, root := range s.roots {
+		names = append(names, root.Name)
+	}
+	return names
+}
+
+// Root returns the top-level data node with the given name, in the given module if it is
+// not empty
+func (s *Schema) Root(module, name string) *yang.Entry {
+	for _, root := range s.roots {
+		entry := Child(root, name)
+		if entry == nil {
+			continue
+		}
+		if module == "" || Module(entry) == module {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Find returns the entry of the data node at the path of node names, the module of the
+// top-level node being optional
+func (s *Schema) Find(module string, names []string) *yang.Entry {
+	if len(names) == 0 {
+		return nil
+	}
+	entry := s.Root(module, names[0])
+	for _, name := range names[1:] {
+		if entry == nil {
+			return nil
+		}
+		entry = Child(entry, name)
+	}
+	return entry
+}
+
+// moduleByNamespace returns the name of the module of the XML namespace, empty if the
+// namespace is not the namespace of a loaded module
+func (s *Schema) moduleByNamespace(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	module, err := s.modules.FindModuleByNamespace(namespace)
+	if err != nil {
+		return ""
+	}
+	return module.Name
+}
+
+// Child returns the child data node of the entry with the given name, looking through the
+// choices and cases of the entry
+func Child(entry *yang.Entry, name string) *yang.Entry {
+	if child, ok := entry.Dir[name]; ok && !child.IsChoice() && !child.IsCase() {
+		return child
+	}
+	for _, child := range entry.Dir {
+		if child.IsChoice() || child.IsCase() {
+			if found := Child(child, name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// Children returns the child data nodes of the entry, the children of its choices and cases
+// included, list keys first and the others sorted by name
+func Children(entry *yang.Entry) []*yang.Entry {
+	var children []*yang.Entry
+	var collect func(e *yang.Entry)
+	collect = func(e *yang.Entry) {
+		for _, child := range e.Dir {
+			if child.IsChoice() || child.IsCase() {
+				collect(child)
+				continue
+			}
+			children = append(children, child)
+		}
+	}
+	collect(entry)
+
+	keys := Keys(entry)
+	rank := func(e *yang.Entry) int {
+		for i, key := range keys {
+			if e.Name == key {
+				return i
+			}
+		}
+		return len(keys)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		ri, rj := rank(children[i]), rank(children[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return children[i].Name < children[j].Name
+	})
+	return children
+}
+
+// Keys returns the names of the keys of a list entry
+func Keys(entry *yang.Entry) []string {
+	if !entry.IsList() {
+		return nil
+	}
+	return strings.Fields(entry.Key)
+}
+
+// Module returns the name of the module defining the data node, the module of the augment
+// for augmented nodes
+func Module(entry *yang.Entry) string {
+	module, err := entry.InstantiatingModule()
+	if err != nil {
+		return ""
+	}
+	return module
+}
+
+// Path returns the path of the data node, as an instance identifier without the choices and
+// cases and with the module of the top-level node
+func Path(entry *yang.Entry) string {
+	var nodes []*yang.Entry
+	for e := entry; e != nil && e.Parent != nil; e = e.Parent {
+		if !e.IsChoice() && !e.IsCase() {
+			nodes = append([]*yang.Entry{e}, nodes...)
+		}
+	}
+
+	var b strings.Builder
+	parentModule := ""
+	for _, node := range nodes {
+		b.WriteString("/")
+		if module := Module(node); module != parentModule {
+			b.WriteString(module + ":")
+			parentModule = module
+		}
+		b.WriteString(node.Name)
+	}
+	return b.String()
+}
+
+// identityModule returns the module defining the identity
+func identityModule(identity *yang.Identity) *yang.Module {
+	module := yang.RootNode(identity)
+	if module != nil && module.Kind() == "submodule" && module.BelongsTo != nil {
+		if parent, ok := module.Modules.Modules[module.BelongsTo.Name]; ok {
+			return parent
+		}
+	}
+	return module
+}
+
+// Error is an error of the data of a request against the schema of its model
+type Error struct {
+	// Tag is the NETCONF error-tag of the error
+	Tag string
+	// AppTag is the error-app-tag of the error, empty if none
+	AppTag string
+	// Path is the path of the data node in error, empty if unknown
+	Path string
+	// BadElement is the name of the element in error, empty if unknown
+	BadElement string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	return e.Message
+}
+
+// withBadElement sets the name of the element in error
+func (e *Error) withBadElement(name string) *Error {
+	e.BadElement = name
+	return e
+}
+
+const (
+	// TagUnknownElement is the error-tag of the elements that are not defined by the schema
+	TagUnknownElement = "unknown-element"
+	// TagBadElement is the error-tag of the elements having an invalid value
+	TagBadElement = "bad-element"
+	// TagInvalidValue is the error-tag of the values that are not valid
+	TagInvalidValue = "invalid-value"
+	// TagMalformedMessage is the error-tag of the data that can not be decoded
+	TagMalformedMessage = "malformed-message"
+)
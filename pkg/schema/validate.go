@@ -0,0 +1,415 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+const (
+	// NetconfBaseNamespace is the namespace of the operation attribute of the edit-config data
+	NetconfBaseNamespace = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+	// TagMissingElement is the error-tag of the missing mandatory nodes and list keys
+	TagMissingElement = "missing-element"
+	// TagDataMissing is the error-tag of the mandatory choices without case
+	TagDataMissing = "data-missing"
+	// TagOperationFailed is the error-tag of the violated must and element count constraints
+	TagOperationFailed = "operation-failed"
+
+	operationCreate  = "create"
+	operationReplace = "replace"
+	operationDelete  = "delete"
+	operationRemove  = "remove"
+)
+
+// dataNode is a data node of the data of a request, with the entry of its schema
+type dataNode struct {
+	entry    *yang.Entry
+	parent   *dataNode
+	children []*dataNode
+	// text is the value of a leaf or leaf-list
+	text string
+	// operation is the edit operation of the node, inherited from its parent
+	operation string
+}
+
+// stringValue returns the XPath string value of the node
+func (n *dataNode) stringValue() string {
+	if len(n.children) == 0 {
+		return strings.TrimSpace(n.text)
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(child.stringValue())
+	}
+	return b.String()
+}
+
+// path returns the instance identifier of the node, the list entries being identified by
+// their keys
+func (n *dataNode) path() string {
+	var nodes []*dataNode
+	for node := n; node != nil && node.entry != nil; node = node.parent {
+		nodes = append([]*dataNode{node}, nodes...)
+	}
+
+	var b strings.Builder
+	parentModule := ""
+	for _, node := range nodes {
+		b.WriteString("/")
+		if module := Module(node.entry); module != parentModule {
+			b.WriteString(module + ":")
+			parentModule = module
+		}
+		b.WriteString(node.entry.Name)
+		for _, key := range Keys(node.entry) {
+			if child := node.child(key); child != nil {
+				fmt.Fprintf(&b, "[%s='%s']", key, child.stringValue())
+			}
+		}
+	}
+	return b.String()
+}
+
+// child returns the first child node with the given name
+func (n *dataNode) child(name string) *dataNode {
+	for _, child := range n.children {
+		if child.entry.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// creates returns whether the operation of the node creates or replaces it, the node then
+// having to hold its mandatory nodes
+func (n *dataNode) creates() bool {
+	return n.operation == operationCreate || n.operation == operationReplace
+}
+
+// deletes returns whether the operation of the node deletes it
+func (n *dataNode) deletes() bool {
+	return n.operation == operationDelete || n.operation == operationRemove
+}
+
+// Validate checks the XML data of an edit-config against the schema: the nodes must be
+// configuration nodes defined by the schema, the values must match their type and its
+// restrictions, the list entries must have all their keys and be unique, and the element
+// counts and the must and when expressions must hold. The mandatory nodes and minimum
+// element counts are checked for the nodes the edit creates or replaces, the
+// defaultOperation applying to the nodes without operation attribute. The must and when
+// expressions are only evaluated when the nodes they refer to are in the data.
+func (s *Schema) Validate(data string, defaultOperation string) error {
+	elements, err := parseElements(data)
+	if err != nil {
+		return &Error{
+			Tag:     TagMalformedMessage,
+			Message: fmt.Sprintf("malformed data: %v", err),
+		}
+	}
+
+	root := &dataNode{operation: defaultOperation}
+	for _, e := range elements {
+		err = s.buildNode(root, e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return root.walk(func(n *dataNode) error {
+		err := n.checkChildren()
+		if err != nil {
+			return err
+		}
+		return n.checkExpressions(root)
+	})
+}
+
+// buildNode adds the data node of the element to its parent, checking its value
+func (s *Schema) buildNode(parent *dataNode, e *element) error {
+	var entry *yang.Entry
+	if parent.entry == nil {
+		entry = s.Root(s.moduleByNamespace(e.name.Space), e.name.Local)
+	} else {
+		entry = Child(parent.entry, e.name.Local)
+	}
+	if entry == nil {
+		return &Error{
+			Tag:        TagUnknownElement,
+			Path:       parent.path() + "/" + e.name.Local,
+			BadElement: e.name.Local,
+			Message:    fmt.Sprintf("element %s is not defined by the schema", e.name.Local),
+		}
+	}
+
+	n := &dataNode{
+		entry:     entry,
+		parent:    parent,
+		text:      e.text,
+		operation: parent.operation,
+	}
+	if e.operation != "" {
+		n.operation = e.operation
+	}
+	parent.children = append(parent.children, n)
+
+	if entry.ReadOnly() {
+		return n.error(TagInvalidValue, "", fmt.Sprintf("%s is not configuration data", entry.Name))
+	}
+
+	if entry.IsLeaf() || entry.IsLeafList() {
+		if len(e.children) > 0 {
+			return n.error(TagBadElement, "", fmt.Sprintf("leaf %s can not have child elements", entry.Name))
+		}
+		if n.deletes() && strings.TrimSpace(e.text) == "" {
+			return nil
+		}
+		_, err := s.decodeLeaf(entry, entry.Type, e.text, e.prefixes)
+		if err != nil {
+			return n.error(TagInvalidValue, "", err.Error())
+		}
+		return nil
+	}
+
+	for _, child := range e.children {
+		err := s.buildNode(n, child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk calls f for the node and its descendants, depth first
+func (n *dataNode) walk(f func(*dataNode) error) error {
+	err := f(n)
+	if err != nil {
+		return err
+	}
+	for _, child := range n.children {
+		err = child.walk(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkChildren checks the keys of the list entries, the uniqueness and the number of the
+// child nodes and, if the node is created or replaced, its mandatory nodes
+func (n *dataNode) checkChildren() error {
+	if n.entry != nil && n.entry.IsList() {
+		for _, key := range Keys(n.entry) {
+			if n.child(key) == nil {
+				return n.error(TagMissingElement, "", fmt.Sprintf("list entry %s has no key %s", n.entry.Name, key)).withBadElement(key)
+			}
+		}
+	}
+
+	counts := make(map[*yang.Entry]int)
+	seen := make(map[string]*dataNode)
+	for _, child := range n.children {
+		counts[child.entry]++
+		if child.deletes() {
+			continue
+		}
+
+		identity := ""
+		switch {
+		case child.entry.IsList():
+			var keys []string
+			for _, key := range Keys(child.entry) {
+				if keyNode := child.child(key); keyNode != nil {
+					keys = append(keys, keyNode.stringValue())
+				}
+			}
+			identity = child.entry.Name + "[" + strings.Join(keys, ",") + "]"
+		case child.entry.IsLeafList():
+			identity = child.entry.Name + "[.=" + child.stringValue() + "]"
+		case child.entry.IsLeaf() || child.entry.IsContainer():
+			identity = child.entry.Name
+		}
+		if identity == "" {
+			continue
+		}
+		if _, ok := seen[identity]; ok {
+			return child.error(TagBadElement, "", fmt.Sprintf("%s appears more than once", child.entry.Name))
+		}
+		seen[identity] = child
+	}
+
+	for entry, count := range counts {
+		if entry.ListAttr != nil && entry.ListAttr.MaxElements > 0 && uint64(count) > entry.ListAttr.MaxElements {
+			return n.error(TagOperationFailed, "too-many-elements",
+				fmt.Sprintf("%s has %d elements, at most %d are allowed", entry.Name, count, entry.ListAttr.MaxElements)).withBadElement(entry.Name)
+		}
+	}
+
+	if n.entry == nil || !n.creates() || n.entry.IsLeaf() || n.entry.IsLeafList() {
+		return nil
+	}
+	return n.checkMandatory(n.entry, counts)
+}
+
+// checkMandatory checks the mandatory nodes of the entry, a data node or the active case of
+// a choice, are in the children of the node
+func (n *dataNode) checkMandatory(entry *yang.Entry, counts map[*yang.Entry]int) error {
+	for _, child := range entry.Dir {
+		switch {
+		case child.IsChoice():
+			active := activeCase(child, counts)
+			if active == nil {
+				if child.Mandatory == yang.TSTrue {
+					return n.error(TagDataMissing, "missing-choice",
+						fmt.Sprintf("a case of the mandatory choice %s is required", child.Name)).withBadElement(child.Name)
+				}
+				continue
+			}
+			err := n.checkMandatory(active, counts)
+			if err != nil {
+				return err
+			}
+		case child.IsCase():
+			err := n.checkMandatory(child, counts)
+			if err != nil {
+				return err
+			}
+		case child.IsLeaf():
+			if child.Mandatory == yang.TSTrue && counts[child] == 0 && !child.ReadOnly() {
+				return n.error(TagMissingElement, "",
+					fmt.Sprintf("mandatory leaf %s is missing", child.Name)).withBadElement(child.Name)
+			}
+		case child.IsList() || child.IsLeafList():
+			if child.ListAttr != nil && uint64(counts[child]) < child.ListAttr.MinElements && !child.ReadOnly() {
+				return n.error(TagOperationFailed, "too-few-elements",
+					fmt.Sprintf("%s has %d elements, at least %d are required", child.Name, counts[child], child.ListAttr.MinElements)).withBadElement(child.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// activeCase returns the case of the choice having data nodes in the counts, nil if none
+func activeCase(choice *yang.Entry, counts map[*yang.Entry]int) *yang.Entry {
+	for _, c := range choice.Dir {
+		if hasData(c, counts) {
+			return c
+		}
+	}
+	return nil
+}
+
+func hasData(entry *yang.Entry, counts map[*yang.Entry]int) bool {
+	if counts[entry] > 0 {
+		return true
+	}
+	if entry.IsChoice() || entry.IsCase() {
+		for _, child := range entry.Dir {
+			if hasData(child, counts) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkExpressions evaluates the when and must expressions of the node
+func (n *dataNode) checkExpressions(root *dataNode) error {
+	if n.entry == nil || n.deletes() {
+		return nil
+	}
+
+	if when, ok := n.entry.GetWhenXPath(); ok {
+		holds, known := evaluate(when, root, n)
+		if known && !holds {
+			return n.error(TagUnknownElement, "",
+				fmt.Sprintf("%s is not allowed, its when condition %q is false", n.entry.Name, when)).withBadElement(n.entry.Name)
+		}
+	}
+	// the when condition of an augment applies to its nodes with the target as context
+	if augment, ok := parentNode(n.entry).(*yang.Augment); ok && augment.When != nil && n.parent != nil && n.parent.entry != nil {
+		holds, known := evaluate(augment.When.Name, root, n.parent)
+		if known && !holds {
+			return n.error(TagUnknownElement, "",
+				fmt.Sprintf("%s is not allowed, its when condition %q is false", n.entry.Name, augment.When.Name)).withBadElement(n.entry.Name)
+		}
+	}
+
+	for _, must := range mustStatements(n.entry) {
+		holds, known := evaluate(must.Name, root, n)
+		if !known || holds {
+			continue
+		}
+		appTag := "must-violation"
+		if must.ErrorAppTag != nil {
+			appTag = must.ErrorAppTag.Name
+		}
+		message := fmt.Sprintf("must condition %q of %s is not satisfied", must.Name, n.entry.Name)
+		if must.ErrorMessage != nil {
+			message = must.ErrorMessage.Name
+		}
+		return n.error(TagOperationFailed, appTag, message)
+	}
+	return nil
+}
+
+// evaluate evaluates an XPath expression with the node as context, returning whether the
+// result is known: the expression must be supported and select nodes of the data
+func evaluate(expr string, root, n *dataNode) (bool, bool) {
+	e, err := parseXPath(expr)
+	if err != nil {
+		log.Debugf("Not evaluating %q: %v", expr, err)
+		return false, false
+	}
+	c := &xcontext{root: root, current: n}
+	v, err := e.eval(c, n)
+	if err != nil {
+		log.Debugf("Not evaluating %q: %v", expr, err)
+		return false, false
+	}
+	if c.unknown {
+		return false, false
+	}
+	return xboolean(v), true
+}
+
+func parentNode(entry *yang.Entry) yang.Node {
+	if entry.Node == nil {
+		return nil
+	}
+	return entry.Node.ParentNode()
+}
+
+// mustStatements returns the must statements of the node of the entry
+func mustStatements(entry *yang.Entry) []*yang.Must {
+	switch node := entry.Node.(type) {
+	case *yang.Container:
+		return node.Must
+	case *yang.List:
+		return node.Must
+	case *yang.Leaf:
+		return node.Must
+	case *yang.LeafList:
+		return node.Must
+	}
+	return nil
+}
+
+func (n *dataNode) error(tag, appTag, message string) *Error {
+	e := &Error{
+		Tag:     tag,
+		AppTag:  appTag,
+		Path:    n.path(),
+		Message: message,
+	}
+	if n.entry != nil {
+		e.BadElement = n.entry.Name
+	}
+	return e
+}
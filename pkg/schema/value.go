@@ -0,0 +1,295 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// predicates matches the predicates of the path of a leafref
+var predicates = regexp.MustCompile(`\[[^\]]*\]`)
+
+// leafrefType returns the type of the leaf referred by the leafref type of the entry, nil if
+// the leaf can not be found
+func leafrefType(entry *yang.Entry, typ *yang.YangType) *yang.YangType {
+	target := entry.Find(predicates.ReplaceAllString(typ.Path, ""))
+	if target == nil || target.Type == nil || target.Type.Kind == yang.Yleafref {
+		return nil
+	}
+	return target.Type
+}
+
+// decodeLeaf converts the text of a leaf element to its RFC 7951 JSON value, the prefixes
+// mapping the XML prefixes in scope to their namespaces
+func (s *Schema) decodeLeaf(entry *yang.Entry, typ *yang.YangType, text string, prefixes map[string]string) (interface{}, error) {
+	if typ.Kind != yang.Ystring && typ.Kind != yang.Yunion {
+		text = strings.TrimSpace(text)
+	}
+
+	switch typ.Kind {
+	case yang.Yint8, yang.Yint16, yang.Yint32:
+		v, err := strconv.ParseInt(text, 10, intBits(typ.Kind))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %s", text, typ.Kind)
+		}
+		return v, checkRange(typ, text, yang.FromInt(v))
+	case yang.Yuint8, yang.Yuint16, yang.Yuint32:
+		v, err := strconv.ParseUint(text, 10, intBits(typ.Kind))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %s", text, typ.Kind)
+		}
+		return v, checkRange(typ, text, yang.FromUint(v))
+	case yang.Yint64:
+		// 64 bits integers are JSON strings (RFC 7951, section 6.1)
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %s", text, typ.Kind)
+		}
+		return text, checkRange(typ, text, yang.FromInt(v))
+	case yang.Yuint64:
+		v, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %s", text, typ.Kind)
+		}
+		return text, checkRange(typ, text, yang.FromUint(v))
+	case yang.Ydecimal64:
+		v, err := yang.ParseDecimal(text, uint8(typ.FractionDigits))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %s with %d fraction digits", text, typ.Kind, typ.FractionDigits)
+		}
+		return text, checkRange(typ, text, v)
+	case yang.Ystring:
+		err := checkLength(typ, text, utf8.RuneCountInString(text))
+		if err != nil {
+			return nil, err
+		}
+		return text, checkPatterns(typ, text)
+	case yang.Ybinary:
+		v, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid base64 encoded %s", text, typ.Kind)
+		}
+		return text, checkLength(typ, text, len(v))
+	case yang.Ybits:
+		for _, bit := range strings.Fields(text) {
+			if typ.Bit != nil && !typ.Bit.IsDefined(bit) {
+				return nil, fmt.Errorf("%q is not a bit of the bits type", bit)
+			}
+		}
+		return strings.Join(strings.Fields(text), " "), nil
+	case yang.Ybool:
+		switch text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a valid %s", text, typ.Kind)
+	case yang.Yempty:
+		if text != "" {
+			return nil, fmt.Errorf("a leaf of type empty has no value")
+		}
+		return []interface{}{nil}, nil
+	case yang.Yenum:
+		if typ.Enum != nil && !typ.Enum.IsDefined(text) {
+			return nil, fmt.Errorf("%q is not a valid enumeration value", text)
+		}
+		return text, nil
+	case yang.Yidentityref:
+		return s.decodeIdentity(typ, text, prefixes)
+	case yang.Yleafref:
+		if target := leafrefType(entry, typ); target != nil {
+			return s.decodeLeaf(entry, target, text, prefixes)
+		}
+		return text, nil
+	case yang.Yunion:
+		for _, member := range typ.Type {
+			if v, err := s.decodeLeaf(entry, member, text, prefixes); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("%q does not match any type of the union", text)
+	}
+	return text, nil
+}
+
+// checkRange checks the number is in the range of the type, if any
+func checkRange(typ *yang.YangType, text string, n yang.Number) error {
+	if len(typ.Range) > 0 && !typ.Range.Contains(yang.YangRange{{Min: n, Max: n}}) {
+		return fmt.Errorf("%s is not in the range %s", text, typ.Range)
+	}
+	return nil
+}
+
+// checkLength checks the length of the value is in the length of the type, if any
+func checkLength(typ *yang.YangType, text string, length int) error {
+	n := yang.FromInt(int64(length))
+	if len(typ.Length) > 0 && !typ.Length.Contains(yang.YangRange{{Min: n, Max: n}}) {
+		return fmt.Errorf("the length of %q is not in the length %s", text, typ.Length)
+	}
+	return nil
+}
+
+var (
+	patternsMu sync.Mutex
+	// patterns caches the patterns of the types compiled as Go regular expressions, nil for
+	// the XSD regular expressions Go can not compile
+	patterns = make(map[string]*regexp.Regexp)
+)
+
+// checkPatterns checks the value matches all the patterns of the type. The patterns are XSD
+// regular expressions, those using a syntax Go does not support being ignored.
+func checkPatterns(typ *yang.YangType, text string) error {
+	for _, pattern := range typ.Pattern {
+		patternsMu.Lock()
+		re, ok := patterns[pattern]
+		if !ok {
+			// XSD regular expressions are implicitly anchored
+			var err error
+			re, err = regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				log.Debugf("Ignoring pattern %q: %v", pattern, err)
+				re = nil
+			}
+			patterns[pattern] = re
+		}
+		patternsMu.Unlock()
+
+		if re != nil && !re.MatchString(text) {
+			return fmt.Errorf("%q does not match the pattern %q", text, pattern)
+		}
+	}
+	return nil
+}
+
+// decodeIdentity converts an identity qualified by a XML prefix to the identity qualified
+// by the name of its module
+func (s *Schema) decodeIdentity(typ *yang.YangType, text string, prefixes map[string]string) (interface{}, error) {
+	module, name := "", text
+	if i := strings.Index(text, ":"); i >= 0 {
+		module = s.moduleByNamespace(prefixes[text[:i]])
+		name = text[i+1:]
+	}
+
+	if typ.IdentityBase == nil {
+		return text, nil
+	}
+	for _, identity := range typ.IdentityBase.Values {
+		if identity.Name != name {
+			continue
+		}
+		identityModule := identityModule(identity)
+		if identityModule == nil {
+			continue
+		}
+		if module == "" || module == identityModule.Name {
+			return identityModule.Name + ":" + name, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not an identity derived from %s", text, typ.IdentityBase.Name)
+}
+
+// encodeLeaf formats a RFC 7951 JSON value as the text of a leaf element, returning the
+// namespace declaration the text needs, if any
+func (s *Schema) encodeLeaf(entry *yang.Entry, typ *yang.YangType, value interface{}) (string, *xmlns, error) {
+	switch typ.Kind {
+	case yang.Yempty:
+		return "", nil, nil
+	case yang.Yidentityref:
+		text, ok := value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("%v is not an identity", value)
+		}
+		return s.encodeIdentity(typ, text)
+	case yang.Yleafref:
+		if target := leafrefType(entry, typ); target != nil {
+			return s.encodeLeaf(entry, target, value)
+		}
+	case yang.Yunion:
+		for _, member := range typ.Type {
+			if member.Kind == yang.Yidentityref || member.Kind == yang.Yleafref {
+				if text, ns, err := s.encodeLeaf(entry, member, value); err == nil {
+					return text, ns, nil
+				}
+			}
+		}
+	}
+	return scalarText(value), nil, nil
+}
+
+// encodeIdentity converts an identity qualified by the name of its module to the identity
+// qualified by the prefix of its module
+func (s *Schema) encodeIdentity(typ *yang.YangType, text string) (string, *xmlns, error) {
+	module, name := "", text
+	if i := strings.Index(text, ":"); i >= 0 {
+		module, name = text[:i], text[i+1:]
+	}
+	if typ.IdentityBase == nil {
+		return text, nil, nil
+	}
+	for _, identity := range typ.IdentityBase.Values {
+		identityModule := identityModule(identity)
+		if identity.Name != name || identityModule == nil {
+			continue
+		}
+		if module != "" && module != identityModule.Name {
+			continue
+		}
+		if identityModule.Prefix == nil || identityModule.Namespace == nil {
+			return name, nil, nil
+		}
+		ns := &xmlns{
+			prefix:    identityModule.Prefix.Name,
+			namespace: identityModule.Namespace.Name,
+		}
+		return ns.prefix + ":" + name, ns, nil
+	}
+	return "", nil, fmt.Errorf("%q is not an identity derived from %s", text, typ.IdentityBase.Name)
+}
+
+// xmlns is a namespace declaration
+type xmlns struct {
+	prefix    string
+	namespace string
+}
+
+// scalarText formats a JSON scalar as the text of an element
+func scalarText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		// the [null] value of the leaves of type empty
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func intBits(kind yang.TypeKind) int {
+	switch kind {
+	case yang.Yint8, yang.Yuint8:
+		return 8
+	case yang.Yint16, yang.Yuint16:
+		return 16
+	case yang.Yint32, yang.Yuint32:
+		return 32
+	}
+	return 64
+}
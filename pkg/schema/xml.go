@@ -0,0 +1,268 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// element is an element of XML data, with the namespaces of the prefixes in its scope
+type element struct {
+	name     xml.Name
+	prefixes map[string]string
+	children []*element
+	text     string
+	// operation is the edit-config operation attribute of the element, if any
+	operation string
+}
+
+// parseElements parses the sibling top-level elements of XML data
+func parseElements(data string) ([]*element, error) {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+
+	var roots []*element
+	var stack []*element
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			prefixes := make(map[string]string)
+			if len(stack) > 0 {
+				for prefix, namespace := range stack[len(stack)-1].prefixes {
+					prefixes[prefix] = namespace
+				}
+			}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					prefixes[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					prefixes[""] = attr.Value
+				}
+			}
+			e := &element{name: t.Name, prefixes: prefixes}
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "operation" && attr.Name.Space == NetconfBaseNamespace {
+					e.operation = attr.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			} else {
+				roots = append(roots, e)
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	return roots, nil
+}
+
+// Node is a top-level data node of XML data
+type Node struct {
+	Name xml.Name
+	// Operation is the edit-config operation attribute of the node, if any
+	Operation string
+	// Data is the XML of the node
+	Data string
+}
+
+// SplitNodes splits XML data in its top-level data nodes, in their order
+func SplitNodes(data string) ([]Node, error) {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+
+	var nodes []Node
+	depth := 0
+	start := int64(0)
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &Error{
+				Tag:     TagMalformedMessage,
+				Message: fmt.Sprintf("malformed data: %v", err),
+			}
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				start = offset
+				node := Node{Name: t.Name}
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "operation" && attr.Name.Space == NetconfBaseNamespace {
+						node.Operation = attr.Value
+					}
+				}
+				nodes = append(nodes, node)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				nodes[len(nodes)-1].Data = data[start:decoder.InputOffset()]
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// XMLToJSON converts the top-level data nodes of XML data to a JSON object. With a schema,
+// the object is the RFC 7951 encoding of the nodes, its values being typed and its members
+// qualified by their module where the module changes. Without a schema, the leaves are
+// strings, the repeated elements arrays and the names are not qualified. The attributes of
+// the elements are ignored.
+func XMLToJSON(s *Schema, data string) ([]byte, error) {
+	elements, err := parseElements(data)
+	if err != nil {
+		return nil, &Error{
+			Tag:     TagMalformedMessage,
+			Message: fmt.Sprintf("malformed data: %v", err),
+		}
+	}
+
+	tree := make(map[string]interface{})
+	for _, e := range elements {
+		if s == nil {
+			addUntyped(tree, e)
+			continue
+		}
+		err = s.decodeNode(tree, nil, e, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(tree)
+}
+
+// decodeNode adds the data node of the element to the object of its parent entry, nil for
+// the top-level nodes
+func (s *Schema) decodeNode(tree map[string]interface{}, parent *yang.Entry, e *element, parentModule string) error {
+	var entry *yang.Entry
+	parentPath := ""
+	if parent == nil {
+		entry = s.Root(s.moduleByNamespace(e.name.Space), e.name.Local)
+	} else {
+		entry = Child(parent, e.name.Local)
+		parentPath = Path(parent)
+	}
+	if entry == nil {
+		return &Error{
+			Tag:        TagUnknownElement,
+			Path:       parentPath + "/" + e.name.Local,
+			BadElement: e.name.Local,
+			Message:    fmt.Sprintf("element %s is not defined by the schema", e.name.Local),
+		}
+	}
+
+	module := Module(entry)
+	name := entry.Name
+	if module != parentModule {
+		name = module + ":" + name
+	}
+
+	if entry.IsLeaf() || entry.IsLeafList() {
+		if len(e.children) > 0 {
+			return &Error{
+				Tag:        TagBadElement,
+				Path:       Path(entry),
+				BadElement: entry.Name,
+				Message:    fmt.Sprintf("leaf %s can not have child elements", entry.Name),
+			}
+		}
+		value, err := s.decodeLeaf(entry, entry.Type, e.text, e.prefixes)
+		if err != nil {
+			return &Error{
+				Tag:        TagInvalidValue,
+				Path:       Path(entry),
+				BadElement: entry.Name,
+				Message:    err.Error(),
+			}
+		}
+		if entry.IsLeafList() {
+			values, _ := tree[name].([]interface{})
+			tree[name] = append(values, value)
+			return nil
+		}
+		if _, ok := tree[name]; ok {
+			return duplicateError(entry)
+		}
+		tree[name] = value
+		return nil
+	}
+
+	object := make(map[string]interface{})
+	for _, child := range e.children {
+		err := s.decodeNode(object, entry, child, module)
+		if err != nil {
+			return err
+		}
+	}
+	if entry.IsList() {
+		entries, _ := tree[name].([]interface{})
+		tree[name] = append(entries, object)
+		return nil
+	}
+	if _, ok := tree[name]; ok {
+		return duplicateError(entry)
+	}
+	tree[name] = object
+	return nil
+}
+
+func duplicateError(entry *yang.Entry) error {
+	return &Error{
+		Tag:        TagBadElement,
+		Path:       Path(entry),
+		BadElement: entry.Name,
+		Message:    fmt.Sprintf("%s appears more than once", entry.Name),
+	}
+}
+
+// addUntyped adds the element to the object of its parent without schema, the repeated
+// elements becoming arrays
+func addUntyped(tree map[string]interface{}, e *element) {
+	var value interface{}
+	if len(e.children) == 0 {
+		value = strings.TrimSpace(e.text)
+	} else {
+		object := make(map[string]interface{})
+		for _, child := range e.children {
+			addUntyped(object, child)
+		}
+		value = object
+	}
+
+	switch existing := tree[e.name.Local].(type) {
+	case nil:
+		tree[e.name.Local] = value
+	case []interface{}:
+		tree[e.name.Local] = append(existing, value)
+	default:
+		tree[e.name.Local] = []interface{}{existing, value}
+	}
+}
@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const ricModule = `module ric {
+  namespace "urn:onf:ric";
+  prefix ric;
+  import ric-types { prefix rt; }
+  revision 2022-01-01;
+  container ric {
+    leaf report_period { type uint32; }
+    leaf enabled { type boolean; }
+    leaf offset { type int64; }
+    leaf gain { type decimal64 { fraction-digits 2; } }
+    leaf algorithm { type identityref { base rt:algorithm; } }
+    list cell {
+      key "id";
+      leaf id { type uint16; }
+      leaf name { type string; }
+    }
+    leaf-list tags { type string; }
+  }
+}
+`
+
+const ricTypesModule = `module ric-types {
+  namespace "urn:onf:ric-types";
+  prefix rt;
+  revision 2022-01-01;
+  identity algorithm;
+  identity round-robin { base algorithm; }
+}
+`
+
+// testSchema loads the schema of the ric model from its modules
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range map[string]string{"ric.yang": ricModule, "ric-types.yang": ricTypesModule} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry, err := NewRegistry([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := registry.Schema(context.Background(), "ric", "1.0.0")
+	if !ok {
+		t.Fatal("no schema for the ric model")
+	}
+	return s
+}
+
+func TestXMLToJSON(t *testing.T) {
+	s := testSchema(t)
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "numbers and booleans",
+			data: `<ric xmlns="urn:onf:ric"><report_period>10</report_period><enabled>true</enabled></ric>`,
+			want: `{"ric:ric":{"enabled":true,"report_period":10}}`,
+		},
+		{
+			name: "64 bits integers and decimals",
+			data: `<ric xmlns="urn:onf:ric"><offset>-5</offset><gain>1.50</gain></ric>`,
+			want: `{"ric:ric":{"gain":"1.50","offset":"-5"}}`,
+		},
+		{
+			name: "single list entry",
+			data: `<ric xmlns="urn:onf:ric"><cell><id>1</id><name>a</name></cell><tags>x</tags></ric>`,
+			want: `{"ric:ric":{"cell":[{"id":1,"name":"a"}],"tags":["x"]}}`,
+		},
+		{
+			name: "identityref",
+			data: `<ric xmlns="urn:onf:ric" xmlns:t="urn:onf:ric-types"><algorithm>t:round-robin</algorithm></ric>`,
+			want: `{"ric:ric":{"algorithm":"ric-types:round-robin"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := XMLToJSON(s, test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestXMLToJSONErrors(t *testing.T) {
+	s := testSchema(t)
+	tests := []struct {
+		name string
+		data string
+		tag  string
+	}{
+		{name: "unknown element", data: `<ric xmlns="urn:onf:ric"><missing>1</missing></ric>`, tag: TagUnknownElement},
+		{name: "invalid number", data: `<ric xmlns="urn:onf:ric"><report_period>ten</report_period></ric>`, tag: TagInvalidValue},
+		{name: "unknown identity", data: `<ric xmlns="urn:onf:ric" xmlns:t="urn:onf:ric-types"><algorithm>t:random</algorithm></ric>`, tag: TagInvalidValue},
+		{name: "malformed", data: `<ric xmlns="urn:onf:ric">`, tag: TagMalformedMessage},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := XMLToJSON(s, test.data)
+			schemaErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("error %v is not a schema error", err)
+			}
+			if schemaErr.Tag != test.tag {
+				t.Errorf("error tag %s, want %s", schemaErr.Tag, test.tag)
+			}
+		})
+	}
+}
+
+func TestJSONToXML(t *testing.T) {
+	s := testSchema(t)
+	value := `{"ric:ric":{"report_period":10,"enabled":true,"offset":"-5","algorithm":"ric-types:round-robin","cell":[{"name":"a","id":1}]}}`
+	got, err := JSONToXML(s, &gnmi.Path{}, []byte(value), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<ric xmlns="urn:onf:ric">` +
+		`<algorithm xmlns:rt="urn:onf:ric-types">rt:round-robin</algorithm>` +
+		`<cell><id>1</id><name>a</name></cell>` +
+		`<enabled>true</enabled><offset>-5</offset><report_period>10</report_period></ric>`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// the XML translated back is the JSON value
+	back, err := XMLToJSON(s, string(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected, actual interface{}
+	_ = json.Unmarshal([]byte(value), &expected)
+	_ = json.Unmarshal(back, &actual)
+	if mustMarshal(t, expected) != mustMarshal(t, actual) {
+		t.Errorf("round trip %s, want %s", back, value)
+	}
+}
+
+func TestJSONToXMLWithoutSchema(t *testing.T) {
+	path := &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "ric"}, {Name: "cell", Key: map[string]string{"id": "1"}}}}
+	got, err := JSONToXML(nil, path, []byte(`{"name":"a"}`), "urn:onf:ric")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<ric xmlns="urn:onf:ric"><cell><id>1</id><name>a</name></cell></ric>`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,646 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The must and when expressions are evaluated with a subset of XPath 1.0: location paths
+// with the child, parent and self abbreviations and predicates, literals, numbers, the
+// boolean, comparison and arithmetic operators, and the not, true, false, count, current,
+// string, number, boolean, concat, contains, starts-with and string-length functions.
+// The expressions using other constructs are not evaluated.
+
+// errUnsupported is returned for the expressions using a construct that is not supported
+var errUnsupported = errors.New("unsupported XPath expression")
+
+type xtokenKind int
+
+const (
+	xtokenName xtokenKind = iota
+	xtokenLiteral
+	xtokenNumber
+	xtokenOperator
+	xtokenEOF
+)
+
+type xtoken struct {
+	kind xtokenKind
+	text string
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c == '-' || c == '.' || (c >= '0' && c <= '9')
+}
+
+// tokenizeXPath splits an expression into tokens, the names including their prefix
+func tokenizeXPath(expr string) ([]xtoken, error) {
+	var tokens []xtoken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated literal in %q", expr)
+			}
+			tokens = append(tokens, xtoken{xtokenLiteral, expr[i+1 : i+1+end]})
+			i += end + 2
+		case (c >= '0' && c <= '9') || (c == '.' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9'):
+			start := i
+			for i < len(expr) && ((expr[i] >= '0' && expr[i] <= '9') || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, xtoken{xtokenNumber, expr[start:i]})
+		case isNameStart(c):
+			start := i
+			for i < len(expr) && isNameChar(expr[i]) {
+				i++
+			}
+			// a prefixed name, but not an axis
+			if i+1 < len(expr) && expr[i] == ':' && expr[i+1] != ':' && isNameStart(expr[i+1]) {
+				i++
+				for i < len(expr) && isNameChar(expr[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, xtoken{xtokenName, expr[start:i]})
+		default:
+			if i+1 < len(expr) {
+				switch two := expr[i : i+2]; two {
+				case "!=", "<=", ">=", "//", "..", "::":
+					tokens = append(tokens, xtoken{xtokenOperator, two})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("=<>+-()[],/|*.@$", rune(c)) {
+				return nil, fmt.Errorf("unexpected character %q in %q", c, expr)
+			}
+			tokens = append(tokens, xtoken{xtokenOperator, string(c)})
+			i++
+		}
+	}
+	return append(tokens, xtoken{kind: xtokenEOF}), nil
+}
+
+// xexpr is a parsed XPath expression, evaluated to a node-set, a string, a number or a
+// boolean
+type xexpr interface {
+	eval(c *xcontext, n *dataNode) (interface{}, error)
+}
+
+// xcontext is the context of the evaluation of an expression
+type xcontext struct {
+	root    *dataNode
+	current *dataNode
+	// unknown is set when a location path selects no node, the data of the request being
+	// possibly only a part of the datastore
+	unknown bool
+}
+
+type xparser struct {
+	tokens []xtoken
+	pos    int
+}
+
+// parseXPath parses an expression, returning errUnsupported for the constructs that are not
+// supported
+func parseXPath(expr string) (xexpr, error) {
+	tokens, err := tokenizeXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &xparser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != xtokenEOF {
+		return nil, errUnsupported
+	}
+	return e, nil
+}
+
+func (p *xparser) peek() xtoken {
+	return p.tokens[p.pos]
+}
+
+func (p *xparser) next() xtoken {
+	t := p.tokens[p.pos]
+	if t.kind != xtokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *xparser) isOperator(ops ...string) bool {
+	t := p.peek()
+	for _, op := range ops {
+		if t.kind == xtokenOperator && t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *xparser) isKeyword(keywords ...string) bool {
+	t := p.peek()
+	for _, keyword := range keywords {
+		if t.kind == xtokenName && t.text == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *xparser) expect(op string) error {
+	if !p.isOperator(op) {
+		return errUnsupported
+	}
+	p.next()
+	return nil
+}
+
+func (p *xparser) parseBinary(operand func() (xexpr, error), isOp func() bool) (xexpr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for isOp() {
+		op := p.next().text
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &xbinary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *xparser) parseOr() (xexpr, error) {
+	return p.parseBinary(p.parseAnd, func() bool { return p.isKeyword("or") })
+}
+
+func (p *xparser) parseAnd() (xexpr, error) {
+	return p.parseBinary(p.parseEquality, func() bool { return p.isKeyword("and") })
+}
+
+func (p *xparser) parseEquality() (xexpr, error) {
+	return p.parseBinary(p.parseRelational, func() bool { return p.isOperator("=", "!=") })
+}
+
+func (p *xparser) parseRelational() (xexpr, error) {
+	return p.parseBinary(p.parseAdditive, func() bool { return p.isOperator("<", "<=", ">", ">=") })
+}
+
+func (p *xparser) parseAdditive() (xexpr, error) {
+	return p.parseBinary(p.parseMultiplicative, func() bool { return p.isOperator("+", "-") })
+}
+
+func (p *xparser) parseMultiplicative() (xexpr, error) {
+	return p.parseBinary(p.parseUnary, func() bool { return p.isOperator("*") || p.isKeyword("div", "mod") })
+}
+
+func (p *xparser) parseUnary() (xexpr, error) {
+	if p.isOperator("-") {
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &xbinary{op: "-", left: xnumber(0), right: e}, nil
+	}
+	return p.parseBinary(p.parsePath, func() bool { return p.isOperator("|") })
+}
+
+func (p *xparser) parsePath() (xexpr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == xtokenLiteral:
+		p.next()
+		return xliteral(t.text), nil
+	case t.kind == xtokenNumber:
+		p.next()
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return xnumber(v), nil
+	case p.isOperator("("):
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return p.parseSteps(&xpath{base: e})
+	case t.kind == xtokenName && p.tokens[p.pos+1].kind == xtokenOperator && p.tokens[p.pos+1].text == "(":
+		f, err := p.parseFunction()
+		if err != nil {
+			return nil, err
+		}
+		return p.parseSteps(&xpath{base: f})
+	case p.isOperator("/"):
+		p.next()
+		path := &xpath{absolute: true}
+		if t := p.peek(); t.kind != xtokenName && !p.isOperator(".", "..", "*") {
+			return path, nil
+		}
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, step)
+		return p.parseSteps(path)
+	case t.kind == xtokenName || p.isOperator(".", "..", "*"):
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		return p.parseSteps(&xpath{steps: []xstep{step}})
+	}
+	return nil, errUnsupported
+}
+
+func (p *xparser) parseFunction() (xexpr, error) {
+	f := &xfunction{name: p.next().text}
+	p.next()
+	for !p.isOperator(")") {
+		if len(f.args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		f.args = append(f.args, arg)
+	}
+	p.next()
+	return f, nil
+}
+
+// parseSteps parses the steps following the start of a path
+func (p *xparser) parseSteps(path *xpath) (xexpr, error) {
+	for p.isOperator("/") {
+		p.next()
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, step)
+	}
+	if path.base != nil && len(path.steps) == 0 {
+		return path.base, nil
+	}
+	return path, nil
+}
+
+func (p *xparser) parseStep() (xstep, error) {
+	t := p.next()
+	if t.kind != xtokenName && !(t.kind == xtokenOperator && (t.text == "." || t.text == ".." || t.text == "*")) {
+		return xstep{}, errUnsupported
+	}
+	if p.isOperator("::", "(") {
+		// axes and node tests
+		return xstep{}, errUnsupported
+	}
+	step := xstep{name: t.text}
+	if i := strings.Index(step.name, ":"); i >= 0 {
+		step.name = step.name[i+1:]
+	}
+	for p.isOperator("[") {
+		p.next()
+		predicate, err := p.parseOr()
+		if err != nil {
+			return xstep{}, err
+		}
+		if err := p.expect("]"); err != nil {
+			return xstep{}, err
+		}
+		step.predicates = append(step.predicates, predicate)
+	}
+	return step, nil
+}
+
+type xliteral string
+
+func (e xliteral) eval(c *xcontext, n *dataNode) (interface{}, error) {
+	return string(e), nil
+}
+
+type xnumber float64
+
+func (e xnumber) eval(c *xcontext, n *dataNode) (interface{}, error) {
+	return float64(e), nil
+}
+
+// xpath is a location path, starting from the root, the node-set of an expression or the
+// context node
+type xpath struct {
+	base     xexpr
+	absolute bool
+	steps    []xstep
+}
+
+// xstep is a step of a location path, its name being the local name of the child nodes or
+// one of ".", ".." and "*"
+type xstep struct {
+	name       string
+	predicates []xexpr
+}
+
+func (e *xpath) eval(c *xcontext, n *dataNode) (interface{}, error) {
+	var nodes []*dataNode
+	switch {
+	case e.base != nil:
+		v, err := e.base.eval(c, n)
+		if err != nil {
+			return nil, err
+		}
+		set, ok := v.([]*dataNode)
+		if !ok {
+			return nil, errUnsupported
+		}
+		nodes = set
+	case e.absolute:
+		nodes = []*dataNode{c.root}
+	default:
+		nodes = []*dataNode{n}
+	}
+
+	for _, step := range e.steps {
+		var selected []*dataNode
+		for _, node := range nodes {
+			switch step.name {
+			case ".":
+				selected = append(selected, node)
+			case "..":
+				if node.parent != nil {
+					selected = append(selected, node.parent)
+				}
+			default:
+				for _, child := range node.children {
+					if step.name == "*" || child.entry.Name == step.name {
+						selected = append(selected, child)
+					}
+				}
+			}
+		}
+		for _, predicate := range step.predicates {
+			var filtered []*dataNode
+			for _, node := range selected {
+				v, err := predicate.eval(c, node)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := v.(float64); ok {
+					// positional predicates
+					return nil, errUnsupported
+				}
+				if xboolean(v) {
+					filtered = append(filtered, node)
+				}
+			}
+			selected = filtered
+		}
+		nodes = selected
+	}
+
+	if len(nodes) == 0 {
+		c.unknown = true
+	}
+	return nodes, nil
+}
+
+type xbinary struct {
+	op          string
+	left, right xexpr
+}
+
+func (e *xbinary) eval(c *xcontext, n *dataNode) (interface{}, error) {
+	left, err := e.left.eval(c, n)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(c, n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "or":
+		return xboolean(left) || xboolean(right), nil
+	case "and":
+		return xboolean(left) && xboolean(right), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return xcompare(e.op, left, right), nil
+	case "|":
+		l, lok := left.([]*dataNode)
+		r, rok := right.([]*dataNode)
+		if !lok || !rok {
+			return nil, errUnsupported
+		}
+		return append(append([]*dataNode{}, l...), r...), nil
+	}
+
+	l, r := xnumberValue(left), xnumberValue(right)
+	switch e.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "div":
+		return l / r, nil
+	case "mod":
+		return math.Mod(l, r), nil
+	}
+	return nil, errUnsupported
+}
+
+type xfunction struct {
+	name string
+	args []xexpr
+}
+
+func (e *xfunction) eval(c *xcontext, n *dataNode) (interface{}, error) {
+	args := make([]interface{}, 0, len(e.args))
+	for _, arg := range e.args {
+		v, err := arg.eval(c, n)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	// the functions taking an optional argument apply to the context node without it
+	if len(args) == 0 {
+		switch e.name {
+		case "string", "number", "string-length":
+			args = append(args, []*dataNode{n})
+		}
+	}
+
+	switch {
+	case e.name == "true" && len(args) == 0:
+		return true, nil
+	case e.name == "false" && len(args) == 0:
+		return false, nil
+	case e.name == "current" && len(args) == 0:
+		return []*dataNode{c.current}, nil
+	case e.name == "not" && len(args) == 1:
+		return !xboolean(args[0]), nil
+	case e.name == "boolean" && len(args) == 1:
+		return xboolean(args[0]), nil
+	case e.name == "count" && len(args) == 1:
+		nodes, ok := args[0].([]*dataNode)
+		if !ok {
+			return nil, errUnsupported
+		}
+		return float64(len(nodes)), nil
+	case e.name == "string" && len(args) == 1:
+		return xstring(args[0]), nil
+	case e.name == "number" && len(args) == 1:
+		return xnumberValue(args[0]), nil
+	case e.name == "string-length" && len(args) == 1:
+		return float64(len([]rune(xstring(args[0])))), nil
+	case e.name == "concat" && len(args) >= 2:
+		var b strings.Builder
+		for _, arg := range args {
+			b.WriteString(xstring(arg))
+		}
+		return b.String(), nil
+	case e.name == "contains" && len(args) == 2:
+		return strings.Contains(xstring(args[0]), xstring(args[1])), nil
+	case e.name == "starts-with" && len(args) == 2:
+		return strings.HasPrefix(xstring(args[0]), xstring(args[1])), nil
+	}
+	return nil, errUnsupported
+}
+
+// xcompare compares two values with the rules of XPath 1.0, node-sets being compared by the
+// string values of their nodes
+func xcompare(op string, left, right interface{}) bool {
+	if nodes, ok := left.([]*dataNode); ok {
+		if _, ok := right.(bool); ok {
+			return xcompare(op, xboolean(nodes), right)
+		}
+		for _, node := range nodes {
+			if xcompare(op, node.stringValue(), right) {
+				return true
+			}
+		}
+		return false
+	}
+	if nodes, ok := right.([]*dataNode); ok {
+		if _, ok := left.(bool); ok {
+			return xcompare(op, left, xboolean(nodes))
+		}
+		for _, node := range nodes {
+			if xcompare(op, left, node.stringValue()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch op {
+	case "=", "!=":
+		var equal bool
+		_, lbool := left.(bool)
+		_, rbool := right.(bool)
+		_, lnum := left.(float64)
+		_, rnum := right.(float64)
+		switch {
+		case lbool || rbool:
+			equal = xboolean(left) == xboolean(right)
+		case lnum || rnum:
+			equal = xnumberValue(left) == xnumberValue(right)
+		default:
+			equal = xstring(left) == xstring(right)
+		}
+		return equal == (op == "=")
+	}
+
+	l, r := xnumberValue(left), xnumberValue(right)
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+func xboolean(v interface{}) bool {
+	switch value := v.(type) {
+	case bool:
+		return value
+	case float64:
+		return value != 0 && !math.IsNaN(value)
+	case string:
+		return value != ""
+	case []*dataNode:
+		return len(value) > 0
+	}
+	return false
+}
+
+func xstring(v interface{}) string {
+	switch value := v.(type) {
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		if value == math.Trunc(value) && !math.IsInf(value, 0) {
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case string:
+		return value
+	case []*dataNode:
+		if len(value) == 0 {
+			return ""
+		}
+		return value[0].stringValue()
+	}
+	return ""
+}
+
+func xnumberValue(v interface{}) float64 {
+	switch value := v.(type) {
+	case bool:
+		if value {
+			return 1
+		}
+		return 0
+	case float64:
+		return value
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(xstring(v)), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
@@ -7,12 +7,17 @@ package southbound
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/onosproject/onos-lib-go/pkg/grpc/retry"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-o1t/pkg/metrics"
 	"github.com/openconfig/gnmi/proto/gnmi"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 )
 
 var log = logging.GetLogger("manager")
@@ -25,33 +30,90 @@ type GnmiClient interface {
 	Init(gnmiConn *grpc.ClientConn) error
 	Get(ctx context.Context, request *gnmi.GetRequest) (*gnmi.GetResponse, error)
 	Set(ctx context.Context, request *gnmi.SetRequest) (*gnmi.SetResponse, error)
+	Subscribe(ctx context.Context, request *gnmi.SubscribeRequest) (gnmi.GNMI_SubscribeClient, error)
 }
 
 // GNMIProvisioner handles provisioning of device configuration via gNMI interface.
 type GNMIProvisioner struct {
 	gnmi gnmi.GNMIClient
+	conn *grpc.ClientConn
+	// dialOpts are the options of the connection to onos-config, used to connect to its
+	// model plugins
+	dialOpts []grpc.DialOption
+
+	// modelsMu guards models, the models last listed by ListModels by name:version
+	modelsMu sync.Mutex
+	models   map[string]Model
+	listed   time.Time
 }
 
 // Init initializes the gNMI provisioner
 func (p *GNMIProvisioner) Init(gnmiConn *grpc.ClientConn) error {
 	log.Infof("Initializing new GnmiProvisioner to %s", gnmiConn.Target())
 	p.gnmi = gnmi.NewGNMIClient(gnmiConn)
+	p.conn = gnmiConn
 	return nil
 }
 
+// Close closes the connection to onos-config
+func (p *GNMIProvisioner) Close() error {
+	return p.conn.Close()
+}
+
+// Check checks the connection to onos-config is ready, connecting it if it is idle
+func (p *GNMIProvisioner) Check(ctx context.Context) error {
+	for {
+		state := p.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			p.conn.Connect()
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("connection to %s is %s", p.conn.Target(), state)
+		}
+		if !p.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection to %s is %s", p.conn.Target(), state)
+		}
+	}
+}
+
 // Get passes a gNMI GetRequest to the server which synchronously replies with a GetResponse
 func (p *GNMIProvisioner) Get(ctx context.Context, request *gnmi.GetRequest) (*gnmi.GetResponse, error) {
-	return p.gnmi.Get(ctx, request)
+	start := time.Now()
+	response, err := p.gnmi.Get(ctx, request)
+	metrics.ObserveGnmi("Get", err, time.Since(start))
+	return response, err
 }
 
 // Set passes a gNMI SetRequest to the server which synchronously replies with a SetResponse
 func (p *GNMIProvisioner) Set(ctx context.Context, request *gnmi.SetRequest) (*gnmi.SetResponse, error) {
-	return p.gnmi.Set(ctx, request)
+	start := time.Now()
+	response, err := p.gnmi.Set(ctx, request)
+	metrics.ObserveGnmi("Set", err, time.Since(start))
+	return response, err
+}
+
+// Subscribe opens a gNMI Subscribe stream to the server and sends the subscription request,
+// the responses being received from the returned stream until ctx is done
+func (p *GNMIProvisioner) Subscribe(ctx context.Context, request *gnmi.SubscribeRequest) (gnmi.GNMI_SubscribeClient, error) {
+	start := time.Now()
+	stream, err := p.gnmi.Subscribe(ctx)
+	if err == nil {
+		err = stream.Send(request)
+	}
+	metrics.ObserveGnmi("Subscribe", err, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
 }
 
 func NewGNMIClient(gnmiEndpoint string, opts ...grpc.DialOption) (GnmiClient, error) {
 	optsWithRetry := []grpc.DialOption{
 		grpc.WithStreamInterceptor(retry.RetryingStreamClientInterceptor(retry.WithInterval(100 * time.Millisecond))),
+		// traces the gNMI requests and propagates their trace context to onos-config
+		grpc.WithChainUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
 	}
 	optsWithRetry = append(opts, optsWithRetry...)
 	gnmiConn, err := grpc.Dial(gnmiEndpoint, optsWithRetry...)
@@ -60,7 +122,7 @@ func NewGNMIClient(gnmiEndpoint string, opts ...grpc.DialOption) (GnmiClient, er
 		return nil, err
 	}
 
-	gnmiClient := new(GNMIProvisioner)
+	gnmiClient := &GNMIProvisioner{dialOpts: opts}
 	err = gnmiClient.Init(gnmiConn)
 	if err != nil {
 		log.Error("Unable to setup GNMI provisioner", err)
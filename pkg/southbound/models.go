@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package southbound
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/config/admin"
+)
+
+// modelListInterval bounds how often the models of onos-config are listed to find a model that
+// was not registered when they were last listed
+const modelListInterval = 30 * time.Second
+
+// Model is a model registered in onos-config by a model plugin, with the YANG modules the
+// model is made of and the endpoint of its plugin
+type Model struct {
+	Name     string
+	Version  string
+	Modules  []string
+	Endpoint string
+}
+
+// ModelLister lists the models registered in onos-config
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]Model, error)
+}
+
+// ListModels lists the models of the model plugins registered in onos-config
+func (p *GNMIProvisioner) ListModels(ctx context.Context) ([]Model, error) {
+	stream, err := admin.NewConfigAdminServiceClient(p.conn).ListRegisteredModels(ctx, &admin.ListModelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var models []Model
+	for {
+		plugin, err := stream.Recv()
+		if err == io.EOF {
+			return models, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		info := plugin.GetInfo()
+		if info == nil {
+			continue
+		}
+		model := Model{
+			Name:     info.Name,
+			Version:  info.Version,
+			Endpoint: plugin.Endpoint,
+		}
+		for _, data := range info.ModelData {
+			model.Modules = append(model.Modules, data.Name)
+		}
+		models = append(models, model)
+	}
+}
+
+// model returns the model registered in onos-config with the name and version, listing the
+// models again if it was not registered when they were last listed
+func (p *GNMIProvisioner) model(ctx context.Context, name, version string) (Model, error) {
+	key := name + ":" + version
+
+	p.modelsMu.Lock()
+	model, ok := p.models[key]
+	list := !ok && time.Since(p.listed) > modelListInterval
+	p.modelsMu.Unlock()
+	if ok {
+		return model, nil
+	}
+	if !list {
+		return Model{}, fmt.Errorf("no model plugin registered for %s %s", name, version)
+	}
+
+	// the models are listed without the lock, a slow onos-config delaying only the
+	// validations of the models not listed yet
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		return Model{}, err
+	}
+
+	p.modelsMu.Lock()
+	defer p.modelsMu.Unlock()
+
+	p.listed = time.Now()
+	p.models = make(map[string]Model, len(models))
+	for _, model := range models {
+		p.models[model.Name+":"+model.Version] = model
+	}
+	model, ok = p.models[key]
+	if !ok {
+		return Model{}, fmt.Errorf("no model plugin registered for %s %s", name, version)
+	}
+	return model, nil
+}
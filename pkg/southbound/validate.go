@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package southbound
+
+import (
+	"context"
+
+	"github.com/onosproject/onos-api/go/onos/config/admin"
+	"google.golang.org/grpc"
+)
+
+// ConfigValidator validates configuration data against a model without applying it
+type ConfigValidator interface {
+	// ValidateConfig validates the JSON configuration with the model plugin of the model. The
+	// error is returned when the validation could not be performed, the message explaining
+	// why the configuration is not valid otherwise.
+	ValidateConfig(ctx context.Context, name, version string, config []byte) (bool, string, error)
+}
+
+// ValidateConfig validates the configuration with the model plugin of the model registered in
+// onos-config, connecting to the endpoint it registered with the options of the connection to
+// onos-config
+func (p *GNMIProvisioner) ValidateConfig(ctx context.Context, name, version string, config []byte) (bool, string, error) {
+	model, err := p.model(ctx, name, version)
+	if err != nil {
+		return false, "", err
+	}
+
+	conn, err := grpc.DialContext(ctx, model.Endpoint, p.dialOpts...)
+	if err != nil {
+		return false, "", err
+	}
+	defer conn.Close()
+
+	response, err := admin.NewModelPluginServiceClient(conn).ValidateConfig(ctx, &admin.ValidateConfigRequest{Json: config})
+	if err != nil {
+		return false, "", err
+	}
+	return response.Valid, response.Message, nil
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package southbound
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/config/admin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// configServer is onos-config with a model plugin, serving on the same endpoint
+type configServer struct {
+	admin.UnimplementedConfigAdminServiceServer
+	admin.UnimplementedModelPluginServiceServer
+
+	endpoint string
+	// failAfter makes the list of the models fail after the given number of models, if set
+	failAfter int
+
+	mu    sync.Mutex
+	lists int
+}
+
+func (s *configServer) ListRegisteredModels(request *admin.ListModelsRequest, stream admin.ConfigAdminService_ListRegisteredModelsServer) error {
+	s.mu.Lock()
+	s.lists++
+	s.mu.Unlock()
+
+	plugins := []*admin.ModelPlugin{
+		{Endpoint: "unused:5152", Info: &admin.ModelInfo{Name: "mho", Version: "1.0.0"}},
+		{Endpoint: s.endpoint, Info: &admin.ModelInfo{Name: "ric", Version: "1.0.0"}},
+	}
+	for i, plugin := range plugins {
+		if s.failAfter > 0 && i == s.failAfter {
+			return status.Error(codes.Unavailable, "plugin registry unavailable")
+		}
+		err := stream.Send(plugin)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *configServer) ValidateConfig(ctx context.Context, request *admin.ValidateConfigRequest) (*admin.ValidateConfigResponse, error) {
+	if string(request.Json) == `{"interval":7}` {
+		return &admin.ValidateConfigResponse{Valid: true}, nil
+	}
+	return &admin.ValidateConfigResponse{Valid: false, Message: "invalid interval"}, nil
+}
+
+func (s *configServer) listCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lists
+}
+
+func startConfigServer(t *testing.T, failAfter int) (*configServer, GnmiClient) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &configServer{endpoint: listener.Addr().String(), failAfter: failAfter}
+	server := grpc.NewServer()
+	admin.RegisterConfigAdminServiceServer(server, srv)
+	admin.RegisterModelPluginServiceServer(server, srv)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	// the plugin is only reachable with the options of the connection to onos-config
+	client, err := NewGNMIClient(srv.endpoint, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func TestValidateConfig(t *testing.T) {
+	srv, client := startConfigServer(t, 0)
+	validator := client.(ConfigValidator)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		model   string
+		config  string
+		valid   bool
+		message string
+		err     bool
+	}{
+		{name: "valid", model: "ric", config: `{"interval":7}`, valid: true},
+		{name: "invalid", model: "ric", config: `{"interval":-1}`, message: "invalid interval"},
+		{name: "unknown model", model: "kpimon", config: `{}`, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			valid, message, err := validator.ValidateConfig(ctx, test.model, "1.0.0", []byte(test.config))
+			if (err != nil) != test.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if valid != test.valid || message != test.message {
+				t.Errorf("valid %t message %q, want %t %q", valid, message, test.valid, test.message)
+			}
+		})
+	}
+
+	// the models are listed once, the unknown model being listed again only after the interval
+	if lists := srv.listCount(); lists != 1 {
+		t.Errorf("models listed %d times, want 1", lists)
+	}
+}
+
+func TestListModelsError(t *testing.T) {
+	_, client := startConfigServer(t, 1)
+
+	// the failed stream is retried until the deadline of the request
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	models, err := client.(ModelLister).ListModels(ctx)
+	if err == nil {
+		t.Errorf("partial list of the models %v returned without error", models)
+	}
+	_, _, err = client.(ConfigValidator).ValidateConfig(ctx, "ric", "1.0.0", []byte(`{"interval":7}`))
+	if err == nil {
+		t.Error("config validated with a partial list of the models")
+	}
+}
@@ -31,7 +31,8 @@ type Store interface {
 	// Entries streams the entries from the local store through received go chan
 	Entries(ctx context.Context, ch chan<- *Entry) error
 
-	// Watch watches the event of this local store
+	// Watch watches the event of this local store, the channel being closed when the context
+	// is done or when the events are not received fast enough
 	Watch(ctx context.Context, ch chan<- Event) error
 
 	// Print prints all store entities for debugging
@@ -59,6 +60,10 @@ func (s *store) Print() {
 		switch v.Value.(type) {
 		case *SessionValue:
 			log.Infof("O1T store - session Key: %v, value: %v", k.(Key), v.Value.(*SessionValue))
+		case *CallHomeValue:
+			log.Infof("O1T store - call home Key: %v, value: %v", k.(Key), v.Value.(*CallHomeValue))
+		case *SubscriptionValue:
+			log.Infof("O1T store - subscription Key: %v, value: %v", k.(Key), v.Value.(*SubscriptionValue))
 		}
 	}
 }
@@ -157,7 +162,6 @@ func (s *store) Watch(ctx context.Context, ch chan<- Event) error {
 		if err != nil {
 			log.Error(err)
 		}
-		close(ch)
 	}()
 	return nil
 }
@@ -4,6 +4,8 @@
 
 package store
 
+import "fmt"
+
 type Entry struct {
 	Key   Key
 	Value interface{}
@@ -40,14 +42,158 @@ type Operation struct {
 	Timestamp uint64
 	Namespace string
 	Status    bool
+	// Parts are the parts of an edit applied by the operation, in order, empty for the
+	// operations that do not edit the configuration
+	Parts []OperationPart
+}
+
+// OperationPart is a top-level data node of the config of an edit
+type OperationPart struct {
+	Namespace string
+	Path      string
+	// Applied is set when the part is applied, Error is the error of the part if it failed
+	Applied bool
+	Error   string
 }
 
+// Names of the operations checking a configuration without applying it
+const (
+	// OperationValidate is a validate rpc
+	OperationValidate = "validate"
+	// OperationTestOnly is an edit-config with the test-only test-option
+	OperationTestOnly = "edit-config-test-only"
+)
+
 // For O1 - session mapping
 type Key struct {
 	SessionID string
+	// CallHome is the name of a call home client, set instead of SessionID for the status
+	// of its connection
+	CallHome string
+	// SubscriptionID is the id of a YANG-Push subscription, set with the SessionID of the
+	// session that established it
+	SubscriptionID string
 }
 
 type SessionValue struct {
-	Alive      bool
+	Alive     bool
+	EndReason EndReason
+	// KilledBy is the session that killed the session, for the killed end reason
+	KilledBy string
+	// EndTime is the unix timestamp in nanoseconds the session ended at, the entry of an ended
+	// session being deleted from the store once the session retention elapsed
+	EndTime uint64
+	// User is the authenticated user, SSHSessionID the SSH connection carrying the
+	// session and SourceHost the address of the client
+	User         string
+	SSHSessionID string
+	SourceHost   string
+	// Transport is the transport of the session, ssh or tls
+	Transport string
+	// LoginTime is the unix timestamp in nanoseconds the session was opened at
+	LoginTime  uint64
 	Operations map[string]Operation
 }
+
+// SubscriptionValue is a YANG-Push subscription established by a session, removed when the
+// subscription ends
+type SubscriptionValue struct {
+	ID uint32
+	// Datastore is the subscribed datastore and Namespaces the target:name:version of the
+	// capabilities whose data is selected by the filter
+	Datastore  string
+	Namespaces []string
+	// Filter is the subtree or xpath filter selecting the data, empty for all the data
+	Filter string
+	// Period is the period of a periodic subscription in centiseconds, zero for an on-change
+	// subscription
+	Period          uint32
+	DampeningPeriod uint32
+	SyncOnStart     bool
+	// StopTime is the unix timestamp in nanoseconds the subscription ends at, zero if none
+	StopTime uint64
+}
+
+// EndReason is the reason why a session was terminated
+type EndReason int
+
+const (
+	// EndReasonNone the session was not terminated
+	EndReasonNone EndReason = iota
+	// EndReasonClosed the session was closed by a close-session request
+	EndReasonClosed
+	// EndReasonKilled the session was killed by a kill-session request of another session
+	EndReasonKilled
+	// EndReasonIdleTimeout the session was closed after being idle for too long
+	EndReasonIdleTimeout
+	// EndReasonTransportError the transport of the session was closed or stopped responding
+	EndReasonTransportError
+	// EndReasonShutdown the session was terminated because onos-o1t is stopping
+	EndReasonShutdown
+)
+
+func (r EndReason) String() string {
+	switch r {
+	case EndReasonNone:
+		return "none"
+	case EndReasonClosed:
+		return "closed"
+	case EndReasonKilled:
+		return "killed"
+	case EndReasonIdleTimeout:
+		return "idle-timeout"
+	case EndReasonTransportError:
+		return "transport-error"
+	case EndReasonShutdown:
+		return "shutdown"
+	default:
+		return fmt.Sprintf("EndReason(%d)", r)
+	}
+}
+
+// CallHomeState is the state of the connection to a call home client
+type CallHomeState int
+
+const (
+	// CallHomeConnecting the connection to the client is being established
+	CallHomeConnecting CallHomeState = iota
+	// CallHomeConnected the client is connected
+	CallHomeConnected
+	// CallHomeWaiting the connection is established again after a delay, either a backoff
+	// after a failure or the period of a periodic connection
+	CallHomeWaiting
+	// CallHomeStopped the server does not connect to the client anymore
+	CallHomeStopped
+)
+
+func (s CallHomeState) String() string {
+	switch s {
+	case CallHomeConnecting:
+		return "connecting"
+	case CallHomeConnected:
+		return "connected"
+	case CallHomeWaiting:
+		return "waiting"
+	case CallHomeStopped:
+		return "stopped"
+	default:
+		return fmt.Sprintf("CallHomeState(%d)", s)
+	}
+}
+
+// CallHomeValue is the status of the connection to a call home client
+type CallHomeValue struct {
+	Address string
+	Mode    string
+	State   CallHomeState
+	// Failures counts the consecutive failed connection attempts, LastError is the error of
+	// the last one
+	Failures  int
+	LastError string
+	// Connections counts the established connections, SSHSessionID identifies the last one
+	Connections  int
+	SSHSessionID string
+	// LastConnected and NextAttempt are unix timestamps in nanoseconds
+	LastConnected uint64
+	NextAttempt   uint64
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import "testing"
+
+func TestEndReasonString(t *testing.T) {
+	tests := []struct {
+		reason EndReason
+		want   string
+	}{
+		{EndReasonNone, "none"},
+		{EndReasonKilled, "killed"},
+		{EndReasonShutdown, "shutdown"},
+		{EndReason(42), "EndReason(42)"},
+		{EndReason(-1), "EndReason(-1)"},
+	}
+	for _, test := range tests {
+		if got := test.reason.String(); got != test.want {
+			t.Errorf("EndReason %d is %q, want %q", int(test.reason), got, test.want)
+		}
+	}
+}
+
+func TestCallHomeStateString(t *testing.T) {
+	tests := []struct {
+		state CallHomeState
+		want  string
+	}{
+		{CallHomeConnecting, "connecting"},
+		{CallHomeStopped, "stopped"},
+		{CallHomeState(7), "CallHomeState(7)"},
+	}
+	for _, test := range tests {
+		if got := test.state.String(); got != test.want {
+			t.Errorf("CallHomeState %d is %q, want %q", int(test.state), got, test.want)
+		}
+	}
+}
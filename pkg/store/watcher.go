@@ -15,59 +15,142 @@ type EventChannel chan Event
 
 // Watchers stores the information about watchers
 type Watchers struct {
-	watchers map[uuid.UUID]Watcher
+	watchers map[uuid.UUID]*Watcher
 	rm       sync.RWMutex
 }
 
-// Watcher event watcher
+// maxQueuedEvents is the number of events queued for a watcher not receiving them beyond
+// which the watcher is closed
+var maxQueuedEvents = 1000
+
+// Watcher event watcher, receiving the events in the order they are sent
 type Watcher struct {
 	id uuid.UUID
 	ch chan<- Event
+	// queue holds the events not sent to the channel yet, wake is signaled when one is queued
+	// and overflow closed when the queue is full
+	mu         sync.Mutex
+	queue      []Event
+	overflowed bool
+	wake       chan struct{}
+	overflow   chan struct{}
+	// done is closed when the watcher is removed, stopped once no event is sent to it
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
 }
 
 // NewWatchers creates watchers
 func NewWatchers() *Watchers {
 	return &Watchers{
-		watchers: make(map[uuid.UUID]Watcher),
+		watchers: make(map[uuid.UUID]*Watcher),
 	}
 }
 
-// Send sends an event for all registered watchers
+// Send sends an event for all registered watchers, queued without blocking for a watcher
+// that did not receive the previous ones yet. The channel of a watcher with too many events
+// queued is closed, no event being sent to it anymore.
 func (ws *Watchers) Send(event Event) {
 	ws.rm.RLock()
-	go func() {
-		for _, watcher := range ws.watchers {
-			watcher.ch <- event
-		}
-	}()
-	ws.rm.RUnlock()
+	defer ws.rm.RUnlock()
+	for _, watcher := range ws.watchers {
+		watcher.push(event)
+	}
 }
 
 // AddWatcher adds a watcher
 func (ws *Watchers) AddWatcher(id uuid.UUID, ch chan<- Event) error {
 	ws.rm.Lock()
-	watcher := Watcher{
-		id: id,
-		ch: ch,
+	watcher := &Watcher{
+		id:       id,
+		ch:       ch,
+		wake:     make(chan struct{}, 1),
+		overflow: make(chan struct{}),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
 	}
 	ws.watchers[id] = watcher
 	ws.rm.Unlock()
+	go watcher.run()
 	return nil
 
 }
 
-// RemoveWatcher removes a watcher
+// RemoveWatcher removes a watcher and closes its channel
 func (ws *Watchers) RemoveWatcher(id uuid.UUID) error {
 	ws.rm.Lock()
-	watchers := make(map[uuid.UUID]Watcher, len(ws.watchers)-1)
-	for _, watcher := range ws.watchers {
-		if watcher.id != id {
-			watchers[id] = watcher
+	watcher, ok := ws.watchers[id]
+	delete(ws.watchers, id)
+	ws.rm.Unlock()
+	if !ok {
+		return nil
+	}
+	close(watcher.done)
+	<-watcher.stopped
+	watcher.close()
+	return nil
+
+}
+
+// push queues the event, the watcher overflowing if the queue is full
+func (w *Watcher) push(event Event) {
+	w.mu.Lock()
+	if w.overflowed {
+		w.mu.Unlock()
+		return
+	}
+	if len(w.queue) >= maxQueuedEvents {
+		w.overflowed = true
+		w.queue = nil
+		w.mu.Unlock()
+		log.Warnf("Closing the store watcher %s, %d events not received", w.id, maxQueuedEvents)
+		close(w.overflow)
+		return
+	}
+	w.queue = append(w.queue, event)
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the queued events to the channel in order until the watcher is removed or
+// overflows, its channel being closed when it overflows
+func (w *Watcher) run() {
+	defer close(w.stopped)
+	for {
+		w.mu.Lock()
+		events := w.queue
+		w.queue = nil
+		w.mu.Unlock()
 
+		for _, event := range events {
+			select {
+			case w.ch <- event:
+			case <-w.overflow:
+				w.close()
+				return
+			case <-w.done:
+				return
+			}
+		}
+
+		select {
+		case <-w.wake:
+		case <-w.overflow:
+			w.close()
+			return
+		case <-w.done:
+			return
 		}
 	}
-	ws.watchers = watchers
-	ws.rm.Unlock()
-	return nil
+}
 
+// close closes the channel of the watcher once
+func (w *Watcher) close() {
+	w.closeOnce.Do(func() {
+		close(w.ch)
+	})
 }
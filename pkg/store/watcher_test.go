@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWatcherOrder(t *testing.T) {
+	watchers := NewWatchers()
+	ch := make(chan Event)
+	id := uuid.New()
+	_ = watchers.AddWatcher(id, ch)
+
+	for i := 0; i < 100; i++ {
+		watchers.Send(Event{Key: i})
+	}
+	for i := 0; i < 100; i++ {
+		select {
+		case event := <-ch:
+			if event.Key != i {
+				t.Fatalf("event %v received, want %d", event.Key, i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d not received", i)
+		}
+	}
+
+	_ = watchers.RemoveWatcher(id)
+	if _, ok := <-ch; ok {
+		t.Error("channel of a removed watcher not closed")
+	}
+}
+
+func TestWatcherOverflow(t *testing.T) {
+	max := maxQueuedEvents
+	maxQueuedEvents = 10
+	defer func() {
+		maxQueuedEvents = max
+	}()
+
+	s := NewStore()
+	slow := make(chan Event)
+	fast := make(chan Event, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Watch(ctx, slow); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Watch(ctx, fast); err != nil {
+		t.Fatal(err)
+	}
+
+	// the events are queued for the slow watcher not receiving them until the queue is full
+	for i := 0; i < 20; i++ {
+		_, err := s.Put(ctx, Key{SessionID: "1"}, &SessionValue{Alive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := 0
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-slow:
+			closed = !ok
+			if ok {
+				received++
+			}
+		case <-timeout:
+			t.Fatal("channel of the slow watcher not closed")
+		}
+	}
+	if received > maxQueuedEvents+1 {
+		t.Errorf("%d events received by the slow watcher, at most %d queued", received, maxQueuedEvents)
+	}
+
+	for i := 0; i < 20; i++ {
+		select {
+		case <-fast:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d events received by the fast watcher, want 20", i)
+		}
+	}
+
+	cancel()
+	timeout = time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-fast:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed when the context is done")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var log = logging.GetLogger("tracing")
+
+const (
+	// tracerName is the name of the tracer of the onos-o1t spans
+	tracerName  = "github.com/onosproject/onos-o1t"
+	serviceName = "onos-o1t"
+)
+
+// Attributes of the onos-o1t spans
+const (
+	SessionIDKey    = attribute.Key("netconf.session_id")
+	MessageIDKey    = attribute.Key("netconf.message_id")
+	OperationKey    = attribute.Key("netconf.operation")
+	ErrorTagKey     = attribute.Key("netconf.error_tag")
+	UserKey         = attribute.Key("netconf.user")
+	SSHSessionIDKey = attribute.Key("ssh.session_id")
+	SourceHostKey   = attribute.Key("net.peer.ip")
+	NamespaceKey    = attribute.Key("o1t.namespace")
+	TargetKey       = attribute.Key("o1t.target")
+	TransportKey    = attribute.Key("netconf.transport")
+)
+
+// Config configures the export of the traces
+type Config struct {
+	// Endpoint is the address of the OTLP gRPC collector, empty disables tracing
+	Endpoint string
+	Insecure bool
+	// SampleRatio is the ratio in ]0, 1] of the traces sampled, 1 sampling all of them
+	SampleRatio float64
+}
+
+// ShutdownFunc flushes the pending spans and stops their export
+type ShutdownFunc func(ctx context.Context) error
+
+func init() {
+	// the trace context is propagated in the gRPC metadata of the southbound requests
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Init exports the spans to the OTLP collector of the configuration. Tracing is disabled,
+// with no span recorded, if no endpoint is configured.
+func Init(ctx context.Context, config Config) (ShutdownFunc, error) {
+	if config.Endpoint == "" {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(config.Endpoint),
+	}
+	if config.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Exporting traces to %s", config.Endpoint)
+	return setProvider(sdktrace.WithBatcher(exporter), sdktrace.WithSampler(newSampler(config.SampleRatio))), nil
+}
+
+// newSampler samples the ratio of the traces started by onos-o1t, the spans of a remote
+// parent being sampled as their parent is
+func newSampler(ratio float64) sdktrace.Sampler {
+	sampler := sdktrace.AlwaysSample()
+	if ratio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(ratio)
+	}
+	return sdktrace.ParentBased(sampler)
+}
+
+// InitWithExporter exports all the spans synchronously to the exporter, such as the
+// in-memory exporter of go.opentelemetry.io/otel/sdk/trace/tracetest
+func InitWithExporter(exporter sdktrace.SpanExporter) ShutdownFunc {
+	return setProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sdktrace.AlwaysSample()))
+}
+
+func setProvider(opts ...sdktrace.TracerProviderOption) ShutdownFunc {
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))
+	provider := sdktrace.NewTracerProvider(append(opts, sdktrace.WithResource(res))...)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown
+}
+
+// Start starts a span of onos-o1t
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// SetAttributes sets attributes of the current span of the context
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+// SetError marks the current span of the context as failed
+func SetError(ctx context.Context, description string) {
+	trace.SpanFromContext(ctx).SetStatus(codes.Error, description)
+}
+
+// End ends the span, recording the error if any
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2022-present Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInit(t *testing.T) {
+	shutdown, err := Init(context.Background(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Start(context.Background(), "disabled")
+	if span.IsRecording() {
+		t.Error("span recorded with tracing disabled")
+	}
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown of disabled tracing failed: %v", err)
+	}
+
+	// the exporter connects to the collector lazily, no collector is needed for the spans
+	// to be recorded
+	shutdown, err = Init(context.Background(), Config{Endpoint: "127.0.0.1:4317", Insecure: true, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span = Start(context.Background(), "enabled")
+	if !span.IsRecording() || !span.SpanContext().IsSampled() {
+		t.Error("span not sampled with a sample ratio of 1")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// the export of the span fails without a collector
+	_ = shutdown(ctx)
+}
+
+func TestSampler(t *testing.T) {
+	// TraceIDRatioBased samples the trace ids whose first 8 bytes are below the ratio
+	low := trace.TraceID{7: 1}
+	high := trace.TraceID{0: 0xff, 1: 0xff, 2: 0xff, 3: 0xff, 4: 0xff, 5: 0xff, 6: 0xff, 7: 0xff}
+	sampledParent := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    high,
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+	notSampledParent := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: low,
+		SpanID:  trace.SpanID{1},
+		Remote:  true,
+	}))
+
+	tests := []struct {
+		name    string
+		ratio   float64
+		ctx     context.Context
+		traceID trace.TraceID
+		sampled bool
+	}{
+		{name: "all, low", ratio: 1, ctx: context.Background(), traceID: low, sampled: true},
+		{name: "all, high", ratio: 1, ctx: context.Background(), traceID: high, sampled: true},
+		{name: "half, low", ratio: 0.5, ctx: context.Background(), traceID: low, sampled: true},
+		{name: "half, high", ratio: 0.5, ctx: context.Background(), traceID: high, sampled: false},
+		{name: "sampled parent", ratio: 0.5, ctx: sampledParent, traceID: high, sampled: true},
+		{name: "not sampled parent", ratio: 1, ctx: notSampledParent, traceID: low, sampled: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := newSampler(test.ratio).ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: test.ctx,
+				TraceID:       test.traceID,
+				Name:          "netconf.session",
+			})
+			if sampled := result.Decision == sdktrace.RecordAndSample; sampled != test.sampled {
+				t.Errorf("sampled %t, want %t", sampled, test.sampled)
+			}
+		})
+	}
+}